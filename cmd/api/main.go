@@ -11,13 +11,23 @@ import (
 	"time"
 
 	"github.com/pastorenue/kinance/internal/auth"
+	"github.com/pastorenue/kinance/internal/account"
 	"github.com/pastorenue/kinance/internal/app"
 	"github.com/pastorenue/kinance/internal/budget"
+	"github.com/pastorenue/kinance/internal/calendar"
 	"github.com/pastorenue/kinance/internal/category"
+	"github.com/pastorenue/kinance/internal/duplicate"
 	"github.com/pastorenue/kinance/internal/expense"
+	"github.com/pastorenue/kinance/internal/fx"
+	"github.com/pastorenue/kinance/internal/importer"
 	"github.com/pastorenue/kinance/internal/income"
+	"github.com/pastorenue/kinance/internal/ledger"
+	"github.com/pastorenue/kinance/internal/notification"
 	"github.com/pastorenue/kinance/internal/receipt"
+	"github.com/pastorenue/kinance/internal/reconciliation"
 	"github.com/pastorenue/kinance/internal/repository"
+	"github.com/pastorenue/kinance/internal/scheduler"
+	"github.com/pastorenue/kinance/internal/search"
 	"github.com/pastorenue/kinance/internal/transaction"
 	"github.com/pastorenue/kinance/internal/user"
 	"github.com/pastorenue/kinance/pkg/config"
@@ -38,15 +48,63 @@ func main() {
 		log.Fatal("Failed to connect to database:", err)
 	}
 
+	rateProvider, err := fx.NewProvider(cfg.FX)
+	if err != nil {
+		log.Fatal("Failed to configure exchange rate provider:", err)
+	}
+
 	// Initialize services
+	fxService := fx.NewService(db, rateProvider, cfg.FX, logger)
 	userService := user.NewService(db, logger)
 	authService := auth.NewService(db, cfg.JWT, logger)
-	budgetService := budget.NewService(db, logger)
+	notifier := notification.NewNotifier(db, cfg.Notification, logger)
+	notificationService := notification.NewService(db, logger)
+	budgetService := budget.NewService(db, fxService, notifier, logger)
 	receiptService := receipt.NewService(db, cfg.AI, logger)
-	expenseService := expense.NewService(db, logger)
+	accountService := account.NewService(db, fxService, logger)
+	ledgerService := ledger.NewService(db, logger)
+	searchService := search.NewService(db, logger)
+	expenseService := expense.NewService(db, fxService, logger)
 	categoryService := category.NewService(db, logger)
-	transactionService := transaction.NewService(db, logger)
+	transactionService := transaction.NewService(db, fxService, logger)
 	incomeService := income.NewService(db, logger)
+	calendarService := calendar.NewService(db, expenseService, logger)
+	importService := importer.NewService(db, transactionService, logger)
+	duplicateService := duplicate.NewService(db, transactionService, expenseService, logger)
+	reconciliationService := reconciliation.NewService(db, logger)
+
+	// Evaluate budget alerts whenever spending is recorded
+	expenseService.AddListener(budgetService.ExpenseCreated)
+	transactionService.AddListener(budgetService.TransactionCreated)
+
+	// Initialize background jobs
+	schedulerService := scheduler.NewService(db, cfg.Scheduler, logger)
+	processRecurringExpenses := expenseService.ProcessRecurringExpenses
+	if cfg.Scheduler.RecurringExpenseBackfill {
+		processRecurringExpenses = expenseService.BackfillRecurringExpenses
+	}
+	schedulerService.Register(scheduler.Job{
+		Name:     "recurring_expenses",
+		Interval: time.Duration(cfg.Scheduler.RecurringExpenseInterval) * time.Second,
+		Run:      processRecurringExpenses,
+	})
+	schedulerService.Register(scheduler.Job{
+		Name:     "budget_periods",
+		Interval: time.Duration(cfg.Scheduler.BudgetPeriodInterval) * time.Second,
+		Run:      budgetService.CloseBudgetPeriods,
+	})
+	if rateProvider != nil {
+		schedulerService.Register(scheduler.Job{
+			Name:     "exchange_rates",
+			Interval: time.Duration(cfg.Scheduler.ExchangeRateInterval) * time.Second,
+			Run:      fxService.SyncRates,
+		})
+	}
+	if cfg.Scheduler.Enabled {
+		if err := schedulerService.Start(context.Background()); err != nil {
+			log.Fatal("Failed to start scheduler:", err)
+		}
+	}
 
 	// Initialize OAuth and token repository
 	redisAddr := fmt.Sprintf("%s:%d", cfg.Redis.Host, cfg.Redis.Port)
@@ -67,6 +125,16 @@ func main() {
 		expenseService,
 		categoryService,
 		incomeService,
+		schedulerService,
+		calendarService,
+		notificationService,
+		fxService,
+		accountService,
+		ledgerService,
+		searchService,
+		importService,
+		duplicateService,
+		reconciliationService,
 		oauthHandler,
 		googleHandler,
 		authHandler,
@@ -99,5 +167,9 @@ func main() {
 		logger.Fatal("Server forced to shutdown:", err)
 	}
 
+	if err := schedulerService.Stop(ctx); err != nil {
+		logger.Error("Scheduler forced to stop", "error", err)
+	}
+
 	logger.Info("Server exited")
 }
@@ -0,0 +1,202 @@
+package account
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/pastorenue/kinance/internal/common"
+	"github.com/pastorenue/kinance/pkg/middleware"
+	"github.com/pastorenue/kinance/pkg/pagination"
+)
+
+// defaultHistoryDays is the range of the balance history when no from date is given.
+const defaultHistoryDays = 90
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) CreateAccount(c *gin.Context) {
+	userID, _ := c.Get(middleware.UserIDKey)
+
+	var req CreateAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeBadRequest(c, err.Error())
+		return
+	}
+
+	account, err := h.service.CreateAccount(c.Request.Context(), userID.(uuid.UUID), &req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, common.APIResponse{
+		Success:    true,
+		StatusCode: http.StatusCreated,
+		Data:       account,
+	})
+}
+
+func (h *Handler) GetAccounts(c *gin.Context) {
+	userID, _ := c.Get(middleware.UserIDKey)
+	includeArchived := c.Query("include_archived") == "true"
+
+	var params pagination.Params
+	if err := c.ShouldBindQuery(&params); err != nil {
+		writeBadRequest(c, err.Error())
+		return
+	}
+
+	accounts, err := h.service.GetAccounts(c.Request.Context(), userID.(uuid.UUID), includeArchived, params)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, common.APIResponse{
+		Success:    true,
+		StatusCode: http.StatusOK,
+		Data:       accounts,
+	})
+}
+
+func (h *Handler) GetAccount(c *gin.Context) {
+	userID, _ := c.Get(middleware.UserIDKey)
+	accountID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		writeBadRequest(c, "Invalid account ID")
+		return
+	}
+
+	account, err := h.service.GetAccount(c.Request.Context(), userID.(uuid.UUID), accountID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, common.APIResponse{
+		Success:    true,
+		StatusCode: http.StatusOK,
+		Data:       account,
+	})
+}
+
+func (h *Handler) UpdateAccount(c *gin.Context) {
+	userID, _ := c.Get(middleware.UserIDKey)
+	accountID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		writeBadRequest(c, "Invalid account ID")
+		return
+	}
+
+	var req UpdateAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeBadRequest(c, err.Error())
+		return
+	}
+
+	account, err := h.service.UpdateAccount(c.Request.Context(), userID.(uuid.UUID), accountID, &req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, common.APIResponse{
+		Success:    true,
+		StatusCode: http.StatusOK,
+		Data:       account,
+	})
+}
+
+func (h *Handler) DeleteAccount(c *gin.Context) {
+	userID, _ := c.Get(middleware.UserIDKey)
+	accountID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		writeBadRequest(c, "Invalid account ID")
+		return
+	}
+
+	if err := h.service.DeleteAccount(c.Request.Context(), userID.(uuid.UUID), accountID); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, common.APIResponse{
+		Success:    true,
+		StatusCode: http.StatusOK,
+		Message:    "Account deleted successfully",
+	})
+}
+
+func (h *Handler) GetBalanceHistory(c *gin.Context) {
+	userID, _ := c.Get(middleware.UserIDKey)
+	accountID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		writeBadRequest(c, "Invalid account ID")
+		return
+	}
+
+	to := time.Now()
+	if value := c.Query("to"); value != "" {
+		if to, err = time.Parse(dateLayout, value); err != nil {
+			writeBadRequest(c, "Invalid to parameter, expected YYYY-MM-DD")
+			return
+		}
+	}
+	from := to.AddDate(0, 0, -defaultHistoryDays)
+	if value := c.Query("from"); value != "" {
+		if from, err = time.Parse(dateLayout, value); err != nil {
+			writeBadRequest(c, "Invalid from parameter, expected YYYY-MM-DD")
+			return
+		}
+	}
+	if from.After(to) {
+		writeBadRequest(c, "from must not be after to")
+		return
+	}
+
+	history, err := h.service.GetBalanceHistory(c.Request.Context(), userID.(uuid.UUID), accountID, from, to)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, common.APIResponse{
+		Success:    true,
+		StatusCode: http.StatusOK,
+		Data:       history,
+	})
+}
+
+func writeBadRequest(c *gin.Context, message string) {
+	c.JSON(http.StatusBadRequest, common.APIResponse{
+		Success:    false,
+		StatusCode: http.StatusBadRequest,
+		Error:      message,
+	})
+}
+
+func writeError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, pagination.ErrInvalid):
+		status = http.StatusBadRequest
+	case errors.Is(err, ErrAccountNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrAccountInUse), errors.Is(err, ErrNoFamily):
+		status = http.StatusConflict
+	}
+	c.JSON(status, common.APIResponse{
+		Success:    false,
+		StatusCode: status,
+		Error:      err.Error(),
+	})
+}
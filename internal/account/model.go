@@ -0,0 +1,77 @@
+package account
+
+import (
+	"github.com/google/uuid"
+	"github.com/pastorenue/kinance/internal/common"
+	"github.com/shopspring/decimal"
+)
+
+type AccountType string
+
+const (
+	TypeChecking   AccountType = "checking"
+	TypeSavings    AccountType = "savings"
+	TypeCreditCard AccountType = "credit_card"
+	TypeCash       AccountType = "cash"
+	TypeLoan       AccountType = "loan"
+	TypeInvestment AccountType = "investment"
+)
+
+// Account is where money is held or owed, such as a bank account, a credit card or a cash wallet.
+// Its balance is never stored: it is the opening balance plus the journal postings to the account
+// for its transactions, expenses, incomes and transfers. Credit cards and loans usually have a
+// negative balance.
+type Account struct {
+	common.BaseModel
+	UserID         uuid.UUID       `json:"user_id" gorm:"not null;index"`
+	FamilyID       *uuid.UUID      `json:"family_id" gorm:"index"` // Set when the account is shared with the owner's family
+	Name           string          `json:"name" gorm:"not null"`
+	Type           AccountType     `json:"type" gorm:"type:varchar(20);not null"`
+	Institution    string          `json:"institution"`
+	Number         string          `json:"number" gorm:"type:varchar(34)"` // IBAN, account or card number as shown on statements
+	Currency       common.Currency `json:"currency" gorm:"type:varchar(3);not null;default:EUR"`
+	OpeningBalance decimal.Decimal `json:"opening_balance" gorm:"type:decimal(20,4);not null;default:0"`
+	IsArchived     bool            `json:"is_archived" gorm:"default:false"`
+}
+
+type CreateAccountRequest struct {
+	Name           string          `json:"name" binding:"required"`
+	Type           AccountType     `json:"type" binding:"required,oneof=checking savings credit_card cash loan investment"`
+	Institution    string          `json:"institution"`
+	Number         string          `json:"number" binding:"omitempty,max=34"`
+	Currency       common.Currency `json:"currency" binding:"omitempty,currency"` // Defaults to EUR
+	OpeningBalance decimal.Decimal `json:"opening_balance"`
+	Shared         bool            `json:"shared"` // Share with the family of the owner
+}
+
+type UpdateAccountRequest struct {
+	Name           *string          `json:"name"`
+	Institution    *string          `json:"institution"`
+	Number         *string          `json:"number" binding:"omitempty,max=34"`
+	OpeningBalance *decimal.Decimal `json:"opening_balance"` // In the currency of the account
+	Shared         *bool            `json:"shared"`
+	IsArchived     *bool            `json:"is_archived"`
+}
+
+type AccountResponse struct {
+	Account
+	Balance      *common.Money  `json:"balance"`                 // In the currency of the account; null while an exchange rate is missing
+	Balances     []common.Money `json:"balances"`                // Per currency of the activity, unconverted; the account currency comes first
+	BalanceError string         `json:"balance_error,omitempty"` // Why Balance is null
+}
+
+// BalancePoint is the balance of an account at the end of a day with activity.
+type BalancePoint struct {
+	Date    string       `json:"date"`
+	Change  common.Money `json:"change"`
+	Balance common.Money `json:"balance"`
+}
+
+type BalanceHistoryResponse struct {
+	AccountID uuid.UUID      `json:"account_id"`
+	From      string         `json:"from"`
+	To        string         `json:"to"`
+	Opening   common.Money   `json:"opening"` // Balance at the start of From
+	Closing   common.Money   `json:"closing"` // Balance at the end of To
+	Points    []BalancePoint `json:"points"`
+}
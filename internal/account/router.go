@@ -0,0 +1,14 @@
+package account
+
+import "github.com/gin-gonic/gin"
+
+func RegisterRoutes(versionedGroup *gin.RouterGroup, svc *Service) {
+	accountHandler := NewHandler(svc)
+	protected := versionedGroup.Group("/accounts")
+	protected.GET("/", accountHandler.GetAccounts)
+	protected.POST("/", accountHandler.CreateAccount)
+	protected.GET("/:id", accountHandler.GetAccount)
+	protected.PUT("/:id", accountHandler.UpdateAccount)
+	protected.DELETE("/:id", accountHandler.DeleteAccount)
+	protected.GET("/:id/balance-history", accountHandler.GetBalanceHistory)
+}
@@ -0,0 +1,321 @@
+package account
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pastorenue/kinance/internal/common"
+	"github.com/pastorenue/kinance/internal/fx"
+	"github.com/pastorenue/kinance/internal/ledger"
+	"github.com/pastorenue/kinance/pkg/pagination"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+const dateLayout = "2006-01-02"
+
+var (
+	ErrAccountNotFound = errors.New("account not found")
+	ErrAccountInUse    = errors.New("account has transactions, archive it instead")
+	ErrNoFamily        = errors.New("only users in a family can share accounts")
+)
+
+type Service struct {
+	db     *gorm.DB
+	rates  *fx.Service
+	logger common.Logger
+}
+
+func NewService(db *gorm.DB, rates *fx.Service, logger common.Logger) *Service {
+	return &Service{
+		db:     db,
+		rates:  rates,
+		logger: logger,
+	}
+}
+
+func (s *Service) CreateAccount(ctx context.Context, userID uuid.UUID, req *CreateAccountRequest) (*AccountResponse, error) {
+	currency := req.Currency.OrDefault()
+	account := &Account{
+		UserID:         userID,
+		Name:           req.Name,
+		Type:           req.Type,
+		Institution:    req.Institution,
+		Number:         req.Number,
+		Currency:       currency,
+		OpeningBalance: common.NewMoney(req.OpeningBalance, currency).Amount,
+	}
+	if req.Shared {
+		familyID, err := s.familyOf(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		account.FamilyID = familyID
+	}
+
+	if err := s.db.WithContext(ctx).Create(account).Error; err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Account created successfully", "account_id", account.ID, "user_id", userID)
+	return s.GetAccount(ctx, userID, account.ID)
+}
+
+// accountPages are the orders accounts can be listed in, alphabetical by default.
+var accountPages = pagination.Spec[Account]{
+	Table: "accounts",
+	Sorts: map[string]pagination.Column[Account]{
+		"name":       {Expr: "accounts.name", Value: func(a *Account) any { return a.Name }},
+		"created_at": {Expr: "accounts.created_at", Value: func(a *Account) any { return a.CreatedAt }},
+	},
+	Default: "name",
+	Order:   pagination.Asc,
+	ID:      func(a *Account) uuid.UUID { return a.ID },
+}
+
+// GetAccounts returns the accounts of the user and the accounts shared with their family.
+func (s *Service) GetAccounts(ctx context.Context, userID uuid.UUID, includeArchived bool, params pagination.Params) (*pagination.Page[AccountResponse], error) {
+	query := s.db.WithContext(ctx).Where("id IN (?)", AccessibleIDs(s.db, userID))
+	if !includeArchived {
+		query = query.Where("is_archived = ?", false)
+	}
+
+	page, err := pagination.Paginate(query, accountPages, params)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]AccountResponse, 0, len(page.Data))
+	for i := range page.Data {
+		response, err := s.response(ctx, &page.Data[i])
+		if err != nil {
+			return nil, err
+		}
+		responses = append(responses, *response)
+	}
+	return pagination.WithData(page, responses), nil
+}
+
+func (s *Service) GetAccount(ctx context.Context, userID, accountID uuid.UUID) (*AccountResponse, error) {
+	account, err := Lookup(ctx, s.db, userID, accountID)
+	if err != nil {
+		return nil, err
+	}
+	return s.response(ctx, account)
+}
+
+func (s *Service) UpdateAccount(ctx context.Context, userID, accountID uuid.UUID, req *UpdateAccountRequest) (*AccountResponse, error) {
+	account, err := Lookup(ctx, s.db, userID, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		account.Name = *req.Name
+	}
+	if req.Institution != nil {
+		account.Institution = *req.Institution
+	}
+	if req.Number != nil {
+		account.Number = *req.Number
+	}
+	if req.OpeningBalance != nil {
+		account.OpeningBalance = common.NewMoney(*req.OpeningBalance, account.Currency).Amount
+	}
+	if req.Shared != nil {
+		if *req.Shared {
+			familyID, err := s.familyOf(ctx, account.UserID)
+			if err != nil {
+				return nil, err
+			}
+			account.FamilyID = familyID
+		} else {
+			account.FamilyID = nil
+		}
+	}
+	if req.IsArchived != nil {
+		account.IsArchived = *req.IsArchived
+	}
+
+	if err := s.db.WithContext(ctx).Save(account).Error; err != nil {
+		return nil, err
+	}
+	return s.GetAccount(ctx, userID, account.ID)
+}
+
+// DeleteAccount deletes an account without activity. Accounts that have transactions, expenses or
+// incomes must be archived instead, so that their history keeps its account.
+func (s *Service) DeleteAccount(ctx context.Context, userID, accountID uuid.UUID) error {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var linked int64
+		if err := tx.Raw(`
+			SELECT (SELECT COUNT(*) FROM transactions WHERE account_id = ?) +
+			       (SELECT COUNT(*) FROM expenses WHERE account_id = ?) +
+			       (SELECT COUNT(*) FROM incomes WHERE account_id = ?)`, accountID, accountID, accountID).
+			Scan(&linked).Error; err != nil {
+			return err
+		}
+		if linked > 0 {
+			return ErrAccountInUse
+		}
+
+		result := tx.Where("id = ? AND user_id = ?", accountID, userID).Delete(&Account{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrAccountNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info("Account deleted successfully", "account_id", accountID, "user_id", userID)
+	return nil
+}
+
+// GetBalanceHistory returns the balance of the account at the start of from and at the end of
+// every day with activity up to and including to.
+func (s *Service) GetBalanceHistory(ctx context.Context, userID, accountID uuid.UUID, from, to time.Time) (*BalanceHistoryResponse, error) {
+	account, err := Lookup(ctx, s.db, userID, accountID)
+	if err != nil {
+		return nil, err
+	}
+	from, to = dateOf(from), dateOf(to)
+	end := to.AddDate(0, 0, 1)
+
+	days, err := s.activity(ctx, account.ID, end)
+	if err != nil {
+		return nil, err
+	}
+
+	converter := s.rates.NewConverter(account.Currency)
+	balance := account.OpeningBalance
+	response := &BalanceHistoryResponse{
+		AccountID: account.ID,
+		From:      from.Format(dateLayout),
+		To:        to.Format(dateLayout),
+		Points:    []BalancePoint{},
+	}
+	opened := false
+	for i := 0; i < len(days); {
+		day := dateOf(days[i].Day)
+		if !opened && !day.Before(from) {
+			response.Opening = common.NewMoney(balance, account.Currency)
+			opened = true
+		}
+
+		// Rows are ordered by day, so the rows of one day in different currencies are adjacent
+		change := decimal.Zero
+		for ; i < len(days) && dateOf(days[i].Day).Equal(day); i++ {
+			converted, err := converter.Convert(ctx, days[i].Total, days[i].Currency, day)
+			if err != nil {
+				return nil, err
+			}
+			change = change.Add(converted)
+		}
+		balance = balance.Add(change)
+
+		if opened {
+			response.Points = append(response.Points, BalancePoint{
+				Date:    day.Format(dateLayout),
+				Change:  common.NewMoney(change, account.Currency),
+				Balance: common.NewMoney(balance, account.Currency),
+			})
+		}
+	}
+	if !opened {
+		response.Opening = common.NewMoney(balance, account.Currency)
+	}
+	response.Closing = common.NewMoney(balance, account.Currency)
+	return response, nil
+}
+
+// response returns the account with its current balance. Activity in a currency without a stored
+// exchange rate leaves the converted balance out rather than failing, since rates are optional.
+func (s *Service) response(ctx context.Context, account *Account) (*AccountResponse, error) {
+	days, err := s.activity(ctx, account.ID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	response := &AccountResponse{Account: *account, Balances: nativeBalances(account, days)}
+	balance, err := s.convert(ctx, account, days)
+	switch {
+	case err == nil:
+		response.Balance = &balance
+	case errors.Is(err, fx.ErrRateNotFound):
+		response.BalanceError = err.Error()
+	default:
+		return nil, err
+	}
+	return response, nil
+}
+
+// convert adds the activity to the opening balance in the account's currency, converting each day
+// at its own rate.
+func (s *Service) convert(ctx context.Context, account *Account, days []ledger.DailyTotal) (common.Money, error) {
+	converter := s.rates.NewConverter(account.Currency)
+	balance := account.OpeningBalance
+	for _, day := range days {
+		converted, err := converter.Convert(ctx, day.Total, day.Currency, day.Day)
+		if err != nil {
+			return common.Money{}, err
+		}
+		balance = balance.Add(converted)
+	}
+	return common.NewMoney(balance, account.Currency), nil
+}
+
+// nativeBalances adds up the opening balance and the activity per currency without converting.
+func nativeBalances(account *Account, days []ledger.DailyTotal) []common.Money {
+	sums := map[common.Currency]decimal.Decimal{account.Currency: account.OpeningBalance}
+	currencies := []common.Currency{account.Currency}
+	for _, day := range days {
+		if _, ok := sums[day.Currency]; !ok {
+			currencies = append(currencies, day.Currency)
+		}
+		sums[day.Currency] = sums[day.Currency].Add(day.Total)
+	}
+	others := currencies[1:]
+	sort.Slice(others, func(i, j int) bool { return others[i] < others[j] })
+
+	balances := make([]common.Money, len(currencies))
+	for i, currency := range currencies {
+		balances[i] = common.NewMoney(sums[currency], currency)
+	}
+	return balances
+}
+
+// activity returns the daily net change of the account's balance before until, ordered by day,
+// from the journal: every expense, income, transfer and transaction on the account is posted
+// there, and linked records are posted once.
+func (s *Service) activity(ctx context.Context, accountID uuid.UUID, until time.Time) ([]ledger.DailyTotal, error) {
+	return ledger.AccountActivity(s.db.WithContext(ctx), accountID, until)
+}
+
+func (s *Service) familyOf(ctx context.Context, userID uuid.UUID) (*uuid.UUID, error) {
+	var familyID uuid.NullUUID
+	err := s.db.WithContext(ctx).Table("users").
+		Select("family_id").
+		Where("id = ?", userID).
+		Row().Scan(&familyID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if !familyID.Valid {
+		return nil, ErrNoFamily
+	}
+	return &familyID.UUID, nil
+}
+
+func dateOf(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
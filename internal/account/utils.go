@@ -0,0 +1,46 @@
+package account
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AccessibleIDs returns a subquery selecting the accounts the user owns or shares through their
+// family, for use as "account_id IN (?)".
+func AccessibleIDs(db *gorm.DB, userID uuid.UUID) *gorm.DB {
+	return db.Raw(`
+		SELECT id FROM accounts
+		WHERE user_id = ?
+		   OR family_id IN (SELECT family_id FROM users WHERE id = ? AND family_id IS NOT NULL)`, userID, userID)
+}
+
+// CheckAccess returns ErrAccountNotFound unless the user may record activity on the account.
+func CheckAccess(ctx context.Context, db *gorm.DB, userID, accountID uuid.UUID) error {
+	var count int64
+	if err := db.WithContext(ctx).Model(&Account{}).
+		Where("id = ? AND id IN (?)", accountID, AccessibleIDs(db, userID)).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrAccountNotFound
+	}
+	return nil
+}
+
+// Lookup returns the account if the user owns it or shares it through their family.
+func Lookup(ctx context.Context, db *gorm.DB, userID, accountID uuid.UUID) (*Account, error) {
+	var account Account
+	if err := db.WithContext(ctx).
+		Where("id = ? AND id IN (?)", accountID, AccessibleIDs(db, userID)).
+		First(&account).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAccountNotFound
+		}
+		return nil, err
+	}
+	return &account, nil
+}
@@ -5,13 +5,23 @@ import (
 
 	"github.com/gin-gonic/gin"
 	redoc "github.com/mvrilo/go-redoc"
+	"github.com/pastorenue/kinance/internal/account"
 	"github.com/pastorenue/kinance/internal/auth"
 	"github.com/pastorenue/kinance/internal/budget"
+	"github.com/pastorenue/kinance/internal/calendar"
 	"github.com/pastorenue/kinance/internal/category"
+	"github.com/pastorenue/kinance/internal/duplicate"
 	"github.com/pastorenue/kinance/internal/expense"
+	"github.com/pastorenue/kinance/internal/fx"
+	"github.com/pastorenue/kinance/internal/importer"
 	"github.com/pastorenue/kinance/internal/income"
+	"github.com/pastorenue/kinance/internal/ledger"
+	"github.com/pastorenue/kinance/internal/notification"
 	"github.com/pastorenue/kinance/internal/receipt"
+	"github.com/pastorenue/kinance/internal/reconciliation"
 	"github.com/pastorenue/kinance/internal/repository"
+	"github.com/pastorenue/kinance/internal/scheduler"
+	"github.com/pastorenue/kinance/internal/search"
 	"github.com/pastorenue/kinance/internal/transaction"
 	"github.com/pastorenue/kinance/internal/user"
 	"github.com/pastorenue/kinance/pkg/config"
@@ -28,11 +38,23 @@ func SetupRouter(
 	expenseSvc *expense.Service,
 	categorySvc *category.Service,
 	incomeSvc *income.Service,
+	schedulerSvc *scheduler.Service,
+	calendarSvc *calendar.Service,
+	notificationSvc *notification.Service,
+	fxSvc *fx.Service,
+	accountSvc *account.Service,
+	ledgerSvc *ledger.Service,
+	searchSvc *search.Service,
+	importSvc *importer.Service,
+	duplicateSvc *duplicate.Service,
+	reconciliationSvc *reconciliation.Service,
 	oauthHandler *auth.OAuthHandler,
 	googleHandler *auth.GoogleHandler,
 	authHandler *auth.Handler,
 	tokenRepo *repository.TokenRepository,
 ) *gin.Engine {
+	registerValidators()
+
 	router := gin.New()
 
 	// Global middleware
@@ -71,6 +93,9 @@ func SetupRouter(
 			cfg,
 		)
 
+		// Calendar feeds authenticate with their own token since calendar apps cannot send bearer headers
+		calendar.RegisterFeedRoutes(v1, calendarSvc)
+
 		// Protected routes
 		protected := v1.Group("/")
 		protected.Use(middleware.AuthRequired(func(token string) (any, error) {
@@ -84,6 +109,16 @@ func SetupRouter(
 			expense.RegisterRoutes(protected, expenseSvc)
 			category.RegisterRoutes(protected, categorySvc)
 			income.RegisterRoutes(protected, incomeSvc)
+			scheduler.RegisterRoutes(protected, schedulerSvc)
+			calendar.RegisterRoutes(protected, calendarSvc)
+			notification.RegisterRoutes(protected, notificationSvc)
+			fx.RegisterRoutes(protected, fxSvc)
+			account.RegisterRoutes(protected, accountSvc)
+			ledger.RegisterRoutes(protected, ledgerSvc)
+			search.RegisterRoutes(protected, searchSvc)
+			importer.RegisterRoutes(protected, importSvc)
+			duplicate.RegisterRoutes(protected, duplicateSvc)
+			reconciliation.RegisterRoutes(protected, reconciliationSvc)
 		}
 	}
 
@@ -0,0 +1,19 @@
+package app
+
+import (
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/pastorenue/kinance/internal/common"
+)
+
+// registerValidators adds the application's custom validation tags to gin's validator.
+func registerValidators() {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	// currency accepts ISO 4217 codes from the common catalogue, e.g. "EUR"
+	_ = v.RegisterValidation("currency", func(fl validator.FieldLevel) bool {
+		return common.Currency(fl.Field().String()).IsValid()
+	})
+}
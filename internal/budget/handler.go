@@ -1,12 +1,17 @@
 package budget
 
 import (
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/pastorenue/kinance/internal/category"
 	"github.com/pastorenue/kinance/internal/common"
+	"github.com/pastorenue/kinance/internal/fx"
 	"github.com/pastorenue/kinance/pkg/middleware"
+	"github.com/pastorenue/kinance/pkg/pagination"
 )
 
 type Handler struct {
@@ -33,7 +38,7 @@ func (h *Handler) CreateBudget(c *gin.Context) {
 
 	budget, err := h.service.CreateBudget(c.Request.Context(), userID.(uuid.UUID), &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, common.APIResponse{
+		c.JSON(budgetErrorStatus(err), common.APIResponse{
 			Success: false,
 			Error:   err.Error(),
 		})
@@ -49,14 +54,27 @@ func (h *Handler) CreateBudget(c *gin.Context) {
 func (h *Handler) GetBudgets(c *gin.Context) {
 	userID, _ := c.Get(middleware.UserIDKey)
 
-	var pagination common.PaginationParams
-	if err := c.ShouldBindQuery(&pagination); err != nil {
-		pagination = common.PaginationParams{Page: 1, PageSize: 20}
+	var params pagination.Params
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, common.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
 	}
 
-	result, err := h.service.GetBudgets(c.Request.Context(), userID.(uuid.UUID), &pagination)
+	filter, err := common.ParseFilter(c.Query("filter"), BudgetFilters)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, common.APIResponse{
+		c.JSON(http.StatusBadRequest, common.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	result, err := h.service.GetBudgets(c.Request.Context(), userID.(uuid.UUID), filter, params)
+	if err != nil {
+		c.JSON(pagination.HTTPStatus(err), common.APIResponse{
 			Success: false,
 			Error:   err.Error(),
 		})
@@ -117,7 +135,7 @@ func (h *Handler) UpdateBudget(c *gin.Context) {
 
 	budget, err := h.service.UpdateBudget(c.Request.Context(), userID.(uuid.UUID), budgetID, &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, common.APIResponse{
+		c.JSON(budgetErrorStatus(err), common.APIResponse{
 			Success: false,
 			Error:   err.Error(),
 		})
@@ -154,3 +172,162 @@ func (h *Handler) DeleteBudget(c *gin.Context) {
 		Message: "Budget deleted successfully",
 	})
 }
+
+func (h *Handler) GetBudgetPeriods(c *gin.Context) {
+	userID, _ := c.Get(middleware.UserIDKey)
+	budgetID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.APIResponse{
+			Success: false,
+			Error:   "Invalid budget ID",
+		})
+		return
+	}
+
+	var params pagination.Params
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, common.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	periods, err := h.service.GetBudgetPeriods(c.Request.Context(), userID.(uuid.UUID), budgetID, params)
+	if err != nil {
+		c.JSON(pagination.HTTPStatus(err), common.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, common.APIResponse{
+		Success: true,
+		Data:    periods,
+	})
+}
+
+func (h *Handler) GetEnvelopeSummary(c *gin.Context) {
+	userID, _ := c.Get(middleware.UserIDKey)
+
+	month := time.Now()
+	if value := c.Query("month"); value != "" {
+		parsed, err := time.Parse("2006-01", value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, common.APIResponse{
+				Success: false,
+				Error:   "Invalid month, expected YYYY-MM",
+			})
+			return
+		}
+		month = parsed
+	}
+
+	summary, err := h.service.GetEnvelopeSummary(c.Request.Context(), userID.(uuid.UUID), month)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, common.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, common.APIResponse{
+		Success: true,
+		Data:    summary,
+	})
+}
+
+func (h *Handler) AssignToEnvelope(c *gin.Context) {
+	userID, _ := c.Get(middleware.UserIDKey)
+
+	var req AssignEnvelopeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	summary, err := h.service.AssignToEnvelope(c.Request.Context(), userID.(uuid.UUID), &req)
+	if err != nil {
+		c.JSON(budgetErrorStatus(err), common.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, common.APIResponse{
+		Success: true,
+		Data:    summary,
+	})
+}
+
+func (h *Handler) MoveBetweenEnvelopes(c *gin.Context) {
+	userID, _ := c.Get(middleware.UserIDKey)
+
+	var req MoveEnvelopeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	summary, err := h.service.MoveBetweenEnvelopes(c.Request.Context(), userID.(uuid.UUID), &req)
+	if err != nil {
+		c.JSON(budgetErrorStatus(err), common.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, common.APIResponse{
+		Success: true,
+		Data:    summary,
+	})
+}
+
+func (h *Handler) CoverOverspending(c *gin.Context) {
+	userID, _ := c.Get(middleware.UserIDKey)
+
+	var req CoverOverspendingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	summary, err := h.service.CoverOverspending(c.Request.Context(), userID.(uuid.UUID), &req)
+	if err != nil {
+		c.JSON(budgetErrorStatus(err), common.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, common.APIResponse{
+		Success: true,
+		Data:    summary,
+	})
+}
+
+func budgetErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, category.ErrCategoryNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, fx.ErrRateNotFound):
+		// The amounts the change depends on cannot be converted until the rate is stored
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
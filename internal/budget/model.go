@@ -1,22 +1,61 @@
 package budget
 
 import (
+	"time"
+
 	"github.com/google/uuid"
+	"github.com/pastorenue/kinance/internal/category"
 	"github.com/pastorenue/kinance/internal/common"
+	"github.com/pastorenue/kinance/pkg/pagination"
+	"github.com/shopspring/decimal"
 )
 
 type Budget struct {
 	common.BaseModel
-	UserID         uuid.UUID  `json:"user_id" gorm:"not null;index"`
-	FamilyID       *uuid.UUID `json:"family_id" gorm:"index"`
-	Name           string     `json:"name" gorm:"not null"`
-	Description    string     `json:"description"`
-	Amount         float64    `json:"amount" gorm:"not null"`
-	Spent          float64    `json:"spent" gorm:"default:0"`
-	Category       string     `json:"category" gorm:"not null"`
-	Period         Period     `json:"period" gorm:"default:monthly"`
-	IsActive       bool       `json:"is_active" gorm:"default:true"`
-	AlertThreshold float64    `json:"alert_threshold" gorm:"default:80"` // Alert when 80% spent
+	UserID         uuid.UUID          `json:"user_id" gorm:"not null;index"`
+	FamilyID       *uuid.UUID         `json:"family_id" gorm:"index"`
+	Name           string             `json:"name" gorm:"not null"`
+	Description    string             `json:"description"`
+	Amount         common.Money       `json:"amount" gorm:"embedded"`
+	CategoryID     uuid.UUID          `json:"category_id" gorm:"type:uuid;index"` // Spending in subcategories counts too
+	Category       *category.Category `json:"category" gorm:"foreignKey:CategoryID"`
+	Mode           Mode               `json:"mode" gorm:"type:varchar(20);default:standard"`
+	Period         Period             `json:"period" gorm:"default:monthly"`
+	AnchorDay      int                `json:"anchor_day" gorm:"default:1"`   // Day of month (monthly, yearly) or ISO weekday (weekly) a period starts on
+	AnchorMonth    int                `json:"anchor_month" gorm:"default:1"` // Month a yearly period starts in
+	Rollover       RolloverMode       `json:"rollover" gorm:"type:varchar(20);default:none"`
+	IsActive       bool               `json:"is_active" gorm:"default:true"`
+	AlertThreshold float64            `json:"alert_threshold" gorm:"default:80"` // Alert when 80% spent
+}
+
+type Mode string
+
+const (
+	ModeStandard Mode = "standard"
+	ModeEnvelope Mode = "envelope" // Zero-based: money is assigned to the budget from income each month
+)
+
+type RolloverMode string
+
+const (
+	RolloverNone    RolloverMode = "none"
+	RolloverUnspent RolloverMode = "unspent" // Carry only what was left over
+	RolloverAll     RolloverMode = "all"     // Carry leftovers and overspending
+)
+
+// BudgetPeriod is the snapshot of a closed budget period. The current period is never
+// stored; it is computed on read and snapshotted once it has ended.
+type BudgetPeriod struct {
+	common.BaseModel
+	BudgetID    uuid.UUID    `json:"budget_id" gorm:"type:uuid;not null;uniqueIndex:idx_budget_period_start"`
+	StartDate   time.Time    `json:"start_date" gorm:"not null;uniqueIndex:idx_budget_period_start"`
+	EndDate     time.Time    `json:"end_date" gorm:"not null"` // Exclusive
+	Budgeted    common.Money `json:"budgeted" gorm:"embedded;embeddedPrefix:budgeted_"`
+	RolloverIn  common.Money `json:"rollover_in" gorm:"embedded;embeddedPrefix:rollover_in_"`
+	Spent       common.Money `json:"spent" gorm:"embedded;embeddedPrefix:spent_"`
+	Remaining   common.Money `json:"remaining" gorm:"embedded;embeddedPrefix:remaining_"`
+	RolloverOut common.Money `json:"rollover_out" gorm:"embedded;embeddedPrefix:rollover_out_"`
+	ClosedAt    *time.Time   `json:"closed_at"`
 }
 
 type Period string
@@ -28,20 +67,142 @@ const (
 )
 
 type CreateBudgetRequest struct {
-	Name           string  `json:"name" binding:"required"`
-	Description    string  `json:"description"`
-	Amount         float64 `json:"amount" binding:"required,gt=0"`
-	Category       string  `json:"category" binding:"required"`
-	Period         Period  `json:"period" binding:"required"`
-	AlertThreshold float64 `json:"alert_threshold" binding:"min=0,max=100"`
+	Name           string          `json:"name" binding:"required"`
+	Description    string          `json:"description"`
+	Amount         decimal.Decimal `json:"amount" binding:"required"`
+	Currency       common.Currency `json:"currency" binding:"omitempty,currency"` // Defaults to EUR
+	CategoryID     uuid.UUID       `json:"category_id" binding:"required"`
+	Mode           Mode            `json:"mode" binding:"omitempty,oneof=standard envelope"`
+	Period         Period          `json:"period" binding:"omitempty,oneof=weekly monthly yearly"` // Required for standard budgets
+	AnchorDay      int             `json:"anchor_day" binding:"omitempty,min=1,max=31"`
+	AnchorMonth    int             `json:"anchor_month" binding:"omitempty,min=1,max=12"`
+	Rollover       RolloverMode    `json:"rollover" binding:"omitempty,oneof=none unspent all"`
+	AlertThreshold float64         `json:"alert_threshold" binding:"min=0,max=100"`
 }
 
 type UpdateBudgetRequest struct {
-	Name           string  `json:"name"`
-	Description    string  `json:"description"`
-	Amount         float64 `json:"amount" binding:"gt=0"`
-	Category       string  `json:"category"`
-	Period         Period  `json:"period"`
-	AlertThreshold float64 `json:"alert_threshold" binding:"min=0,max=100"`
-	IsActive       *bool   `json:"is_active"`
+	Name           string           `json:"name"`
+	Description    string           `json:"description"`
+	Amount         *decimal.Decimal `json:"amount"` // In the currency of the budget
+	CategoryID     *uuid.UUID       `json:"category_id"`
+	Period         Period           `json:"period" binding:"omitempty,oneof=weekly monthly yearly"`
+	AnchorDay      *int             `json:"anchor_day" binding:"omitempty,min=1,max=31"`
+	AnchorMonth    *int             `json:"anchor_month" binding:"omitempty,min=1,max=12"`
+	Rollover       RolloverMode     `json:"rollover" binding:"omitempty,oneof=none unspent all"`
+	AlertThreshold float64          `json:"alert_threshold" binding:"min=0,max=100"`
+	IsActive       *bool            `json:"is_active"`
+}
+
+// BudgetResponse is a budget with its spending in the current period, derived from expenses
+// and expense transactions at read time. Amounts that need an exchange rate that is not stored
+// are null, and RateError says which rate is missing.
+type BudgetResponse struct {
+	Budget
+	PeriodStart     time.Time      `json:"period_start"`
+	PeriodEnd       time.Time      `json:"period_end"`
+	RolloverIn      *common.Money  `json:"rollover_in"`
+	Available       *common.Money  `json:"available"` // Amount plus rollover from the previous period
+	Spent           *common.Money  `json:"spent"`
+	SpentByCurrency []common.Money `json:"spent_by_currency"` // Per currency of the spending, unconverted; the budget currency comes first
+	Remaining       *common.Money  `json:"remaining"`
+	PercentUsed     *float64       `json:"percent_used"`
+	DaysLeft        int            `json:"days_left"`
+	RateError       string         `json:"rate_error,omitempty"`
+}
+
+// BudgetAlert records that a budget crossed an alert threshold in a period. The unique index
+// makes each threshold fire at most once per budget period.
+type BudgetAlert struct {
+	common.BaseModel
+	BudgetID    uuid.UUID    `json:"budget_id" gorm:"type:uuid;not null;uniqueIndex:idx_budget_alert_period"`
+	PeriodStart time.Time    `json:"period_start" gorm:"not null;uniqueIndex:idx_budget_alert_period"`
+	Threshold   float64      `json:"threshold" gorm:"not null;uniqueIndex:idx_budget_alert_period"`
+	Spent       common.Money `json:"spent" gorm:"embedded;embeddedPrefix:spent_"`
+	Available   common.Money `json:"available" gorm:"embedded;embeddedPrefix:available_"`
+	PercentUsed float64      `json:"percent_used"`
+}
+
+type BudgetPeriodsResponse struct {
+	Current BudgetResponse                 `json:"current"`
+	History *pagination.Page[BudgetPeriod] `json:"history"`
+}
+
+type AllocationKind string
+
+const (
+	AllocationAssign   AllocationKind = "assign"
+	AllocationMoveIn   AllocationKind = "move_in"
+	AllocationMoveOut  AllocationKind = "move_out"
+	AllocationCoverIn  AllocationKind = "cover_in"
+	AllocationCoverOut AllocationKind = "cover_out"
+)
+
+// EnvelopeAllocation is an append-only change to the money assigned to an envelope in a month.
+// Moves between envelopes are stored as two rows sharing a TransferID.
+type EnvelopeAllocation struct {
+	common.BaseModel
+	UserID     uuid.UUID      `json:"user_id" gorm:"not null;index"`
+	BudgetID   uuid.UUID      `json:"budget_id" gorm:"type:uuid;not null;index"`
+	Month      time.Time      `json:"month" gorm:"type:date;not null;index"`
+	Amount     common.Money   `json:"amount" gorm:"embedded"`
+	Kind       AllocationKind `json:"kind" gorm:"type:varchar(20);not null"`
+	TransferID *uuid.UUID     `json:"transfer_id,omitempty" gorm:"index"`
+	Note       string         `json:"note"`
+}
+
+type AssignEnvelopeRequest struct {
+	BudgetID uuid.UUID       `json:"budget_id" binding:"required"`
+	Month    string          `json:"month"` // YYYY-MM, defaults to the current month
+	Amount   decimal.Decimal `json:"amount" binding:"required"`
+	Note     string          `json:"note"`
+}
+
+type MoveEnvelopeRequest struct {
+	FromBudgetID uuid.UUID       `json:"from_budget_id" binding:"required"`
+	ToBudgetID   uuid.UUID       `json:"to_budget_id" binding:"required,nefield=FromBudgetID"`
+	Month        string          `json:"month"` // YYYY-MM, defaults to the current month
+	Amount       decimal.Decimal `json:"amount" binding:"required"`
+	Note         string          `json:"note"`
+}
+
+type CoverOverspendingRequest struct {
+	BudgetID     uuid.UUID `json:"budget_id" binding:"required"`
+	FromBudgetID uuid.UUID `json:"from_budget_id" binding:"required,nefield=BudgetID"`
+	Month        string    `json:"month"` // YYYY-MM, defaults to the current month
+}
+
+// EnvelopeSummary is the zero-based view of a month: income that still has to be assigned and
+// the state of every envelope. It is in the user's reporting currency: income is converted into it
+// and only envelopes budgeted in it are included. Amounts that need an exchange rate that is not
+// stored are null, and RateError says which rate is missing.
+type EnvelopeSummary struct {
+	Month            string           `json:"month"`
+	Currency         common.Currency  `json:"currency"`
+	Income           *common.Money    `json:"income"`             // All income received up to the end of the month
+	IncomeByCurrency []common.Money   `json:"income_by_currency"` // Per currency of the income, unconverted
+	Assigned         common.Money     `json:"assigned"`           // Assigned to envelopes in this month
+	ToBeAssigned     *common.Money    `json:"to_be_assigned"`     // Income not yet assigned to any envelope
+	Overspent        common.Money     `json:"overspent"`          // Sum of negative envelope balances
+	OverspentCount   int              `json:"overspent_count"`    // Envelopes that must be covered from another envelope
+	Envelopes        []EnvelopeStatus `json:"envelopes"`
+	RateError        string           `json:"rate_error,omitempty"`
+
+	rateErr error
+}
+
+type EnvelopeStatus struct {
+	BudgetID           uuid.UUID          `json:"budget_id"`
+	Name               string             `json:"name"`
+	CategoryID         uuid.UUID          `json:"category_id"`
+	Category           *category.Category `json:"category,omitempty"`
+	Target             common.Money       `json:"target"`               // The budget amount, used as the monthly goal
+	CarriedOver        *common.Money      `json:"carried_over"`         // Available at the end of the previous month
+	Assigned           common.Money       `json:"assigned"`             // Assigned in this month
+	Activity           *common.Money      `json:"activity"`             // Spent in this month
+	ActivityByCurrency []common.Money     `json:"activity_by_currency"` // Per currency of the spending in this month, unconverted
+	Available          *common.Money      `json:"available"`            // CarriedOver + Assigned - Activity
+	IsOverspent        bool               `json:"is_overspent"`
+	RateError          string             `json:"rate_error,omitempty"`
+
+	rateErr error
 }
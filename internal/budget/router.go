@@ -11,4 +11,11 @@ func RegisterRoutes(versionedGroup *gin.RouterGroup, svc *Service) {
 	protected.GET("/:id", budgetHandler.GetBudget)
 	protected.PUT("/:id", budgetHandler.UpdateBudget)
 	protected.DELETE("/:id", budgetHandler.DeleteBudget)
+	protected.GET("/:id/periods", budgetHandler.GetBudgetPeriods)
+
+	// Zero-based envelope budgeting
+	protected.GET("/envelopes", budgetHandler.GetEnvelopeSummary)
+	protected.POST("/envelopes/assign", budgetHandler.AssignToEnvelope)
+	protected.POST("/envelopes/move", budgetHandler.MoveBetweenEnvelopes)
+	protected.POST("/envelopes/cover", budgetHandler.CoverOverspending)
 }
\ No newline at end of file
@@ -3,89 +3,144 @@ package budget
 import (
 	"context"
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/pastorenue/kinance/internal/category"
 	"github.com/pastorenue/kinance/internal/common"
+	"github.com/pastorenue/kinance/internal/expense"
+	"github.com/pastorenue/kinance/internal/fx"
+	"github.com/pastorenue/kinance/internal/ledger"
+	"github.com/pastorenue/kinance/internal/notification"
+	"github.com/pastorenue/kinance/internal/transaction"
+	"github.com/pastorenue/kinance/pkg/pagination"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+const alertTimeout = 30 * time.Second
+
 type Service struct {
-	db     *gorm.DB
-	logger common.Logger
+	db       *gorm.DB
+	rates    *fx.Service
+	notifier notification.Notifier
+	logger   common.Logger
 }
 
-func NewService(db *gorm.DB, logger common.Logger) *Service {
+func NewService(db *gorm.DB, rates *fx.Service, notifier notification.Notifier, logger common.Logger) *Service {
 	return &Service{
-		db:     db,
-		logger: logger,
+		db:       db,
+		rates:    rates,
+		notifier: notifier,
+		logger:   logger,
 	}
 }
 
-func (s *Service) CreateBudget(ctx context.Context, userID uuid.UUID, req *CreateBudgetRequest) (*Budget, error) {
+func (s *Service) CreateBudget(ctx context.Context, userID uuid.UUID, req *CreateBudgetRequest) (*BudgetResponse, error) {
+	if !req.Amount.IsPositive() {
+		return nil, errors.New("amount must be greater than zero")
+	}
+	if err := category.CheckAccess(ctx, s.db, userID, req.CategoryID); err != nil {
+		return nil, err
+	}
+
 	budget := &Budget{
 		UserID:         userID,
 		Name:           req.Name,
 		Description:    req.Description,
-		Amount:         req.Amount,
-		Category:       req.Category,
+		Amount:         common.NewMoney(req.Amount, req.Currency.OrDefault()),
+		CategoryID:     req.CategoryID,
+		Mode:           req.Mode,
 		Period:         req.Period,
+		AnchorDay:      req.AnchorDay,
+		AnchorMonth:    req.AnchorMonth,
+		Rollover:       req.Rollover,
 		AlertThreshold: req.AlertThreshold,
 		IsActive:       true,
 	}
+	if budget.AnchorDay == 0 {
+		budget.AnchorDay = 1
+	}
+	if budget.AnchorMonth == 0 {
+		budget.AnchorMonth = 1
+	}
+	if budget.Rollover == "" {
+		budget.Rollover = RolloverNone
+	}
+	if budget.Mode == "" {
+		budget.Mode = ModeStandard
+	}
+	if budget.Mode == ModeEnvelope && budget.Period == "" {
+		budget.Period = PeriodMonthly
+	}
+	if err := validateSchedule(budget); err != nil {
+		return nil, err
+	}
 
 	if err := s.db.WithContext(ctx).Create(budget).Error; err != nil {
 		return nil, err
 	}
 
 	s.logger.Info("Budget created successfully", "budget_id", budget.ID, "user_id", userID)
-	return budget, nil
+	return s.GetBudget(ctx, userID, budget.ID)
 }
 
-func (s *Service) GetBudgets(ctx context.Context, userID uuid.UUID, pagination *common.PaginationParams) (*common.PaginatedResponse, error) {
-	var budgets []Budget
-	var total int64
-
-	query := s.db.WithContext(ctx).Where("user_id = ? AND is_active = ?", userID, true)
+// budgetPages are the orders budgets can be listed in.
+var budgetPages = pagination.Spec[Budget]{
+	Table: "budgets",
+	Sorts: map[string]pagination.Column[Budget]{
+		"created_at": {Expr: "budgets.created_at", Value: func(b *Budget) any { return b.CreatedAt }},
+		"name":       {Expr: "budgets.name", Value: func(b *Budget) any { return b.Name }},
+		"amount":     {Expr: "budgets.amount", Value: func(b *Budget) any { return b.Amount.Amount }},
+	},
+	Default: "created_at",
+	ID:      func(b *Budget) uuid.UUID { return b.ID },
+}
 
-	// Count total records
-	if err := query.Model(&Budget{}).Count(&total).Error; err != nil {
-		return nil, err
-	}
+// BudgetFilters are the fields budgets can be filtered on.
+var BudgetFilters = common.FilterFields{
+	"name":        {Column: "budgets.name", Ops: []common.FilterOp{common.OpEq, common.OpLike}},
+	"amount":      {Column: "budgets.amount", Kind: common.FilterNumber},
+	"currency":    {Column: "budgets.currency"},
+	"category_id": {Column: "budgets.category_id", Kind: common.FilterUUID},
+	"mode":        {Column: "budgets.mode", Values: []string{string(ModeStandard), string(ModeEnvelope)}},
+	"period":      {Column: "budgets.period", Values: []string{string(PeriodWeekly), string(PeriodMonthly), string(PeriodYearly)}},
+	"rollover":    {Column: "budgets.rollover", Values: []string{string(RolloverNone), string(RolloverUnspent), string(RolloverAll)}},
+	"created_at":  {Column: "budgets.created_at", Kind: common.FilterTime},
+}
 
-	// Apply pagination
-	offset := (pagination.Page - 1) * pagination.PageSize
-	if err := query.Offset(offset).Limit(pagination.PageSize).Find(&budgets).Error; err != nil {
+func (s *Service) GetBudgets(ctx context.Context, userID uuid.UUID, filter common.Filter, params pagination.Params) (*pagination.Page[BudgetResponse], error) {
+	query := s.db.WithContext(ctx).Where("user_id = ? AND is_active = ?", userID, true).Scopes(filter.Scope)
+	page, err := pagination.Paginate(query, budgetPages, params, "Category")
+	if err != nil {
 		return nil, err
 	}
 
-	totalPages := int(total) / pagination.PageSize
-	if int(total)%pagination.PageSize > 0 {
-		totalPages++
+	responses := make([]BudgetResponse, 0, len(page.Data))
+	for _, budget := range page.Data {
+		response, err := s.buildResponse(ctx, budget, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		responses = append(responses, *response)
 	}
-
-	return &common.PaginatedResponse{
-		Data: budgets,
-		Pagination: common.Pagination{
-			Page:       pagination.Page,
-			PageSize:   pagination.PageSize,
-			Total:      total,
-			TotalPages: totalPages,
-		},
-	}, nil
+	return pagination.WithData(page, responses), nil
 }
 
-func (s *Service) GetBudget(ctx context.Context, userID, budgetID uuid.UUID) (*Budget, error) {
+func (s *Service) GetBudget(ctx context.Context, userID, budgetID uuid.UUID) (*BudgetResponse, error) {
 	var budget Budget
-	if err := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", budgetID, userID).First(&budget).Error; err != nil {
+	if err := s.db.WithContext(ctx).Preload("Category").Where("id = ? AND user_id = ?", budgetID, userID).First(&budget).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("budget not found")
 		}
 		return nil, err
 	}
-	return &budget, nil
+	return s.buildResponse(ctx, budget, time.Now())
 }
 
-func (s *Service) UpdateBudget(ctx context.Context, userID, budgetID uuid.UUID, req *UpdateBudgetRequest) (*Budget, error) {
+func (s *Service) UpdateBudget(ctx context.Context, userID, budgetID uuid.UUID, req *UpdateBudgetRequest) (*BudgetResponse, error) {
 	var budget Budget
 	if err := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", budgetID, userID).First(&budget).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -101,15 +156,33 @@ func (s *Service) UpdateBudget(ctx context.Context, userID, budgetID uuid.UUID,
 	if req.Description != "" {
 		budget.Description = req.Description
 	}
-	if req.Amount > 0 {
-		budget.Amount = req.Amount
+	if req.Amount != nil {
+		if !req.Amount.IsPositive() {
+			return nil, errors.New("amount must be greater than zero")
+		}
+		budget.Amount = common.NewMoney(*req.Amount, budget.Amount.Currency)
 	}
-	if req.Category != "" {
-		budget.Category = req.Category
+	if req.CategoryID != nil {
+		if err := category.CheckAccess(ctx, s.db, userID, *req.CategoryID); err != nil {
+			return nil, err
+		}
+		budget.CategoryID = *req.CategoryID
 	}
 	if req.Period != "" {
 		budget.Period = req.Period
 	}
+	if req.AnchorDay != nil {
+		budget.AnchorDay = *req.AnchorDay
+	}
+	if req.AnchorMonth != nil {
+		budget.AnchorMonth = *req.AnchorMonth
+	}
+	if err := validateSchedule(&budget); err != nil {
+		return nil, err
+	}
+	if req.Rollover != "" {
+		budget.Rollover = req.Rollover
+	}
 	if req.AlertThreshold > 0 {
 		budget.AlertThreshold = req.AlertThreshold
 	}
@@ -117,29 +190,820 @@ func (s *Service) UpdateBudget(ctx context.Context, userID, budgetID uuid.UUID,
 		budget.IsActive = *req.IsActive
 	}
 
-	if err := s.db.WithContext(ctx).Save(&budget).Error; err != nil {
+	if err := s.db.WithContext(ctx).Omit("Category").Save(&budget).Error; err != nil {
 		return nil, err
 	}
 
-	return &budget, nil
+	return s.GetBudget(ctx, userID, budget.ID)
 }
 
 func (s *Service) DeleteBudget(ctx context.Context, userID, budgetID uuid.UUID) error {
-	result := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", budgetID, userID).Delete(&Budget{})
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND user_id = ?", budgetID, userID).Delete(&Budget{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("budget not found")
+		}
+		if err := tx.Where("budget_id = ?", budgetID).Delete(&BudgetAlert{}).Error; err != nil {
+			return err
+		}
+		// Money assigned to a deleted envelope goes back to be assigned
+		if err := tx.Where("budget_id = ?", budgetID).Delete(&EnvelopeAllocation{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("budget_id = ?", budgetID).Delete(&BudgetPeriod{}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info("Budget deleted successfully", "budget_id", budgetID, "user_id", userID)
+	return nil
+}
+
+// CalculateSpent sums the spending of the budget's category and its subcategories within [start, end),
+// converted into the budget's currency at the rate of the day of each expense. Spending is read from
+// the journal, where an expense transaction with a linked expense is posted once.
+func (s *Service) CalculateSpent(ctx context.Context, budget *Budget, start, end time.Time) (common.Money, error) {
+	currency := budget.Amount.Currency
+	totals, err := s.spentTotals(ctx, budget, start, end)
+	if err != nil {
+		return common.Money{}, err
+	}
+
+	total, err := s.convertTotals(ctx, currency, totals)
+	if err != nil {
+		return common.Money{}, err
+	}
+	return common.NewMoney(total, currency), nil
+}
+
+// spending is CalculateSpent for responses: a missing exchange rate leaves the converted total out
+// instead of failing, and the spending per currency is returned as well.
+func (s *Service) spending(ctx context.Context, budget *Budget, start, end time.Time) (*converted, error) {
+	totals, err := s.spentTotals(ctx, budget, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return s.convert(ctx, budget.Amount.Currency, totals)
+}
+
+// spentTotals returns the daily spending of the budget's category and its subcategories within [start, end).
+func (s *Service) spentTotals(ctx context.Context, budget *Budget, start, end time.Time) ([]ledger.DailyTotal, error) {
+	return ledger.CategoryActivity(s.db.WithContext(ctx), budget.UserID, ledger.SourceExpense,
+		category.SubtreeIDs(s.db, budget.CategoryID), start, end)
+}
+
+// periodPages are the orders the closed periods of a budget can be listed in, oldest first by default.
+var periodPages = pagination.Spec[BudgetPeriod]{
+	Table: "budget_periods",
+	Sorts: map[string]pagination.Column[BudgetPeriod]{
+		"start_date": {Expr: "budget_periods.start_date", Value: func(p *BudgetPeriod) any { return p.StartDate }},
+	},
+	Default: "start_date",
+	Order:   pagination.Asc,
+	ID:      func(p *BudgetPeriod) uuid.UUID { return p.ID },
+}
+
+// GetBudgetPeriods returns the current period of the budget and a page of the snapshots of its
+// closed periods, oldest first unless the page is sorted otherwise.
+func (s *Service) GetBudgetPeriods(ctx context.Context, userID, budgetID uuid.UUID, params pagination.Params) (*BudgetPeriodsResponse, error) {
+	current, err := s.GetBudget(ctx, userID, budgetID)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := pagination.Paginate(s.db.WithContext(ctx).Where("budget_id = ?", budgetID), periodPages, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BudgetPeriodsResponse{
+		Current: *current,
+		History: history,
+	}, nil
+}
+
+// CloseBudgetPeriods snapshots every period of every active budget that has ended since it was last run.
+func (s *Service) CloseBudgetPeriods(ctx context.Context) error {
+	var budgets []Budget
+	if err := s.db.WithContext(ctx).Where("is_active = ?", true).Find(&budgets).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var failed int
+	for i := range budgets {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if _, err := s.closePeriods(ctx, &budgets[i], now); err != nil {
+			s.logger.Error("Failed to close budget periods", "error", err, "budget_id", budgets[i].ID)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to close periods of %d budgets", failed)
+	}
+	return nil
+}
+
+// closePeriods snapshots each period between the last snapshot (or the budget's creation) and the
+// current period, carrying rollover from one period into the next. It returns the latest snapshot,
+// or nil while the budget is still in its first period; on error, the latest snapshot stored before
+// it. Snapshots are keyed by (budget_id, start_date), so concurrent calls never store a period twice.
+func (s *Service) closePeriods(ctx context.Context, budget *Budget, now time.Time) (*BudgetPeriod, error) {
+	var last *BudgetPeriod
+	var start time.Time
+
+	var latest BudgetPeriod
+	err := s.db.WithContext(ctx).Where("budget_id = ?", budget.ID).Order("start_date DESC").First(&latest).Error
+	switch {
+	case err == nil:
+		last = &latest
+		start = latest.EndDate
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		start, _ = budget.PeriodAt(budget.CreatedAt)
+	default:
+		return nil, err
+	}
+
+	currentStart, _ := budget.PeriodAt(now)
+	for start.Before(currentStart) {
+		_, end := budget.PeriodAt(start)
+		// After the period or anchor changes, the last old-style period is cut short at the new boundary
+		if end.After(currentStart) {
+			end = currentStart
+		}
+
+		spent, err := s.CalculateSpent(ctx, budget, start, end)
+		if err != nil {
+			return last, err
+		}
+
+		rolloverIn := rolloverOutOf(last, budget.Amount.Currency)
+		remaining := common.NewMoney(budget.Amount.Amount.Add(rolloverIn.Amount).Sub(spent.Amount), budget.Amount.Currency)
+		closedAt := now
+
+		period := &BudgetPeriod{
+			BudgetID:    budget.ID,
+			StartDate:   start,
+			EndDate:     end,
+			Budgeted:    budget.Amount,
+			RolloverIn:  rolloverIn,
+			Spent:       spent,
+			Remaining:   remaining,
+			RolloverOut: budget.RolloverFrom(remaining),
+			ClosedAt:    &closedAt,
+		}
+		period.ID = uuid.New()
+
+		result := s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(period)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 0 {
+			// Another request closed this period first
+			if err := s.db.WithContext(ctx).
+				Where("budget_id = ? AND start_date = ?", budget.ID, start).
+				First(period).Error; err != nil {
+				return nil, err
+			}
+		} else {
+			s.logger.Info("Budget period closed", "budget_id", budget.ID, "start_date", start, "spent", period.Spent.String())
+		}
+
+		last = period
+		start = period.EndDate
+	}
+
+	return last, nil
+}
+
+// buildResponse computes the current period of the budget. Spending in a currency without a stored
+// exchange rate leaves the amounts that depend on it null rather than failing, since rates are
+// optional; earlier periods are closed once the rate is there.
+func (s *Service) buildResponse(ctx context.Context, budget Budget, now time.Time) (*BudgetResponse, error) {
+	start, end := budget.PeriodAt(now)
+	currency := budget.Amount.Currency
+	response := &BudgetResponse{Budget: budget, PeriodEnd: end, DaysLeft: daysLeft(now, end)}
+
+	var rolloverIn *common.Money
+	last, err := s.closePeriods(ctx, &budget, now)
+	switch {
+	case err == nil:
+		in := rolloverOutOf(last, currency)
+		rolloverIn = &in
+	case errors.Is(err, fx.ErrRateNotFound):
+		// A period that could not be closed carries an unknown amount, unless nothing rolls over
+		if budget.Rollover == RolloverNone {
+			in := common.ZeroMoney(currency)
+			rolloverIn = &in
+		}
+		response.RateError = err.Error()
+	default:
+		s.logger.Error("Failed to close budget periods", "error", err, "budget_id", budget.ID)
+		return nil, err
+	}
+	if last != nil {
+		// A switch to a longer period must not count spending already in a closed snapshot
+		if last.EndDate.After(start) {
+			start = last.EndDate
+		}
+	}
+	response.PeriodStart = start
+
+	spent, err := s.spending(ctx, &budget, start, end)
+	if err != nil {
+		s.logger.Error("Failed to calculate budget spending", "error", err, "budget_id", budget.ID)
+		return nil, err
+	}
+	response.Spent = spent.Total
+	response.SpentByCurrency = spent.ByCurrency
+	if spent.Err != nil {
+		response.RateError = spent.Err.Error()
+	}
+
+	if rolloverIn == nil {
+		return response, nil
+	}
+	available := common.NewMoney(budget.Amount.Amount.Add(rolloverIn.Amount), currency)
+	response.RolloverIn = rolloverIn
+	response.Available = &available
+	if spent.Total == nil {
+		return response, nil
+	}
+
+	percentUsed := decimal.Zero
+	if available.IsPositive() {
+		percentUsed = spent.Total.Amount.Div(available.Amount).Mul(decimal.NewFromInt(100))
+	}
+	remaining := common.NewMoney(available.Amount.Sub(spent.Total.Amount), currency)
+	percent := percentUsed.Round(2).InexactFloat64()
+	response.Remaining = &remaining
+	response.PercentUsed = &percent
+	return response, nil
+}
+
+// ExpenseCreated is an expense.Listener that checks the alerts of the budgets covering the expense.
+func (s *Service) ExpenseCreated(ctx context.Context, expense *expense.Expense) {
+	s.checkAlertsAsync(ctx, expense.UserID, expense.CategoryID)
+}
+
+// TransactionCreated is a transaction.Listener that checks the alerts of the budgets covering an expense transaction.
+func (s *Service) TransactionCreated(ctx context.Context, t *transaction.Transaction) {
+	if t.Type != transaction.TypeExpense || t.ExcludeFromAnalytics {
+		return
+	}
+	s.checkAlertsAsync(ctx, t.UserID, t.CategoryID)
+}
+
+// checkAlertsAsync evaluates alerts in the background so slow notifiers never delay the request
+// that created the expense.
+func (s *Service) checkAlertsAsync(ctx context.Context, userID, categoryID uuid.UUID) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), alertTimeout)
+	go func() {
+		defer cancel()
+		if err := s.CheckAlerts(ctx, userID, categoryID); err != nil {
+			s.logger.Error("Failed to check budget alerts", "error", err, "user_id", userID, "category_id", categoryID)
+		}
+	}()
+}
+
+// CheckAlerts evaluates every active budget of the user that covers the category, directly or
+// through a parent category, and raises an alert for each threshold crossed in the current period:
+// the budget's AlertThreshold and 100%.
+func (s *Service) CheckAlerts(ctx context.Context, userID, categoryID uuid.UUID) error {
+	var budgets []Budget
+	if err := s.db.WithContext(ctx).
+		Preload("Category").
+		Where("user_id = ? AND is_active = ?", userID, true).
+		Where("category_id IN (?)", category.AncestorIDs(s.db, categoryID)).
+		Find(&budgets).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, budget := range budgets {
+		response, err := s.buildResponse(ctx, budget, now)
+		if err != nil {
+			return err
+		}
+		if response.PercentUsed == nil {
+			// Alerts wait until the spending can be converted
+			s.logger.Info("Budget alerts skipped", "budget_id", budget.ID, "reason", response.RateError)
+			continue
+		}
+		for _, threshold := range alertThresholds(&budget) {
+			if *response.PercentUsed < threshold {
+				continue
+			}
+			if err := s.raiseAlert(ctx, response, threshold); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// raiseAlert stores the alert for the current period and notifies the user, unless the
+// threshold already fired in this period. The spending of the budget must be known.
+func (s *Service) raiseAlert(ctx context.Context, budget *BudgetResponse, threshold float64) error {
+	alert := &BudgetAlert{
+		BudgetID:    budget.ID,
+		PeriodStart: budget.PeriodStart,
+		Threshold:   threshold,
+		Spent:       *budget.Spent,
+		Available:   *budget.Available,
+		PercentUsed: *budget.PercentUsed,
+	}
+	alert.ID = uuid.New()
+
+	result := s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(alert)
 	if result.Error != nil {
 		return result.Error
 	}
-
 	if result.RowsAffected == 0 {
-		return errors.New("budget not found")
+		return nil
 	}
 
-	s.logger.Info("Budget deleted successfully", "budget_id", budgetID, "user_id", userID)
+	msg := notification.Message{
+		UserID: budget.UserID,
+		Type:   "budget_threshold_reached",
+		Title:  fmt.Sprintf("Budget %q reached %.0f%%", budget.Name, threshold),
+		Body: fmt.Sprintf(
+			"You have spent %s of %s (%.2f%%) in the period %s to %s.",
+			alert.Spent,
+			alert.Available,
+			alert.PercentUsed,
+			budget.PeriodStart.Format("2006-01-02"),
+			budget.PeriodEnd.AddDate(0, 0, -1).Format("2006-01-02"),
+		),
+		Data: map[string]interface{}{
+			"budget_id":    budget.ID,
+			"threshold":    threshold,
+			"spent":        alert.Spent,
+			"available":    alert.Available,
+			"percent_used": alert.PercentUsed,
+			"period_start": budget.PeriodStart,
+			"period_end":   budget.PeriodEnd,
+		},
+	}
+	if threshold >= 100 {
+		msg.Type = "budget_exceeded"
+		msg.Title = fmt.Sprintf("Budget %q is used up", budget.Name)
+	}
+
+	s.logger.Info("Budget alert raised", "budget_id", budget.ID, "threshold", threshold, "percent_used", alert.PercentUsed)
+	if err := s.notifier.Notify(ctx, msg); err != nil {
+		// The alert stays recorded so a flaky channel cannot cause repeated alerts
+		s.logger.Error("Failed to deliver budget alert", "error", err, "budget_id", budget.ID)
+	}
 	return nil
 }
 
-func (s *Service) UpdateSpentAmount(ctx context.Context, budgetID uuid.UUID, amount float64) error {
-	return s.db.WithContext(ctx).Model(&Budget{}).
-		Where("id = ?", budgetID).
-		Update("spent", gorm.Expr("spent + ?", amount)).Error
+// alertThresholds returns the percentages at which the budget alerts. An AlertThreshold of 0 or
+// at least 100 leaves only the 100% alert.
+func alertThresholds(budget *Budget) []float64 {
+	if budget.AlertThreshold > 0 && budget.AlertThreshold < 100 {
+		return []float64{budget.AlertThreshold, 100}
+	}
+	return []float64{100}
+}
+
+func validateSchedule(budget *Budget) error {
+	if budget.Period == "" {
+		return errors.New("period is required")
+	}
+	if budget.Mode == ModeEnvelope && (budget.Period != PeriodMonthly || budget.AnchorDay != 1) {
+		return errors.New("envelope budgets run on calendar months")
+	}
+	if budget.Period == PeriodWeekly && budget.AnchorDay > 7 {
+		return errors.New("anchor_day must be a weekday between 1 (Monday) and 7 (Sunday) for weekly budgets")
+	}
+	return nil
+}
+
+/* Get the zero-based envelope view of a month.
+ *
+ * @param ctx - The context for the request
+ * @param userID - The ID of the user
+ * @param month - Any time within the month
+ * @returns The income still to be assigned and the state of every envelope
+ */
+func (s *Service) GetEnvelopeSummary(ctx context.Context, userID uuid.UUID, month time.Time) (*EnvelopeSummary, error) {
+	return s.envelopeSummary(ctx, s.db.WithContext(ctx), userID, monthOf(month))
+}
+
+/* Assign income to an envelope, or take money back out of it with a negative amount.
+ * Money can only be assigned while there is income left to assign, and only money that is
+ * still available in the envelope can be taken back.
+ *
+ * @param ctx - The context for the request
+ * @param userID - The ID of the user
+ * @param req - The assignment
+ * @returns The updated envelope view of the month
+ */
+func (s *Service) AssignToEnvelope(ctx context.Context, userID uuid.UUID, req *AssignEnvelopeRequest) (*EnvelopeSummary, error) {
+	month, err := parseMonth(req.Month)
+	if err != nil {
+		return nil, err
+	}
+	if req.Amount.IsZero() {
+		return nil, errors.New("amount cannot be zero")
+	}
+
+	var summary *EnvelopeSummary
+	var amount common.Money
+	err = s.withEnvelopeLock(ctx, userID, func(tx *gorm.DB) error {
+		current, err := s.envelopeSummary(ctx, tx, userID, month)
+		if err != nil {
+			return err
+		}
+		envelope, err := findEnvelope(current, req.BudgetID)
+		if err != nil {
+			return err
+		}
+
+		amount = common.NewMoney(req.Amount, current.Currency)
+		if err := checkAssign(current, envelope, amount); err != nil {
+			return err
+		}
+
+		allocation := newAllocation(userID, req.BudgetID, month, amount, AllocationAssign, nil, req.Note)
+		if err := tx.Create(allocation).Error; err != nil {
+			return err
+		}
+
+		summary, err = s.envelopeSummary(ctx, tx, userID, month)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Money assigned to envelope", "budget_id", req.BudgetID, "user_id", userID, "amount", amount.String())
+	return summary, nil
+}
+
+/* Move available money from one envelope to another.
+ *
+ * @param ctx - The context for the request
+ * @param userID - The ID of the user
+ * @param req - The source and destination envelopes and the amount
+ * @returns The updated envelope view of the month
+ */
+func (s *Service) MoveBetweenEnvelopes(ctx context.Context, userID uuid.UUID, req *MoveEnvelopeRequest) (*EnvelopeSummary, error) {
+	month, err := parseMonth(req.Month)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.moveBetweenEnvelopes(ctx, userID, month, req.FromBudgetID, req.ToBudgetID, func(from, _ *EnvelopeStatus) (common.Money, error) {
+		return common.NewMoney(req.Amount, from.Target.Currency), nil
+	}, AllocationMoveOut, AllocationMoveIn, req.Note)
+}
+
+/* Cover the overspending of an envelope with money available in another envelope.
+ * Exactly the overspent amount is moved, so the covered envelope ends at zero.
+ *
+ * @param ctx - The context for the request
+ * @param userID - The ID of the user
+ * @param req - The overspent envelope and the envelope to cover it from
+ * @returns The updated envelope view of the month
+ */
+func (s *Service) CoverOverspending(ctx context.Context, userID uuid.UUID, req *CoverOverspendingRequest) (*EnvelopeSummary, error) {
+	month, err := parseMonth(req.Month)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.moveBetweenEnvelopes(ctx, userID, month, req.FromBudgetID, req.BudgetID, func(_, to *EnvelopeStatus) (common.Money, error) {
+		return overspending(to)
+	}, AllocationCoverOut, AllocationCoverIn, "Cover overspending")
+}
+
+func (s *Service) moveBetweenEnvelopes(
+	ctx context.Context,
+	userID uuid.UUID,
+	month time.Time,
+	fromID, toID uuid.UUID,
+	amountOf func(from, to *EnvelopeStatus) (common.Money, error),
+	outKind, inKind AllocationKind,
+	note string,
+) (*EnvelopeSummary, error) {
+	var summary *EnvelopeSummary
+	err := s.withEnvelopeLock(ctx, userID, func(tx *gorm.DB) error {
+		current, err := s.envelopeSummary(ctx, tx, userID, month)
+		if err != nil {
+			return err
+		}
+		from, err := findEnvelope(current, fromID)
+		if err != nil {
+			return err
+		}
+		to, err := findEnvelope(current, toID)
+		if err != nil {
+			return err
+		}
+
+		amount, err := amountOf(from, to)
+		if err != nil {
+			return err
+		}
+		if err := checkMove(from, amount); err != nil {
+			return err
+		}
+
+		transferID := uuid.New()
+		allocations := []*EnvelopeAllocation{
+			newAllocation(userID, fromID, month, amount.Neg(), outKind, &transferID, note),
+			newAllocation(userID, toID, month, amount, inKind, &transferID, note),
+		}
+		if err := tx.Create(allocations).Error; err != nil {
+			return err
+		}
+
+		summary, err = s.envelopeSummary(ctx, tx, userID, month)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Money moved between envelopes", "from_budget_id", fromID, "to_budget_id", toID, "user_id", userID)
+	return summary, nil
+}
+
+// withEnvelopeLock runs fn in a transaction holding a per-user advisory lock, so concurrent
+// assignments cannot together assign more income than the user has.
+func (s *Service) withEnvelopeLock(ctx context.Context, userID uuid.UUID, fn func(tx *gorm.DB) error) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "envelopes:"+userID.String()).Error; err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
+
+// envelopeSummary computes the envelope view of the month starting at month, in the user's
+// reporting currency. Available amounts carry over from month to month, so an envelope's balance
+// is everything ever assigned to it minus everything spent from it since it was created. Income or
+// spending in a currency without a stored exchange rate leaves the amounts that depend on it null.
+func (s *Service) envelopeSummary(ctx context.Context, db *gorm.DB, userID uuid.UUID, month time.Time) (*EnvelopeSummary, error) {
+	end := month.AddDate(0, 1, 0)
+	currency, err := s.rates.ReportingCurrency(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var envelopes []Budget
+	if err := db.Preload("Category").
+		Where("user_id = ? AND mode = ? AND is_active = ? AND currency = ?", userID, ModeEnvelope, true, currency).
+		Order("name").
+		Find(&envelopes).Error; err != nil {
+		return nil, err
+	}
+
+	income, err := s.incomeUntil(ctx, db, userID, end, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	var totals []allocationTotals
+	if err := db.Model(&EnvelopeAllocation{}).
+		Select("budget_id, COALESCE(SUM(CASE WHEN month = ? THEN amount ELSE 0 END), 0) AS assigned, COALESCE(SUM(amount), 0) AS cumulative", month).
+		Where("user_id = ? AND month < ? AND currency = ?", userID, end, currency).
+		Group("budget_id").
+		Scan(&totals).Error; err != nil {
+		return nil, err
+	}
+
+	totalAssigned := decimal.Zero
+	byBudget := make(map[uuid.UUID]allocationTotals, len(totals))
+	for _, t := range totals {
+		byBudget[t.BudgetID] = t
+		totalAssigned = totalAssigned.Add(t.Cumulative)
+	}
+
+	summary := &EnvelopeSummary{
+		Month:            month.Format("2006-01"),
+		Currency:         currency,
+		Income:           income.Total,
+		IncomeByCurrency: income.ByCurrency,
+		Envelopes:        make([]EnvelopeStatus, 0, len(envelopes)),
+		rateErr:          income.Err,
+	}
+	if income.Total != nil {
+		toBeAssigned := common.NewMoney(income.Total.Amount.Sub(totalAssigned), currency)
+		summary.ToBeAssigned = &toBeAssigned
+	} else {
+		summary.RateError = income.Err.Error()
+	}
+
+	monthAssigned := decimal.Zero
+	overspent := decimal.Zero
+	for i := range envelopes {
+		envelope := &envelopes[i]
+		since := monthOf(envelope.CreatedAt)
+
+		// Spending before the envelope was created is not taken out of it
+		var activity, spentBefore *converted
+		if month.Before(since) {
+			activity, spentBefore = nothingSpent(currency), nothingSpent(currency)
+		} else {
+			if activity, err = s.spending(ctx, envelope, month, end); err != nil {
+				return nil, err
+			}
+			if spentBefore, err = s.spending(ctx, envelope, since, month); err != nil {
+				return nil, err
+			}
+		}
+
+		t := byBudget[envelope.ID]
+		monthAssigned = monthAssigned.Add(t.Assigned)
+		status := newEnvelopeStatus(envelope, t, activity, spentBefore, currency)
+		if status.IsOverspent {
+			overspent = overspent.Add(status.Available.Amount.Neg())
+			summary.OverspentCount++
+		}
+		summary.Envelopes = append(summary.Envelopes, status)
+	}
+
+	summary.Assigned = common.NewMoney(monthAssigned, currency)
+	summary.Overspent = common.NewMoney(overspent, currency)
+	return summary, nil
+}
+
+// incomeUntil sums the user's income received before end, converted into currency at the rate of
+// the day it was received. Income is read from the journal, where it is posted as a credit to its
+// category, and an income transaction with a linked income is posted once.
+func (s *Service) incomeUntil(ctx context.Context, db *gorm.DB, userID uuid.UUID, end time.Time, currency common.Currency) (*converted, error) {
+	totals, err := ledger.CategoryActivity(db, userID, ledger.SourceIncome, nil, time.Time{}, end)
+	if err != nil {
+		return nil, err
+	}
+	for i := range totals {
+		totals[i].Total = totals[i].Total.Neg()
+	}
+	return s.convert(ctx, currency, totals)
+}
+
+// converted is a sum of daily totals in one currency. Total is nil when an exchange rate is
+// missing, and Err says which; ByCurrency always holds the unconverted sums.
+type converted struct {
+	Total      *common.Money
+	ByCurrency []common.Money
+	Err        error
+}
+
+// convert sums the totals into currency like convertTotals, but reports a missing exchange rate in
+// the result instead of failing, since rates are optional.
+func (s *Service) convert(ctx context.Context, currency common.Currency, totals []ledger.DailyTotal) (*converted, error) {
+	result := &converted{ByCurrency: nativeTotals(currency, totals)}
+	sum, err := s.convertTotals(ctx, currency, totals)
+	switch {
+	case err == nil:
+		total := common.NewMoney(sum, currency)
+		result.Total = &total
+	case errors.Is(err, fx.ErrRateNotFound):
+		result.Err = err
+	default:
+		return nil, err
+	}
+	return result, nil
+}
+
+func nothingSpent(currency common.Currency) *converted {
+	zero := common.ZeroMoney(currency)
+	return &converted{Total: &zero, ByCurrency: []common.Money{zero}}
+}
+
+// convertTotals converts each daily total into currency at the rate of its day and adds them up.
+func (s *Service) convertTotals(ctx context.Context, currency common.Currency, totals []ledger.DailyTotal) (decimal.Decimal, error) {
+	converter := s.rates.NewConverter(currency)
+	sum := decimal.Zero
+	for _, t := range totals {
+		converted, err := converter.Convert(ctx, t.Total, t.Currency, t.Day)
+		if err != nil {
+			return decimal.Zero, err
+		}
+		sum = sum.Add(converted)
+	}
+	return sum, nil
+}
+
+func findEnvelope(summary *EnvelopeSummary, budgetID uuid.UUID) (*EnvelopeStatus, error) {
+	for i := range summary.Envelopes {
+		if summary.Envelopes[i].BudgetID == budgetID {
+			return &summary.Envelopes[i], nil
+		}
+	}
+	return nil, errors.New("envelope not found")
+}
+
+// allocationTotals are the amounts assigned to an envelope in a month and in all months up to its end.
+type allocationTotals struct {
+	BudgetID   uuid.UUID
+	Assigned   decimal.Decimal
+	Cumulative decimal.Decimal
+}
+
+// newEnvelopeStatus returns the state of the envelope in a month from what was assigned to it and
+// what was spent from it in the month and before it. The available amount carries over from month
+// to month, so money assigned and not spent in earlier months is still available.
+func newEnvelopeStatus(envelope *Budget, t allocationTotals, activity, spentBefore *converted, currency common.Currency) EnvelopeStatus {
+	status := EnvelopeStatus{
+		BudgetID:           envelope.ID,
+		Name:               envelope.Name,
+		CategoryID:         envelope.CategoryID,
+		Category:           envelope.Category,
+		Target:             envelope.Amount,
+		Assigned:           common.NewMoney(t.Assigned, currency),
+		Activity:           activity.Total,
+		ActivityByCurrency: activity.ByCurrency,
+	}
+	switch {
+	case activity.Err != nil:
+		status.rateErr = activity.Err
+	case spentBefore.Err != nil:
+		status.rateErr = spentBefore.Err
+	default:
+		carriedOver := common.NewMoney(t.Cumulative.Sub(t.Assigned).Sub(spentBefore.Total.Amount), currency)
+		available := common.NewMoney(carriedOver.Amount.Add(t.Assigned).Sub(activity.Total.Amount), currency)
+		status.CarriedOver = &carriedOver
+		status.Available = &available
+		status.IsOverspent = available.IsNegative()
+	}
+	if status.rateErr != nil {
+		status.RateError = status.rateErr.Error()
+	}
+	return status
+}
+
+// checkAssign reports whether the amount can be assigned to the envelope: money can only be
+// assigned while there is income left to assign, and only money still available in the envelope
+// can be taken back out with a negative amount.
+func checkAssign(summary *EnvelopeSummary, envelope *EnvelopeStatus, amount common.Money) error {
+	switch {
+	case amount.IsZero():
+		return errors.New("amount cannot be zero")
+	case amount.IsPositive():
+		if summary.ToBeAssigned == nil {
+			return summary.rateErr
+		}
+		if amount.Amount.GreaterThan(summary.ToBeAssigned.Amount) {
+			return fmt.Errorf("only %s is left to assign", summary.ToBeAssigned)
+		}
+	default:
+		if envelope.Available == nil {
+			return envelope.rateErr
+		}
+		if amount.Amount.Neg().GreaterThan(envelope.Available.Amount) {
+			return fmt.Errorf("only %s is available in %s", envelope.Available, envelope.Name)
+		}
+	}
+	return nil
+}
+
+// checkMove reports whether the amount can be moved out of the envelope.
+func checkMove(from *EnvelopeStatus, amount common.Money) error {
+	if !amount.IsPositive() {
+		return errors.New("amount must be greater than zero")
+	}
+	if from.Available == nil {
+		return from.rateErr
+	}
+	if amount.Amount.GreaterThan(from.Available.Amount) {
+		return fmt.Errorf("only %s is available in %s", from.Available, from.Name)
+	}
+	return nil
+}
+
+// overspending returns the amount that brings the overspent envelope back to zero.
+func overspending(envelope *EnvelopeStatus) (common.Money, error) {
+	if envelope.Available == nil {
+		return common.Money{}, envelope.rateErr
+	}
+	if !envelope.IsOverspent {
+		return common.Money{}, fmt.Errorf("%s is not overspent", envelope.Name)
+	}
+	return envelope.Available.Neg(), nil
+}
+
+func newAllocation(userID, budgetID uuid.UUID, month time.Time, amount common.Money, kind AllocationKind, transferID *uuid.UUID, note string) *EnvelopeAllocation {
+	allocation := &EnvelopeAllocation{
+		UserID:     userID,
+		BudgetID:   budgetID,
+		Month:      month,
+		Amount:     amount,
+		Kind:       kind,
+		TransferID: transferID,
+		Note:       note,
+	}
+	allocation.ID = uuid.New()
+	return allocation
 }
@@ -0,0 +1,180 @@
+package budget
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/pastorenue/kinance/internal/common"
+	"github.com/pastorenue/kinance/internal/fx"
+	"github.com/shopspring/decimal"
+)
+
+var errNoRate = fmt.Errorf("%w: no USD to EUR rate on 2026-10-14", fx.ErrRateNotFound)
+
+// spent returns a converted EUR total.
+func spent(amount string) *converted {
+	total := money(amount, "EUR")
+	return &converted{Total: &total, ByCurrency: []common.Money{total}}
+}
+
+// envelope returns the status of an envelope with the given available amount, or with a missing
+// exchange rate when available is empty.
+func envelope(available string) *EnvelopeStatus {
+	status := &EnvelopeStatus{BudgetID: uuid.New(), Name: "Groceries"}
+	if available == "" {
+		status.rateErr = errNoRate
+		return status
+	}
+	amount := money(available, "EUR")
+	status.Available = &amount
+	status.IsOverspent = amount.IsNegative()
+	return status
+}
+
+func TestNewEnvelopeStatus(t *testing.T) {
+	missing := &converted{ByCurrency: []common.Money{money("0", "EUR"), money("12", "USD")}, Err: errNoRate}
+	tests := []struct {
+		name                  string
+		assigned, cumulative  string
+		spentBefore, activity *converted
+		carriedOver           string
+		available             string
+		overspent             bool
+	}{
+		{name: "first month", assigned: "200", cumulative: "200", spentBefore: spent("0"), activity: spent("50"), carriedOver: "0", available: "150"},
+		{name: "leftovers carry over", assigned: "100", cumulative: "300", spentBefore: spent("120"), activity: spent("30"), carriedOver: "80", available: "150"},
+		{name: "nothing assigned this month", assigned: "0", cumulative: "300", spentBefore: spent("120"), activity: spent("30"), carriedOver: "180", available: "150"},
+		{name: "overspent", assigned: "50", cumulative: "50", spentBefore: spent("0"), activity: spent("80"), carriedOver: "0", available: "-30", overspent: true},
+		{name: "overspending carries over", assigned: "0", cumulative: "100", spentBefore: spent("130"), activity: spent("0"), carriedOver: "-30", available: "-30", overspent: true},
+		{name: "spent exactly what was assigned", assigned: "80", cumulative: "80", spentBefore: spent("0"), activity: spent("80"), carriedOver: "0", available: "0"},
+		{name: "rate missing this month", assigned: "100", cumulative: "100", spentBefore: spent("0"), activity: missing},
+		{name: "rate missing before", assigned: "100", cumulative: "300", spentBefore: missing, activity: spent("30")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			budget := &Budget{Name: "Groceries", Amount: money("300", "EUR")}
+			totals := allocationTotals{Assigned: decimal.RequireFromString(tt.assigned), Cumulative: decimal.RequireFromString(tt.cumulative)}
+			got := newEnvelopeStatus(budget, totals, tt.activity, tt.spentBefore, "EUR")
+
+			if got.Assigned.Amount.String() != tt.assigned || got.Activity != tt.activity.Total || len(got.ActivityByCurrency) != len(tt.activity.ByCurrency) {
+				t.Errorf("assigned %s, activity %v %v, want %s and the activity of the month", got.Assigned, got.Activity, got.ActivityByCurrency, tt.assigned)
+			}
+			if tt.available == "" {
+				if got.CarriedOver != nil || got.Available != nil || got.IsOverspent || !errors.Is(got.rateErr, fx.ErrRateNotFound) || got.RateError == "" {
+					t.Errorf("status = %+v, want null amounts and the missing rate", got)
+				}
+				return
+			}
+			if got.CarriedOver == nil || got.Available == nil {
+				t.Fatalf("status = %+v, want carried over and available amounts", got)
+			}
+			if got.CarriedOver.Amount.String() != tt.carriedOver || got.Available.Amount.String() != tt.available || got.IsOverspent != tt.overspent {
+				t.Errorf("carried over %s, available %s, overspent %v, want %s, %s, %v",
+					got.CarriedOver.Amount, got.Available.Amount, got.IsOverspent, tt.carriedOver, tt.available, tt.overspent)
+			}
+			if got.RateError != "" {
+				t.Errorf("rate error = %q, want none", got.RateError)
+			}
+		})
+	}
+}
+
+func TestCheckAssign(t *testing.T) {
+	toBeAssigned := money("100", "EUR")
+	income := &EnvelopeSummary{ToBeAssigned: &toBeAssigned}
+	noIncome := &EnvelopeSummary{rateErr: errNoRate}
+
+	tests := []struct {
+		name     string
+		summary  *EnvelopeSummary
+		envelope *EnvelopeStatus
+		amount   string
+		want     string
+		wantRate bool
+	}{
+		{name: "part of the income", summary: income, envelope: envelope("40"), amount: "60"},
+		{name: "all of the income", summary: income, envelope: envelope("40"), amount: "100"},
+		{name: "more than the income", summary: income, envelope: envelope("40"), amount: "100.01", want: "is left to assign"},
+		{name: "zero", summary: income, envelope: envelope("40"), amount: "0", want: "cannot be zero"},
+		{name: "take back what is available", summary: income, envelope: envelope("40"), amount: "-40"},
+		{name: "take back more than is available", summary: income, envelope: envelope("40"), amount: "-40.01", want: "is available in Groceries"},
+		{name: "take back from an overspent envelope", summary: income, envelope: envelope("-10"), amount: "-1", want: "is available in Groceries"},
+		{name: "income in an unconverted currency", summary: noIncome, envelope: envelope("40"), amount: "10", wantRate: true},
+		{name: "take back while the income is unconverted", summary: noIncome, envelope: envelope("40"), amount: "-10"},
+		{name: "take back from an unconverted envelope", summary: income, envelope: envelope(""), amount: "-10", wantRate: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkAssign(tt.summary, tt.envelope, money(tt.amount, "EUR"))
+			checkError(t, err, tt.want, tt.wantRate)
+		})
+	}
+}
+
+func TestCheckMove(t *testing.T) {
+	tests := []struct {
+		name     string
+		from     *EnvelopeStatus
+		amount   string
+		want     string
+		wantRate bool
+	}{
+		{name: "part of what is available", from: envelope("40"), amount: "25"},
+		{name: "all that is available", from: envelope("40"), amount: "40"},
+		{name: "more than is available", from: envelope("40"), amount: "40.01", want: "only 40"},
+		{name: "from an overspent envelope", from: envelope("-10"), amount: "5", want: "is available in Groceries"},
+		{name: "zero", from: envelope("40"), amount: "0", want: "greater than zero"},
+		{name: "negative", from: envelope("40"), amount: "-5", want: "greater than zero"},
+		{name: "from an unconverted envelope", from: envelope(""), amount: "5", wantRate: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkError(t, checkMove(tt.from, money(tt.amount, "EUR")), tt.want, tt.wantRate)
+		})
+	}
+}
+
+func TestOverspending(t *testing.T) {
+	tests := []struct {
+		name     string
+		envelope *EnvelopeStatus
+		amount   string
+		want     string
+		wantRate bool
+	}{
+		{name: "overspent", envelope: envelope("-30.25"), amount: "30.25"},
+		{name: "money left", envelope: envelope("10"), want: "Groceries is not overspent"},
+		{name: "empty", envelope: envelope("0"), want: "Groceries is not overspent"},
+		{name: "unconverted", envelope: envelope(""), wantRate: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			amount, err := overspending(tt.envelope)
+			checkError(t, err, tt.want, tt.wantRate)
+			if err == nil && amount.Amount.String() != tt.amount {
+				t.Errorf("overspending = %s, want %s", amount.Amount, tt.amount)
+			}
+		})
+	}
+}
+
+// checkError fails unless err is nil, wraps fx.ErrRateNotFound when wantRate is set, or
+// contains want.
+func checkError(t *testing.T, err error, want string, wantRate bool) {
+	t.Helper()
+	switch {
+	case wantRate:
+		if !errors.Is(err, fx.ErrRateNotFound) {
+			t.Errorf("error = %v, want ErrRateNotFound", err)
+		}
+	case want != "":
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("error = %v, want one containing %q", err, want)
+		}
+	case err != nil:
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+package budget
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/pastorenue/kinance/internal/common"
+	"github.com/pastorenue/kinance/internal/ledger"
+	"github.com/shopspring/decimal"
+)
+
+// PeriodAt returns the [start, end) window of the budget period containing t, in UTC.
+// Weekly periods start on the AnchorDay weekday (1 = Monday), monthly periods on the AnchorDay
+// of the month and yearly periods on AnchorDay of AnchorMonth. Anchor days past the end of a
+// short month fall on its last day, so a period anchored on the 31st starts on Feb 28.
+func (b *Budget) PeriodAt(t time.Time) (time.Time, time.Time) {
+	day := dateOf(t)
+	anchorDay := b.AnchorDay
+	if anchorDay < 1 {
+		anchorDay = 1
+	}
+
+	switch b.Period {
+	case PeriodWeekly:
+		if anchorDay > 7 {
+			anchorDay = 1
+		}
+		weekday := (int(day.Weekday())+6)%7 + 1
+		start := day.AddDate(0, 0, -((weekday - anchorDay + 7) % 7))
+		return start, start.AddDate(0, 0, 7)
+	case PeriodYearly:
+		anchorMonth := time.Month(b.AnchorMonth)
+		if anchorMonth < time.January || anchorMonth > time.December {
+			anchorMonth = time.January
+		}
+		start := anchoredDate(day.Year(), anchorMonth, anchorDay)
+		if day.Before(start) {
+			start = anchoredDate(day.Year()-1, anchorMonth, anchorDay)
+		}
+		return start, anchoredDate(start.Year()+1, anchorMonth, anchorDay)
+	default:
+		start := anchoredDate(day.Year(), day.Month(), anchorDay)
+		if day.Before(start) {
+			start = anchoredDate(day.Year(), day.Month()-1, anchorDay)
+		}
+		return start, anchoredDate(start.Year(), start.Month()+1, anchorDay)
+	}
+}
+
+// RolloverFrom returns the amount a period with the given remaining balance carries into the next one.
+func (b *Budget) RolloverFrom(remaining common.Money) common.Money {
+	switch b.Rollover {
+	case RolloverAll:
+		return remaining
+	case RolloverUnspent:
+		if remaining.IsPositive() {
+			return remaining
+		}
+	}
+	return common.ZeroMoney(remaining.Currency)
+}
+
+// nativeTotals adds up the daily totals per currency without converting, currency first and the
+// others in alphabetical order.
+func nativeTotals(currency common.Currency, totals []ledger.DailyTotal) []common.Money {
+	sums := map[common.Currency]decimal.Decimal{currency: decimal.Zero}
+	currencies := []common.Currency{currency}
+	for _, t := range totals {
+		if _, ok := sums[t.Currency]; !ok {
+			currencies = append(currencies, t.Currency)
+		}
+		sums[t.Currency] = sums[t.Currency].Add(t.Total)
+	}
+	others := currencies[1:]
+	sort.Slice(others, func(i, j int) bool { return others[i] < others[j] })
+
+	result := make([]common.Money, len(currencies))
+	for i, c := range currencies {
+		result[i] = common.NewMoney(sums[c], c)
+	}
+	return result
+}
+
+// rolloverOutOf returns what the closed period last carries into the next one, or zero when there
+// is no closed period yet.
+func rolloverOutOf(last *BudgetPeriod, currency common.Currency) common.Money {
+	if last == nil {
+		return common.ZeroMoney(currency)
+	}
+	return last.RolloverOut
+}
+
+// anchoredDate returns the given day of the month, clamped to the last day of short months.
+// Months outside 1-12 are normalised, so month 0 is December of the previous year.
+func anchoredDate(year int, month time.Month, day int) time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	lastDay := first.AddDate(0, 1, -1).Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+// daysLeft returns the number of calendar days from t until end, counting the day of t.
+func daysLeft(t, end time.Time) int {
+	day := dateOf(t)
+	if !day.Before(end) {
+		return 0
+	}
+	return int(end.Sub(day).Hours() / 24)
+}
+
+func dateOf(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// monthOf returns the first day of the month containing t, in UTC.
+func monthOf(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// parseMonth parses a YYYY-MM month, defaulting to the current month when empty.
+func parseMonth(value string) (time.Time, error) {
+	if value == "" {
+		return monthOf(time.Now()), nil
+	}
+	month, err := time.Parse("2006-01", value)
+	if err != nil {
+		return time.Time{}, errors.New("month must be in YYYY-MM format")
+	}
+	return month, nil
+}
@@ -0,0 +1,170 @@
+package budget
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pastorenue/kinance/internal/common"
+	"github.com/pastorenue/kinance/internal/ledger"
+	"github.com/shopspring/decimal"
+)
+
+const dateLayout = "2006-01-02"
+
+func day(t *testing.T, value string) time.Time {
+	t.Helper()
+	d, err := time.Parse(dateLayout, value)
+	if err != nil {
+		t.Fatalf("parse %q: %v", value, err)
+	}
+	return d
+}
+
+func money(amount string, currency common.Currency) common.Money {
+	return common.Money{Amount: decimal.RequireFromString(amount), Currency: currency}
+}
+
+func TestPeriodAt(t *testing.T) {
+	tests := []struct {
+		name        string
+		period      Period
+		anchorDay   int
+		anchorMonth int
+		at          string
+		start, end  string
+	}{
+		{name: "monthly on the first", period: PeriodMonthly, anchorDay: 1, at: "2026-10-17", start: "2026-10-01", end: "2026-11-01"},
+		{name: "monthly without an anchor", period: PeriodMonthly, at: "2026-10-17", start: "2026-10-01", end: "2026-11-01"},
+		{name: "monthly before the anchor", period: PeriodMonthly, anchorDay: 15, at: "2026-01-10", start: "2025-12-15", end: "2026-01-15"},
+		{name: "31st in February", period: PeriodMonthly, anchorDay: 31, at: "2026-02-15", start: "2026-01-31", end: "2026-02-28"},
+		{name: "31st from February 28", period: PeriodMonthly, anchorDay: 31, at: "2026-02-28", start: "2026-02-28", end: "2026-03-31"},
+		{name: "31st in March", period: PeriodMonthly, anchorDay: 31, at: "2026-03-30", start: "2026-02-28", end: "2026-03-31"},
+		{name: "30th in a leap February", period: PeriodMonthly, anchorDay: 30, at: "2028-02-29", start: "2028-02-29", end: "2028-03-30"},
+		{name: "31st in April", period: PeriodMonthly, anchorDay: 31, at: "2026-04-30", start: "2026-04-30", end: "2026-05-31"},
+		{name: "weekly from Monday", period: PeriodWeekly, anchorDay: 1, at: "2026-10-17", start: "2026-10-12", end: "2026-10-19"},
+		{name: "weekly from Sunday", period: PeriodWeekly, anchorDay: 7, at: "2026-10-17", start: "2026-10-11", end: "2026-10-18"},
+		{name: "weekly on the anchor day", period: PeriodWeekly, anchorDay: 6, at: "2026-10-17", start: "2026-10-17", end: "2026-10-24"},
+		{name: "weekly past Sunday starts on Monday", period: PeriodWeekly, anchorDay: 9, at: "2026-10-17", start: "2026-10-12", end: "2026-10-19"},
+		{name: "yearly from April 6", period: PeriodYearly, anchorDay: 6, anchorMonth: 4, at: "2026-10-17", start: "2026-04-06", end: "2027-04-06"},
+		{name: "yearly before the anchor month", period: PeriodYearly, anchorDay: 6, anchorMonth: 4, at: "2026-03-01", start: "2025-04-06", end: "2026-04-06"},
+		{name: "yearly from February 29", period: PeriodYearly, anchorDay: 29, anchorMonth: 2, at: "2026-10-17", start: "2026-02-28", end: "2027-02-28"},
+		{name: "yearly in a leap year", period: PeriodYearly, anchorDay: 29, anchorMonth: 2, at: "2028-02-29", start: "2028-02-29", end: "2029-02-28"},
+		{name: "yearly without an anchor month", period: PeriodYearly, anchorDay: 1, at: "2026-10-17", start: "2026-01-01", end: "2027-01-01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &Budget{Period: tt.period, AnchorDay: tt.anchorDay, AnchorMonth: tt.anchorMonth}
+			start, end := b.PeriodAt(day(t, tt.at))
+			if start.Format(dateLayout) != tt.start || end.Format(dateLayout) != tt.end {
+				t.Errorf("PeriodAt(%s) = %s to %s, want %s to %s", tt.at,
+					start.Format(dateLayout), end.Format(dateLayout), tt.start, tt.end)
+			}
+		})
+	}
+}
+
+func TestPeriodAtUTC(t *testing.T) {
+	// 23:30 on October 31 in New York is already November in UTC.
+	at := time.Date(2026, 10, 31, 23, 30, 0, 0, time.FixedZone("EST", -5*60*60))
+	start, end := (&Budget{Period: PeriodMonthly, AnchorDay: 1}).PeriodAt(at)
+	if start.Format(dateLayout) != "2026-11-01" || end.Format(dateLayout) != "2026-12-01" || start.Location() != time.UTC {
+		t.Errorf("PeriodAt = %s to %s, want the UTC period 2026-11-01 to 2026-12-01", start, end)
+	}
+}
+
+func TestAnchoredDate(t *testing.T) {
+	tests := []struct {
+		year  int
+		month time.Month
+		day   int
+		want  string
+	}{
+		{year: 2026, month: time.October, day: 17, want: "2026-10-17"},
+		{year: 2026, month: time.February, day: 31, want: "2026-02-28"},
+		{year: 2028, month: time.February, day: 31, want: "2028-02-29"},
+		{year: 2026, month: time.April, day: 31, want: "2026-04-30"},
+		{year: 2026, month: 0, day: 15, want: "2025-12-15"},
+		{year: 2026, month: 13, day: 31, want: "2027-01-31"},
+		{year: 2026, month: -1, day: 31, want: "2025-11-30"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := anchoredDate(tt.year, tt.month, tt.day).Format(dateLayout); got != tt.want {
+				t.Errorf("anchoredDate(%d, %d, %d) = %s, want %s", tt.year, tt.month, tt.day, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRolloverFrom(t *testing.T) {
+	tests := []struct {
+		name      string
+		mode      RolloverMode
+		remaining string
+		want      string
+	}{
+		{name: "none keeps nothing left over", mode: RolloverNone, remaining: "120.50", want: "0"},
+		{name: "none keeps no overspending", mode: RolloverNone, remaining: "-30", want: "0"},
+		{name: "unset mode carries nothing", remaining: "120.50", want: "0"},
+		{name: "unspent carries what was left", mode: RolloverUnspent, remaining: "120.50", want: "120.5"},
+		{name: "unspent drops overspending", mode: RolloverUnspent, remaining: "-30", want: "0"},
+		{name: "all carries what was left", mode: RolloverAll, remaining: "120.50", want: "120.5"},
+		{name: "all carries overspending", mode: RolloverAll, remaining: "-30", want: "-30"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := (&Budget{Rollover: tt.mode}).RolloverFrom(money(tt.remaining, "EUR"))
+			if got.Amount.String() != tt.want || got.Currency != "EUR" {
+				t.Errorf("RolloverFrom(%s) = %s %s, want %s EUR", tt.remaining, got.Amount, got.Currency, tt.want)
+			}
+		})
+	}
+}
+
+func TestDaysLeft(t *testing.T) {
+	end := day(t, "2026-11-01")
+	tests := []struct {
+		at   time.Time
+		want int
+	}{
+		{at: day(t, "2026-10-01"), want: 31},
+		{at: time.Date(2026, 10, 31, 18, 0, 0, 0, time.UTC), want: 1},
+		{at: end, want: 0},
+		{at: day(t, "2026-12-01"), want: 0},
+	}
+	for _, tt := range tests {
+		if got := daysLeft(tt.at, end); got != tt.want {
+			t.Errorf("daysLeft(%s) = %d, want %d", tt.at.Format(time.RFC3339), got, tt.want)
+		}
+	}
+}
+
+func TestNativeTotals(t *testing.T) {
+	total := func(currency common.Currency, amount string) ledger.DailyTotal {
+		return ledger.DailyTotal{Currency: currency, Total: decimal.RequireFromString(amount)}
+	}
+	tests := []struct {
+		name   string
+		totals []ledger.DailyTotal
+		want   string
+	}{
+		{name: "no activity", want: "0 EUR"},
+		{
+			name:   "budget currency first, the others in order",
+			totals: []ledger.DailyTotal{total("USD", "10"), total("EUR", "4.50"), total("CHF", "3"), total("USD", "2.25")},
+			want:   "4.5 EUR, 3 CHF, 12.25 USD",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, m := range nativeTotals("EUR", tt.totals) {
+				got = append(got, m.Amount.String()+" "+string(m.Currency))
+			}
+			if strings.Join(got, ", ") != tt.want {
+				t.Errorf("nativeTotals = %s, want %s", strings.Join(got, ", "), tt.want)
+			}
+		})
+	}
+}
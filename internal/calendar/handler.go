@@ -0,0 +1,85 @@
+package calendar
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/pastorenue/kinance/internal/common"
+	"github.com/pastorenue/kinance/pkg/middleware"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) CreateFeedToken(c *gin.Context) {
+	userID, _ := c.Get(middleware.UserIDKey)
+
+	token, feedToken, err := h.service.CreateFeedToken(c.Request.Context(), userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, common.APIResponse{
+			Success:    false,
+			StatusCode: http.StatusInternalServerError,
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+
+	c.JSON(http.StatusCreated, common.APIResponse{
+		Success:    true,
+		StatusCode: http.StatusCreated,
+		Message:    "Store this token now, it will not be shown again",
+		Data: FeedTokenResponse{
+			Token:     token,
+			FeedURL:   fmt.Sprintf("%s://%s/api/v1/calendar/feed/%s/bills.ics", scheme, c.Request.Host, token),
+			CreatedAt: feedToken.CreatedAt,
+		},
+	})
+}
+
+func (h *Handler) RevokeFeedToken(c *gin.Context) {
+	userID, _ := c.Get(middleware.UserIDKey)
+
+	if err := h.service.RevokeFeedToken(c.Request.Context(), userID.(uuid.UUID)); err != nil {
+		c.JSON(http.StatusInternalServerError, common.APIResponse{
+			Success:    false,
+			StatusCode: http.StatusInternalServerError,
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, common.APIResponse{
+		Success:    true,
+		StatusCode: http.StatusOK,
+		Message:    "Calendar token revoked successfully",
+	})
+}
+
+func (h *Handler) GetBillsFeed(c *gin.Context) {
+	feed, err := h.service.GetBillsFeed(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrInvalidFeedToken) {
+			status = http.StatusNotFound
+		}
+		c.String(status, err.Error())
+		return
+	}
+
+	c.Header("Content-Disposition", `inline; filename="bills.ics"`)
+	c.Header("Cache-Control", "private, max-age=900")
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(feed))
+}
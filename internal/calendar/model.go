@@ -0,0 +1,24 @@
+package calendar
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pastorenue/kinance/internal/common"
+)
+
+// FeedToken grants read access to a user's bills feed. Calendar apps cannot send bearer
+// headers, so the token travels in the feed URL; only its SHA-256 hash is stored.
+type FeedToken struct {
+	common.BaseModel
+	UserID     uuid.UUID  `json:"user_id" gorm:"not null;index"`
+	TokenHash  string     `json:"-" gorm:"type:char(64);not null;uniqueIndex"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+type FeedTokenResponse struct {
+	Token     string    `json:"token"` // Only returned once, when the token is created
+	FeedURL   string    `json:"feed_url"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,16 @@
+package calendar
+
+import "github.com/gin-gonic/gin"
+
+func RegisterRoutes(versionedGroup *gin.RouterGroup, svc *Service) {
+	calendarHandler := NewHandler(svc)
+	protected := versionedGroup.Group("/calendar")
+	protected.POST("/token", calendarHandler.CreateFeedToken)
+	protected.DELETE("/token", calendarHandler.RevokeFeedToken)
+}
+
+// RegisterFeedRoutes registers the token-protected feed outside the JWT-protected group.
+func RegisterFeedRoutes(versionedGroup *gin.RouterGroup, svc *Service) {
+	calendarHandler := NewHandler(svc)
+	versionedGroup.GET("/calendar/feed/:token/bills.ics", calendarHandler.GetBillsFeed)
+}
@@ -0,0 +1,197 @@
+package calendar
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pastorenue/kinance/internal/common"
+	"github.com/pastorenue/kinance/internal/expense"
+	"gorm.io/gorm"
+)
+
+const (
+	feedPastWindow   = 30 * 24 * time.Hour
+	feedFutureWindow = 365 * 24 * time.Hour
+	productID        = "-//Kinance//Bills Calendar//EN"
+)
+
+var ErrInvalidFeedToken = errors.New("invalid or revoked calendar token")
+
+type Service struct {
+	db         *gorm.DB
+	expenseSvc *expense.Service
+	logger     common.Logger
+}
+
+func NewService(db *gorm.DB, expenseSvc *expense.Service, logger common.Logger) *Service {
+	return &Service{db: db, expenseSvc: expenseSvc, logger: logger}
+}
+
+// CreateFeedToken issues a new feed token for the user and revokes any previous one,
+// so there is always at most one working feed URL per user.
+func (s *Service) CreateFeedToken(ctx context.Context, userID uuid.UUID) (string, *FeedToken, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", nil, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	feedToken := &FeedToken{
+		UserID:    userID,
+		TokenHash: hashToken(token),
+	}
+	feedToken.ID = uuid.New()
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&FeedToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", userID).
+			Update("revoked_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Create(feedToken).Error
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	s.logger.Info("Calendar feed token created", "user_id", userID, "token_id", feedToken.ID)
+	return token, feedToken, nil
+}
+
+func (s *Service) RevokeFeedToken(ctx context.Context, userID uuid.UUID) error {
+	result := s.db.WithContext(ctx).Model(&FeedToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("no active calendar token")
+	}
+
+	s.logger.Info("Calendar feed token revoked", "user_id", userID)
+	return nil
+}
+
+// GetBillsFeed renders the upcoming recurring bills of the token owner as an iCalendar document.
+func (s *Service) GetBillsFeed(ctx context.Context, token string) (string, error) {
+	var feedToken FeedToken
+	if err := s.db.WithContext(ctx).
+		Where("token_hash = ? AND revoked_at IS NULL", hashToken(token)).
+		First(&feedToken).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrInvalidFeedToken
+		}
+		return "", err
+	}
+
+	now := time.Now().UTC()
+	if err := s.db.WithContext(ctx).Model(&feedToken).Update("last_used_at", now).Error; err != nil {
+		s.logger.Error("Failed to record calendar token usage", "error", err, "token_id", feedToken.ID)
+	}
+
+	projection, err := s.expenseSvc.GetRecurringExpenseProjection(ctx, feedToken.UserID, now.Add(-feedPastWindow), now.Add(feedFutureWindow))
+	if err != nil {
+		return "", err
+	}
+
+	categoryNames := make(map[uuid.UUID]string, len(projection.Categories))
+	for _, c := range projection.Categories {
+		categoryNames[c.CategoryID] = c.CategoryName
+	}
+
+	var b strings.Builder
+	writeLine(&b, "BEGIN:VCALENDAR")
+	writeLine(&b, "VERSION:2.0")
+	writeLine(&b, "PRODID:"+productID)
+	writeLine(&b, "CALSCALE:GREGORIAN")
+	writeLine(&b, "METHOD:PUBLISH")
+	writeLine(&b, "X-WR-CALNAME:Kinance bills")
+
+	stamp := now.Format("20060102T150405Z")
+	for _, day := range projection.Days {
+		for _, occurrence := range day.Occurrences {
+			if !occurrence.IsBillable() {
+				continue
+			}
+			writeEvent(&b, occurrence, categoryNames[occurrence.CategoryID], stamp)
+		}
+	}
+
+	writeLine(&b, "END:VCALENDAR")
+	return b.String(), nil
+}
+
+func writeEvent(b *strings.Builder, occurrence expense.Occurrence, categoryName string, stamp string) {
+	dueDate := occurrence.DueDate.UTC()
+	amount := occurrence.Amount.String()
+
+	summary := occurrence.Description
+	if summary == "" {
+		summary = "Bill"
+	}
+	if categoryName == "" {
+		categoryName = occurrence.CategoryID.String()
+	}
+	description := fmt.Sprintf(
+		"Amount: %s\nCategory: %s\nPayment method: %s",
+		amount,
+		categoryName,
+		occurrence.PaymentMethod,
+	)
+
+	writeLine(b, "BEGIN:VEVENT")
+	// The UID only depends on the series and the due day, so clients update events in place
+	writeLine(b, fmt.Sprintf("UID:%s-%s@kinance", occurrence.RecurringExpenseID, dueDate.Format("20060102")))
+	writeLine(b, "DTSTAMP:"+stamp)
+	writeLine(b, "DTSTART;VALUE=DATE:"+dueDate.Format("20060102"))
+	writeLine(b, "DTEND;VALUE=DATE:"+dueDate.AddDate(0, 0, 1).Format("20060102"))
+	writeLine(b, "SUMMARY:"+escapeText(fmt.Sprintf("%s (%s)", summary, amount)))
+	writeLine(b, "DESCRIPTION:"+escapeText(description))
+	writeLine(b, "CATEGORIES:"+escapeText(categoryName))
+	writeLine(b, "TRANSP:TRANSPARENT")
+	writeLine(b, "END:VEVENT")
+}
+
+// writeLine writes a content line terminated by CRLF, folding it at 75 octets as RFC 5545 requires.
+func writeLine(b *strings.Builder, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		// Never split a multi-byte UTF-8 sequence
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines start with a space that counts towards the limit
+		limit = 74
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
+
+func escapeText(s string) string {
+	replacer := strings.NewReplacer(
+		"\\", "\\\\",
+		";", "\\;",
+		",", "\\,",
+		"\r\n", "\\n",
+		"\n", "\\n",
+	)
+	return replacer.Replace(s)
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
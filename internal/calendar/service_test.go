@@ -0,0 +1,140 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/pastorenue/kinance/internal/common"
+	"github.com/pastorenue/kinance/internal/expense"
+	"github.com/shopspring/decimal"
+)
+
+// unfold joins folded content lines back together, as calendar clients do.
+func unfold(s string) string {
+	return strings.ReplaceAll(s, "\r\n ", "")
+}
+
+func TestWriteLine(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		lines []int // Octets of each physical line, without the CRLF
+	}{
+		{name: "short", line: "SUMMARY:Rent", lines: []int{12}},
+		{name: "exactly 75 octets", line: strings.Repeat("a", 75), lines: []int{75}},
+		{name: "76 octets", line: strings.Repeat("a", 76), lines: []int{75, 2}},
+		{name: "continuations hold 74 octets after the space", line: strings.Repeat("a", 224), lines: []int{75, 75, 75, 2}},
+		{name: "multi-byte character at the limit", line: strings.Repeat("a", 74) + "é" + "b", lines: []int{74, 4}},
+		{name: "multi-byte characters only", line: strings.Repeat("€", 30), lines: []int{75, 16}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			writeLine(&b, tt.line)
+			out := b.String()
+
+			if !strings.HasSuffix(out, "\r\n") {
+				t.Fatalf("output %q does not end with CRLF", out)
+			}
+			physical := strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n")
+			if len(physical) != len(tt.lines) {
+				t.Fatalf("got %d lines %q, want %d", len(physical), physical, len(tt.lines))
+			}
+			for i, line := range physical {
+				if len(line) != tt.lines[i] {
+					t.Errorf("line %d is %d octets, want %d", i, len(line), tt.lines[i])
+				}
+				if i > 0 && !strings.HasPrefix(line, " ") {
+					t.Errorf("continuation line %d = %q, want a leading space", i, line)
+				}
+				if !utf8.ValidString(line) {
+					t.Errorf("line %d = %q splits a UTF-8 sequence", i, line)
+				}
+			}
+			if got := strings.TrimSuffix(unfold(out), "\r\n"); got != tt.line {
+				t.Errorf("unfolded = %q, want %q", got, tt.line)
+			}
+		})
+	}
+}
+
+func TestEscapeText(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "Rent", want: "Rent"},
+		{text: "Rent, October", want: `Rent\, October`},
+		{text: "Gas; electricity", want: `Gas\; electricity`},
+		{text: "Amount: 10\nCategory: Home", want: `Amount: 10\nCategory: Home`},
+		{text: "Windows\r\nline", want: `Windows\nline`},
+		{text: `C:\bills`, want: `C:\\bills`},
+		{text: `already \, escaped`, want: `already \\\, escaped`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := escapeText(tt.text); got != tt.want {
+				t.Errorf("escapeText(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteEvent(t *testing.T) {
+	series := uuid.MustParse("0b5c3f0e-7d1a-4c2b-9a8e-3f6d2c1b0a99")
+	occurrence := expense.Occurrence{
+		RecurringExpenseID: series,
+		DueDate:            time.Date(2026, 10, 31, 0, 0, 0, 0, time.UTC),
+		Amount:             common.NewMoney(decimal.RequireFromString("1250"), "EUR"),
+		CategoryID:         uuid.New(),
+		Description:        "Rent; flat, 2nd floor",
+		PaymentMethod:      common.BankTransfer,
+		Status:             expense.OccurrenceScheduled,
+	}
+	render := func(o expense.Occurrence, stamp string) map[string]string {
+		var b strings.Builder
+		writeEvent(&b, o, "Housing", stamp)
+		properties := make(map[string]string)
+		for _, line := range strings.Split(strings.TrimSuffix(unfold(b.String()), "\r\n"), "\r\n") {
+			name, value, _ := strings.Cut(line, ":")
+			properties[name] = value
+		}
+		return properties
+	}
+
+	first := render(occurrence, "20261017T080000Z")
+	if want := "0b5c3f0e-7d1a-4c2b-9a8e-3f6d2c1b0a99-20261031@kinance"; first["UID"] != want {
+		t.Errorf("UID = %q, want %q", first["UID"], want)
+	}
+	if first["DTSTART;VALUE=DATE"] != "20261031" || first["DTEND;VALUE=DATE"] != "20261101" {
+		t.Errorf("dates = %s to %s, want the due day as an all-day event", first["DTSTART;VALUE=DATE"], first["DTEND;VALUE=DATE"])
+	}
+	if !strings.HasPrefix(first["SUMMARY"], `Rent\; flat\, 2nd floor (`) {
+		t.Errorf("SUMMARY = %q, want the escaped description", first["SUMMARY"])
+	}
+
+	// Regenerating the feed later, with a changed amount or a due time on the same day, keeps the UID
+	changed := occurrence
+	changed.Amount = common.NewMoney(decimal.RequireFromString("1300"), "EUR")
+	changed.DueDate = occurrence.DueDate.Add(9 * time.Hour)
+	again := render(changed, "20261018T080000Z")
+	if again["UID"] != first["UID"] {
+		t.Errorf("UID changed between regenerations: %q and %q", first["UID"], again["UID"])
+	}
+	if again["DTSTAMP"] == first["DTSTAMP"] {
+		t.Errorf("DTSTAMP = %q in both feeds, want the time of each", again["DTSTAMP"])
+	}
+
+	next := occurrence
+	next.DueDate = occurrence.DueDate.AddDate(0, 1, 0)
+	other := occurrence
+	other.RecurringExpenseID = uuid.New()
+	for name, o := range map[string]expense.Occurrence{"next occurrence": next, "other series": other} {
+		if uid := render(o, "20261017T080000Z")["UID"]; uid == first["UID"] {
+			t.Errorf("%s has the same UID %q", name, uid)
+		}
+	}
+}
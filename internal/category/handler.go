@@ -7,6 +7,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/pastorenue/kinance/internal/common"
 	"github.com/pastorenue/kinance/pkg/middleware"
+	"github.com/pastorenue/kinance/pkg/pagination"
 )
 
 type Handler struct {
@@ -48,11 +49,21 @@ func (h *Handler) CreateCategory(c *gin.Context) {
 func (h *Handler) GetCategories(c *gin.Context) {
 	userID, _ := c.Get(middleware.UserIDKey)
 
-	categories, err := h.service.GetCategories(c.Request.Context(), userID.(uuid.UUID))
+	var params pagination.Params
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, common.APIResponse{
+			Success:    false,
+			StatusCode: http.StatusBadRequest,
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	categories, err := h.service.GetCategories(c.Request.Context(), userID.(uuid.UUID), params)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, common.APIResponse{
+		c.JSON(pagination.HTTPStatus(err), common.APIResponse{
 			Success:    false,
-			StatusCode: http.StatusInternalServerError,
+			StatusCode: pagination.HTTPStatus(err),
 			Error:      err.Error(),
 		})
 		return
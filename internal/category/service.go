@@ -6,9 +6,12 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/pastorenue/kinance/internal/common"
+	"github.com/pastorenue/kinance/pkg/pagination"
 	"gorm.io/gorm"
 )
 
+var ErrCategoryNotFound = errors.New("category not found")
+
 type Service struct {
 	db     *gorm.DB
 	logger common.Logger
@@ -34,19 +37,27 @@ func (s *Service) CreateCategory(ctx context.Context, userID uuid.UUID, req *Cre
 	return category, nil
 }
 
-func (s *Service) GetCategories(ctx context.Context, userID uuid.UUID) ([]Category, error) {
-	var categories []Category
-	if err := s.db.WithContext(ctx).Where("user_id = ?", userID).Find(&categories).Error; err != nil {
-		return nil, err
-	}
-	return categories, nil
+// categoryPages are the orders categories can be listed in, alphabetical by default.
+var categoryPages = pagination.Spec[Category]{
+	Table: "categories",
+	Sorts: map[string]pagination.Column[Category]{
+		"name":       {Expr: "categories.name", Value: func(c *Category) any { return c.Name }},
+		"created_at": {Expr: "categories.created_at", Value: func(c *Category) any { return c.CreatedAt }},
+	},
+	Default: "name",
+	Order:   pagination.Asc,
+	ID:      func(c *Category) uuid.UUID { return c.ID },
+}
+
+func (s *Service) GetCategories(ctx context.Context, userID uuid.UUID, params pagination.Params) (*pagination.Page[Category], error) {
+	return pagination.Paginate(s.db.WithContext(ctx).Where("user_id = ?", userID), categoryPages, params)
 }
 
 func (s *Service) GetCategoryByID(ctx context.Context, userID uuid.UUID, categoryID uuid.UUID) (*Category, error) {
 	var category Category
 	if err := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", categoryID, userID).First(&category).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("category not found")
+			return nil, ErrCategoryNotFound
 		}
 		return nil, err
 	}
@@ -57,7 +68,7 @@ func (s *Service) UpdateCategory(ctx context.Context, userID uuid.UUID, category
 	var category Category
 	if err := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", categoryID, userID).First(&category).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("category not found")
+			return nil, ErrCategoryNotFound
 		}
 		return nil, err
 	}
@@ -0,0 +1,46 @@
+package category
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CheckAccess returns ErrCategoryNotFound unless the category belongs to the user.
+func CheckAccess(ctx context.Context, db *gorm.DB, userID, categoryID uuid.UUID) error {
+	var count int64
+	if err := db.WithContext(ctx).Model(&Category{}).
+		Where("id = ? AND user_id = ?", categoryID, userID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrCategoryNotFound
+	}
+	return nil
+}
+
+// SubtreeIDs returns a subquery selecting the category and all of its descendants,
+// for use as "category_id IN (?)".
+func SubtreeIDs(db *gorm.DB, categoryID uuid.UUID) *gorm.DB {
+	return db.Raw(`
+		WITH RECURSIVE category_tree AS (
+			SELECT id FROM categories WHERE id = ?
+			UNION
+			SELECT c.id FROM categories c JOIN category_tree t ON c.parent_category_id = t.id
+		)
+		SELECT id FROM category_tree`, categoryID)
+}
+
+// AncestorIDs returns a subquery selecting the category and all of its parents up to the root,
+// for use as "category_id IN (?)".
+func AncestorIDs(db *gorm.DB, categoryID uuid.UUID) *gorm.DB {
+	return db.Raw(`
+		WITH RECURSIVE category_path AS (
+			SELECT id, parent_category_id FROM categories WHERE id = ?
+			UNION
+			SELECT c.id, c.parent_category_id FROM categories c JOIN category_path p ON c.id = p.parent_category_id
+		)
+		SELECT id FROM category_path`, categoryID)
+}
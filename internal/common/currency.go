@@ -0,0 +1,231 @@
+package common
+
+import "sort"
+
+// Currency is an ISO 4217 alphabetic currency code, e.g. "EUR".
+type Currency string
+
+// DefaultCurrency is used for amounts recorded without an explicit currency.
+const DefaultCurrency Currency = "EUR"
+
+// CurrencyInfo describes a currency of the ISO 4217 catalogue.
+type CurrencyInfo struct {
+	Code       Currency `json:"code"`
+	Numeric    string   `json:"numeric"`     // ISO 4217 numeric code, e.g. "978"
+	MinorUnits int32    `json:"minor_units"` // Decimal places, e.g. 2 for EUR and 0 for JPY
+	Symbol     string   `json:"symbol"`
+	Name       string   `json:"name"`
+}
+
+// currencies is the ISO 4217 list of currencies in circulation. Fund codes, precious metals and
+// testing codes are left out since nobody pays for groceries in them.
+var currencies = []CurrencyInfo{
+	{Code: "AED", Numeric: "784", MinorUnits: 2, Symbol: "د.إ", Name: "UAE Dirham"},
+	{Code: "AFN", Numeric: "971", MinorUnits: 2, Symbol: "؋", Name: "Afghani"},
+	{Code: "ALL", Numeric: "008", MinorUnits: 2, Symbol: "L", Name: "Lek"},
+	{Code: "AMD", Numeric: "051", MinorUnits: 2, Symbol: "֏", Name: "Armenian Dram"},
+	{Code: "AOA", Numeric: "973", MinorUnits: 2, Symbol: "Kz", Name: "Kwanza"},
+	{Code: "ARS", Numeric: "032", MinorUnits: 2, Symbol: "$", Name: "Argentine Peso"},
+	{Code: "AUD", Numeric: "036", MinorUnits: 2, Symbol: "A$", Name: "Australian Dollar"},
+	{Code: "AWG", Numeric: "533", MinorUnits: 2, Symbol: "ƒ", Name: "Aruban Florin"},
+	{Code: "AZN", Numeric: "944", MinorUnits: 2, Symbol: "₼", Name: "Azerbaijan Manat"},
+	{Code: "BAM", Numeric: "977", MinorUnits: 2, Symbol: "KM", Name: "Convertible Mark"},
+	{Code: "BBD", Numeric: "052", MinorUnits: 2, Symbol: "Bds$", Name: "Barbados Dollar"},
+	{Code: "BDT", Numeric: "050", MinorUnits: 2, Symbol: "৳", Name: "Taka"},
+	{Code: "BHD", Numeric: "048", MinorUnits: 3, Symbol: ".د.ب", Name: "Bahraini Dinar"},
+	{Code: "BIF", Numeric: "108", MinorUnits: 0, Symbol: "FBu", Name: "Burundi Franc"},
+	{Code: "BMD", Numeric: "060", MinorUnits: 2, Symbol: "$", Name: "Bermudian Dollar"},
+	{Code: "BND", Numeric: "096", MinorUnits: 2, Symbol: "B$", Name: "Brunei Dollar"},
+	{Code: "BOB", Numeric: "068", MinorUnits: 2, Symbol: "Bs", Name: "Boliviano"},
+	{Code: "BRL", Numeric: "986", MinorUnits: 2, Symbol: "R$", Name: "Brazilian Real"},
+	{Code: "BSD", Numeric: "044", MinorUnits: 2, Symbol: "$", Name: "Bahamian Dollar"},
+	{Code: "BTN", Numeric: "064", MinorUnits: 2, Symbol: "Nu.", Name: "Ngultrum"},
+	{Code: "BWP", Numeric: "072", MinorUnits: 2, Symbol: "P", Name: "Pula"},
+	{Code: "BYN", Numeric: "933", MinorUnits: 2, Symbol: "Br", Name: "Belarusian Ruble"},
+	{Code: "BZD", Numeric: "084", MinorUnits: 2, Symbol: "BZ$", Name: "Belize Dollar"},
+	{Code: "CAD", Numeric: "124", MinorUnits: 2, Symbol: "CA$", Name: "Canadian Dollar"},
+	{Code: "CDF", Numeric: "976", MinorUnits: 2, Symbol: "FC", Name: "Congolese Franc"},
+	{Code: "CHF", Numeric: "756", MinorUnits: 2, Symbol: "CHF", Name: "Swiss Franc"},
+	{Code: "CLP", Numeric: "152", MinorUnits: 0, Symbol: "$", Name: "Chilean Peso"},
+	{Code: "CNY", Numeric: "156", MinorUnits: 2, Symbol: "¥", Name: "Yuan Renminbi"},
+	{Code: "COP", Numeric: "170", MinorUnits: 2, Symbol: "$", Name: "Colombian Peso"},
+	{Code: "CRC", Numeric: "188", MinorUnits: 2, Symbol: "₡", Name: "Costa Rican Colon"},
+	{Code: "CUP", Numeric: "192", MinorUnits: 2, Symbol: "$", Name: "Cuban Peso"},
+	{Code: "CVE", Numeric: "132", MinorUnits: 2, Symbol: "Esc", Name: "Cabo Verde Escudo"},
+	{Code: "CZK", Numeric: "203", MinorUnits: 2, Symbol: "Kč", Name: "Czech Koruna"},
+	{Code: "DJF", Numeric: "262", MinorUnits: 0, Symbol: "Fdj", Name: "Djibouti Franc"},
+	{Code: "DKK", Numeric: "208", MinorUnits: 2, Symbol: "kr", Name: "Danish Krone"},
+	{Code: "DOP", Numeric: "214", MinorUnits: 2, Symbol: "RD$", Name: "Dominican Peso"},
+	{Code: "DZD", Numeric: "012", MinorUnits: 2, Symbol: "د.ج", Name: "Algerian Dinar"},
+	{Code: "EGP", Numeric: "818", MinorUnits: 2, Symbol: "E£", Name: "Egyptian Pound"},
+	{Code: "ERN", Numeric: "232", MinorUnits: 2, Symbol: "Nfk", Name: "Nakfa"},
+	{Code: "ETB", Numeric: "230", MinorUnits: 2, Symbol: "Br", Name: "Ethiopian Birr"},
+	{Code: "EUR", Numeric: "978", MinorUnits: 2, Symbol: "€", Name: "Euro"},
+	{Code: "FJD", Numeric: "242", MinorUnits: 2, Symbol: "FJ$", Name: "Fiji Dollar"},
+	{Code: "FKP", Numeric: "238", MinorUnits: 2, Symbol: "£", Name: "Falkland Islands Pound"},
+	{Code: "GBP", Numeric: "826", MinorUnits: 2, Symbol: "£", Name: "Pound Sterling"},
+	{Code: "GEL", Numeric: "981", MinorUnits: 2, Symbol: "₾", Name: "Lari"},
+	{Code: "GHS", Numeric: "936", MinorUnits: 2, Symbol: "GH₵", Name: "Ghana Cedi"},
+	{Code: "GIP", Numeric: "292", MinorUnits: 2, Symbol: "£", Name: "Gibraltar Pound"},
+	{Code: "GMD", Numeric: "270", MinorUnits: 2, Symbol: "D", Name: "Dalasi"},
+	{Code: "GNF", Numeric: "324", MinorUnits: 0, Symbol: "FG", Name: "Guinean Franc"},
+	{Code: "GTQ", Numeric: "320", MinorUnits: 2, Symbol: "Q", Name: "Quetzal"},
+	{Code: "GYD", Numeric: "328", MinorUnits: 2, Symbol: "G$", Name: "Guyana Dollar"},
+	{Code: "HKD", Numeric: "344", MinorUnits: 2, Symbol: "HK$", Name: "Hong Kong Dollar"},
+	{Code: "HNL", Numeric: "340", MinorUnits: 2, Symbol: "L", Name: "Lempira"},
+	{Code: "HTG", Numeric: "332", MinorUnits: 2, Symbol: "G", Name: "Gourde"},
+	{Code: "HUF", Numeric: "348", MinorUnits: 2, Symbol: "Ft", Name: "Forint"},
+	{Code: "IDR", Numeric: "360", MinorUnits: 2, Symbol: "Rp", Name: "Rupiah"},
+	{Code: "ILS", Numeric: "376", MinorUnits: 2, Symbol: "₪", Name: "New Israeli Sheqel"},
+	{Code: "INR", Numeric: "356", MinorUnits: 2, Symbol: "₹", Name: "Indian Rupee"},
+	{Code: "IQD", Numeric: "368", MinorUnits: 3, Symbol: "ع.د", Name: "Iraqi Dinar"},
+	{Code: "IRR", Numeric: "364", MinorUnits: 2, Symbol: "﷼", Name: "Iranian Rial"},
+	{Code: "ISK", Numeric: "352", MinorUnits: 0, Symbol: "kr", Name: "Iceland Krona"},
+	{Code: "JMD", Numeric: "388", MinorUnits: 2, Symbol: "J$", Name: "Jamaican Dollar"},
+	{Code: "JOD", Numeric: "400", MinorUnits: 3, Symbol: "د.ا", Name: "Jordanian Dinar"},
+	{Code: "JPY", Numeric: "392", MinorUnits: 0, Symbol: "¥", Name: "Yen"},
+	{Code: "KES", Numeric: "404", MinorUnits: 2, Symbol: "KSh", Name: "Kenyan Shilling"},
+	{Code: "KGS", Numeric: "417", MinorUnits: 2, Symbol: "с", Name: "Som"},
+	{Code: "KHR", Numeric: "116", MinorUnits: 2, Symbol: "៛", Name: "Riel"},
+	{Code: "KMF", Numeric: "174", MinorUnits: 0, Symbol: "CF", Name: "Comorian Franc"},
+	{Code: "KPW", Numeric: "408", MinorUnits: 2, Symbol: "₩", Name: "North Korean Won"},
+	{Code: "KRW", Numeric: "410", MinorUnits: 0, Symbol: "₩", Name: "Won"},
+	{Code: "KWD", Numeric: "414", MinorUnits: 3, Symbol: "د.ك", Name: "Kuwaiti Dinar"},
+	{Code: "KYD", Numeric: "136", MinorUnits: 2, Symbol: "CI$", Name: "Cayman Islands Dollar"},
+	{Code: "KZT", Numeric: "398", MinorUnits: 2, Symbol: "₸", Name: "Tenge"},
+	{Code: "LAK", Numeric: "418", MinorUnits: 2, Symbol: "₭", Name: "Lao Kip"},
+	{Code: "LBP", Numeric: "422", MinorUnits: 2, Symbol: "ل.ل", Name: "Lebanese Pound"},
+	{Code: "LKR", Numeric: "144", MinorUnits: 2, Symbol: "Rs", Name: "Sri Lanka Rupee"},
+	{Code: "LRD", Numeric: "430", MinorUnits: 2, Symbol: "L$", Name: "Liberian Dollar"},
+	{Code: "LSL", Numeric: "426", MinorUnits: 2, Symbol: "L", Name: "Loti"},
+	{Code: "LYD", Numeric: "434", MinorUnits: 3, Symbol: "ل.د", Name: "Libyan Dinar"},
+	{Code: "MAD", Numeric: "504", MinorUnits: 2, Symbol: "د.م.", Name: "Moroccan Dirham"},
+	{Code: "MDL", Numeric: "498", MinorUnits: 2, Symbol: "L", Name: "Moldovan Leu"},
+	{Code: "MGA", Numeric: "969", MinorUnits: 2, Symbol: "Ar", Name: "Malagasy Ariary"},
+	{Code: "MKD", Numeric: "807", MinorUnits: 2, Symbol: "ден", Name: "Denar"},
+	{Code: "MMK", Numeric: "104", MinorUnits: 2, Symbol: "K", Name: "Kyat"},
+	{Code: "MNT", Numeric: "496", MinorUnits: 2, Symbol: "₮", Name: "Tugrik"},
+	{Code: "MOP", Numeric: "446", MinorUnits: 2, Symbol: "MOP$", Name: "Pataca"},
+	{Code: "MRU", Numeric: "929", MinorUnits: 2, Symbol: "UM", Name: "Ouguiya"},
+	{Code: "MUR", Numeric: "480", MinorUnits: 2, Symbol: "₨", Name: "Mauritius Rupee"},
+	{Code: "MVR", Numeric: "462", MinorUnits: 2, Symbol: "Rf", Name: "Rufiyaa"},
+	{Code: "MWK", Numeric: "454", MinorUnits: 2, Symbol: "MK", Name: "Malawi Kwacha"},
+	{Code: "MXN", Numeric: "484", MinorUnits: 2, Symbol: "MX$", Name: "Mexican Peso"},
+	{Code: "MYR", Numeric: "458", MinorUnits: 2, Symbol: "RM", Name: "Malaysian Ringgit"},
+	{Code: "MZN", Numeric: "943", MinorUnits: 2, Symbol: "MT", Name: "Mozambique Metical"},
+	{Code: "NAD", Numeric: "516", MinorUnits: 2, Symbol: "N$", Name: "Namibia Dollar"},
+	{Code: "NGN", Numeric: "566", MinorUnits: 2, Symbol: "₦", Name: "Naira"},
+	{Code: "NIO", Numeric: "558", MinorUnits: 2, Symbol: "C$", Name: "Cordoba Oro"},
+	{Code: "NOK", Numeric: "578", MinorUnits: 2, Symbol: "kr", Name: "Norwegian Krone"},
+	{Code: "NPR", Numeric: "524", MinorUnits: 2, Symbol: "Rs", Name: "Nepalese Rupee"},
+	{Code: "NZD", Numeric: "554", MinorUnits: 2, Symbol: "NZ$", Name: "New Zealand Dollar"},
+	{Code: "OMR", Numeric: "512", MinorUnits: 3, Symbol: "ر.ع.", Name: "Rial Omani"},
+	{Code: "PAB", Numeric: "590", MinorUnits: 2, Symbol: "B/.", Name: "Balboa"},
+	{Code: "PEN", Numeric: "604", MinorUnits: 2, Symbol: "S/", Name: "Sol"},
+	{Code: "PGK", Numeric: "598", MinorUnits: 2, Symbol: "K", Name: "Kina"},
+	{Code: "PHP", Numeric: "608", MinorUnits: 2, Symbol: "₱", Name: "Philippine Peso"},
+	{Code: "PKR", Numeric: "586", MinorUnits: 2, Symbol: "Rs", Name: "Pakistan Rupee"},
+	{Code: "PLN", Numeric: "985", MinorUnits: 2, Symbol: "zł", Name: "Zloty"},
+	{Code: "PYG", Numeric: "600", MinorUnits: 0, Symbol: "₲", Name: "Guarani"},
+	{Code: "QAR", Numeric: "634", MinorUnits: 2, Symbol: "ر.ق", Name: "Qatari Rial"},
+	{Code: "RON", Numeric: "946", MinorUnits: 2, Symbol: "lei", Name: "Romanian Leu"},
+	{Code: "RSD", Numeric: "941", MinorUnits: 2, Symbol: "дин", Name: "Serbian Dinar"},
+	{Code: "RUB", Numeric: "643", MinorUnits: 2, Symbol: "₽", Name: "Russian Ruble"},
+	{Code: "RWF", Numeric: "646", MinorUnits: 0, Symbol: "FRw", Name: "Rwanda Franc"},
+	{Code: "SAR", Numeric: "682", MinorUnits: 2, Symbol: "ر.س", Name: "Saudi Riyal"},
+	{Code: "SBD", Numeric: "090", MinorUnits: 2, Symbol: "SI$", Name: "Solomon Islands Dollar"},
+	{Code: "SCR", Numeric: "690", MinorUnits: 2, Symbol: "₨", Name: "Seychelles Rupee"},
+	{Code: "SDG", Numeric: "938", MinorUnits: 2, Symbol: "ج.س.", Name: "Sudanese Pound"},
+	{Code: "SEK", Numeric: "752", MinorUnits: 2, Symbol: "kr", Name: "Swedish Krona"},
+	{Code: "SGD", Numeric: "702", MinorUnits: 2, Symbol: "S$", Name: "Singapore Dollar"},
+	{Code: "SHP", Numeric: "654", MinorUnits: 2, Symbol: "£", Name: "Saint Helena Pound"},
+	{Code: "SLE", Numeric: "925", MinorUnits: 2, Symbol: "Le", Name: "Leone"},
+	{Code: "SOS", Numeric: "706", MinorUnits: 2, Symbol: "Sh", Name: "Somali Shilling"},
+	{Code: "SRD", Numeric: "968", MinorUnits: 2, Symbol: "$", Name: "Surinam Dollar"},
+	{Code: "SSP", Numeric: "728", MinorUnits: 2, Symbol: "£", Name: "South Sudanese Pound"},
+	{Code: "STN", Numeric: "930", MinorUnits: 2, Symbol: "Db", Name: "Dobra"},
+	{Code: "SVC", Numeric: "222", MinorUnits: 2, Symbol: "₡", Name: "El Salvador Colon"},
+	{Code: "SYP", Numeric: "760", MinorUnits: 2, Symbol: "£S", Name: "Syrian Pound"},
+	{Code: "SZL", Numeric: "748", MinorUnits: 2, Symbol: "E", Name: "Lilangeni"},
+	{Code: "THB", Numeric: "764", MinorUnits: 2, Symbol: "฿", Name: "Baht"},
+	{Code: "TJS", Numeric: "972", MinorUnits: 2, Symbol: "SM", Name: "Somoni"},
+	{Code: "TMT", Numeric: "934", MinorUnits: 2, Symbol: "m", Name: "Turkmenistan New Manat"},
+	{Code: "TND", Numeric: "788", MinorUnits: 3, Symbol: "د.ت", Name: "Tunisian Dinar"},
+	{Code: "TOP", Numeric: "776", MinorUnits: 2, Symbol: "T$", Name: "Pa'anga"},
+	{Code: "TRY", Numeric: "949", MinorUnits: 2, Symbol: "₺", Name: "Turkish Lira"},
+	{Code: "TTD", Numeric: "780", MinorUnits: 2, Symbol: "TT$", Name: "Trinidad and Tobago Dollar"},
+	{Code: "TWD", Numeric: "901", MinorUnits: 2, Symbol: "NT$", Name: "New Taiwan Dollar"},
+	{Code: "TZS", Numeric: "834", MinorUnits: 2, Symbol: "TSh", Name: "Tanzanian Shilling"},
+	{Code: "UAH", Numeric: "980", MinorUnits: 2, Symbol: "₴", Name: "Hryvnia"},
+	{Code: "UGX", Numeric: "800", MinorUnits: 0, Symbol: "USh", Name: "Uganda Shilling"},
+	{Code: "USD", Numeric: "840", MinorUnits: 2, Symbol: "$", Name: "US Dollar"},
+	{Code: "UYU", Numeric: "858", MinorUnits: 2, Symbol: "$U", Name: "Peso Uruguayo"},
+	{Code: "UZS", Numeric: "860", MinorUnits: 2, Symbol: "soʻm", Name: "Uzbekistan Sum"},
+	{Code: "VED", Numeric: "926", MinorUnits: 2, Symbol: "Bs.D", Name: "Bolívar Soberano (digital)"},
+	{Code: "VES", Numeric: "928", MinorUnits: 2, Symbol: "Bs.S", Name: "Bolívar Soberano"},
+	{Code: "VND", Numeric: "704", MinorUnits: 0, Symbol: "₫", Name: "Dong"},
+	{Code: "VUV", Numeric: "548", MinorUnits: 0, Symbol: "VT", Name: "Vatu"},
+	{Code: "WST", Numeric: "882", MinorUnits: 2, Symbol: "WS$", Name: "Tala"},
+	{Code: "XAF", Numeric: "950", MinorUnits: 0, Symbol: "FCFA", Name: "CFA Franc BEAC"},
+	{Code: "XCD", Numeric: "951", MinorUnits: 2, Symbol: "EC$", Name: "East Caribbean Dollar"},
+	{Code: "XCG", Numeric: "532", MinorUnits: 2, Symbol: "Cg", Name: "Caribbean Guilder"},
+	{Code: "XOF", Numeric: "952", MinorUnits: 0, Symbol: "CFA", Name: "CFA Franc BCEAO"},
+	{Code: "XPF", Numeric: "953", MinorUnits: 0, Symbol: "₣", Name: "CFP Franc"},
+	{Code: "YER", Numeric: "886", MinorUnits: 2, Symbol: "﷼", Name: "Yemeni Rial"},
+	{Code: "ZAR", Numeric: "710", MinorUnits: 2, Symbol: "R", Name: "Rand"},
+	{Code: "ZMW", Numeric: "967", MinorUnits: 2, Symbol: "ZK", Name: "Zambian Kwacha"},
+	{Code: "ZWG", Numeric: "924", MinorUnits: 2, Symbol: "ZiG", Name: "Zimbabwe Gold"},
+}
+
+var currencyByCode = func() map[Currency]CurrencyInfo {
+	byCode := make(map[Currency]CurrencyInfo, len(currencies))
+	for _, info := range currencies {
+		byCode[info.Code] = info
+	}
+	return byCode
+}()
+
+// Currencies returns the catalogue, ordered by code.
+func Currencies() []CurrencyInfo {
+	result := make([]CurrencyInfo, len(currencies))
+	copy(result, currencies)
+	sort.Slice(result, func(i, j int) bool { return result[i].Code < result[j].Code })
+	return result
+}
+
+// LookupCurrency returns the catalogue entry of the currency.
+func LookupCurrency(c Currency) (CurrencyInfo, bool) {
+	info, ok := currencyByCode[c]
+	return info, ok
+}
+
+// IsValid reports whether the currency is in the ISO 4217 catalogue.
+func (c Currency) IsValid() bool {
+	_, ok := currencyByCode[c]
+	return ok
+}
+
+// OrDefault returns the currency, or DefaultCurrency when it is empty.
+func (c Currency) OrDefault() Currency {
+	if c == "" {
+		return DefaultCurrency
+	}
+	return c
+}
+
+// MinorUnits returns the number of decimal places of the currency, e.g. 2 for EUR and 0 for JPY.
+// Codes outside the catalogue are treated as having 2.
+func (c Currency) MinorUnits() int32 {
+	if info, ok := currencyByCode[c]; ok {
+		return info.MinorUnits
+	}
+	return 2
+}
+
+// Symbol returns the currency symbol, or the code when the currency has none.
+func (c Currency) Symbol() string {
+	if info, ok := currencyByCode[c]; ok && info.Symbol != "" {
+		return info.Symbol
+	}
+	return string(c)
+}
@@ -1,17 +1,5 @@
 package common
 
-type Currency string
-
-const (
-	EUR Currency = "EUR"
-	USD Currency = "USD"
-	GBP Currency = "GBP"
-	JPY Currency = "JPY"
-	CHF Currency = "CHF"
-	NGN Currency = "NGN"
-	// Add more currencies as needed
-)
-
 type PaymentMethod string
 
 const (
@@ -0,0 +1,257 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+var ErrInvalidFilter = errors.New("invalid filter")
+
+type FilterOp string
+
+const (
+	OpEq    FilterOp = "="
+	OpNe    FilterOp = "!="
+	OpGt    FilterOp = ">"
+	OpGte   FilterOp = ">="
+	OpLt    FilterOp = "<"
+	OpLte   FilterOp = "<="
+	OpIn    FilterOp = "in"    // field=in:(a,b)
+	OpNotIn FilterOp = "notin" // field!=in:(a,b)
+	OpLike  FilterOp = "like"  // field=like:text, a case-insensitive substring match
+)
+
+// comparisons are written before the value, longest first so ">=" is not read as ">".
+var comparisons = []FilterOp{OpGte, OpLte, OpNe, OpGt, OpLt, OpEq}
+
+type FilterKind int
+
+const (
+	FilterString FilterKind = iota
+	FilterNumber
+	FilterTime // YYYY-MM-DD stands for the whole day; RFC 3339 for an instant
+	FilterUUID
+	FilterBool
+)
+
+// FilterField is a field clients may filter a list on.
+type FilterField struct {
+	Column string // SQL expression the field is read from; never taken from the request
+	Kind   FilterKind
+	Ops    []FilterOp // Defaults to the operators that make sense for Kind
+	Values []string   // Accepted values of enum-like string fields
+}
+
+// FilterFields is the allowlist of fields of a list, by the name used in the query string.
+type FilterFields map[string]FilterField
+
+// Filter is a parsed filter. Apply it with query.Scopes(filter.Scope); the zero Filter matches everything.
+type Filter struct {
+	clauses []filterClause
+}
+
+type filterClause struct {
+	sql  string
+	args []interface{}
+}
+
+// ParseFilter reads a filter of clauses separated by semicolons, such as
+// "amount>50;category_id=in:(id1,id2);created_at>=2025-01-01". Only fields and operators in fields
+// are accepted and values are bound as parameters, so the resulting scope is safe to run.
+func ParseFilter(raw string, fields FilterFields) (Filter, error) {
+	var filter Filter
+	for _, part := range strings.Split(raw, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, op, value, err := splitClause(part)
+		if err != nil {
+			return Filter{}, err
+		}
+		field, ok := fields[name]
+		if !ok {
+			return Filter{}, fmt.Errorf("%w: unknown field %q, expected one of %s", ErrInvalidFilter, name, strings.Join(fields.names(), ", "))
+		}
+		if !field.allows(op) {
+			return Filter{}, fmt.Errorf("%w: %s does not support %s", ErrInvalidFilter, name, op)
+		}
+		clause, err := field.clause(name, op, value)
+		if err != nil {
+			return Filter{}, err
+		}
+		filter.clauses = append(filter.clauses, clause)
+	}
+	return filter, nil
+}
+
+// Scope adds the clauses of the filter to query.
+func (f Filter) Scope(query *gorm.DB) *gorm.DB {
+	for _, clause := range f.clauses {
+		query = query.Where(clause.sql, clause.args...)
+	}
+	return query
+}
+
+func splitClause(part string) (string, FilterOp, string, error) {
+	end := strings.IndexFunc(part, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_')
+	})
+	if end <= 0 {
+		return "", "", "", fmt.Errorf("%w: %q is not of the form field<operator>value", ErrInvalidFilter, part)
+	}
+	name, rest := part[:end], part[end:]
+
+	for _, op := range comparisons {
+		if !strings.HasPrefix(rest, string(op)) {
+			continue
+		}
+		value := strings.TrimSpace(rest[len(op):])
+		switch {
+		case strings.HasPrefix(value, "in:(") && strings.HasSuffix(value, ")"):
+			if op == OpEq {
+				return name, OpIn, value[4 : len(value)-1], nil
+			}
+			if op == OpNe {
+				return name, OpNotIn, value[4 : len(value)-1], nil
+			}
+		case strings.HasPrefix(value, "like:"):
+			if op == OpEq {
+				return name, OpLike, value[5:], nil
+			}
+		default:
+			return name, op, value, nil
+		}
+		return "", "", "", fmt.Errorf("%w: %q is not of the form field<operator>value", ErrInvalidFilter, part)
+	}
+	return "", "", "", fmt.Errorf("%w: %q is not of the form field<operator>value", ErrInvalidFilter, part)
+}
+
+func (f FilterField) allows(op FilterOp) bool {
+	ops := f.Ops
+	if ops == nil {
+		switch f.Kind {
+		case FilterNumber, FilterTime:
+			ops = []FilterOp{OpEq, OpNe, OpGt, OpGte, OpLt, OpLte}
+		case FilterBool:
+			ops = []FilterOp{OpEq, OpNe}
+		default:
+			ops = []FilterOp{OpEq, OpNe, OpIn, OpNotIn}
+		}
+	}
+	for _, allowed := range ops {
+		if allowed == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (f FilterField) clause(name string, op FilterOp, raw string) (filterClause, error) {
+	switch op {
+	case OpIn, OpNotIn:
+		var values []interface{}
+		for _, item := range strings.Split(raw, ",") {
+			value, err := f.parse(name, strings.TrimSpace(item))
+			if err != nil {
+				return filterClause{}, err
+			}
+			values = append(values, value)
+		}
+		keyword := "IN"
+		if op == OpNotIn {
+			keyword = "NOT IN"
+		}
+		return filterClause{sql: fmt.Sprintf("%s %s ?", f.Column, keyword), args: []interface{}{values}}, nil
+	case OpLike:
+		return filterClause{sql: f.Column + ` ILIKE ? ESCAPE '\'`, args: []interface{}{"%" + escapeLike(raw) + "%"}}, nil
+	}
+
+	// A day compares as the range of instants it spans, so created_at<=2025-01-31 includes that day.
+	if f.Kind == FilterTime {
+		if day, err := time.Parse("2006-01-02", raw); err == nil {
+			next := day.AddDate(0, 0, 1)
+			switch op {
+			case OpEq:
+				return filterClause{sql: fmt.Sprintf("%s >= ? AND %s < ?", f.Column, f.Column), args: []interface{}{day, next}}, nil
+			case OpNe:
+				return filterClause{sql: fmt.Sprintf("(%s < ? OR %s >= ?)", f.Column, f.Column), args: []interface{}{day, next}}, nil
+			case OpGt:
+				return filterClause{sql: f.Column + " >= ?", args: []interface{}{next}}, nil
+			case OpLte:
+				return filterClause{sql: f.Column + " < ?", args: []interface{}{next}}, nil
+			default:
+				return filterClause{sql: fmt.Sprintf("%s %s ?", f.Column, op), args: []interface{}{day}}, nil
+			}
+		}
+	}
+
+	value, err := f.parse(name, raw)
+	if err != nil {
+		return filterClause{}, err
+	}
+	return filterClause{sql: fmt.Sprintf("%s %s ?", f.Column, op), args: []interface{}{value}}, nil
+}
+
+func (f FilterField) parse(name, raw string) (interface{}, error) {
+	switch f.Kind {
+	case FilterNumber:
+		value, err := decimal.NewFromString(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s expects a number, got %q", ErrInvalidFilter, name, raw)
+		}
+		return value, nil
+	case FilterTime:
+		if value, err := time.Parse("2006-01-02", raw); err == nil {
+			return value, nil
+		}
+		value, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s expects a date (YYYY-MM-DD) or an RFC 3339 time, got %q", ErrInvalidFilter, name, raw)
+		}
+		return value, nil
+	case FilterUUID:
+		value, err := uuid.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s expects an ID, got %q", ErrInvalidFilter, name, raw)
+		}
+		return value, nil
+	case FilterBool:
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s expects true or false, got %q", ErrInvalidFilter, name, raw)
+		}
+		return value, nil
+	default:
+		if f.Values == nil {
+			return raw, nil
+		}
+		for _, allowed := range f.Values {
+			if strings.EqualFold(raw, allowed) {
+				return allowed, nil
+			}
+		}
+		return nil, fmt.Errorf("%w: %s expects one of %s, got %q", ErrInvalidFilter, name, strings.Join(f.Values, ", "), raw)
+	}
+}
+
+func (f FilterFields) names() []string {
+	names := make([]string, 0, len(f))
+	for name := range f {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
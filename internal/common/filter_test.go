@@ -0,0 +1,152 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+var testFields = FilterFields{
+	"amount":      {Column: "expenses.amount", Kind: FilterNumber},
+	"created_at":  {Column: "expenses.created_at", Kind: FilterTime},
+	"category_id": {Column: "expenses.category_id", Kind: FilterUUID},
+	"is_active":   {Column: "expenses.is_active", Kind: FilterBool},
+	"status":      {Column: "expenses.status", Kind: FilterString, Values: []string{"pending", "completed"}},
+	"description": {Column: "expenses.description", Kind: FilterString, Ops: []FilterOp{OpEq, OpLike}},
+}
+
+// render writes the clauses of a filter as SQL with the arguments in brackets.
+func render(f Filter) string {
+	parts := make([]string, len(f.clauses))
+	for i, clause := range f.clauses {
+		args := make([]string, len(clause.args))
+		for j, arg := range clause.args {
+			switch arg := arg.(type) {
+			case time.Time:
+				args[j] = arg.Format(time.RFC3339)
+			case []interface{}:
+				items := make([]string, len(arg))
+				for k, item := range arg {
+					items[k] = fmt.Sprint(item)
+				}
+				args[j] = "(" + strings.Join(items, ",") + ")"
+			default:
+				args[j] = fmt.Sprint(arg)
+			}
+		}
+		parts[i] = clause.sql + " [" + strings.Join(args, " ") + "]"
+	}
+	return strings.Join(parts, "; ")
+}
+
+func TestParseFilter(t *testing.T) {
+	id1, id2 := uuid.MustParse("6f1c1c8e-2a4f-4f0e-9d6b-0a1b2c3d4e5f"), uuid.MustParse("0d9a7e21-5b1f-4c3e-8a2d-1e2f3a4b5c6d")
+
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{name: "empty", raw: "", want: ""},
+		{name: "blank clauses", raw: " ; ;", want: ""},
+		{name: "number", raw: "amount>50", want: "expenses.amount > ? [50]"},
+		{name: "greater or equal is not read as greater", raw: "amount>=50.5", want: "expenses.amount >= ? [50.5]"},
+		{name: "not equal", raw: "amount!=0", want: "expenses.amount != ? [0]"},
+		{name: "spaces around the clause and the value", raw: " amount<= 10 ", want: "expenses.amount <= ? [10]"},
+		{
+			name: "several clauses",
+			raw:  "amount>50;is_active=true",
+			want: "expenses.amount > ? [50]; expenses.is_active = ? [true]",
+		},
+		{
+			name: "in",
+			raw:  fmt.Sprintf("category_id=in:(%s, %s)", id1, id2),
+			want: fmt.Sprintf("expenses.category_id IN ? [(%s,%s)]", id1, id2),
+		},
+		{
+			name: "not in",
+			raw:  fmt.Sprintf("category_id!=in:(%s)", id1),
+			want: fmt.Sprintf("expenses.category_id NOT IN ? [(%s)]", id1),
+		},
+		{name: "enum value in any case", raw: "status=Completed", want: "expenses.status = ? [completed]"},
+		{name: "like escapes wildcards", raw: `description=like:50%_off`, want: `expenses.description ILIKE ? ESCAPE '\' [%50\%\_off%]`},
+		{
+			name: "day equals the whole day",
+			raw:  "created_at=2026-10-16",
+			want: "expenses.created_at >= ? AND expenses.created_at < ? [2026-10-16T00:00:00Z 2026-10-17T00:00:00Z]",
+		},
+		{
+			name: "not on a day",
+			raw:  "created_at!=2026-10-16",
+			want: "(expenses.created_at < ? OR expenses.created_at >= ?) [2026-10-16T00:00:00Z 2026-10-17T00:00:00Z]",
+		},
+		{name: "after a day", raw: "created_at>2026-10-16", want: "expenses.created_at >= ? [2026-10-17T00:00:00Z]"},
+		{name: "up to a day includes it", raw: "created_at<=2026-10-16", want: "expenses.created_at < ? [2026-10-17T00:00:00Z]"},
+		{name: "from a day", raw: "created_at>=2026-10-16", want: "expenses.created_at >= ? [2026-10-16T00:00:00Z]"},
+		{name: "instant", raw: "created_at<2026-10-16T12:30:00Z", want: "expenses.created_at < ? [2026-10-16T12:30:00Z]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := ParseFilter(tt.raw, testFields)
+			if err != nil {
+				t.Fatalf("ParseFilter(%q): %v", tt.raw, err)
+			}
+			if got := render(filter); got != tt.want {
+				t.Errorf("ParseFilter(%q)\n got %s\nwant %s", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{name: "no operator", raw: "amount", want: "is not of the form"},
+		{name: "no field", raw: ">50", want: "is not of the form"},
+		{name: "space before the operator", raw: "amount <= 10", want: "is not of the form"},
+		{name: "unknown field", raw: "user_id=1", want: `unknown field "user_id"`},
+		{name: "column injection", raw: "amount);DROP TABLE expenses;--=1", want: "is not of the form"},
+		{name: "operator not allowed for the kind", raw: "is_active>true", want: "is_active does not support >"},
+		{name: "in not allowed for numbers", raw: "amount=in:(1,2)", want: "amount does not support in"},
+		{name: "like on a field without it", raw: "status=like:pend", want: "status does not support like"},
+		{name: "like with another comparison", raw: "description!=like:x", want: "is not of the form"},
+		{name: "in with a comparison", raw: "status>in:(pending)", want: "is not of the form"},
+		{name: "not a number", raw: "amount>fifty", want: "amount expects a number"},
+		{name: "not a date", raw: "created_at>=16.10.2026", want: "created_at expects a date"},
+		{name: "not an ID", raw: "category_id=42", want: "category_id expects an ID"},
+		{name: "not a bool", raw: "is_active=yes", want: "is_active expects true or false"},
+		{name: "not an enum value", raw: "status=lost", want: "status expects one of pending, completed"},
+		{name: "bad item in a list", raw: "category_id=in:(42)", want: "category_id expects an ID"},
+		{name: "error in a later clause", raw: "amount>1;amount>x", want: "amount expects a number"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := ParseFilter(tt.raw, testFields)
+			if !errors.Is(err, ErrInvalidFilter) || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("ParseFilter(%q) error = %v, want ErrInvalidFilter containing %q", tt.raw, err, tt.want)
+			}
+			if len(filter.clauses) != 0 {
+				t.Errorf("ParseFilter(%q) returned %d clauses with the error", tt.raw, len(filter.clauses))
+			}
+		})
+	}
+}
+
+func TestParseFilterNumberIsDecimal(t *testing.T) {
+	filter, err := ParseFilter("amount=0.1", testFields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, ok := filter.clauses[0].args[0].(decimal.Decimal)
+	if !ok || !value.Equal(decimal.RequireFromString("0.1")) {
+		t.Errorf("argument = %#v, want the decimal 0.1", filter.clauses[0].args[0])
+	}
+}
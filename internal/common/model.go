@@ -1,10 +1,15 @@
 package common
 
 import (
+	"errors"
 	"github.com/google/uuid"
 	"time"
 )
 
+// ErrReconciled is returned for changes to reconciled transactions, and to the expenses and
+// incomes they stand for, that would change a reconciled balance.
+var ErrReconciled = errors.New("reconciled transactions cannot be changed or deleted")
+
 type BaseModel struct {
 	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
 	CreatedAt time.Time `json:"created_at"`
@@ -18,20 +23,3 @@ type APIResponse struct {
 	Error      string      `json:"error,omitempty"`
 	StatusCode int         `json:"status_code,omitempty"`
 }
-
-type PaginationParams struct {
-	Page     int `form:"page" binding:"min=1"`
-	PageSize int `form:"page_size" binding:"min=1,max=100"`
-}
-
-type PaginatedResponse struct {
-	Data       interface{} `json:"data"`
-	Pagination Pagination  `json:"pagination"`
-}
-
-type Pagination struct {
-	Page       int   `json:"page"`
-	PageSize   int   `json:"page_size"`
-	Total      int64 `json:"total"`
-	TotalPages int   `json:"total_pages"`
-}
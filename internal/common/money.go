@@ -0,0 +1,128 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+var ErrCurrencyMismatch = errors.New("currency mismatch")
+
+// Money is an amount in a currency, kept as a decimal to avoid floating point drift.
+//
+// Models embed it with `gorm:"embedded"`, which stores the amount in a numeric column with the
+// currency next to it, so SQL can still SUM and compare amounts. Rows holding several amounts
+// use a prefix, e.g. `gorm:"embedded;embeddedPrefix:tax_"` for tax_amount and tax_currency.
+// Money deliberately does not implement driver.Valuer, as GORM would then store it in one column.
+type Money struct {
+	Amount   decimal.Decimal `gorm:"type:decimal(20,4);not null;default:0"`
+	Currency Currency        `gorm:"type:varchar(3);not null;default:EUR"`
+}
+
+// NewMoney returns amount in currency, rounded to the currency's minor units.
+func NewMoney(amount decimal.Decimal, currency Currency) Money {
+	return Money{Amount: amount, Currency: currency}.Round()
+}
+
+// ZeroMoney returns a zero amount in currency.
+func ZeroMoney(currency Currency) Money {
+	return Money{Amount: decimal.Zero, Currency: currency}
+}
+
+// MoneyFromMinorUnits returns the amount given in minor units, e.g. cents for EUR.
+func MoneyFromMinorUnits(units int64, currency Currency) Money {
+	return Money{Amount: decimal.New(units, -currency.MinorUnits()), Currency: currency}
+}
+
+// MinorUnits returns the amount in minor units of its currency, e.g. 1234 for 12.34 EUR.
+func (m Money) MinorUnits() int64 {
+	return m.Round().Amount.Shift(m.Currency.MinorUnits()).IntPart()
+}
+
+// Round rounds the amount half away from zero to the minor units of its currency.
+func (m Money) Round() Money {
+	return Money{Amount: m.Amount.Round(m.Currency.MinorUnits()), Currency: m.Currency}
+}
+
+func (m Money) Add(other Money) (Money, error) {
+	if err := m.checkCurrency(other); err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: m.Amount.Add(other.Amount), Currency: m.Currency}, nil
+}
+
+func (m Money) Sub(other Money) (Money, error) {
+	if err := m.checkCurrency(other); err != nil {
+		return Money{}, err
+	}
+	return Money{Amount: m.Amount.Sub(other.Amount), Currency: m.Currency}, nil
+}
+
+// Mul multiplies the amount by factor and rounds the result to minor units.
+func (m Money) Mul(factor decimal.Decimal) Money {
+	return Money{Amount: m.Amount.Mul(factor), Currency: m.Currency}.Round()
+}
+
+func (m Money) Neg() Money {
+	return Money{Amount: m.Amount.Neg(), Currency: m.Currency}
+}
+
+// Cmp compares two amounts in the same currency, returning -1, 0 or +1.
+func (m Money) Cmp(other Money) (int, error) {
+	if err := m.checkCurrency(other); err != nil {
+		return 0, err
+	}
+	return m.Amount.Cmp(other.Amount), nil
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount.IsZero()
+}
+
+func (m Money) IsPositive() bool {
+	return m.Amount.IsPositive()
+}
+
+func (m Money) IsNegative() bool {
+	return m.Amount.IsNegative()
+}
+
+// String formats the amount with the minor units of its currency, e.g. "12.34 EUR" or "1200 JPY".
+func (m Money) String() string {
+	return fmt.Sprintf("%s %s", m.Amount.StringFixed(m.Currency.MinorUnits()), m.Currency)
+}
+
+type moneyJSON struct {
+	Amount   decimal.Decimal `json:"amount"`
+	Currency Currency        `json:"currency"`
+}
+
+// MarshalJSON encodes Money as {"amount": "12.34", "currency": "EUR"}. The amount is a string
+// with exactly the currency's minor units so clients never see binary floating point values.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount   string   `json:"amount"`
+		Currency Currency `json:"currency"`
+	}{m.Amount.StringFixed(m.Currency.MinorUnits()), m.Currency})
+}
+
+// UnmarshalJSON accepts the amount as a string or a number.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var v moneyJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return fmt.Errorf("invalid money value: %w", err)
+	}
+	m.Amount = v.Amount
+	m.Currency = Currency(strings.ToUpper(string(v.Currency)))
+	return nil
+}
+
+func (m Money) checkCurrency(other Money) error {
+	if m.Currency != other.Currency {
+		return fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func money(amount string, currency Currency) Money {
+	return Money{Amount: decimal.RequireFromString(amount), Currency: currency}
+}
+
+func TestNewMoneyRounds(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency Currency
+		want     string
+	}{
+		{amount: "12.345", currency: "EUR", want: "12.35 EUR"},
+		{amount: "12.344", currency: "EUR", want: "12.34 EUR"},
+		{amount: "-12.345", currency: "EUR", want: "-12.35 EUR"},
+		{amount: "1200.5", currency: "JPY", want: "1201 JPY"},
+		{amount: "1.2345", currency: "BHD", want: "1.235 BHD"},
+		{amount: "7", currency: "EUR", want: "7.00 EUR"},
+		{amount: "0.005", currency: "XYZ", want: "0.01 XYZ"}, // Codes outside the catalogue have 2 minor units
+	}
+	for _, tt := range tests {
+		t.Run(tt.amount+" "+string(tt.currency), func(t *testing.T) {
+			if got := NewMoney(decimal.RequireFromString(tt.amount), tt.currency).String(); got != tt.want {
+				t.Errorf("NewMoney(%s, %s) = %s, want %s", tt.amount, tt.currency, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyMinorUnits(t *testing.T) {
+	tests := []struct {
+		money Money
+		units int64
+	}{
+		{money: money("12.34", "EUR"), units: 1234},
+		{money: money("-0.01", "EUR"), units: -1},
+		{money: money("12.345", "EUR"), units: 1235},
+		{money: money("1200", "JPY"), units: 1200},
+		{money: money("1.234", "BHD"), units: 1234},
+	}
+	for _, tt := range tests {
+		t.Run(tt.money.String(), func(t *testing.T) {
+			if got := tt.money.MinorUnits(); got != tt.units {
+				t.Errorf("MinorUnits() = %d, want %d", got, tt.units)
+			}
+			if back := MoneyFromMinorUnits(tt.units, tt.money.Currency); !back.Amount.Equal(tt.money.Round().Amount) {
+				t.Errorf("MoneyFromMinorUnits(%d) = %s, want %s", tt.units, back, tt.money.Round())
+			}
+		})
+	}
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	tests := []struct {
+		name    string
+		op      func(a, b Money) (Money, error)
+		a, b    Money
+		want    string
+		wantErr bool
+	}{
+		{name: "add", op: Money.Add, a: money("0.1", "EUR"), b: money("0.2", "EUR"), want: "0.30 EUR"},
+		{name: "sub below zero", op: Money.Sub, a: money("5", "EUR"), b: money("7.5", "EUR"), want: "-2.50 EUR"},
+		{name: "add another currency", op: Money.Add, a: money("1", "EUR"), b: money("1", "USD"), wantErr: true},
+		{name: "sub another currency", op: Money.Sub, a: money("1", "EUR"), b: money("1", "USD"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.op(tt.a, tt.b)
+			if tt.wantErr {
+				if !errors.Is(err, ErrCurrencyMismatch) {
+					t.Fatalf("error = %v, want ErrCurrencyMismatch", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+
+	if got := money("10", "EUR").Mul(decimal.RequireFromString("0.333")); got.String() != "3.33 EUR" {
+		t.Errorf("Mul = %s, want 3.33 EUR", got)
+	}
+	if got := money("2.5", "EUR").Neg(); got.String() != "-2.50 EUR" || !got.IsNegative() {
+		t.Errorf("Neg = %s, want -2.50 EUR", got)
+	}
+	if cmp, err := money("2", "EUR").Cmp(money("10", "EUR")); err != nil || cmp != -1 {
+		t.Errorf("Cmp = %d, %v, want -1", cmp, err)
+	}
+	if _, err := money("2", "EUR").Cmp(money("2", "GBP")); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Cmp across currencies error = %v, want ErrCurrencyMismatch", err)
+	}
+	if !ZeroMoney("EUR").IsZero() || ZeroMoney("EUR").IsPositive() {
+		t.Error("ZeroMoney is not zero")
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		money Money
+		want  string
+	}{
+		{name: "two minor units", money: money("12.3", "EUR"), want: `{"amount":"12.30","currency":"EUR"}`},
+		{name: "no minor units", money: money("1200", "JPY"), want: `{"amount":"1200","currency":"JPY"}`},
+		{name: "negative", money: money("-0.5", "USD"), want: `{"amount":"-0.50","currency":"USD"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.money)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("Marshal = %s, want %s", data, tt.want)
+			}
+		})
+	}
+
+	decodes := []struct {
+		name    string
+		data    string
+		want    string
+		wantErr bool
+	}{
+		{name: "string amount", data: `{"amount":"12.34","currency":"EUR"}`, want: "12.34 EUR"},
+		{name: "number amount", data: `{"amount":12.34,"currency":"eur"}`, want: "12.34 EUR"},
+		{name: "not an amount", data: `{"amount":"twelve","currency":"EUR"}`, wantErr: true},
+		{name: "not an object", data: `"12.34 EUR"`, wantErr: true},
+	}
+	for _, tt := range decodes {
+		t.Run(tt.name, func(t *testing.T) {
+			var m Money
+			err := json.Unmarshal([]byte(tt.data), &m)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Unmarshal(%s) = %s, want an error", tt.data, m)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.String() != tt.want {
+				t.Errorf("Unmarshal(%s) = %s, want %s", tt.data, m, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package duplicate
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/pastorenue/kinance/internal/common"
+	"github.com/pastorenue/kinance/internal/expense"
+	"github.com/pastorenue/kinance/internal/transaction"
+	"github.com/pastorenue/kinance/pkg/middleware"
+)
+
+const dateLayout = "2006-01-02"
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// FindDuplicates lists likely duplicates among the records of the last 90 days, or of from to to.
+func (h *Handler) FindDuplicates(c *gin.Context) {
+	userID, _ := c.Get(middleware.UserIDKey)
+
+	now := time.Now().UTC()
+	query := &Query{
+		From:     now.AddDate(0, 0, -90),
+		To:       now.AddDate(0, 0, 1),
+		Days:     DefaultDays,
+		MinScore: DefaultMinScore,
+		Limit:    DefaultLimit,
+	}
+	if value := c.Query("from"); value != "" {
+		from, err := time.Parse(dateLayout, value)
+		if err != nil {
+			writeBadRequest(c, "Invalid from parameter, expected YYYY-MM-DD")
+			return
+		}
+		query.From = from
+	}
+	if value := c.Query("to"); value != "" {
+		to, err := time.Parse(dateLayout, value)
+		if err != nil {
+			writeBadRequest(c, "Invalid to parameter, expected YYYY-MM-DD")
+			return
+		}
+		query.To = to.AddDate(0, 0, 1)
+	}
+	if !query.From.Before(query.To) {
+		writeBadRequest(c, "from must not be after to")
+		return
+	}
+	if value := c.Query("days"); value != "" {
+		days, err := strconv.Atoi(value)
+		if err != nil || days < 0 || days > MaxDays {
+			writeBadRequest(c, "Invalid days parameter, expected 0 to 31")
+			return
+		}
+		query.Days = days
+	}
+	if value := c.Query("min_score"); value != "" {
+		minScore, err := strconv.ParseFloat(value, 64)
+		if err != nil || minScore < 0 || minScore > 1 {
+			writeBadRequest(c, "Invalid min_score parameter, expected 0 to 1")
+			return
+		}
+		query.MinScore = minScore
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > MaxLimit {
+			writeBadRequest(c, "Invalid limit parameter, expected 1 to 200")
+			return
+		}
+		query.Limit = limit
+	}
+
+	candidates, err := h.service.FindDuplicates(c.Request.Context(), userID.(uuid.UUID), query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, common.APIResponse{
+			Success:    false,
+			StatusCode: http.StatusInternalServerError,
+			Error:      "Failed to find duplicates",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, common.APIResponse{
+		Success:    true,
+		StatusCode: http.StatusOK,
+		Data:       candidates,
+	})
+}
+
+// Merge keeps one record of a pair and deletes the other, returning the kept record.
+func (h *Handler) Merge(c *gin.Context) {
+	userID, _ := c.Get(middleware.UserIDKey)
+
+	var req MergeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeBadRequest(c, err.Error())
+		return
+	}
+
+	kept, err := h.service.Merge(c.Request.Context(), userID.(uuid.UUID), &req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, common.APIResponse{
+		Success:    true,
+		StatusCode: http.StatusOK,
+		Data:       kept,
+	})
+}
+
+func (h *Handler) Dismiss(c *gin.Context) {
+	userID, _ := c.Get(middleware.UserIDKey)
+
+	var req DismissRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeBadRequest(c, err.Error())
+		return
+	}
+
+	if err := h.service.Dismiss(c.Request.Context(), userID.(uuid.UUID), &req); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func writeBadRequest(c *gin.Context, message string) {
+	c.JSON(http.StatusBadRequest, common.APIResponse{
+		Success:    false,
+		StatusCode: http.StatusBadRequest,
+		Error:      message,
+	})
+}
+
+func writeError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrInvalidMerge), errors.Is(err, expense.ErrInvalidMerge),
+		errors.Is(err, transaction.ErrInvalidTransaction):
+		status = http.StatusBadRequest
+	case errors.Is(err, transaction.ErrTransactionNotFound), errors.Is(err, transaction.ErrLinkTargetNotFound),
+		errors.Is(err, expense.ErrExpenseNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, transaction.ErrTransferLeg), errors.Is(err, transaction.ErrAlreadyLinked),
+		errors.Is(err, transaction.ErrReconciled):
+		status = http.StatusConflict
+	}
+	c.JSON(status, common.APIResponse{
+		Success:    false,
+		StatusCode: status,
+		Error:      err.Error(),
+	})
+}
@@ -0,0 +1,75 @@
+package duplicate
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pastorenue/kinance/internal/common"
+)
+
+type RecordType string
+
+const (
+	RecordTransaction RecordType = "transaction"
+	RecordExpense     RecordType = "expense" // An expense without a transaction, such as one entered by hand
+)
+
+// Record is one of a pair of possible duplicates.
+type Record struct {
+	Type        RecordType   `json:"type"`
+	ID          uuid.UUID    `json:"id"`
+	Amount      common.Money `json:"amount"`
+	Date        time.Time    `json:"date"`
+	Description string       `json:"description"`
+	Merchant    string       `json:"merchant,omitempty"`
+	AccountID   *uuid.UUID   `json:"account_id,omitempty"`
+	Reference   string       `json:"reference,omitempty"` // Bank reference of imported transactions
+	ReceiptID   *uuid.UUID   `json:"receipt_id,omitempty"`
+}
+
+// Candidate is a pair of records that probably stand for the same payment. Score runs from 0 to
+// 1; a shared bank reference or receipt scores 1.
+type Candidate struct {
+	First   Record    `json:"first"`
+	Second  Record    `json:"second"`
+	Score   float64   `json:"score"`
+	Reasons []string  `json:"reasons"`
+	Keep    RecordRef `json:"keep"` // Suggested record to keep when merging
+}
+
+// Query narrows the search for duplicates.
+type Query struct {
+	From     time.Time // Inclusive
+	To       time.Time // Exclusive
+	Days     int       // Days two records may be apart
+	MinScore float64
+	Limit    int
+}
+
+type RecordRef struct {
+	Type RecordType `json:"type" binding:"required,oneof=transaction expense"`
+	ID   uuid.UUID  `json:"id" binding:"required"`
+}
+
+// MergeRequest keeps one record of a pair and deletes the other. A transaction is always kept
+// over an expense without one.
+type MergeRequest struct {
+	Keep   RecordRef `json:"keep" binding:"required"`
+	Remove RecordRef `json:"remove" binding:"required"`
+}
+
+// DismissRequest marks a pair as not duplicates, so it is no longer suggested.
+type DismissRequest struct {
+	First  RecordRef `json:"first" binding:"required"`
+	Second RecordRef `json:"second" binding:"required"`
+}
+
+// DismissedPair is a pair the user marked as not duplicates. First is the lower of the two records.
+type DismissedPair struct {
+	common.BaseModel
+	UserID     uuid.UUID  `json:"user_id" gorm:"not null;uniqueIndex:idx_dismissed_pair"`
+	FirstType  RecordType `json:"first_type" gorm:"type:varchar(20);not null;uniqueIndex:idx_dismissed_pair"`
+	FirstID    uuid.UUID  `json:"first_id" gorm:"type:uuid;not null;uniqueIndex:idx_dismissed_pair"`
+	SecondType RecordType `json:"second_type" gorm:"type:varchar(20);not null;uniqueIndex:idx_dismissed_pair"`
+	SecondID   uuid.UUID  `json:"second_id" gorm:"type:uuid;not null;uniqueIndex:idx_dismissed_pair"`
+}
@@ -0,0 +1,11 @@
+package duplicate
+
+import "github.com/gin-gonic/gin"
+
+func RegisterRoutes(versionedGroup *gin.RouterGroup, svc *Service) {
+	duplicateHandler := NewHandler(svc)
+	protected := versionedGroup.Group("/duplicates")
+	protected.GET("", duplicateHandler.FindDuplicates)
+	protected.POST("/merge", duplicateHandler.Merge)
+	protected.POST("/dismiss", duplicateHandler.Dismiss)
+}
@@ -0,0 +1,281 @@
+package duplicate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pastorenue/kinance/internal/common"
+	"github.com/pastorenue/kinance/internal/expense"
+	"github.com/pastorenue/kinance/internal/transaction"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	DefaultDays     = 3
+	MaxDays         = 31
+	DefaultMinScore = 0.5
+	DefaultLimit    = 50
+	MaxLimit        = 200
+
+	// maxPairs caps the pairs scored per request; users with thousands of identical payments,
+	// such as daily fares, would otherwise get a quadratic number of them.
+	maxPairs = 2000
+)
+
+var ErrInvalidMerge = errors.New("invalid merge")
+
+type Service struct {
+	db           *gorm.DB
+	transactions *transaction.Service
+	expenses     *expense.Service
+	logger       common.Logger
+}
+
+func NewService(db *gorm.DB, transactions *transaction.Service, expenses *expense.Service, logger common.Logger) *Service {
+	return &Service{db: db, transactions: transactions, expenses: expenses, logger: logger}
+}
+
+// records selects the transactions and the expenses without a transaction of @user dated in the
+// query, with a common set of columns. Transfers and canceled transactions are never duplicates.
+const records = `
+	SELECT 'transaction' AS record_type, t.id, t.type AS kind, t.amount, t.currency, t.transaction_date AS date,
+	       t.description, COALESCE(m.name, '') AS merchant, t.account_id,
+	       COALESCE(t.metadata->'import'->>'reference', '') AS reference, t.receipt_id, t.created_at
+	FROM transactions t LEFT JOIN merchants m ON m.id = t.merchant_id
+	WHERE t.user_id = @user AND t.type IN ('expense', 'income') AND t.transfer_id IS NULL
+	  AND t.status <> 'canceled' AND t.transaction_date >= @from AND t.transaction_date < @to
+	UNION ALL
+	SELECT 'expense', e.id, 'expense', e.amount, e.currency, COALESCE(e.due_date, e.created_at),
+	       e.description, '', e.account_id, '', NULL::uuid, e.created_at
+	FROM expenses e
+	WHERE e.user_id = @user AND e.transaction_id IS NULL
+	  AND NOT EXISTS (SELECT 1 FROM transactions t WHERE t.processing_object_id = e.id)
+	  AND COALESCE(e.due_date, e.created_at) >= @from AND COALESCE(e.due_date, e.created_at) < @to`
+
+// pairs joins the records to each other: same kind and currency, amounts within 1% and dates
+// within @days. Two bank lines with different references on one account are separate payments,
+// and records on different accounts cannot be the same payment.
+var pairs = fmt.Sprintf(`
+	WITH r AS (%s)
+	SELECT a.record_type AS first_type, a.id AS first_id, a.amount AS first_amount, a.currency,
+	       a.date AS first_date, a.description AS first_description, a.merchant AS first_merchant,
+	       a.account_id AS first_account_id, a.reference AS first_reference, a.receipt_id AS first_receipt_id,
+	       a.created_at AS first_created_at,
+	       b.record_type AS second_type, b.id AS second_id, b.amount AS second_amount,
+	       b.date AS second_date, b.description AS second_description, b.merchant AS second_merchant,
+	       b.account_id AS second_account_id, b.reference AS second_reference, b.receipt_id AS second_receipt_id,
+	       b.created_at AS second_created_at,
+	       similarity(lower(trim(a.merchant || ' ' || a.description)), lower(trim(b.merchant || ' ' || b.description))) AS similarity
+	FROM r a JOIN r b
+	  ON (a.record_type, a.id) < (b.record_type, b.id)
+	 AND a.kind = b.kind AND a.currency = b.currency
+	 AND abs(a.amount - b.amount) <= GREATEST(0.01, abs(a.amount) * 0.01)
+	 AND abs(extract(epoch FROM a.date - b.date)) <= @days * 86400
+	WHERE NOT (a.reference <> '' AND b.reference <> '' AND a.reference <> b.reference
+	           AND a.account_id IS NOT DISTINCT FROM b.account_id)
+	  AND (a.account_id IS NULL OR b.account_id IS NULL OR a.account_id = b.account_id)
+	  AND NOT EXISTS (
+	      SELECT 1 FROM dismissed_pairs d
+	      WHERE d.user_id = @user AND d.first_type = a.record_type AND d.first_id = a.id
+	        AND d.second_type = b.record_type AND d.second_id = b.id)
+	ORDER BY a.date DESC
+	LIMIT @pairs`, records)
+
+type pairRow struct {
+	FirstType         RecordType
+	FirstID           uuid.UUID
+	FirstAmount       decimal.Decimal
+	Currency          common.Currency
+	FirstDate         time.Time
+	FirstDescription  string
+	FirstMerchant     string
+	FirstAccountID    *uuid.UUID
+	FirstReference    string
+	FirstReceiptID    *uuid.UUID
+	FirstCreatedAt    time.Time
+	SecondType        RecordType
+	SecondID          uuid.UUID
+	SecondAmount      decimal.Decimal
+	SecondDate        time.Time
+	SecondDescription string
+	SecondMerchant    string
+	SecondAccountID   *uuid.UUID
+	SecondReference   string
+	SecondReceiptID   *uuid.UUID
+	SecondCreatedAt   time.Time
+	Similarity        float64
+}
+
+// FindDuplicates returns the pairs of the user's records that probably stand for the same
+// payment, most likely first. Pairs the user dismissed are left out.
+func (s *Service) FindDuplicates(ctx context.Context, userID uuid.UUID, query *Query) ([]Candidate, error) {
+	days := query.Days
+	if days < 0 || days > MaxDays {
+		days = DefaultDays
+	}
+	limit := query.Limit
+	if limit <= 0 || limit > MaxLimit {
+		limit = DefaultLimit
+	}
+
+	var rows []pairRow
+	if err := s.db.WithContext(ctx).Raw(pairs, map[string]interface{}{
+		"user":  userID,
+		"from":  query.From,
+		"to":    query.To,
+		"days":  days,
+		"pairs": maxPairs,
+	}).Scan(&rows).Error; err != nil {
+		s.logger.Error("Failed to find duplicates", "user_id", userID, "error", err)
+		return nil, err
+	}
+
+	candidates := make([]Candidate, 0, len(rows))
+	for _, row := range rows {
+		candidate := score(row, days)
+		if candidate.Score >= query.MinScore {
+			candidates = append(candidates, candidate)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	return candidates, nil
+}
+
+// score rates how likely the pair is one payment. A shared bank reference or receipt is
+// conclusive; otherwise the amount counts for up to 0.4, and the closeness of the dates and the
+// similarity of merchant and description for up to 0.3 each.
+func score(row pairRow, days int) Candidate {
+	candidate := Candidate{
+		First: Record{
+			Type: row.FirstType, ID: row.FirstID, Amount: common.NewMoney(row.FirstAmount, row.Currency),
+			Date: row.FirstDate, Description: row.FirstDescription, Merchant: row.FirstMerchant,
+			AccountID: row.FirstAccountID, Reference: row.FirstReference, ReceiptID: row.FirstReceiptID,
+		},
+		Second: Record{
+			Type: row.SecondType, ID: row.SecondID, Amount: common.NewMoney(row.SecondAmount, row.Currency),
+			Date: row.SecondDate, Description: row.SecondDescription, Merchant: row.SecondMerchant,
+			AccountID: row.SecondAccountID, Reference: row.SecondReference, ReceiptID: row.SecondReceiptID,
+		},
+	}
+	candidate.Keep = suggestKeep(row)
+
+	switch {
+	case row.FirstReference != "" && row.FirstReference == row.SecondReference:
+		candidate.Score = 1
+		candidate.Reasons = []string{"same bank reference"}
+		return candidate
+	case row.FirstReceiptID != nil && row.SecondReceiptID != nil && *row.FirstReceiptID == *row.SecondReceiptID:
+		candidate.Score = 1
+		candidate.Reasons = []string{"same receipt"}
+		return candidate
+	}
+
+	if row.FirstAmount.Equal(row.SecondAmount) {
+		candidate.Score += 0.4
+		candidate.Reasons = append(candidate.Reasons, "same amount")
+	} else {
+		candidate.Score += 0.2
+		candidate.Reasons = append(candidate.Reasons, "amounts within 1%")
+	}
+
+	apart := math.Abs(row.FirstDate.Sub(row.SecondDate).Hours()) / 24
+	candidate.Score += 0.3 * math.Max(0, 1-apart/float64(days+1))
+	switch whole := int(math.Round(apart)); {
+	case apart < 1:
+		candidate.Reasons = append(candidate.Reasons, "same day")
+	case whole == 1:
+		candidate.Reasons = append(candidate.Reasons, "1 day apart")
+	default:
+		candidate.Reasons = append(candidate.Reasons, fmt.Sprintf("%d days apart", whole))
+	}
+
+	candidate.Score += 0.3 * row.Similarity
+	if row.Similarity >= 0.3 {
+		candidate.Reasons = append(candidate.Reasons, "similar merchant or description")
+	}
+	candidate.Score = math.Round(candidate.Score*100) / 100
+	return candidate
+}
+
+// suggestKeep prefers a transaction over an expense without one, then the transaction imported
+// from the bank, then the older record.
+func suggestKeep(row pairRow) RecordRef {
+	first := RecordRef{Type: row.FirstType, ID: row.FirstID}
+	second := RecordRef{Type: row.SecondType, ID: row.SecondID}
+	switch {
+	case row.FirstType != row.SecondType:
+		if row.FirstType == RecordTransaction {
+			return first
+		}
+		return second
+	case (row.FirstReference != "") != (row.SecondReference != ""):
+		if row.FirstReference != "" {
+			return first
+		}
+		return second
+	case row.SecondCreatedAt.Before(row.FirstCreatedAt):
+		return second
+	default:
+		return first
+	}
+}
+
+// Merge keeps one record of a pair of duplicates and deletes the other. The kept record takes over
+// the receipts, tags and linked expense or income of the other; see transaction.MergeTransactions.
+func (s *Service) Merge(ctx context.Context, userID uuid.UUID, req *MergeRequest) (interface{}, error) {
+	keep, remove := req.Keep, req.Remove
+	switch {
+	case keep.Type == RecordTransaction && remove.Type == RecordTransaction:
+		return s.transactions.MergeTransactions(ctx, userID, keep.ID, remove.ID)
+	case keep.Type == RecordTransaction && remove.Type == RecordExpense:
+		return s.transactions.MergeExpense(ctx, userID, keep.ID, remove.ID)
+	case keep.Type == RecordExpense && remove.Type == RecordExpense:
+		return s.expenses.MergeExpenses(ctx, userID, keep.ID, remove.ID)
+	default:
+		return nil, fmt.Errorf("%w: keep the transaction, which is what the bank booked, and remove the expense", ErrInvalidMerge)
+	}
+}
+
+// Dismiss marks a pair as not duplicates. Dismissing a pair twice is not an error.
+func (s *Service) Dismiss(ctx context.Context, userID uuid.UUID, req *DismissRequest) error {
+	first, second := req.First, req.Second
+	if first == second {
+		return fmt.Errorf("%w: a record is not a duplicate of itself", ErrInvalidMerge)
+	}
+	if !less(first, second) {
+		first, second = second, first
+	}
+
+	pair := &DismissedPair{
+		UserID:     userID,
+		FirstType:  first.Type,
+		FirstID:    first.ID,
+		SecondType: second.Type,
+		SecondID:   second.ID,
+	}
+	if err := s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(pair).Error; err != nil {
+		s.logger.Error("Failed to dismiss duplicates", "user_id", userID, "error", err)
+		return err
+	}
+	return nil
+}
+
+// less orders records like the duplicate query does: by type, then by the bytes of the ID.
+func less(a RecordRef, b RecordRef) bool {
+	if a.Type != b.Type {
+		return a.Type < b.Type
+	}
+	return a.ID.String() < b.ID.String()
+}
@@ -0,0 +1,137 @@
+package duplicate
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// pair returns two transactions of 12.50 EUR on the same day, created a minute apart.
+func pair() pairRow {
+	day := time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)
+	return pairRow{
+		FirstType: RecordTransaction, FirstID: uuid.New(), FirstAmount: decimal.RequireFromString("12.50"), Currency: "EUR",
+		FirstDate: day, FirstCreatedAt: day.Add(time.Minute),
+		SecondType: RecordTransaction, SecondID: uuid.New(), SecondAmount: decimal.RequireFromString("12.50"),
+		SecondDate: day, SecondCreatedAt: day.Add(2 * time.Minute),
+	}
+}
+
+func TestScore(t *testing.T) {
+	receipt := uuid.New()
+	tests := []struct {
+		name    string
+		change  func(r *pairRow)
+		days    int
+		want    float64
+		reasons string
+	}{
+		{
+			name:   "same bank reference",
+			change: func(r *pairRow) { r.FirstReference, r.SecondReference = "BANK-1", "BANK-1" },
+			days:   3, want: 1, reasons: "same bank reference",
+		},
+		{
+			name:   "same receipt",
+			change: func(r *pairRow) { r.FirstReceiptID, r.SecondReceiptID = &receipt, &receipt },
+			days:   3, want: 1, reasons: "same receipt",
+		},
+		{
+			name:   "same amount and day and description",
+			change: func(r *pairRow) { r.Similarity = 1 },
+			days:   3, want: 1, reasons: "same amount, same day, similar merchant or description",
+		},
+		{
+			name: "amounts within 1% two days apart",
+			change: func(r *pairRow) {
+				r.SecondAmount = decimal.RequireFromString("12.60")
+				r.SecondDate = r.FirstDate.AddDate(0, 0, 2)
+				r.Similarity = 0.2
+			},
+			days: 3, want: 0.41, reasons: "amounts within 1%, 2 days apart",
+		},
+		{
+			name: "one day apart in a window of one day",
+			change: func(r *pairRow) {
+				r.SecondDate = r.FirstDate.AddDate(0, 0, -1)
+				r.Similarity = 0.5
+			},
+			days: 1, want: 0.7, reasons: "same amount, 1 day apart, similar merchant or description",
+		},
+		{
+			name:   "hours apart on the same day",
+			change: func(r *pairRow) { r.SecondDate = r.FirstDate.Add(20 * time.Hour) },
+			days:   0, want: 0.45, reasons: "same amount, same day",
+		},
+		{
+			name:   "different bank references are not conclusive",
+			change: func(r *pairRow) { r.FirstReference, r.SecondReference = "BANK-1", "BANK-2" },
+			days:   3, want: 0.7, reasons: "same amount, same day",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			row := pair()
+			tt.change(&row)
+			got := score(row, tt.days)
+			if got.Score != tt.want || strings.Join(got.Reasons, ", ") != tt.reasons {
+				t.Errorf("score = %v (%s), want %v (%s)", got.Score, strings.Join(got.Reasons, ", "), tt.want, tt.reasons)
+			}
+			if got.First.ID != row.FirstID || got.Second.ID != row.SecondID || got.First.Amount.Currency != "EUR" {
+				t.Errorf("candidate = %+v, want the records of the pair", got)
+			}
+		})
+	}
+}
+
+func TestSuggestKeep(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(r *pairRow)
+		first  bool
+	}{
+		{name: "transaction over an expense", change: func(r *pairRow) { r.FirstType = RecordExpense }, first: false},
+		{name: "expense is never kept over a transaction", change: func(r *pairRow) { r.SecondType = RecordExpense }, first: true},
+		{name: "imported transaction", change: func(r *pairRow) { r.SecondReference = "BANK-1" }, first: false},
+		{name: "older record", change: func(r *pairRow) {}, first: true},
+		{name: "older second record", change: func(r *pairRow) { r.SecondCreatedAt = r.FirstCreatedAt.Add(-time.Hour) }, first: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			row := pair()
+			tt.change(&row)
+			want := RecordRef{Type: row.SecondType, ID: row.SecondID}
+			if tt.first {
+				want = RecordRef{Type: row.FirstType, ID: row.FirstID}
+			}
+			if got := suggestKeep(row); got != want {
+				t.Errorf("suggestKeep = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestLess(t *testing.T) {
+	low := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	high := uuid.MustParse("ffffffff-0000-0000-0000-000000000000")
+	tests := []struct {
+		name string
+		a, b RecordRef
+		want bool
+	}{
+		{name: "expense before transaction", a: RecordRef{Type: RecordExpense, ID: high}, b: RecordRef{Type: RecordTransaction, ID: low}, want: true},
+		{name: "transaction after expense", a: RecordRef{Type: RecordTransaction, ID: low}, b: RecordRef{Type: RecordExpense, ID: high}, want: false},
+		{name: "same type by ID", a: RecordRef{Type: RecordTransaction, ID: low}, b: RecordRef{Type: RecordTransaction, ID: high}, want: true},
+		{name: "same record", a: RecordRef{Type: RecordTransaction, ID: low}, b: RecordRef{Type: RecordTransaction, ID: low}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := less(tt.a, tt.b); got != tt.want {
+				t.Errorf("less(%+v, %+v) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
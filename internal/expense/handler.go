@@ -1,13 +1,16 @@
 package expense
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/pastorenue/kinance/internal/common"
 	"github.com/pastorenue/kinance/pkg/middleware"
+	"github.com/pastorenue/kinance/pkg/pagination"
 )
 
 type Handler struct {
@@ -48,14 +51,27 @@ func (h *Handler) CreateExpense(c *gin.Context) {
 func (h *Handler) GetExpenses(c *gin.Context) {
 	userID, _ := c.Get(middleware.UserIDKey)
 
-	var pagination common.PaginationParams
-	if err := c.ShouldBindQuery(&pagination); err != nil {
-		pagination = common.PaginationParams{Page: 1, PageSize: 20}
+	var params pagination.Params
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, common.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
 	}
 
-	result, err := h.service.GetExpenses(c.Request.Context(), userID.(uuid.UUID), &pagination)
+	filter, err := common.ParseFilter(c.Query("filter"), ExpenseFilters)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, common.APIResponse{
+		c.JSON(http.StatusBadRequest, common.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	result, err := h.service.GetExpenses(c.Request.Context(), userID.(uuid.UUID), filter, params)
+	if err != nil {
+		c.JSON(pagination.HTTPStatus(err), common.APIResponse{
 			Success: false,
 			Error:   err.Error(),
 		})
@@ -97,7 +113,7 @@ func (h *Handler) DeleteExpense(c *gin.Context) {
 
 	err := h.service.DeleteExpense(c.Request.Context(), userID, expenseID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, common.APIResponse{
+		c.JSON(expenseErrorStatus(err), common.APIResponse{
 			Success: false,
 			Error:   err.Error(),
 		})
@@ -127,7 +143,7 @@ func (h *Handler) UpdateExpense(c *gin.Context) {
 
 	expense, err := h.service.UpdateExpense(c.Request.Context(), userID, expenseID, &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, common.APIResponse{
+		c.JSON(expenseErrorStatus(err), common.APIResponse{
 			Success: false,
 			Error:   err.Error(),
 		})
@@ -151,14 +167,18 @@ func (h *Handler) GetExpensesByCategoryID(c *gin.Context) {
 		return
 	}
 
-	var pagination common.PaginationParams
-	if err := c.ShouldBindQuery(&pagination); err != nil {
-		pagination = common.PaginationParams{Page: 1, PageSize: 20}
+	var params pagination.Params
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, common.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
 	}
 
-	expenses, err := h.service.GetExpensesByCategoryID(c.Request.Context(), userID.(uuid.UUID), categoryID)
+	expenses, err := h.service.GetExpensesByCategoryID(c.Request.Context(), userID.(uuid.UUID), categoryID, params)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, common.APIResponse{
+		c.JSON(pagination.HTTPStatus(err), common.APIResponse{
 			Success: false,
 			Error:   err.Error(),
 		})
@@ -204,16 +224,20 @@ func (h *Handler) CreateRecurringExpense(c *gin.Context) {
 func (h *Handler) GetRecurringExpenses(c *gin.Context) {
 	userID, _ := c.Get(middleware.UserIDKey)
 
-	var pagination common.PaginationParams
-	if err := c.ShouldBindQuery(&pagination); err != nil {
-		pagination = common.PaginationParams{Page: 1, PageSize: 20}
+	var params pagination.Params
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, common.APIResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
 	}
 
-	result, err := h.service.GetRecurringExpenses(c.Request.Context(), userID.(uuid.UUID))
+	result, err := h.service.GetRecurringExpenses(c.Request.Context(), userID.(uuid.UUID), params)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, common.APIResponse{
+		c.JSON(pagination.HTTPStatus(err), common.APIResponse{
 			Success:    false,
-			StatusCode: http.StatusInternalServerError,
+			StatusCode: pagination.HTTPStatus(err),
 			Error:      err.Error(),
 		})
 		return
@@ -336,7 +360,201 @@ func (h *Handler) GetRecurringExpenseHistory(c *gin.Context) {
 		return
 	}
 
-	result, err := h.service.GetRecurringExpenseHistory(c.Request.Context(), userID.(uuid.UUID), recurringExpenseID)
+	var params pagination.Params
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, common.APIResponse{
+			Success:    false,
+			StatusCode: http.StatusBadRequest,
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	result, err := h.service.GetRecurringExpenseHistory(c.Request.Context(), userID.(uuid.UUID), recurringExpenseID, params)
+	if err != nil {
+		c.JSON(pagination.HTTPStatus(err), common.APIResponse{
+			Success:    false,
+			StatusCode: pagination.HTTPStatus(err),
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, common.APIResponse{
+		Success:    true,
+		StatusCode: http.StatusOK,
+		Data:       result,
+	})
+}
+func (h *Handler) BackfillRecurringExpense(c *gin.Context) {
+	userID, _ := c.Get(middleware.UserIDKey)
+	recurringExpenseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.APIResponse{
+			Success:    false,
+			StatusCode: http.StatusBadRequest,
+			Error:      "Invalid recurring expense ID",
+		})
+		return
+	}
+
+	result, err := h.service.BackfillRecurringExpense(c.Request.Context(), userID.(uuid.UUID), recurringExpenseID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, common.APIResponse{
+			Success:    false,
+			StatusCode: http.StatusInternalServerError,
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, common.APIResponse{
+		Success:    true,
+		StatusCode: http.StatusOK,
+		Data:       result,
+	})
+}
+
+func (h *Handler) CreateOccurrenceException(c *gin.Context) {
+	userID, _ := c.Get(middleware.UserIDKey)
+	recurringExpenseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.APIResponse{
+			Success:    false,
+			StatusCode: http.StatusBadRequest,
+			Error:      "Invalid recurring expense ID",
+		})
+		return
+	}
+
+	var req CreateOccurrenceExceptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.APIResponse{
+			Success:    false,
+			StatusCode: http.StatusBadRequest,
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	result, err := h.service.CreateOccurrenceException(c.Request.Context(), userID.(uuid.UUID), recurringExpenseID, &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, common.APIResponse{
+			Success:    false,
+			StatusCode: http.StatusInternalServerError,
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, common.APIResponse{
+		Success:    true,
+		StatusCode: http.StatusCreated,
+		Data:       result,
+	})
+}
+
+func (h *Handler) GetOccurrenceExceptions(c *gin.Context) {
+	userID, _ := c.Get(middleware.UserIDKey)
+	recurringExpenseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.APIResponse{
+			Success:    false,
+			StatusCode: http.StatusBadRequest,
+			Error:      "Invalid recurring expense ID",
+		})
+		return
+	}
+
+	result, err := h.service.GetOccurrenceExceptions(c.Request.Context(), userID.(uuid.UUID), recurringExpenseID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, common.APIResponse{
+			Success:    false,
+			StatusCode: http.StatusInternalServerError,
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, common.APIResponse{
+		Success:    true,
+		StatusCode: http.StatusOK,
+		Data:       result,
+	})
+}
+
+func (h *Handler) DeleteOccurrenceException(c *gin.Context) {
+	userID, _ := c.Get(middleware.UserIDKey)
+	recurringExpenseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.APIResponse{
+			Success:    false,
+			StatusCode: http.StatusBadRequest,
+			Error:      "Invalid recurring expense ID",
+		})
+		return
+	}
+	exceptionID, err := uuid.Parse(c.Param("exception_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.APIResponse{
+			Success:    false,
+			StatusCode: http.StatusBadRequest,
+			Error:      "Invalid occurrence exception ID",
+		})
+		return
+	}
+
+	err = h.service.DeleteOccurrenceException(c.Request.Context(), userID.(uuid.UUID), recurringExpenseID, exceptionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, common.APIResponse{
+			Success:    false,
+			StatusCode: http.StatusInternalServerError,
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, common.APIResponse{
+		Success:    true,
+		StatusCode: http.StatusOK,
+		Data:       "Occurrence exception deleted successfully",
+	})
+}
+
+func (h *Handler) GetRecurringExpenseProjection(c *gin.Context) {
+	userID, _ := c.Get(middleware.UserIDKey)
+
+	from := time.Now().UTC().Truncate(24 * time.Hour)
+	if fromStr := c.Query("from"); fromStr != "" {
+		parsed, err := time.Parse("2006-01-02", fromStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, common.APIResponse{
+				Success:    false,
+				StatusCode: http.StatusBadRequest,
+				Error:      "Invalid from parameter, expected YYYY-MM-DD",
+			})
+			return
+		}
+		from = parsed
+	}
+
+	to := from.AddDate(0, 1, 0)
+	if toStr := c.Query("to"); toStr != "" {
+		parsed, err := time.Parse("2006-01-02", toStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, common.APIResponse{
+				Success:    false,
+				StatusCode: http.StatusBadRequest,
+				Error:      "Invalid to parameter, expected YYYY-MM-DD",
+			})
+			return
+		}
+		to = parsed
+	}
+	// Include every occurrence due on the last day of the window
+	to = to.Add(24*time.Hour - time.Nanosecond)
+
+	result, err := h.service.GetRecurringExpenseProjection(c.Request.Context(), userID.(uuid.UUID), from, to)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, common.APIResponse{
 			Success:    false,
@@ -352,6 +570,7 @@ func (h *Handler) GetRecurringExpenseHistory(c *gin.Context) {
 		Data:       result,
 	})
 }
+
 func (h *Handler) GetUpcomingRecurringExpenses(c *gin.Context) {
 	userID, _ := c.Get(middleware.UserIDKey)
 	intervalStr := c.Query("interval")
@@ -359,7 +578,7 @@ func (h *Handler) GetUpcomingRecurringExpenses(c *gin.Context) {
 	var err error
 	if intervalStr != "" {
 		interval, err = strconv.Atoi(intervalStr)
-		if err != nil {
+		if err != nil || interval < 0 {
 			c.JSON(http.StatusBadRequest, common.APIResponse{
 				Success:    false,
 				StatusCode: http.StatusBadRequest,
@@ -386,3 +605,14 @@ func (h *Handler) GetUpcomingRecurringExpenses(c *gin.Context) {
 		Data:       result,
 	})
 }
+
+func expenseErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrExpenseNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, common.ErrReconciled):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
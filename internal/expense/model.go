@@ -20,12 +20,15 @@ const (
 
 type RecurringExpense struct {
 	common.BaseModel
-	Amount        decimal.Decimal      `gorm:"type:decimal(10,2);not null" json:"amount"`
+	Amount        common.Money         `gorm:"embedded" json:"amount"`
 	Description   string               `json:"description"`
 	CategoryID    uuid.UUID            `gorm:"not null" json:"category_id"`
 	Category      *category.Category   `json:"category"`
 	UserID        uuid.UUID            `gorm:"not null" json:"user_id"`
+	AccountID     *uuid.UUID           `gorm:"type:uuid" json:"account_id,omitempty"` // Copied to the generated expenses
 	Frequency     RecurringFrequency   `gorm:"type:recurring_frequency;not null" json:"frequency"`
+	RRule         string               `gorm:"type:text" json:"rrule"`           // RFC 5545 RRULE, derived from Frequency when empty
+	CustomRule    bool                 `gorm:"default:false" json:"custom_rule"` // RRule was given by the user rather than derived from Frequency
 	PaymentMethod common.PaymentMethod `gorm:"type:payment_method" json:"payment_method"`
 	IsActive      bool                 `gorm:"default:true" json:"is_active"`
 	Expenses      []Expense            `gorm:"foreignKey:RecurringExpenseID" json:"expenses"`
@@ -35,16 +38,65 @@ type RecurringExpense struct {
 	LastProcessed time.Time            `json:"last_processed,omitempty"`
 }
 
+type OccurrenceAction string
+
+const (
+	OccurrenceSkip     OccurrenceAction = "skip"
+	OccurrenceOverride OccurrenceAction = "override"
+	OccurrencePause    OccurrenceAction = "pause"
+)
+
+// OccurrenceException changes a single occurrence of a recurring expense, or pauses the
+// series from OccurrenceDate until PauseUntil, without editing the whole series.
+type OccurrenceException struct {
+	common.BaseModel
+	RecurringExpenseID uuid.UUID        `gorm:"not null;uniqueIndex:idx_occurrence_exception_date" json:"recurring_expense_id"`
+	UserID             uuid.UUID        `gorm:"not null;index" json:"user_id"`
+	Action             OccurrenceAction `gorm:"type:varchar(20);not null" json:"action"`
+	OccurrenceDate     time.Time        `gorm:"type:date;not null;uniqueIndex:idx_occurrence_exception_date" json:"occurrence_date"`
+	PauseUntil         *time.Time       `gorm:"type:date" json:"pause_until,omitempty"`     // Pause only; occurrences before this date are not generated
+	Amount             *decimal.Decimal `gorm:"type:decimal(20,4)" json:"amount,omitempty"` // In the currency of the series
+	CategoryID         *uuid.UUID       `json:"category_id,omitempty"`
+	Note               string           `json:"note"`
+}
+
+type OccurrenceStatus string
+
+const (
+	OccurrenceScheduled  OccurrenceStatus = "scheduled"
+	OccurrenceSkipped    OccurrenceStatus = "skipped"
+	OccurrencePaused     OccurrenceStatus = "paused"
+	OccurrenceOverridden OccurrenceStatus = "overridden"
+)
+
+// Occurrence is a single dated instance of a recurring expense with its exceptions applied.
+type Occurrence struct {
+	RecurringExpenseID uuid.UUID            `json:"recurring_expense_id"`
+	DueDate            time.Time            `json:"due_date"`
+	Amount             common.Money         `json:"amount"`
+	CategoryID         uuid.UUID            `json:"category_id"`
+	Description        string               `json:"description"`
+	PaymentMethod      common.PaymentMethod `json:"payment_method"`
+	Status             OccurrenceStatus     `json:"status"`
+	ExceptionID        *uuid.UUID           `json:"exception_id,omitempty"`
+}
+
+// IsBillable reports whether the occurrence turns into an expense.
+func (o Occurrence) IsBillable() bool {
+	return o.Status == OccurrenceScheduled || o.Status == OccurrenceOverridden
+}
+
 type Expense struct {
 	common.BaseModel
-	Amount             decimal.Decimal      `gorm:"type:decimal(10,2);not null" json:"amount" binding:"required"`
-	Currency           *common.Currency     `json:"currency" binding:"required"`
+	Amount             common.Money         `gorm:"embedded" json:"amount"`
 	Description        string               `json:"description" binding:"required"`
 	CategoryID         uuid.UUID            `gorm:"not null" json:"category_id" binding:"required"`
 	Category           *category.Category   `gorm:"foreignKey:CategoryID" json:"category"`
 	UserID             uuid.UUID            `gorm:"not null" json:"user_id" binding:"required"`
-	RecurringExpenseID *uuid.UUID           `gorm:"index" json:"recurring_expense_id,omitempty"`
+	AccountID          *uuid.UUID           `gorm:"type:uuid;index" json:"account_id,omitempty"`
+	RecurringExpenseID *uuid.UUID           `gorm:"index;uniqueIndex:idx_recurring_expense_due_date" json:"recurring_expense_id,omitempty"`
 	RecurringExpense   *RecurringExpense    `gorm:"foreignKey:RecurringExpenseID" json:"recurring_expense,omitempty"`
+	DueDate            *time.Time           `gorm:"uniqueIndex:idx_recurring_expense_due_date" json:"due_date,omitempty"` // Occurrence date for generated recurring expenses, transaction date for expenses of transactions
 	ReceiptURL         string               `json:"receipt_url,omitempty"`
 	PaymentMethod      common.PaymentMethod `gorm:"type:payment_method" json:"payment_method"`
 	TransactionID      *uuid.UUID           `gorm:"index" json:"transaction_id,omitempty"`
@@ -54,8 +106,10 @@ type Expense struct {
 
 type CreateExpenseRequest struct {
 	Amount             decimal.Decimal      `json:"amount" binding:"required"`
+	Currency           common.Currency      `json:"currency" binding:"omitempty,currency"` // Defaults to EUR
 	Description        string               `json:"description"`
 	CategoryID         uuid.UUID            `json:"category_id" binding:"required"`
+	AccountID          *uuid.UUID           `json:"account_id,omitempty"`
 	PaymentMethod      common.PaymentMethod `json:"payment_method" binding:"required,oneof=cash card bank_transfer"`
 	RecurringExpenseID *uuid.UUID           `json:"recurring_expense_id,omitempty"`
 	ReceiptURL         string               `json:"receipt_url,omitempty"`
@@ -65,15 +119,19 @@ type UpdateExpenseRequest struct {
 	Amount        *decimal.Decimal      `json:"amount" binding:"omitempty"`
 	Description   *string               `json:"description"`
 	CategoryID    *uuid.UUID            `json:"category_id"`
+	AccountID     *uuid.UUID            `json:"account_id"`
 	PaymentMethod *common.PaymentMethod `json:"payment_method" binding:"omitempty,oneof=cash card bank_transfer"`
 	ReceiptURL    *string               `json:"receipt_url,omitempty"`
 }
 
 type CreateRecurringExpenseRequest struct {
 	Amount        decimal.Decimal      `json:"amount" binding:"required"`
+	Currency      common.Currency      `json:"currency" binding:"omitempty,currency"` // Defaults to EUR
 	Description   string               `json:"description"`
 	CategoryID    uuid.UUID            `json:"category_id" binding:"required"`
-	Frequency     RecurringFrequency   `json:"frequency" binding:"required,oneof=daily weekly monthly yearly"`
+	AccountID     *uuid.UUID           `json:"account_id,omitempty"`
+	Frequency     RecurringFrequency   `json:"frequency" binding:"omitempty,oneof=daily weekly monthly yearly"`
+	RRule         string               `json:"rrule"` // e.g. FREQ=MONTHLY;BYMONTHDAY=15,-1; required when frequency is empty
 	PaymentMethod common.PaymentMethod `json:"payment_method" binding:"required,oneof=cash card bank_transfer"`
 	StartDate     time.Time            `json:"start_date" binding:"required"`
 	EndDate       *time.Time           `json:"end_date,omitempty"`
@@ -83,14 +141,54 @@ type UpdateRecurringExpenseRequest struct {
 	Amount        *decimal.Decimal      `json:"amount" binding:"omitempty"`
 	Description   *string               `json:"description"`
 	CategoryID    *uuid.UUID            `json:"category_id"`
+	AccountID     *uuid.UUID            `json:"account_id"` // Applies to occurrences generated from now on
 	Frequency     *RecurringFrequency   `json:"frequency" binding:"omitempty,oneof=daily weekly monthly yearly"`
+	RRule         *string               `json:"rrule"` // An empty rule derives the schedule from the frequency again
 	PaymentMethod *common.PaymentMethod `json:"payment_method" binding:"omitempty,oneof=cash card bank_transfer"`
 	StartDate     *time.Time            `json:"start_date"`
 	EndDate       *time.Time            `json:"end_date,omitempty"`
 	IsActive      *bool                 `json:"is_active"`
 }
 
+type CreateOccurrenceExceptionRequest struct {
+	Action         OccurrenceAction `json:"action" binding:"required,oneof=skip override pause"`
+	OccurrenceDate time.Time        `json:"occurrence_date" binding:"required"`
+	PauseUntil     *time.Time       `json:"pause_until,omitempty"`
+	Amount         *decimal.Decimal `json:"amount,omitempty"`
+	CategoryID     *uuid.UUID       `json:"category_id,omitempty"`
+	Note           string           `json:"note"`
+}
+
 type RecurringExpenseResponse struct {
 	RecurringExpense RecurringExpense `json:"recurring_expense"`
 	DaysUntilDue     int              `json:"days_until_due"`
+	NextOccurrence   *Occurrence      `json:"next_occurrence,omitempty"`
+}
+
+// ProjectionResponse totals are in the user's reporting currency, converted at the rate of each
+// due date, which is the latest known rate for future occurrences.
+type ProjectionResponse struct {
+	From       time.Time            `json:"from"`
+	To         time.Time            `json:"to"`
+	Currency   common.Currency      `json:"currency"`
+	Total      decimal.Decimal      `json:"total"`
+	Days       []ProjectionDay      `json:"days"`
+	Categories []ProjectionCategory `json:"categories"`
+}
+
+// ProjectionDay groups the occurrences due on one calendar day. Skipped and paused
+// occurrences are listed but do not count towards the totals.
+type ProjectionDay struct {
+	Date                  string                        `json:"date"`
+	Total                 decimal.Decimal               `json:"total"`
+	RunningTotal          decimal.Decimal               `json:"running_total"`
+	CategoryRunningTotals map[uuid.UUID]decimal.Decimal `json:"category_running_totals"`
+	Occurrences           []Occurrence                  `json:"occurrences"`
+}
+
+type ProjectionCategory struct {
+	CategoryID   uuid.UUID       `json:"category_id"`
+	CategoryName string          `json:"category_name"`
+	Total        decimal.Decimal `json:"total"`
+	Count        int             `json:"count"`
 }
@@ -20,5 +20,10 @@ func RegisterRoutes(versionedGroup *gin.RouterGroup, svc *Service) {
 	protected.PUT("/recurring/:id", expHandler.UpdateRecurringExpense)
 	protected.DELETE("/recurring/:id", expHandler.DeleteRecurringExpense)
 	protected.GET("/recurring/:id/history", expHandler.GetRecurringExpenseHistory)
+	protected.POST("/recurring/:id/backfill", expHandler.BackfillRecurringExpense)
+	protected.GET("/recurring/:id/occurrences", expHandler.GetOccurrenceExceptions)
+	protected.POST("/recurring/:id/occurrences", expHandler.CreateOccurrenceException)
+	protected.DELETE("/recurring/:id/occurrences/:exception_id", expHandler.DeleteOccurrenceException)
 	protected.GET("/recurring/upcoming", expHandler.GetUpcomingRecurringExpenses)
+	protected.GET("/recurring/projection", expHandler.GetRecurringExpenseProjection)
 }
@@ -3,36 +3,78 @@ package expense
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sort"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/pastorenue/kinance/internal/account"
+	"github.com/pastorenue/kinance/internal/category"
 	"github.com/pastorenue/kinance/internal/common"
+	"github.com/pastorenue/kinance/internal/fx"
+	"github.com/pastorenue/kinance/internal/ledger"
+	"github.com/pastorenue/kinance/internal/recurrence"
+	"github.com/pastorenue/kinance/pkg/pagination"
 	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrExpenseNotFound = errors.New("expense not found")
+	ErrInvalidMerge    = errors.New("invalid merge")
 )
 
 type Service struct {
-	db     *gorm.DB
-	logger common.Logger
+	db        *gorm.DB
+	rates     *fx.Service
+	logger    common.Logger
+	listeners []Listener
 }
 
-func NewService(db *gorm.DB, logger common.Logger) *Service {
+// Listener is called after new expenses have been committed, for example to evaluate budget alerts.
+type Listener func(ctx context.Context, expense *Expense)
+
+func NewService(db *gorm.DB, rates *fx.Service, logger common.Logger) *Service {
 	return &Service{
 		db:     db,
+		rates:  rates,
 		logger: logger,
 	}
 }
 
+// AddListener registers a listener for created expenses. Listeners must be added before the service is used.
+func (s *Service) AddListener(listener Listener) {
+	s.listeners = append(s.listeners, listener)
+}
+
+func (s *Service) notifyCreated(ctx context.Context, expenses ...Expense) {
+	for i := range expenses {
+		for _, listener := range s.listeners {
+			listener(ctx, &expenses[i])
+		}
+	}
+}
+
 func (s *Service) CreateExpense(ctx context.Context, userID uuid.UUID, req *CreateExpenseRequest) (*Expense, error) {
 	if req.Amount.LessThanOrEqual(decimal.Zero) {
 		return nil, errors.New("amount must be greater than zero")
 	}
+	if err := category.CheckAccess(ctx, s.db, userID, req.CategoryID); err != nil {
+		return nil, err
+	}
+	if req.AccountID != nil {
+		if err := account.CheckAccess(ctx, s.db, userID, *req.AccountID); err != nil {
+			return nil, err
+		}
+	}
 
 	expense := &Expense{
-		Amount:             req.Amount,
+		Amount:             common.NewMoney(req.Amount, req.Currency.OrDefault()),
 		Description:        req.Description,
 		CategoryID:         req.CategoryID,
 		UserID:             userID,
+		AccountID:          req.AccountID,
 		PaymentMethod:      req.PaymentMethod,
 		ReceiptURL:         req.ReceiptURL,
 		RecurringExpenseID: req.RecurringExpenseID,
@@ -40,7 +82,12 @@ func (s *Service) CreateExpense(ctx context.Context, userID uuid.UUID, req *Crea
 
 	expense.ID = uuid.New()
 
-	if err := s.db.WithContext(ctx).Create(expense).Error; err != nil {
+	if err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(expense).Error; err != nil {
+			return err
+		}
+		return ledger.Post(tx, LedgerEntry(expense))
+	}); err != nil {
 		return nil, err
 	}
 
@@ -49,6 +96,7 @@ func (s *Service) CreateExpense(ctx context.Context, userID uuid.UUID, req *Crea
 		return nil, err
 	}
 
+	s.notifyCreated(ctx, *expense)
 	return expense, nil
 }
 
@@ -56,16 +104,18 @@ func (s *Service) UpdateExpense(ctx context.Context, userID uuid.UUID, expenseID
 	var expense Expense
 	if err := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", expenseID, userID).First(&expense).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("expense not found")
+			return nil, ErrExpenseNotFound
 		}
 		return nil, err
 	}
+	changesBalance := (req.Amount != nil && !req.Amount.Equal(expense.Amount.Amount)) ||
+		(req.AccountID != nil && (expense.AccountID == nil || *req.AccountID != *expense.AccountID))
 
 	if req.Amount != nil {
 		if req.Amount.LessThanOrEqual(decimal.Zero) {
 			return nil, errors.New("amount must be greater than zero")
 		}
-		expense.Amount = *req.Amount
+		expense.Amount = common.NewMoney(*req.Amount, expense.Amount.Currency)
 	}
 	if req.Description != nil {
 		expense.Description = *req.Description
@@ -73,6 +123,12 @@ func (s *Service) UpdateExpense(ctx context.Context, userID uuid.UUID, expenseID
 	if req.CategoryID != nil {
 		expense.CategoryID = *req.CategoryID
 	}
+	if req.AccountID != nil {
+		if err := account.CheckAccess(ctx, s.db, userID, *req.AccountID); err != nil {
+			return nil, err
+		}
+		expense.AccountID = req.AccountID
+	}
 	if req.PaymentMethod != nil {
 		expense.PaymentMethod = *req.PaymentMethod
 	}
@@ -80,7 +136,24 @@ func (s *Service) UpdateExpense(ctx context.Context, userID uuid.UUID, expenseID
 		expense.ReceiptURL = *req.ReceiptURL
 	}
 
-	if err := s.db.WithContext(ctx).Save(&expense).Error; err != nil {
+	if err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if changesBalance {
+			locked, err := reconciled(tx, userID, expense.ID)
+			if err != nil {
+				return err
+			}
+			if locked {
+				return fmt.Errorf("%w: only the description, category and receipt of its expense can be changed", common.ErrReconciled)
+			}
+		}
+		if err := tx.Save(&expense).Error; err != nil {
+			return err
+		}
+		if err := syncTransaction(tx, &expense); err != nil {
+			return err
+		}
+		return ledger.Replace(tx, LedgerEntry(&expense))
+	}); err != nil {
 		return nil, err
 	}
 
@@ -92,116 +165,201 @@ func (s *Service) UpdateExpense(ctx context.Context, userID uuid.UUID, expenseID
 	return &expense, nil
 }
 
+// DeleteExpense deletes the expense together with the transaction it stands for.
 func (s *Service) DeleteExpense(ctx context.Context, userID uuid.UUID, expenseID uuid.UUID) error {
-	if err := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", expenseID, userID).Delete(&Expense{}).Error; err != nil {
-		return err
-	}
-	return nil
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		locked, err := reconciled(tx, userID, expenseID)
+		if err != nil {
+			return err
+		}
+		if locked {
+			return common.ErrReconciled
+		}
+		result := tx.Where("id = ? AND user_id = ?", expenseID, userID).Delete(&Expense{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		if err := deleteTransaction(tx, userID, expenseID); err != nil {
+			return err
+		}
+		return ledger.Reverse(tx, ledger.SourceExpense, expenseID)
+	})
 }
 
-func (s *Service) GetExpenses(ctx context.Context, userID uuid.UUID, pagination *common.PaginationParams) ([]Expense, error) {
-	var expenses []Expense
+// MergeExpenses deletes the expense removeID as a duplicate of keepID, which takes over its receipt
+// and recurring expense. An expense of a transaction is merged through the transaction instead.
+func (s *Service) MergeExpenses(ctx context.Context, userID uuid.UUID, keepID uuid.UUID, removeID uuid.UUID) (*Expense, error) {
+	if keepID == removeID {
+		return nil, fmt.Errorf("%w: an expense cannot be merged into itself", ErrInvalidMerge)
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var expenses []Expense
+		if err := tx.Where("id IN ? AND user_id = ?", []uuid.UUID{keepID, removeID}, userID).Find(&expenses).Error; err != nil {
+			return err
+		}
+		if len(expenses) != 2 {
+			return ErrExpenseNotFound
+		}
+		keep, duplicate := &expenses[0], &expenses[1]
+		if keep.ID != keepID {
+			keep, duplicate = duplicate, keep
+		}
 
-	offset := (pagination.Page - 1) * pagination.PageSize
-	if err := s.db.WithContext(ctx).Where("user_id = ?", userID).Preload("Category").Offset(offset).Limit(pagination.PageSize).Find(&expenses).Error; err != nil {
+		var linked int64
+		if err := tx.Table("transactions").Where("processing_object_id = ?", duplicate.ID).Count(&linked).Error; err != nil {
+			return err
+		}
+		if duplicate.TransactionID != nil || linked > 0 {
+			return fmt.Errorf("%w: expense %s belongs to a transaction; merge the transaction instead", ErrInvalidMerge, duplicate.ID)
+		}
+		if keep.Amount.Currency != duplicate.Amount.Currency {
+			return fmt.Errorf("%w: the expenses are in %s and %s", ErrInvalidMerge, keep.Amount.Currency, duplicate.Amount.Currency)
+		}
+		return Absorb(tx, keep, duplicate)
+	})
+	if err != nil {
+		s.logger.Error("Failed to merge expenses", "keep_id", keepID, "remove_id", removeID, "error", err)
 		return nil, err
 	}
 
-	return expenses, nil
+	s.logger.Info("Expenses merged", "keep_id", keepID, "remove_id", removeID)
+	return s.GetExpenseByID(ctx, userID, keepID)
+}
+
+// expensePages are the orders expenses can be listed in. The date of an expense is its due date,
+// or the day it was recorded.
+var expensePages = pagination.Spec[Expense]{
+	Table: "expenses",
+	Sorts: map[string]pagination.Column[Expense]{
+		"date":       {Expr: "COALESCE(expenses.due_date, expenses.created_at)", Value: func(e *Expense) any { return e.Date() }},
+		"amount":     {Expr: "expenses.amount", Value: func(e *Expense) any { return e.Amount.Amount }},
+		"created_at": {Expr: "expenses.created_at", Value: func(e *Expense) any { return e.CreatedAt }},
+	},
+	Default: "date",
+	ID:      func(e *Expense) uuid.UUID { return e.ID },
+}
+
+// ExpenseFilters are the fields expenses can be filtered on.
+var ExpenseFilters = common.FilterFields{
+	"amount":               {Column: "expenses.amount", Kind: common.FilterNumber},
+	"currency":             {Column: "expenses.currency"},
+	"category_id":          {Column: "expenses.category_id", Kind: common.FilterUUID},
+	"account_id":           {Column: "expenses.account_id", Kind: common.FilterUUID},
+	"recurring_expense_id": {Column: "expenses.recurring_expense_id", Kind: common.FilterUUID},
+	"payment_method":       {Column: "expenses.payment_method", Values: []string{string(common.Cash), string(common.Card), string(common.BankTransfer)}},
+	"description":          {Column: "expenses.description", Ops: []common.FilterOp{common.OpEq, common.OpLike}},
+	"date":                 {Column: "COALESCE(expenses.due_date, expenses.created_at)", Kind: common.FilterTime},
+	"created_at":           {Column: "expenses.created_at", Kind: common.FilterTime},
+}
+
+func (s *Service) GetExpenses(ctx context.Context, userID uuid.UUID, filter common.Filter, params pagination.Params) (*pagination.Page[Expense], error) {
+	query := s.db.WithContext(ctx).Where("user_id = ?", userID).Scopes(filter.Scope)
+	return pagination.Paginate(query, expensePages, params, "Category")
 }
 
 func (s *Service) GetExpenseByID(ctx context.Context, userID uuid.UUID, expenseID uuid.UUID) (*Expense, error) {
 	var expense Expense
 	if err := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", expenseID, userID).Preload("Category").First(&expense).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("expense not found")
+			return nil, ErrExpenseNotFound
 		}
 		return nil, err
 	}
 	return &expense, nil
 }
 
-func (s *Service) GetExpensesByCategoryID(ctx context.Context, userID uuid.UUID, categoryID uuid.UUID) ([]Expense, error) {
-	var expenses []Expense
-	if err := s.db.WithContext(ctx).Where("user_id = ? AND category_id = ?", userID, categoryID).Preload("Category").Find(&expenses).Error; err != nil {
-		return nil, err
-	}
-	return expenses, nil
+func (s *Service) GetExpensesByCategoryID(ctx context.Context, userID uuid.UUID, categoryID uuid.UUID, params pagination.Params) (*pagination.Page[Expense], error) {
+	query := s.db.WithContext(ctx).Where("user_id = ? AND category_id = ?", userID, categoryID)
+	return pagination.Paginate(query, expensePages, params, "Category")
 }
 
-func (s *Service) GetTotalExpensesByCategory(ctx context.Context, userID uuid.UUID) (map[uuid.UUID]decimal.Decimal, error) {
-	type Result struct {
-		CategoryID uuid.UUID
-		Total      decimal.Decimal
-	}
-
-	var results []Result
-	if err := s.db.WithContext(ctx).Model(&Expense{}).
-		Select("category_id, SUM(amount) as total").
-		Where("user_id = ?", userID).
-		Group("category_id").
-		Scan(&results).Error; err != nil {
+func (s *Service) GetTotalExpensesByCategory(ctx context.Context, userID uuid.UUID) (map[uuid.UUID]common.Money, error) {
+	totals, _, err := s.convertedTotals(ctx, userID, "category_id::text")
+	if err != nil {
 		return nil, err
 	}
 
-	totalMap := make(map[uuid.UUID]decimal.Decimal)
-	for _, r := range results {
-		totalMap[r.CategoryID] = r.Total
+	totalMap := make(map[uuid.UUID]common.Money, len(totals))
+	for key, total := range totals {
+		categoryID, err := uuid.Parse(key)
+		if err != nil {
+			return nil, err
+		}
+		totalMap[categoryID] = total
 	}
 
 	return totalMap, nil
 }
 
-func (s *Service) GetTotalExpenses(ctx context.Context, userID uuid.UUID) (decimal.Decimal, error) {
-	var total decimal.Decimal
-	if err := s.db.WithContext(ctx).Model(&Expense{}).
-		Select("COALESCE(SUM(amount), 0)").
-		Where("user_id = ?", userID).
-		Scan(&total).Error; err != nil {
-		return decimal.Zero, err
+func (s *Service) GetTotalExpenses(ctx context.Context, userID uuid.UUID) (common.Money, error) {
+	totals, currency, err := s.convertedTotals(ctx, userID, "''")
+	if err != nil {
+		return common.Money{}, err
+	}
+	if total, ok := totals[""]; ok {
+		return total, nil
 	}
-	return total, nil
+	return common.ZeroMoney(currency), nil
 }
 
 func (s *Service) CreateRecurringExpense(ctx context.Context, userID uuid.UUID, req *CreateRecurringExpenseRequest) (*RecurringExpense, error) {
 	if req.Amount.LessThanOrEqual(decimal.Zero) {
 		return nil, errors.New("amount must be greater than zero")
 	}
+	if err := category.CheckAccess(ctx, s.db, userID, req.CategoryID); err != nil {
+		return nil, err
+	}
+
+	if req.AccountID != nil {
+		if err := account.CheckAccess(ctx, s.db, userID, *req.AccountID); err != nil {
+			return nil, err
+		}
+	}
+
+	frequency, rule, err := resolveSchedule(req.Frequency, req.RRule, req.StartDate)
+	if err != nil {
+		return nil, err
+	}
+	firstDueDate, ok := rule.First()
+	if !ok {
+		return nil, errors.New("recurrence rule has no occurrences")
+	}
 
 	recurringExpense := &RecurringExpense{
-		Amount:        req.Amount,
+		Amount:        common.NewMoney(req.Amount, req.Currency.OrDefault()),
 		Description:   req.Description,
 		CategoryID:    req.CategoryID,
 		UserID:        userID,
-		Frequency:     req.Frequency,
+		AccountID:     req.AccountID,
+		Frequency:     frequency,
+		RRule:         rule.String(),
+		CustomRule:    req.RRule != "",
 		PaymentMethod: req.PaymentMethod,
 		StartDate:     req.StartDate,
 		EndDate:       req.EndDate,
-		NextDueDate:   req.StartDate, // Initialize with start date
+		NextDueDate:   firstDueDate, // Initialize with the first occurrence on or after the start date
+		IsActive:      true,
 	}
 
 	recurringExpense.ID = uuid.New()
 	recurringExpense.LastProcessed = time.Now()
 
+	var generated []Expense
+
 	// Use a transaction to ensure atomicity
-	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		if err := tx.Create(recurringExpense).Error; err != nil {
 			return err
 		}
 
 		// Create an initial expense if the start date is today or in the past
 		if !recurringExpense.StartDate.After(time.Now()) {
-			expense := &Expense{
-				Amount:             recurringExpense.Amount,
-				Description:        recurringExpense.Description,
-				CategoryID:         recurringExpense.CategoryID,
-				UserID:             recurringExpense.UserID,
-				PaymentMethod:      recurringExpense.PaymentMethod,
-				RecurringExpenseID: &recurringExpense.ID,
-			}
-			expense.ID = uuid.New()
-
-			if err := tx.Create(expense).Error; err != nil {
+			expenses, err := s.generateOccurrences(tx, recurringExpense, time.Now(), false)
+			if err != nil {
 				s.logger.Error(
 					"Failed to create initial expense for recurring expense",
 					"error",
@@ -210,7 +368,9 @@ func (s *Service) CreateRecurringExpense(ctx context.Context, userID uuid.UUID,
 					recurringExpense.ID,
 				)
 				return err
-			} else {
+			}
+			generated = expenses
+			for _, expense := range expenses {
 				s.logger.Info(
 					"Created initial expense for recurring expense",
 					"recurring_expense_id",
@@ -219,19 +379,6 @@ func (s *Service) CreateRecurringExpense(ctx context.Context, userID uuid.UUID,
 					expense.ID,
 				)
 			}
-
-			// Calculate the next due date after creating the initial expense
-			recurringExpense.CalculateNextDueDate()
-			if err := tx.Save(recurringExpense).Error; err != nil {
-				s.logger.Error(
-					"Failed to update next due date for recurring expense",
-					"error",
-					err,
-					"recurring_expense_id",
-					recurringExpense.ID,
-				)
-				return err
-			}
 		}
 		// Load the category and expenses relationship
 		if err := tx.WithContext(ctx).Preload("Category").Preload("Expenses").First(recurringExpense, recurringExpense.ID).Error; err != nil {
@@ -250,23 +397,38 @@ func (s *Service) CreateRecurringExpense(ctx context.Context, userID uuid.UUID,
 	if err != nil {
 		return nil, err
 	}
+
+	s.notifyCreated(ctx, generated...)
 	return recurringExpense, nil
 }
 
-func (s *Service) GetRecurringExpenses(ctx context.Context, userID uuid.UUID) ([]RecurringExpenseResponse, error) {
-	var recurringExpenses []RecurringExpense
-	if err := s.db.WithContext(ctx).Where("user_id = ?", userID).Find(&recurringExpenses).Error; err != nil {
+// recurringExpensePages are the orders recurring expenses can be listed in, next due first by default.
+var recurringExpensePages = pagination.Spec[RecurringExpense]{
+	Table: "recurring_expenses",
+	Sorts: map[string]pagination.Column[RecurringExpense]{
+		"next_due_date": {Expr: "recurring_expenses.next_due_date", Value: func(re *RecurringExpense) any { return re.NextDueDate }},
+		"amount":        {Expr: "recurring_expenses.amount", Value: func(re *RecurringExpense) any { return re.Amount.Amount }},
+		"created_at":    {Expr: "recurring_expenses.created_at", Value: func(re *RecurringExpense) any { return re.CreatedAt }},
+	},
+	Default: "next_due_date",
+	Order:   pagination.Asc,
+	ID:      func(re *RecurringExpense) uuid.UUID { return re.ID },
+}
+
+func (s *Service) GetRecurringExpenses(ctx context.Context, userID uuid.UUID, params pagination.Params) (*pagination.Page[RecurringExpenseResponse], error) {
+	page, err := pagination.Paginate(s.db.WithContext(ctx).Where("user_id = ?", userID), recurringExpensePages, params)
+	if err != nil {
 		return nil, err
 	}
 
-	var responses []RecurringExpenseResponse
-	for _, rec := range recurringExpenses {
+	responses := make([]RecurringExpenseResponse, 0, len(page.Data))
+	for _, rec := range page.Data {
 		responses = append(responses, RecurringExpenseResponse{
 			RecurringExpense: rec,
 			DaysUntilDue:     rec.DaysUntilNextDue(time.Now()),
 		})
 	}
-	return responses, nil
+	return pagination.WithData(page, responses), nil
 }
 
 func (s *Service) DeleteRecurringExpense(ctx context.Context, userID uuid.UUID, recurringExpenseID uuid.UUID) error {
@@ -276,75 +438,61 @@ func (s *Service) DeleteRecurringExpense(ctx context.Context, userID uuid.UUID,
 	return nil
 }
 
-func (s *Service) GetTotalExpensesByMonth(ctx context.Context, userID uuid.UUID) (map[string]decimal.Decimal, error) {
-	type Result struct {
-		Month string
-		Total decimal.Decimal
-	}
-
-	var results []Result
-	if err := s.db.WithContext(ctx).Model(&Expense{}).
-		Select("TO_CHAR(created_at, 'YYYY-MM') as month, SUM(amount) as total").
-		Where("user_id = ?", userID).
-		Group("month").
-		Order("month").
-		Scan(&results).Error; err != nil {
-		return nil, err
-	}
-
-	totalMap := make(map[string]decimal.Decimal)
-	for _, r := range results {
-		totalMap[r.Month] = r.Total
-	}
-
-	return totalMap, nil
+func (s *Service) GetTotalExpensesByMonth(ctx context.Context, userID uuid.UUID) (map[string]common.Money, error) {
+	totals, _, err := s.convertedTotals(ctx, userID, "TO_CHAR(COALESCE(due_date, created_at), 'YYYY-MM')")
+	return totals, err
 }
 
-func (s *Service) GetTotalExpensesByDay(ctx context.Context, userID uuid.UUID) (map[string]decimal.Decimal, error) {
-	type Result struct {
-		Day   string
-		Total decimal.Decimal
-	}
+func (s *Service) GetTotalExpensesByDay(ctx context.Context, userID uuid.UUID) (map[string]common.Money, error) {
+	totals, _, err := s.convertedTotals(ctx, userID, "TO_CHAR(COALESCE(due_date, created_at), 'YYYY-MM-DD')")
+	return totals, err
+}
 
-	var results []Result
-	if err := s.db.WithContext(ctx).Model(&Expense{}).
-		Select("TO_CHAR(created_at, 'YYYY-MM-DD') as day, SUM(amount) as total").
-		Where("user_id = ?", userID).
-		Group("day").
-		Order("day").
-		Scan(&results).Error; err != nil {
-		return nil, err
-	}
+func (s *Service) GetTotalExpensesByPaymentMethod(ctx context.Context, userID uuid.UUID) (map[string]common.Money, error) {
+	totals, _, err := s.convertedTotals(ctx, userID, "payment_method::text")
+	return totals, err
+}
 
-	totalMap := make(map[string]decimal.Decimal)
-	for _, r := range results {
-		totalMap[r.Day] = r.Total
+// convertedTotals sums the user's expenses per bucket, an SQL expression, in the user's reporting
+// currency. Each currency is converted at the rate of the day the expenses were made, their due
+// date or else their creation date; date buckets must use the same day.
+func (s *Service) convertedTotals(ctx context.Context, userID uuid.UUID, bucket string) (map[string]common.Money, common.Currency, error) {
+	currency, err := s.rates.ReportingCurrency(ctx, userID)
+	if err != nil {
+		return nil, "", err
 	}
 
-	return totalMap, nil
-}
-
-func (s *Service) GetTotalExpensesByPaymentMethod(ctx context.Context, userID uuid.UUID) (map[string]decimal.Decimal, error) {
 	type Result struct {
-		PaymentMethod string
-		Total         decimal.Decimal
+		Bucket   string
+		Currency common.Currency
+		Day      time.Time
+		Total    decimal.Decimal
 	}
 
 	var results []Result
 	if err := s.db.WithContext(ctx).Model(&Expense{}).
-		Select("payment_method, SUM(amount) as total").
+		Select(bucket+" AS bucket, currency, DATE(COALESCE(due_date, created_at)) AS day, SUM(amount) AS total").
 		Where("user_id = ?", userID).
-		Group("payment_method").
+		Group("bucket, currency, day").
 		Scan(&results).Error; err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
-	totalMap := make(map[string]decimal.Decimal)
+	converter := s.rates.NewConverter(currency)
+	sums := make(map[string]decimal.Decimal)
 	for _, r := range results {
-		totalMap[r.PaymentMethod] = r.Total
+		converted, err := converter.Convert(ctx, r.Total, r.Currency, r.Day)
+		if err != nil {
+			return nil, "", err
+		}
+		sums[r.Bucket] = sums[r.Bucket].Add(converted)
 	}
 
-	return totalMap, nil
+	totals := make(map[string]common.Money, len(sums))
+	for key, sum := range sums {
+		totals[key] = common.NewMoney(sum, currency)
+	}
+	return totals, currency, nil
 }
 
 func (s *Service) UpdateRecurringExpense(ctx context.Context, userID uuid.UUID, recurringExpenseID uuid.UUID, req *UpdateRecurringExpenseRequest) (*RecurringExpense, error) {
@@ -362,7 +510,7 @@ func (s *Service) UpdateRecurringExpense(ctx context.Context, userID uuid.UUID,
 		if req.Amount.LessThanOrEqual(decimal.Zero) {
 			return nil, errors.New("amount must be greater than zero")
 		}
-		recurringExpense.Amount = *req.Amount
+		recurringExpense.Amount = common.NewMoney(*req.Amount, recurringExpense.Amount.Currency)
 	}
 	if req.Description != nil {
 		recurringExpense.Description = *req.Description
@@ -370,25 +518,109 @@ func (s *Service) UpdateRecurringExpense(ctx context.Context, userID uuid.UUID,
 	if req.CategoryID != nil {
 		recurringExpense.CategoryID = *req.CategoryID
 	}
-	if req.Frequency != nil {
-		recurringExpense.Frequency = *req.Frequency
+	if req.AccountID != nil {
+		if err := account.CheckAccess(ctx, s.db, userID, *req.AccountID); err != nil {
+			return nil, err
+		}
+		recurringExpense.AccountID = req.AccountID
 	}
 	if req.PaymentMethod != nil {
 		recurringExpense.PaymentMethod = *req.PaymentMethod
 	}
-	if req.StartDate != nil {
-		recurringExpense.StartDate = *req.StartDate
-	}
 	if req.EndDate != nil {
 		recurringExpense.EndDate = req.EndDate
 	}
 
+	if req.Frequency != nil || req.RRule != nil || req.StartDate != nil {
+		if err := s.reschedule(ctx, &recurringExpense, req); err != nil {
+			return nil, err
+		}
+	}
+	if req.IsActive != nil && *req.IsActive != recurringExpense.IsActive {
+		if !*req.IsActive {
+			recurringExpense.Deactivate()
+		} else if err := recurringExpense.Resume(time.Now()); err != nil {
+			return nil, err
+		}
+	}
+
 	if err := s.db.WithContext(ctx).Save(&recurringExpense).Error; err != nil {
 		return nil, err
 	}
 	return &recurringExpense, nil
 }
 
+// reschedule applies a schedule change and re-anchors NextDueDate on the new rule. Occurrences
+// that were already generated are kept, so the next due date is the first one after the latest.
+func (s *Service) reschedule(ctx context.Context, re *RecurringExpense, req *UpdateRecurringExpenseRequest) error {
+	custom := re.customRule()
+	if req.StartDate != nil {
+		re.StartDate = *req.StartDate
+	}
+
+	// A rule derived from the frequency depends on the start day, so it is derived again rather
+	// than kept when only the start date changes.
+	frequency := re.Frequency
+	var ruleText string
+	switch {
+	case req.RRule != nil && *req.RRule != "":
+		ruleText = *req.RRule
+	case req.Frequency != nil:
+		frequency = *req.Frequency
+	case req.RRule == nil && custom:
+		ruleText = re.RRule
+	}
+
+	frequency, rule, err := resolveSchedule(frequency, ruleText, re.StartDate)
+	if err != nil {
+		return err
+	}
+	re.Frequency = frequency
+	re.RRule = rule.String()
+	re.CustomRule = ruleText != ""
+
+	var last Expense
+	err = s.db.WithContext(ctx).
+		Where("recurring_expense_id = ? AND due_date IS NOT NULL", re.ID).
+		Order("due_date DESC").
+		First(&last).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	var next time.Time
+	var ok bool
+	if err == nil && !last.DueDate.Before(re.StartDate) {
+		next, ok = rule.Next(*last.DueDate)
+	} else {
+		next, ok = rule.First()
+	}
+	if !ok {
+		re.Deactivate()
+		return nil
+	}
+	re.NextDueDate = next
+	return nil
+}
+
+// resolveSchedule validates the schedule of a recurring expense. An explicit RRULE takes
+// precedence; otherwise the rule equivalent to the frequency is used. The returned frequency
+// is the base frequency of the rule so the legacy column stays meaningful.
+func resolveSchedule(frequency RecurringFrequency, rrule string, startDate time.Time) (RecurringFrequency, *recurrence.Rule, error) {
+	if rrule == "" {
+		if frequency == "" {
+			return "", nil, errors.New("either frequency or rrule is required")
+		}
+		rrule = recurrence.FromFrequency(string(frequency), startDate)
+	}
+
+	rule, err := recurrence.Parse(rrule, startDate)
+	if err != nil {
+		return "", nil, err
+	}
+	return RecurringFrequency(rule.Frequency()), rule, nil
+}
+
 func (s *Service) GetRecurringExpenseByID(ctx context.Context, userID uuid.UUID, recurringExpenseID uuid.UUID) (*RecurringExpenseResponse, error) {
 	var recurringExpense RecurringExpense
 	if err := s.db.WithContext(ctx).
@@ -411,116 +643,503 @@ func (s *Service) GetRecurringExpenseByID(ctx context.Context, userID uuid.UUID,
  * @param ctx - The context for the request
  * @param userID - The ID of the user
  * @param recurringExpenseID - The ID of the recurring expense
- * @returns A slice of Expense and an error if the database query fails.
+ * @param params - The cursor, size and order of the page
+ * @returns A page of Expense and an error if the database query fails.
  */
-func (s *Service) GetRecurringExpenseHistory(ctx context.Context, userID uuid.UUID, recurringExpenseID uuid.UUID) ([]Expense, error) {
-	var expenses []Expense
-	if err := s.db.WithContext(ctx).
-		Where("user_id = ? AND recurring_expense_id = ?", userID, recurringExpenseID).
-		Preload("Category").
-		Find(&expenses).Error; err != nil {
-		return nil, err
-	}
-	return expenses, nil
+func (s *Service) GetRecurringExpenseHistory(ctx context.Context, userID uuid.UUID, recurringExpenseID uuid.UUID, params pagination.Params) (*pagination.Page[Expense], error) {
+	query := s.db.WithContext(ctx).Where("user_id = ? AND recurring_expense_id = ?", userID, recurringExpenseID)
+	return pagination.Paginate(query, expensePages, params, "Category")
 }
 
-/* Get upcoming recurring expenses due in the next offset days
+/* Get upcoming recurring expenses due from today up to dueInterval days from now, inclusive,
+* soonest first.
 *
+* Skipped and paused occurrences are ignored, and overridden occurrences report their
+* one-off amount and category in NextOccurrence.
 * @param ctx - The context for the request
 * @param userID - The ID of the user
-* @param dueInterval - The number of days to check for upcoming expenses
+* @param dueInterval - The number of days ahead to check for upcoming expenses; 0 is today only
 * @returns A list of upcoming recurring expenses
  */
 func (s *Service) GetUpcomingRecurringExpenses(ctx context.Context, userID uuid.UUID, dueInterval int) ([]RecurringExpenseResponse, error) {
 	var recurringExpenses []RecurringExpense
-	// Calculate the target date in Go and pass it as a parameter
-	targetDate := time.Now().AddDate(0, 0, dueInterval)
+	today := dateOf(time.Now())
+	lastDay := today.AddDate(0, 0, dueInterval)
+
+	if err := s.db.WithContext(ctx).
+		Where("user_id = ? AND is_active = ?", userID, true).
+		Preload("Category").
+		Find(&recurringExpenses).Error; err != nil {
+		return nil, err
+	}
 
-	// Return only recurring expenses with next_due_date just targetDate away only
+	exceptions, err := s.getOccurrenceExceptions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Return only recurring expenses whose next billable occurrence falls within the window
+	result := make([]RecurringExpenseResponse, 0, len(recurringExpenses))
+	for _, re := range recurringExpenses {
+		next, err := re.NextBillableOccurrence(exceptions)
+		if err != nil {
+			s.logger.Error("Failed to compute next occurrence", "error", err, "recurring_expense_id", re.ID)
+			continue
+		}
+		if next == nil {
+			continue
+		}
+		dueDay := dateOf(next.DueDate)
+		if dueDay.Before(today) || dueDay.After(lastDay) {
+			continue
+		}
+		result = append(result, RecurringExpenseResponse{
+			RecurringExpense: re,
+			DaysUntilDue:     int(dueDay.Sub(today).Hours() / 24),
+			NextOccurrence:   next,
+		})
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].DaysUntilDue < result[j].DaysUntilDue })
+	return result, nil
+}
+
+/* Project every active recurring expense of the user into dated occurrences within [from, to].
+ *
+ * Occurrences are grouped per calendar day with the day total and the running total since from,
+ * overall and per category. Skipped and paused occurrences are listed but excluded from totals.
+ * @param ctx - The context for the request
+ * @param userID - The ID of the user
+ * @param from - The start of the window
+ * @param to - The end of the window
+ * @returns The projection and an error if the window is invalid or the database query fails
+ */
+func (s *Service) GetRecurringExpenseProjection(ctx context.Context, userID uuid.UUID, from, to time.Time) (*ProjectionResponse, error) {
+	if to.Before(from) {
+		return nil, errors.New("to must not be before from")
+	}
+	if to.Sub(from) > maxProjectionWindow {
+		return nil, errors.New("projection window cannot exceed two years")
+	}
+
+	var recurringExpenses []RecurringExpense
 	if err := s.db.WithContext(ctx).
-		Where("user_id = ? AND next_due_date = ?", userID, targetDate).
+		Where("user_id = ? AND is_active = ?", userID, true).
+		Where("start_date <= ? AND (end_date IS NULL OR end_date >= ?)", to, from).
 		Find(&recurringExpenses).Error; err != nil {
 		return nil, err
 	}
 
-	// Preload the Category relationship for each recurring expense
+	exceptions, err := s.getOccurrenceExceptions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var occurrences []Occurrence
 	for i := range recurringExpenses {
-		if err := s.db.WithContext(ctx).Model(&recurringExpenses[i]).Association("Category").Find(&recurringExpenses[i].Category); err != nil {
+		expanded, err := recurringExpenses[i].Occurrences(from, to, exceptions)
+		if err != nil {
+			s.logger.Error("Failed to expand recurring expense", "error", err, "recurring_expense_id", recurringExpenses[i].ID)
+			continue
+		}
+		occurrences = append(occurrences, expanded...)
+	}
+	sort.SliceStable(occurrences, func(i, j int) bool {
+		return occurrences[i].DueDate.Before(occurrences[j].DueDate)
+	})
+
+	currency, err := s.rates.ReportingCurrency(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	converter := s.rates.NewConverter(currency)
+
+	response := &ProjectionResponse{
+		From:     from,
+		To:       to,
+		Currency: currency,
+		Total:    decimal.Zero,
+		Days:     []ProjectionDay{},
+	}
+
+	categoryTotals := make(map[uuid.UUID]*ProjectionCategory)
+	var categoryOrder []uuid.UUID
+	for _, occurrence := range occurrences {
+		date := dateOf(occurrence.DueDate).Format("2006-01-02")
+		if len(response.Days) == 0 || response.Days[len(response.Days)-1].Date != date {
+			response.Days = append(response.Days, ProjectionDay{
+				Date:                  date,
+				Total:                 decimal.Zero,
+				RunningTotal:          response.Total,
+				CategoryRunningTotals: make(map[uuid.UUID]decimal.Decimal),
+			})
+		}
+		day := &response.Days[len(response.Days)-1]
+		day.Occurrences = append(day.Occurrences, occurrence)
+
+		if !occurrence.IsBillable() {
+			continue
+		}
+
+		amount, err := converter.Convert(ctx, occurrence.Amount.Amount, occurrence.Amount.Currency, occurrence.DueDate)
+		if err != nil {
+			return nil, err
+		}
+		day.Total = day.Total.Add(amount)
+		response.Total = response.Total.Add(amount)
+		day.RunningTotal = response.Total
+
+		total, ok := categoryTotals[occurrence.CategoryID]
+		if !ok {
+			total = &ProjectionCategory{CategoryID: occurrence.CategoryID, Total: decimal.Zero}
+			categoryTotals[occurrence.CategoryID] = total
+			categoryOrder = append(categoryOrder, occurrence.CategoryID)
+		}
+		total.Total = total.Total.Add(amount)
+		total.Count++
+	}
+
+	// Each day carries the running total of every category seen so far
+	running := make(map[uuid.UUID]decimal.Decimal)
+	for i := range response.Days {
+		for _, occurrence := range response.Days[i].Occurrences {
+			if !occurrence.IsBillable() {
+				continue
+			}
+			// Rates were cached by the loop above
+			amount, err := converter.Convert(ctx, occurrence.Amount.Amount, occurrence.Amount.Currency, occurrence.DueDate)
+			if err != nil {
+				return nil, err
+			}
+			running[occurrence.CategoryID] = running[occurrence.CategoryID].Add(amount)
+		}
+		for categoryID, total := range running {
+			response.Days[i].CategoryRunningTotals[categoryID] = total
+		}
+	}
+
+	var categories []category.Category
+	if len(categoryOrder) > 0 {
+		if err := s.db.WithContext(ctx).Where("id IN ?", categoryOrder).Find(&categories).Error; err != nil {
 			return nil, err
 		}
 	}
+	for _, c := range categories {
+		if total, ok := categoryTotals[c.ID]; ok {
+			total.CategoryName = c.Name
+		}
+	}
 
-	// Return the list of upcoming recurring expenses
-	result := make([]RecurringExpenseResponse, len(recurringExpenses))
-	for i, re := range recurringExpenses {
-		result[i] = RecurringExpenseResponse{
-			RecurringExpense: re,
-			DaysUntilDue:     re.DaysUntilNextDue(time.Now()),
+	response.Categories = make([]ProjectionCategory, 0, len(categoryOrder))
+	for _, categoryID := range categoryOrder {
+		response.Categories = append(response.Categories, *categoryTotals[categoryID])
+	}
+	return response, nil
+}
+
+const maxProjectionWindow = 2 * 366 * 24 * time.Hour
+
+/* Create an exception for a single occurrence of a recurring expense.
+ *
+ * A skip removes one occurrence, an override changes its amount and/or category, and a pause
+ * suppresses every occurrence from OccurrenceDate until PauseUntil.
+ * @param ctx - The context for the request
+ * @param userID - The ID of the user
+ * @param recurringExpenseID - The ID of the recurring expense
+ * @param req - The exception to create
+ * @returns The created exception and an error if validation or persistence fails
+ */
+func (s *Service) CreateOccurrenceException(ctx context.Context, userID uuid.UUID, recurringExpenseID uuid.UUID, req *CreateOccurrenceExceptionRequest) (*OccurrenceException, error) {
+	var re RecurringExpense
+	if err := s.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", recurringExpenseID, userID).
+		First(&re).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("recurring expense not found")
 		}
+		return nil, err
 	}
-	return result, nil
+
+	exception := &OccurrenceException{
+		RecurringExpenseID: re.ID,
+		UserID:             userID,
+		Action:             req.Action,
+		OccurrenceDate:     dateOf(req.OccurrenceDate),
+		Amount:             req.Amount,
+		CategoryID:         req.CategoryID,
+		Note:               req.Note,
+	}
+
+	switch req.Action {
+	case OccurrencePause:
+		if req.PauseUntil == nil || !dateOf(*req.PauseUntil).After(exception.OccurrenceDate) {
+			return nil, errors.New("pause_until must be after occurrence_date")
+		}
+		pauseUntil := dateOf(*req.PauseUntil)
+		exception.PauseUntil = &pauseUntil
+		exception.Amount = nil
+		exception.CategoryID = nil
+	case OccurrenceSkip:
+		exception.Amount = nil
+		exception.CategoryID = nil
+	case OccurrenceOverride:
+		if req.Amount == nil && req.CategoryID == nil {
+			return nil, errors.New("an override requires an amount or a category")
+		}
+		if req.Amount != nil && req.Amount.LessThanOrEqual(decimal.Zero) {
+			return nil, errors.New("amount must be greater than zero")
+		}
+		if req.CategoryID != nil {
+			if err := category.CheckAccess(ctx, s.db, userID, *req.CategoryID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Skips and overrides must target a real occurrence of the schedule
+	if req.Action != OccurrencePause {
+		isOccurrence, err := re.HasOccurrenceOn(exception.OccurrenceDate)
+		if err != nil {
+			return nil, err
+		}
+		if !isOccurrence {
+			return nil, errors.New("recurring expense has no occurrence on occurrence_date")
+		}
+	}
+
+	exception.ID = uuid.New()
+	if err := s.db.WithContext(ctx).Create(exception).Error; err != nil {
+		return nil, err
+	}
+
+	s.logger.Info(
+		"Occurrence exception created",
+		"recurring_expense_id",
+		re.ID,
+		"exception_id",
+		exception.ID,
+		"action",
+		exception.Action,
+	)
+	return exception, nil
+}
+
+func (s *Service) GetOccurrenceExceptions(ctx context.Context, userID uuid.UUID, recurringExpenseID uuid.UUID) ([]OccurrenceException, error) {
+	var exceptions []OccurrenceException
+	if err := s.db.WithContext(ctx).
+		Where("recurring_expense_id = ? AND user_id = ?", recurringExpenseID, userID).
+		Order("occurrence_date").
+		Find(&exceptions).Error; err != nil {
+		return nil, err
+	}
+	return exceptions, nil
+}
+
+func (s *Service) DeleteOccurrenceException(ctx context.Context, userID uuid.UUID, recurringExpenseID uuid.UUID, exceptionID uuid.UUID) error {
+	result := s.db.WithContext(ctx).
+		Where("id = ? AND recurring_expense_id = ? AND user_id = ?", exceptionID, recurringExpenseID, userID).
+		Delete(&OccurrenceException{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("occurrence exception not found")
+	}
+	return nil
+}
+
+// getOccurrenceExceptions returns every exception of the user's recurring expenses.
+func (s *Service) getOccurrenceExceptions(ctx context.Context, userID uuid.UUID) ([]OccurrenceException, error) {
+	var exceptions []OccurrenceException
+	if err := s.db.WithContext(ctx).Where("user_id = ?", userID).Find(&exceptions).Error; err != nil {
+		return nil, err
+	}
+	return exceptions, nil
 }
 
 /* Background Job to process all recurring expenses that are due as of the current time.
  *
- * For each due recurring expense, it creates a corresponding expense record, updates the next due date,
- * and logs the operation. If an error occurs while creating an expense or updating a recurring expense,
+ * For each due recurring expense, it creates the occurrence for the current due date and advances
+ * the next due date in a single database transaction. Inactive recurring expenses and those whose
+ * end date has passed are skipped. If an error occurs while processing a recurring expense,
  * it logs the error and continues processing the remaining items.
  * Returns an error if the initial query for due recurring expenses fails.
  * @param ctx - The context for the request
  * @returns An error if processing fails
  */
 func (s *Service) ProcessRecurringExpenses(ctx context.Context) error {
-	var dueExpenses []RecurringExpense
-	if err := s.db.WithContext(ctx).
-		Where("next_due_date <= ?", time.Now()).
-		Find(&dueExpenses).Error; err != nil {
+	return s.processDueRecurringExpenses(ctx, false)
+}
+
+/* Background Job that behaves like ProcessRecurringExpenses but generates every missed occurrence
+ * since a recurring expense was last processed, instead of only the oldest outstanding one.
+ * @param ctx - The context for the request
+ * @returns An error if processing fails
+ */
+func (s *Service) BackfillRecurringExpenses(ctx context.Context) error {
+	return s.processDueRecurringExpenses(ctx, true)
+}
+
+/* Backfill every missed occurrence of a single recurring expense owned by the user.
+ *
+ * @param ctx - The context for the request
+ * @param userID - The ID of the user
+ * @param recurringExpenseID - The ID of the recurring expense
+ * @returns The expenses that were generated and an error if processing fails
+ */
+func (s *Service) BackfillRecurringExpense(ctx context.Context, userID uuid.UUID, recurringExpenseID uuid.UUID) ([]Expense, error) {
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&RecurringExpense{}).
+		Where("id = ? AND user_id = ?", recurringExpenseID, userID).
+		Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, errors.New("recurring expense not found")
+	}
+
+	return s.processRecurringExpense(ctx, recurringExpenseID, time.Now(), true)
+}
+
+func (s *Service) processDueRecurringExpenses(ctx context.Context, backfill bool) error {
+	now := time.Now()
+
+	var dueIDs []uuid.UUID
+	if err := s.db.WithContext(ctx).Model(&RecurringExpense{}).
+		Where("is_active = ? AND next_due_date <= ?", true, now).
+		Where("end_date IS NULL OR end_date >= next_due_date").
+		Pluck("id", &dueIDs).Error; err != nil {
 		return err
 	}
 
-	for _, re := range dueExpenses {
-		// Create an expense for each due recurring expense
-		expense := &Expense{
-			Amount:             re.Amount,
-			Description:        re.Description,
-			CategoryID:         re.CategoryID,
-			UserID:             re.UserID,
-			PaymentMethod:      re.PaymentMethod,
-			RecurringExpenseID: &re.ID,
+	for _, id := range dueIDs {
+		if ctx.Err() != nil {
+			return ctx.Err()
 		}
-		expense.ID = uuid.New()
 
-		if err := s.db.WithContext(ctx).Create(expense).Error; err != nil {
+		generated, err := s.processRecurringExpense(ctx, id, now, backfill)
+		if err != nil {
 			s.logger.Error(
-				"Failed to create expense for recurring expense",
+				"Failed to process recurring expense",
 				"error",
 				err,
 				"recurring_expense_id",
-				re.ID,
+				id,
 			)
 			continue
 		}
-		re.CalculateNextDueDate()
-		re.LastProcessed = time.Now()
+		if len(generated) > 0 {
+			s.logger.Info(
+				"Processed recurring expense",
+				"recurring_expense_id",
+				id,
+				"occurrences",
+				len(generated),
+			)
+		}
+	}
 
-		// Update the recurring expense with the new next due date
-		if err := s.db.WithContext(ctx).Save(&re).Error; err != nil {
-			s.logger.Error(
-				"Failed to update recurring expense",
-				"error",
-				err,
+	return nil
+}
+
+// processRecurringExpense locks a single recurring expense, generates its due occurrences and advances
+// the next due date atomically. Rows locked by a concurrent run are skipped rather than waited on.
+func (s *Service) processRecurringExpense(ctx context.Context, recurringExpenseID uuid.UUID, now time.Time, backfill bool) ([]Expense, error) {
+	var generated []Expense
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var re RecurringExpense
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("id = ?", recurringExpenseID).
+			First(&re).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				// Locked by another run or deleted in the meantime
+				return nil
+			}
+			return err
+		}
+		if !re.ShouldProcess() {
+			return nil
+		}
+
+		expenses, err := s.generateOccurrences(tx, &re, now, backfill)
+		if err != nil {
+			return err
+		}
+		generated = expenses
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifyCreated(ctx, generated...)
+	return generated, nil
+}
+
+// generateOccurrences creates the expenses due for re up to now and saves the advanced schedule.
+// It must run inside a transaction holding the row lock on re. Each occurrence is keyed by
+// (recurring_expense_id, due_date) so a retried or overlapping run can never insert it twice.
+// Skipped and paused occurrences advance the schedule without creating an expense.
+func (s *Service) generateOccurrences(tx *gorm.DB, re *RecurringExpense, now time.Time, backfill bool) ([]Expense, error) {
+	var generated []Expense
+
+	var exceptions []OccurrenceException
+	if err := tx.Where("recurring_expense_id = ?", re.ID).Find(&exceptions).Error; err != nil {
+		return nil, err
+	}
+
+	for re.IsActive && re.IsDue(now) && !re.HasEnded(re.NextDueDate) {
+		dueDate := re.NextDueDate
+		occurrence := re.OccurrenceAt(dueDate, exceptions)
+		if !occurrence.IsBillable() {
+			s.logger.Info(
+				"Skipped recurring expense occurrence",
 				"recurring_expense_id",
 				re.ID,
+				"due_date",
+				dueDate,
+				"status",
+				occurrence.Status,
 			)
+			re.CalculateNextDueDate()
+			if !backfill {
+				break
+			}
+			continue
+		}
+
+		expense := Expense{
+			Amount:             occurrence.Amount,
+			Description:        occurrence.Description,
+			CategoryID:         occurrence.CategoryID,
+			UserID:             re.UserID,
+			AccountID:          re.AccountID,
+			PaymentMethod:      occurrence.PaymentMethod,
+			RecurringExpenseID: &re.ID,
+			DueDate:            &dueDate,
+		}
+		expense.ID = uuid.New()
+
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&expense)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected > 0 {
+			if err := ledger.Post(tx, LedgerEntry(&expense)); err != nil {
+				return nil, err
+			}
+			generated = append(generated, expense)
+		}
+
+		re.CalculateNextDueDate()
+		if !backfill {
+			break
 		}
-		s.logger.Info(
-			"Processed recurring expense",
-			"recurring_expense_id",
-			re.ID,
-			"next_due_date",
-			re.NextDueDate,
-		)
 	}
 
-	return nil
+	re.LastProcessed = now
+	if err := tx.Save(re).Error; err != nil {
+		return nil, err
+	}
+	return generated, nil
 }
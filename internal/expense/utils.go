@@ -1,9 +1,110 @@
 package expense
 
 import (
+	"errors"
 	"time"
+
+	"github.com/google/uuid"
+	"github.com/pastorenue/kinance/internal/common"
+	"github.com/pastorenue/kinance/internal/ledger"
+	"github.com/pastorenue/kinance/internal/recurrence"
+	"gorm.io/gorm"
 )
 
+// Date is the due date of the expense, or else the day it was recorded, like in budgets and analytics.
+func (e *Expense) Date() time.Time {
+	if e.DueDate != nil {
+		return *e.DueDate
+	}
+	return e.CreatedAt
+}
+
+// LedgerEntry returns the journal entry of the expense, dated on its Date.
+func LedgerEntry(e *Expense) *ledger.JournalEntry {
+	return ledger.ExpenseEntry(e.UserID, ledger.SourceExpense, e.ID, e.Date(), e.Description, e.Amount, e.CategoryID, e.AccountID)
+}
+
+// syncTransaction copies the expense to the transaction it stands for, if any. The transaction
+// is kept out of the journal while it has an expense, so only its row changes. The transactions
+// table is used directly, as the transaction package depends on this one.
+func syncTransaction(tx *gorm.DB, e *Expense) error {
+	return tx.Table("transactions").
+		Where("processing_object_id = ? AND user_id = ?", e.ID, e.UserID).
+		Updates(map[string]interface{}{
+			"amount":         e.Amount.Amount,
+			"currency":       e.Amount.Currency,
+			"description":    e.Description,
+			"category_id":    e.CategoryID,
+			"account_id":     e.AccountID,
+			"payment_method": e.PaymentMethod,
+			"updated_at":     time.Now(),
+		}).Error
+}
+
+// reconciled reports whether the transaction the expense stands for is reconciled, which locks
+// what the expense adds to the balance of its account.
+func reconciled(tx *gorm.DB, userID uuid.UUID, expenseID uuid.UUID) (bool, error) {
+	var count int64
+	err := tx.Table("transactions").
+		Where("processing_object_id = ? AND user_id = ? AND clearing = ?", expenseID, userID, "reconciled").
+		Count(&count).Error
+	return count > 0, err
+}
+
+// deleteTransaction deletes the transaction the expense stands for, if any, like deleting the
+// transaction deletes its expense.
+func deleteTransaction(tx *gorm.DB, userID uuid.UUID, expenseID uuid.UUID) error {
+	var ids []uuid.UUID
+	if err := tx.Table("transactions").
+		Where("processing_object_id = ? AND user_id = ?", expenseID, userID).
+		Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := tx.Exec("DELETE FROM transaction_tags WHERE transaction_id IN ?", ids).Error; err != nil {
+		return err
+	}
+	return tx.Exec("DELETE FROM transactions WHERE id IN ?", ids).Error
+}
+
+// Absorb deletes a duplicate of the expense keep. Keep takes over the receipt and the recurring
+// expense of the duplicate when it has none, so the occurrence stays in the history of its series.
+func Absorb(tx *gorm.DB, keep *Expense, duplicate *Expense) error {
+	if err := tx.Delete(duplicate).Error; err != nil {
+		return err
+	}
+	if err := ledger.Reverse(tx, ledger.SourceExpense, duplicate.ID); err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{}
+	if keep.ReceiptURL == "" && duplicate.ReceiptURL != "" {
+		keep.ReceiptURL = duplicate.ReceiptURL
+		updates["receipt_url"] = keep.ReceiptURL
+	}
+	if keep.RecurringExpenseID == nil && duplicate.RecurringExpenseID != nil {
+		// A series has one expense a day; keep cannot join it on a day already taken.
+		var count int64
+		if keep.DueDate != nil {
+			if err := tx.Model(&Expense{}).
+				Where("recurring_expense_id = ? AND due_date = ?", *duplicate.RecurringExpenseID, *keep.DueDate).
+				Count(&count).Error; err != nil {
+				return err
+			}
+		}
+		if count == 0 {
+			keep.RecurringExpenseID = duplicate.RecurringExpenseID
+			updates["recurring_expense_id"] = *keep.RecurringExpenseID
+		}
+	}
+	if len(updates) == 0 {
+		return nil
+	}
+	return tx.Model(keep).Updates(updates).Error
+}
+
 // Helper methods for recurring expenses
 func (re *RecurringExpense) IsDue(currentDate time.Time) bool {
 	return !re.NextDueDate.After(currentDate)
@@ -21,20 +122,45 @@ func (re *RecurringExpense) ShouldProcess() bool {
 	return time.Now().After(re.NextDueDate) || time.Now().Equal(re.NextDueDate)
 }
 
+// Recurrence returns the schedule of the recurring expense. Rows without an RRULE use the
+// rule equivalent to their Frequency, so series created before RRULE support keep working.
+func (re *RecurringExpense) Recurrence() (*recurrence.Rule, error) {
+	if re.RRule != "" {
+		return recurrence.Parse(re.RRule, re.StartDate)
+	}
+	return recurrence.Parse(recurrence.FromFrequency(string(re.Frequency), re.StartDate), re.StartDate)
+}
+
+// customRule reports whether the schedule was given as an RRULE rather than derived from the
+// frequency. Series stored before CustomRule existed count as custom when their rule differs
+// from the one the frequency derives.
+func (re *RecurringExpense) customRule() bool {
+	if re.CustomRule || re.RRule == "" {
+		return re.CustomRule
+	}
+	derived, err := recurrence.Parse(recurrence.FromFrequency(string(re.Frequency), re.StartDate), re.StartDate)
+	return err != nil || derived.String() != re.RRule
+}
+
+// CalculateNextDueDate advances NextDueDate to the next occurrence of the schedule.
+// Once the schedule is exhausted (COUNT or UNTIL reached) the recurring expense is deactivated.
 func (re *RecurringExpense) CalculateNextDueDate() {
-	switch re.Frequency {
-	case Daily:
-		re.NextDueDate = re.NextDueDate.AddDate(0, 0, 1)
-	case Weekly:
-		re.NextDueDate = re.NextDueDate.AddDate(0, 0, 7)
-	case Monthly:
-		re.NextDueDate = re.NextDueDate.AddDate(0, 1, 0)
-	case Yearly:
-		re.NextDueDate = re.NextDueDate.AddDate(1, 0, 0)
-	default:
-		// Default to monthly if frequency is unknown
-		re.NextDueDate = re.NextDueDate.AddDate(0, 1, 0)
+	rule, err := re.Recurrence()
+	if err != nil {
+		// Fall back to the plain frequency if the stored rule can no longer be parsed
+		rule, err = recurrence.Parse(recurrence.FromFrequency(string(re.Frequency), re.StartDate), re.StartDate)
+		if err != nil {
+			re.Deactivate()
+			return
+		}
 	}
+
+	next, ok := rule.Next(re.NextDueDate)
+	if !ok {
+		re.Deactivate()
+		return
+	}
+	re.NextDueDate = next
 }
 
 func (re *RecurringExpense) DaysUntilNextDue(currentDate time.Time) int {
@@ -59,7 +185,150 @@ func (re *RecurringExpense) Deactivate() {
 	re.IsActive = false
 }
 
+// Resume reactivates the series from the day of now on. Occurrences that fell due while it was
+// inactive are not generated afterwards; a series with no occurrences left stays inactive.
+func (re *RecurringExpense) Resume(now time.Time) error {
+	today := dateOf(now)
+	if !re.NextDueDate.Before(today) {
+		re.Activate()
+		return nil
+	}
+
+	rule, err := re.Recurrence()
+	if err != nil {
+		return err
+	}
+	next, ok := rule.NextOnOrAfter(today)
+	if !ok || re.HasEnded(next) {
+		return errors.New("recurring expense has no occurrences left to resume")
+	}
+	re.NextDueDate = next
+	re.Activate()
+	return nil
+}
+
 func (re *RecurringExpense) CancelReccurence() {
 	re.EndDate = &time.Time{}
 	re.IsActive = false
 }
+
+// OccurrenceAt applies the exceptions of the series to the occurrence due at dueDate.
+// Exceptions are matched by calendar day so clients do not need to echo the exact due time.
+func (re *RecurringExpense) OccurrenceAt(dueDate time.Time, exceptions []OccurrenceException) Occurrence {
+	occurrence := Occurrence{
+		RecurringExpenseID: re.ID,
+		DueDate:            dueDate,
+		Amount:             re.Amount,
+		CategoryID:         re.CategoryID,
+		Description:        re.Description,
+		PaymentMethod:      re.PaymentMethod,
+		Status:             OccurrenceScheduled,
+	}
+
+	day := dateOf(dueDate)
+	for i := range exceptions {
+		ex := &exceptions[i]
+		if ex.RecurringExpenseID != re.ID {
+			continue
+		}
+		start := dateOf(ex.OccurrenceDate)
+
+		switch ex.Action {
+		case OccurrencePause:
+			if !day.Before(start) && (ex.PauseUntil == nil || day.Before(dateOf(*ex.PauseUntil))) {
+				occurrence.Status = OccurrencePaused
+				occurrence.ExceptionID = &ex.ID
+				return occurrence
+			}
+		case OccurrenceSkip:
+			if day.Equal(start) {
+				occurrence.Status = OccurrenceSkipped
+				occurrence.ExceptionID = &ex.ID
+				return occurrence
+			}
+		case OccurrenceOverride:
+			if day.Equal(start) {
+				if ex.Amount != nil {
+					occurrence.Amount = common.NewMoney(*ex.Amount, re.Amount.Currency)
+				}
+				if ex.CategoryID != nil {
+					occurrence.CategoryID = *ex.CategoryID
+				}
+				occurrence.Status = OccurrenceOverridden
+				occurrence.ExceptionID = &ex.ID
+			}
+		}
+	}
+	return occurrence
+}
+
+// NextBillableOccurrence returns the first occurrence at or after NextDueDate that is neither
+// skipped nor paused. It gives up after a bounded number of occurrences so an open-ended pause
+// cannot loop forever.
+func (re *RecurringExpense) NextBillableOccurrence(exceptions []OccurrenceException) (*Occurrence, error) {
+	if !re.IsActive {
+		return nil, nil
+	}
+
+	rule, err := re.Recurrence()
+	if err != nil {
+		return nil, err
+	}
+
+	dueDate, ok := rule.NextOnOrAfter(re.NextDueDate)
+	for i := 0; ok && i < maxOccurrenceLookahead; i++ {
+		if re.HasEnded(dueDate) {
+			return nil, nil
+		}
+		occurrence := re.OccurrenceAt(dueDate, exceptions)
+		if occurrence.IsBillable() {
+			return &occurrence, nil
+		}
+		dueDate, ok = rule.Next(dueDate)
+	}
+	return nil, nil
+}
+
+const maxOccurrenceLookahead = 1000
+
+// Occurrences expands the schedule into dated occurrences within [from, to], bounded by the
+// start and end date of the series. Skipped and paused occurrences are included with their status.
+func (re *RecurringExpense) Occurrences(from, to time.Time, exceptions []OccurrenceException) ([]Occurrence, error) {
+	if from.Before(re.StartDate) {
+		from = re.StartDate
+	}
+	if re.EndDate != nil && to.After(*re.EndDate) {
+		to = *re.EndDate
+	}
+	if to.Before(from) {
+		return nil, nil
+	}
+
+	rule, err := re.Recurrence()
+	if err != nil {
+		return nil, err
+	}
+
+	dueDates := rule.Between(from, to)
+	occurrences := make([]Occurrence, 0, len(dueDates))
+	for _, dueDate := range dueDates {
+		occurrences = append(occurrences, re.OccurrenceAt(dueDate, exceptions))
+	}
+	return occurrences, nil
+}
+
+// HasOccurrenceOn reports whether the schedule has an occurrence on the UTC calendar day of day.
+func (re *RecurringExpense) HasOccurrenceOn(day time.Time) (bool, error) {
+	rule, err := re.Recurrence()
+	if err != nil {
+		return false, err
+	}
+	start := dateOf(day)
+	return len(rule.Between(start, start.Add(24*time.Hour-time.Nanosecond))) > 0, nil
+}
+
+// dateOf truncates t to midnight UTC of its UTC calendar day.
+func dateOf(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,203 @@
+package expense
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pastorenue/kinance/internal/common"
+	"github.com/shopspring/decimal"
+)
+
+func day(t *testing.T, value string) time.Time {
+	t.Helper()
+	d, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		t.Fatalf("parse %q: %v", value, err)
+	}
+	return d
+}
+
+func TestOccurrences(t *testing.T) {
+	seriesID := uuid.New()
+	overrideCategory := uuid.New()
+	end := day(t, "2026-06-30")
+	amount := decimal.RequireFromString("12.5")
+	series := func(rule string, endDate *time.Time) *RecurringExpense {
+		re := &RecurringExpense{
+			Amount:     common.NewMoney(decimal.NewFromInt(10), "EUR"),
+			CategoryID: uuid.New(),
+			Frequency:  Monthly,
+			RRule:      rule,
+			StartDate:  day(t, "2026-01-31"),
+			EndDate:    endDate,
+			IsActive:   true,
+		}
+		re.ID = seriesID
+		return re
+	}
+	exception := func(action OccurrenceAction, date string) OccurrenceException {
+		return OccurrenceException{RecurringExpenseID: seriesID, Action: action, OccurrenceDate: day(t, date)}
+	}
+	pauseUntil := day(t, "2026-05-01")
+
+	tests := []struct {
+		name       string
+		series     *RecurringExpense
+		from, to   string
+		exceptions []OccurrenceException
+		want       string
+	}{
+		{
+			name:   "monthly from the frequency clamps to short months",
+			series: series("", nil),
+			from:   "2026-01-01", to: "2026-04-30",
+			want: "2026-01-31 scheduled 10, 2026-02-28 scheduled 10, 2026-03-31 scheduled 10, 2026-04-30 scheduled 10",
+		},
+		{
+			name:   "bounded by the end date",
+			series: series("", &end),
+			from:   "2026-05-01", to: "2026-12-31",
+			want: "2026-05-31 scheduled 10, 2026-06-30 scheduled 10",
+		},
+		{
+			name:   "custom rule",
+			series: series("FREQ=WEEKLY;BYDAY=SA;COUNT=3", nil),
+			from:   "2026-01-01", to: "2026-12-31",
+			want: "2026-01-31 scheduled 10, 2026-02-07 scheduled 10, 2026-02-14 scheduled 10",
+		},
+		{
+			name:   "range before the start",
+			series: series("", nil),
+			from:   "2025-01-01", to: "2026-01-30",
+			want: "",
+		},
+		{
+			name:   "skip, override and pause",
+			series: series("", nil),
+			from:   "2026-01-01", to: "2026-05-31",
+			exceptions: []OccurrenceException{
+				exception(OccurrenceSkip, "2026-01-31"),
+				{RecurringExpenseID: seriesID, Action: OccurrenceOverride, OccurrenceDate: day(t, "2026-02-28"), Amount: &amount, CategoryID: &overrideCategory},
+				{RecurringExpenseID: seriesID, Action: OccurrencePause, OccurrenceDate: day(t, "2026-03-15"), PauseUntil: &pauseUntil},
+				// Exceptions of other series are ignored
+				{RecurringExpenseID: uuid.New(), Action: OccurrenceSkip, OccurrenceDate: day(t, "2026-05-31")},
+			},
+			want: "2026-01-31 skipped 10, 2026-02-28 overridden 12.5, 2026-03-31 paused 10, 2026-04-30 paused 10, 2026-05-31 scheduled 10",
+		},
+		{
+			name:   "open-ended pause",
+			series: series("", nil),
+			from:   "2026-01-01", to: "2026-02-28",
+			exceptions: []OccurrenceException{exception(OccurrencePause, "2026-02-01")},
+			want:       "2026-01-31 scheduled 10, 2026-02-28 paused 10",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			occurrences, err := tt.series.Occurrences(day(t, tt.from), day(t, tt.to), tt.exceptions)
+			if err != nil {
+				t.Fatalf("Occurrences: %v", err)
+			}
+			got := make([]string, len(occurrences))
+			for i, o := range occurrences {
+				got[i] = fmt.Sprintf("%s %s %s", o.DueDate.Format("2006-01-02"), o.Status, o.Amount.Amount)
+				if o.Status == OccurrenceOverridden && o.CategoryID != overrideCategory {
+					t.Errorf("overridden occurrence on %s kept its category", o.DueDate.Format("2006-01-02"))
+				}
+			}
+			if strings.Join(got, ", ") != tt.want {
+				t.Errorf("occurrences = %s\nwant %s", strings.Join(got, ", "), tt.want)
+			}
+		})
+	}
+}
+
+func TestNextBillableOccurrence(t *testing.T) {
+	re := &RecurringExpense{
+		Amount:      common.NewMoney(decimal.NewFromInt(10), "EUR"),
+		Frequency:   Weekly,
+		StartDate:   day(t, "2026-10-02"),
+		NextDueDate: day(t, "2026-10-09"),
+		IsActive:    true,
+	}
+	re.ID = uuid.New()
+
+	tests := []struct {
+		name       string
+		exceptions []OccurrenceException
+		want       string
+	}{
+		{name: "next due", want: "2026-10-09"},
+		{
+			name:       "skips skipped and paused occurrences",
+			exceptions: []OccurrenceException{{RecurringExpenseID: re.ID, Action: OccurrenceSkip, OccurrenceDate: day(t, "2026-10-09")}, {RecurringExpenseID: re.ID, Action: OccurrencePause, OccurrenceDate: day(t, "2026-10-16"), PauseUntil: timePtr(day(t, "2026-10-24"))}},
+			want:       "2026-10-30",
+		},
+		{
+			name:       "nothing billable under an open-ended pause",
+			exceptions: []OccurrenceException{{RecurringExpenseID: re.ID, Action: OccurrencePause, OccurrenceDate: day(t, "2026-10-01")}},
+			want:       "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			occurrence, err := re.NextBillableOccurrence(tt.exceptions)
+			if err != nil {
+				t.Fatalf("NextBillableOccurrence: %v", err)
+			}
+			got := ""
+			if occurrence != nil {
+				got = occurrence.DueDate.Format("2006-01-02")
+			}
+			if got != tt.want {
+				t.Errorf("next billable = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResume(t *testing.T) {
+	now := day(t, "2026-10-17")
+	tests := []struct {
+		name    string
+		nextDue string
+		endDate *time.Time
+		want    string
+		wantErr bool
+	}{
+		{name: "next due later", nextDue: "2026-10-23", want: "2026-10-23"},
+		{name: "next due today", nextDue: "2026-10-17", want: "2026-10-17"},
+		{name: "missed occurrences are not generated", nextDue: "2026-09-04", want: "2026-10-23"},
+		{name: "ended while inactive", nextDue: "2026-09-04", endDate: timePtr(day(t, "2026-10-01")), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			re := &RecurringExpense{
+				Frequency:   Weekly,
+				StartDate:   day(t, "2026-09-04"),
+				NextDueDate: day(t, tt.nextDue),
+				EndDate:     tt.endDate,
+			}
+			err := re.Resume(now)
+			if tt.wantErr {
+				if err == nil || re.IsActive {
+					t.Fatalf("Resume = %v, active %v, want an error and the series inactive", err, re.IsActive)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resume: %v", err)
+			}
+			if !re.IsActive || re.NextDueDate.Format("2006-01-02") != tt.want {
+				t.Errorf("active %v, next due %s, want active and next due %s", re.IsActive, re.NextDueDate.Format("2006-01-02"), tt.want)
+			}
+		})
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
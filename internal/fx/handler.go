@@ -0,0 +1,120 @@
+package fx
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pastorenue/kinance/internal/common"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) GetRates(c *gin.Context) {
+	base := common.Currency(strings.ToUpper(c.Query("base"))).OrDefault()
+	if !base.IsValid() {
+		c.JSON(http.StatusBadRequest, common.APIResponse{
+			Success:    false,
+			StatusCode: http.StatusBadRequest,
+			Error:      "Invalid base parameter, expected an ISO 4217 currency code",
+		})
+		return
+	}
+	date, ok := parseDate(c, c.Query("date"))
+	if !ok {
+		return
+	}
+
+	rates, err := h.service.GetRates(c.Request.Context(), base, date)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, common.APIResponse{
+		Success:    true,
+		StatusCode: http.StatusOK,
+		Data:       rates,
+	})
+}
+
+func (h *Handler) Convert(c *gin.Context) {
+	var req ConvertRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, common.APIResponse{
+			Success:    false,
+			StatusCode: http.StatusBadRequest,
+			Error:      err.Error(),
+		})
+		return
+	}
+	date, ok := parseDate(c, req.Date)
+	if !ok {
+		return
+	}
+
+	from := common.NewMoney(req.Amount, req.From)
+	rate, err := h.service.Rate(c.Request.Context(), req.From, req.To, date)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, common.APIResponse{
+		Success:    true,
+		StatusCode: http.StatusOK,
+		Data: ConversionResponse{
+			From: from,
+			To:   common.NewMoney(from.Amount.Mul(rate), req.To),
+			Rate: rate,
+			Date: date.Format(dateLayout),
+		},
+	})
+}
+
+// GetCurrencies lists the ISO 4217 currencies amounts can be recorded in.
+func (h *Handler) GetCurrencies(c *gin.Context) {
+	c.JSON(http.StatusOK, common.APIResponse{
+		Success:    true,
+		StatusCode: http.StatusOK,
+		Data:       common.Currencies(),
+	})
+}
+
+// parseDate parses an optional YYYY-MM-DD query value, defaulting to today. It writes the
+// error response itself and reports whether the handler should continue.
+func parseDate(c *gin.Context, value string) (time.Time, bool) {
+	if value == "" {
+		return time.Now(), true
+	}
+	date, err := time.Parse(dateLayout, value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.APIResponse{
+			Success:    false,
+			StatusCode: http.StatusBadRequest,
+			Error:      "Invalid date parameter, expected YYYY-MM-DD",
+		})
+		return time.Time{}, false
+	}
+	return date, true
+}
+
+func writeError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	if errors.Is(err, ErrRateNotFound) {
+		status = http.StatusNotFound
+	}
+	c.JSON(status, common.APIResponse{
+		Success:    false,
+		StatusCode: status,
+		Error:      err.Error(),
+	})
+}
@@ -0,0 +1,39 @@
+package fx
+
+import (
+	"time"
+
+	"github.com/pastorenue/kinance/internal/common"
+	"github.com/shopspring/decimal"
+)
+
+// ExchangeRate is the price of one unit of Base in Quote on a day, e.g. 1 EUR = 1.0842 USD.
+// Each provider publishes at most one rate per pair and day.
+type ExchangeRate struct {
+	common.BaseModel
+	Base   common.Currency `json:"base" gorm:"type:varchar(3);not null;uniqueIndex:idx_exchange_rate_day"`
+	Quote  common.Currency `json:"quote" gorm:"type:varchar(3);not null;uniqueIndex:idx_exchange_rate_day"`
+	Date   time.Time       `json:"date" gorm:"type:date;not null;uniqueIndex:idx_exchange_rate_day"`
+	Rate   decimal.Decimal `json:"rate" gorm:"type:decimal(24,10);not null"`
+	Source string          `json:"source" gorm:"type:varchar(50)"`
+}
+
+type ConvertRequest struct {
+	Amount decimal.Decimal `form:"amount" binding:"required"`
+	From   common.Currency `form:"from" binding:"required,currency"`
+	To     common.Currency `form:"to" binding:"required,currency"`
+	Date   string          `form:"date"` // YYYY-MM-DD, defaults to today
+}
+
+type ConversionResponse struct {
+	From common.Money    `json:"from"`
+	To   common.Money    `json:"to"`
+	Rate decimal.Decimal `json:"rate"`
+	Date string          `json:"date"`
+}
+
+type RatesResponse struct {
+	Base  common.Currency                     `json:"base"`
+	Date  string                              `json:"date"`
+	Rates map[common.Currency]decimal.Decimal `json:"rates"`
+}
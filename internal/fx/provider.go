@@ -0,0 +1,195 @@
+package fx
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pastorenue/kinance/internal/common"
+	"github.com/pastorenue/kinance/pkg/config"
+	"github.com/shopspring/decimal"
+)
+
+const dateLayout = "2006-01-02"
+
+// Provider supplies historical daily exchange rates.
+type Provider interface {
+	Name() string
+	// Rates returns the rates the provider published for the days in [from, to].
+	Rates(ctx context.Context, from, to time.Time) ([]ExchangeRate, error)
+}
+
+// NewProvider returns the rate provider selected by cfg.Provider, or nil when exchange rates
+// are not synced automatically.
+func NewProvider(cfg config.FXConfig) (Provider, error) {
+	switch cfg.Provider {
+	case "":
+		return nil, nil
+	case "csv":
+		if cfg.CSVPath == "" {
+			return nil, errors.New("FX_CSV_PATH is required for the csv rate provider")
+		}
+		return NewCSVProvider(cfg.CSVPath), nil
+	case "http":
+		if cfg.HTTPURL == "" {
+			return nil, errors.New("FX_HTTP_URL is required for the http rate provider")
+		}
+		return NewHTTPProvider(cfg.HTTPURL, common.Currency(cfg.BaseCurrency)), nil
+	default:
+		return nil, fmt.Errorf("unknown rate provider %q", cfg.Provider)
+	}
+}
+
+// CSVProvider reads rates from a file with a date,base,quote,rate header, e.g.
+//
+//	date,base,quote,rate
+//	2026-10-15,EUR,USD,1.0842
+//
+// The file is read on every call, so it can be replaced while the server is running.
+type CSVProvider struct {
+	path string
+}
+
+func NewCSVProvider(path string) *CSVProvider {
+	return &CSVProvider{path: path}
+}
+
+func (p *CSVProvider) Name() string {
+	return "csv"
+}
+
+func (p *CSVProvider) Rates(_ context.Context, from, to time.Time) ([]ExchangeRate, error) {
+	file, err := os.Open(p.path)
+	if err != nil {
+		return nil, fmt.Errorf("csv: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("csv: failed to read header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"date", "base", "quote", "rate"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("csv: missing %s column", name)
+		}
+	}
+
+	var rates []ExchangeRate
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("csv: line %d: %w", line, err)
+		}
+
+		date, err := time.Parse(dateLayout, record[columns["date"]])
+		if err != nil {
+			return nil, fmt.Errorf("csv: line %d: invalid date: %w", line, err)
+		}
+		if date.Before(from) || date.After(to) {
+			continue
+		}
+		rate, err := decimal.NewFromString(record[columns["rate"]])
+		if err != nil || !rate.IsPositive() {
+			return nil, fmt.Errorf("csv: line %d: invalid rate %q", line, record[columns["rate"]])
+		}
+
+		rates = append(rates, ExchangeRate{
+			Base:  common.Currency(strings.ToUpper(record[columns["base"]])),
+			Quote: common.Currency(strings.ToUpper(record[columns["quote"]])),
+			Date:  date,
+			Rate:  rate,
+		})
+	}
+	return rates, nil
+}
+
+// HTTPProvider fetches rates from the time series endpoint of a Frankfurter-compatible API:
+//
+//	GET {url}/2026-10-12..2026-10-16?from=EUR
+//	{"base": "EUR", "start_date": "2026-10-12", "end_date": "2026-10-16",
+//	 "rates": {"2026-10-12": {"USD": 1.0842, "JPY": 162.31}, ...}}
+//
+// Only days with a publication are listed. APIs start the series at the last publication before
+// the range, e.g. on the Friday before a weekend, so days outside the range are dropped.
+type HTTPProvider struct {
+	url    string
+	base   common.Currency
+	client *http.Client
+}
+
+func NewHTTPProvider(url string, base common.Currency) *HTTPProvider {
+	return &HTTPProvider{
+		url:    strings.TrimRight(url, "/"),
+		base:   base.OrDefault(),
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (p *HTTPProvider) Name() string {
+	return "http"
+}
+
+func (p *HTTPProvider) Rates(ctx context.Context, from, to time.Time) ([]ExchangeRate, error) {
+	endpoint := fmt.Sprintf("%s/%s..%s?from=%s", p.url, from.Format(dateLayout), to.Format(dateLayout), url.QueryEscape(string(p.base)))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("http: %w", err)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("http: rate API returned status %d for %s to %s", resp.StatusCode, from.Format(dateLayout), to.Format(dateLayout))
+	}
+
+	var body struct {
+		Base  common.Currency                                `json:"base"`
+		Rates map[string]map[common.Currency]decimal.Decimal `json:"rates"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("http: invalid response: %w", err)
+	}
+
+	var rates []ExchangeRate
+	for day, quotes := range body.Rates {
+		date, err := time.Parse(dateLayout, day)
+		if err != nil {
+			return nil, fmt.Errorf("http: invalid date %q: %w", day, err)
+		}
+		if date.Before(from) || date.After(to) {
+			continue
+		}
+		for quote, rate := range quotes {
+			rates = append(rates, ExchangeRate{
+				Base:  body.Base,
+				Quote: quote,
+				Date:  date,
+				Rate:  rate,
+			})
+		}
+	}
+	return rates, nil
+}
@@ -0,0 +1,183 @@
+package fx
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pastorenue/kinance/internal/common"
+)
+
+func date(t *testing.T, value string) time.Time {
+	t.Helper()
+	d, err := time.Parse(dateLayout, value)
+	if err != nil {
+		t.Fatalf("parse %q: %v", value, err)
+	}
+	return d
+}
+
+// frankfurter serves the time series endpoint from a table of published days. Like the real API,
+// a range starting on a day without publication begins at the last publication before it.
+func frankfurter(t *testing.T, published map[string]string, requests *[]string) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests = append(*requests, r.URL.RequestURI())
+		from, to, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "..")
+		if !ok || r.URL.Query().Get("from") != "EUR" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		days := make([]string, 0, len(published))
+		for day := range published {
+			days = append(days, day)
+		}
+		sort.Strings(days)
+
+		var entries []string
+		start := ""
+		for _, day := range days {
+			switch {
+			case day > to:
+			case day >= from:
+				entries = append(entries, fmt.Sprintf("%q: %s", day, published[day]))
+			default:
+				start = day
+			}
+		}
+		if start != "" {
+			entries = append([]string{fmt.Sprintf("%q: %s", start, published[start])}, entries...)
+		}
+		fmt.Fprintf(w, `{"amount": 1.0, "base": "EUR", "rates": {%s}}`, strings.Join(entries, ", "))
+	}))
+}
+
+func TestHTTPProviderRates(t *testing.T) {
+	// 2026-10-16 is a Friday; nothing is published on the weekend of the 17th and 18th
+	published := map[string]string{
+		"2026-10-15": `{"USD": 1.0842, "JPY": 162.31}`,
+		"2026-10-16": `{"USD": 1.0850, "JPY": 162.02}`,
+		"2026-10-19": `{"USD": 1.0861, "JPY": 161.95}`,
+	}
+
+	tests := []struct {
+		name     string
+		from, to string
+		want     []string
+	}{
+		{
+			name: "weekdays",
+			from: "2026-10-15", to: "2026-10-16",
+			want: []string{"2026-10-15 JPY 162.31", "2026-10-15 USD 1.0842", "2026-10-16 JPY 162.02", "2026-10-16 USD 1.085"},
+		},
+		{
+			name: "range over a weekend",
+			from: "2026-10-16", to: "2026-10-19",
+			want: []string{"2026-10-16 JPY 162.02", "2026-10-16 USD 1.085", "2026-10-19 JPY 161.95", "2026-10-19 USD 1.0861"},
+		},
+		{
+			name: "range starting on a weekend drops the previous Friday",
+			from: "2026-10-17", to: "2026-10-19",
+			want: []string{"2026-10-19 JPY 161.95", "2026-10-19 USD 1.0861"},
+		},
+		{
+			name: "weekend only",
+			from: "2026-10-17", to: "2026-10-18",
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []string
+			server := frankfurter(t, published, &requests)
+			defer server.Close()
+
+			rates, err := NewHTTPProvider(server.URL+"/", "EUR").Rates(context.Background(), date(t, tt.from), date(t, tt.to))
+			if err != nil {
+				t.Fatalf("Rates: %v", err)
+			}
+
+			var got []string
+			for _, rate := range rates {
+				if rate.Base != "EUR" {
+					t.Errorf("base = %s, want EUR", rate.Base)
+				}
+				got = append(got, fmt.Sprintf("%s %s %s", rate.Date.Format(dateLayout), rate.Quote, rate.Rate))
+			}
+			sort.Strings(got)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("rates = %v, want %v", got, tt.want)
+			}
+
+			wantRequest := fmt.Sprintf("/%s..%s?from=EUR", tt.from, tt.to)
+			if len(requests) != 1 || requests[0] != wantRequest {
+				t.Errorf("requests = %v, want one request for %s", requests, wantRequest)
+			}
+		})
+	}
+}
+
+func TestHTTPProviderErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   string
+	}{
+		{name: "status", status: http.StatusBadGateway, body: `{}`, want: "status 502"},
+		{name: "malformed body", status: http.StatusOK, body: `{"rates": [`, want: "invalid response"},
+		{name: "malformed date", status: http.StatusOK, body: `{"base": "EUR", "rates": {"16.10.2026": {"USD": 1.08}}}`, want: "invalid date"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				fmt.Fprint(w, tt.body)
+			}))
+			defer server.Close()
+
+			_, err := NewHTTPProvider(server.URL, "EUR").Rates(context.Background(), date(t, "2026-10-15"), date(t, "2026-10-16"))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Rates error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCSVProviderRates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.csv")
+	content := "Date, Base, Quote, Rate\n" +
+		"2026-10-14,EUR,USD,1.0830\n" +
+		"2026-10-15,eur,usd,1.0842\n" +
+		"2026-10-16,EUR,GBP,0.8612\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	rates, err := NewCSVProvider(path).Rates(context.Background(), date(t, "2026-10-15"), date(t, "2026-10-16"))
+	if err != nil {
+		t.Fatalf("Rates: %v", err)
+	}
+	if len(rates) != 2 {
+		t.Fatalf("got %d rates, want the 2 in range", len(rates))
+	}
+	if rates[0].Base != common.Currency("EUR") || rates[0].Quote != "USD" || rates[0].Rate.String() != "1.0842" {
+		t.Errorf("first rate = %+v, want EUR/USD 1.0842 in upper case", rates[0])
+	}
+
+	bad := filepath.Join(t.TempDir(), "bad.csv")
+	if err := os.WriteFile(bad, []byte("date,base,quote,rate\n2026-10-15,EUR,USD,-1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewCSVProvider(bad).Rates(context.Background(), date(t, "2026-10-15"), date(t, "2026-10-15")); err == nil {
+		t.Error("negative rate was accepted")
+	}
+}
@@ -0,0 +1,12 @@
+package fx
+
+import "github.com/gin-gonic/gin"
+
+func RegisterRoutes(versionedGroup *gin.RouterGroup, svc *Service) {
+	fxHandler := NewHandler(svc)
+	versionedGroup.GET("/currencies", fxHandler.GetCurrencies)
+
+	protected := versionedGroup.Group("/fx")
+	protected.GET("/rates", fxHandler.GetRates)
+	protected.GET("/convert", fxHandler.Convert)
+}
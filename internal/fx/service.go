@@ -0,0 +1,289 @@
+package fx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pastorenue/kinance/internal/common"
+	"github.com/pastorenue/kinance/pkg/config"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrRateNotFound = errors.New("exchange rate not found")
+
+type Service struct {
+	db           *gorm.DB
+	provider     Provider
+	base         common.Currency
+	backfillDays int
+	logger       common.Logger
+}
+
+// NewService returns the exchange rate service. provider may be nil, in which case rates are
+// only read from the database.
+func NewService(db *gorm.DB, provider Provider, cfg config.FXConfig, logger common.Logger) *Service {
+	return &Service{
+		db:           db,
+		provider:     provider,
+		base:         common.Currency(cfg.BaseCurrency).OrDefault(),
+		backfillDays: cfg.BackfillDays,
+		logger:       logger,
+	}
+}
+
+// SyncRates stores the provider's rates from the latest stored day, or BackfillDays ago on the
+// first run, up to today. The latest day is fetched again since providers may still revise it.
+func (s *Service) SyncRates(ctx context.Context) error {
+	if s.provider == nil {
+		return nil
+	}
+
+	today := dateOf(time.Now())
+	from := today.AddDate(0, 0, -s.backfillDays)
+
+	var latest ExchangeRate
+	err := s.db.WithContext(ctx).Where("source = ?", s.provider.Name()).Order("date DESC").First(&latest).Error
+	switch {
+	case err == nil:
+		if latest.Date.After(from) {
+			from = dateOf(latest.Date)
+		}
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return err
+	}
+
+	count, err := s.ImportRates(ctx, from, today)
+	if err != nil {
+		return err
+	}
+	s.logger.Info("Exchange rates synced", "provider", s.provider.Name(), "from", from.Format(dateLayout), "rates", count)
+	return nil
+}
+
+// ImportRates fetches the provider's rates for the days in [from, to] and stores them, replacing
+// rates already stored for the same pair and day.
+func (s *Service) ImportRates(ctx context.Context, from, to time.Time) (int, error) {
+	if s.provider == nil {
+		return 0, errors.New("no exchange rate provider configured")
+	}
+
+	rates, err := s.provider.Rates(ctx, dateOf(from), dateOf(to))
+	if err != nil {
+		return 0, err
+	}
+	rates = uniqueRates(rates)
+	if len(rates) == 0 {
+		return 0, nil
+	}
+	for i := range rates {
+		rates[i].ID = uuid.New()
+		rates[i].Source = s.provider.Name()
+	}
+
+	if err := s.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "base"}, {Name: "quote"}, {Name: "date"}},
+			DoUpdates: clause.AssignmentColumns([]string{"rate", "source", "updated_at"}),
+		}).
+		CreateInBatches(rates, 500).Error; err != nil {
+		return 0, err
+	}
+	return len(rates), nil
+}
+
+// GetRates returns the rates of base against every currency known on date, derived from the
+// latest rates published on or before date.
+func (s *Service) GetRates(ctx context.Context, base common.Currency, date time.Time) (*RatesResponse, error) {
+	var day ExchangeRate
+	if err := s.db.WithContext(ctx).
+		Where("base = ? AND date <= ?", s.base, dateOf(date)).
+		Order("date DESC").
+		First(&day).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: no %s rates on or before %s", ErrRateNotFound, s.base, dateOf(date).Format(dateLayout))
+		}
+		return nil, err
+	}
+
+	var published []ExchangeRate
+	if err := s.db.WithContext(ctx).Where("base = ? AND date = ?", s.base, day.Date).Find(&published).Error; err != nil {
+		return nil, err
+	}
+
+	// Rates are published against the base currency, so other currencies are crossed through it
+	fromBase := map[common.Currency]decimal.Decimal{s.base: decimal.NewFromInt(1)}
+	for _, rate := range published {
+		fromBase[rate.Quote] = rate.Rate
+	}
+	divisor, ok := fromBase[base]
+	if !ok {
+		return nil, fmt.Errorf("%w: no rate for %s on %s", ErrRateNotFound, base, day.Date.Format(dateLayout))
+	}
+
+	response := &RatesResponse{
+		Base:  base,
+		Date:  day.Date.Format(dateLayout),
+		Rates: make(map[common.Currency]decimal.Decimal, len(fromBase)-1),
+	}
+	for quote, rate := range fromBase {
+		if quote != base {
+			response.Rates[quote] = rate.Div(divisor)
+		}
+	}
+	return response, nil
+}
+
+// Rate returns what one unit of from was worth in to on date. It uses the latest rate published
+// on or before date, or the earliest later one for dates before the stored history. Pairs without
+// a stored rate are crossed through the base currency.
+func (s *Service) Rate(ctx context.Context, from, to common.Currency, date time.Time) (decimal.Decimal, error) {
+	if from == to {
+		return decimal.NewFromInt(1), nil
+	}
+	day := dateOf(date)
+
+	rate, ok, err := s.pairRate(ctx, from, to, day)
+	if err != nil || ok {
+		return rate, err
+	}
+
+	if from != s.base && to != s.base {
+		baseToFrom, err := s.Rate(ctx, s.base, from, day)
+		if err != nil {
+			return decimal.Zero, err
+		}
+		baseToTo, err := s.Rate(ctx, s.base, to, day)
+		if err != nil {
+			return decimal.Zero, err
+		}
+		return baseToTo.Div(baseToFrom), nil
+	}
+	return decimal.Zero, fmt.Errorf("%w: %s to %s on %s", ErrRateNotFound, from, to, day.Format(dateLayout))
+}
+
+// Convert converts m into to at the rate of date, rounded to the minor units of to.
+func (s *Service) Convert(ctx context.Context, m common.Money, to common.Currency, date time.Time) (common.Money, error) {
+	rate, err := s.Rate(ctx, m.Currency, to, date)
+	if err != nil {
+		return common.Money{}, err
+	}
+	return common.NewMoney(m.Amount.Mul(rate), to), nil
+}
+
+// ReportingCurrency returns the currency the user wants totals reported in.
+func (s *Service) ReportingCurrency(ctx context.Context, userID uuid.UUID) (common.Currency, error) {
+	var currency sql.NullString
+	err := s.db.WithContext(ctx).Table("users").
+		Select("reporting_currency").
+		Where("id = ?", userID).
+		Row().Scan(&currency)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", err
+	}
+	return common.Currency(currency.String).OrDefault(), nil
+}
+
+// NewConverter returns a converter into to.
+func (s *Service) NewConverter(to common.Currency) *Converter {
+	return &Converter{
+		service: s,
+		to:      to,
+		rates:   make(map[rateKey]decimal.Decimal),
+	}
+}
+
+// pairRate looks up the stored rate of the pair in either direction nearest to day.
+func (s *Service) pairRate(ctx context.Context, from, to common.Currency, day time.Time) (decimal.Decimal, bool, error) {
+	pair := func() *gorm.DB {
+		return s.db.WithContext(ctx).Where("(base = ? AND quote = ?) OR (base = ? AND quote = ?)", from, to, to, from)
+	}
+
+	var rates []ExchangeRate
+	if err := pair().Where("date <= ?", day).Order("date DESC").Limit(1).Find(&rates).Error; err != nil {
+		return decimal.Zero, false, err
+	}
+	if len(rates) == 0 {
+		if err := pair().Where("date > ?", day).Order("date ASC").Limit(1).Find(&rates).Error; err != nil {
+			return decimal.Zero, false, err
+		}
+	}
+	if len(rates) == 0 {
+		return decimal.Zero, false, nil
+	}
+
+	if rates[0].Base == from {
+		return rates[0].Rate, true, nil
+	}
+	return decimal.NewFromInt(1).Div(rates[0].Rate), true, nil
+}
+
+// Converter converts amounts into one currency at the rate of the day each amount was recorded.
+// It caches rates, so aggregates over many rows look up each currency and day only once.
+// A Converter is not safe for concurrent use.
+type Converter struct {
+	service *Service
+	to      common.Currency
+	rates   map[rateKey]decimal.Decimal
+}
+
+type rateKey struct {
+	currency common.Currency
+	day      time.Time
+}
+
+// Currency returns the currency amounts are converted into.
+func (c *Converter) Currency() common.Currency {
+	return c.to
+}
+
+// Convert converts amount in currency at the rate of date. The result is not rounded, so that
+// callers can add up converted amounts before rounding once.
+func (c *Converter) Convert(ctx context.Context, amount decimal.Decimal, currency common.Currency, date time.Time) (decimal.Decimal, error) {
+	if currency == c.to {
+		return amount, nil
+	}
+
+	key := rateKey{currency: currency, day: dateOf(date)}
+	rate, ok := c.rates[key]
+	if !ok {
+		var err error
+		if rate, err = c.service.Rate(ctx, currency, c.to, key.day); err != nil {
+			return decimal.Zero, err
+		}
+		c.rates[key] = rate
+	}
+	return amount.Mul(rate), nil
+}
+
+func dateOf(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// uniqueRates keeps the last rate of each pair and day, since Postgres rejects an upsert that
+// touches the same row twice. Dates are truncated to the day first.
+func uniqueRates(rates []ExchangeRate) []ExchangeRate {
+	type key struct {
+		base, quote common.Currency
+		date        time.Time
+	}
+	index := make(map[key]int, len(rates))
+	unique := make([]ExchangeRate, 0, len(rates))
+	for _, rate := range rates {
+		rate.Date = dateOf(rate.Date)
+		k := key{rate.Base, rate.Quote, rate.Date}
+		if i, ok := index[k]; ok {
+			unique[i] = rate
+			continue
+		}
+		index[k] = len(unique)
+		unique = append(unique, rate)
+	}
+	return unique
+}
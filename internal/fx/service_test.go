@@ -0,0 +1,144 @@
+package fx
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/pastorenue/kinance/internal/common"
+	"github.com/pastorenue/kinance/pkg/config"
+	"github.com/shopspring/decimal"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestUniqueRates(t *testing.T) {
+	friday := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+	rate := func(quote common.Currency, day time.Time, value string) ExchangeRate {
+		return ExchangeRate{Base: "EUR", Quote: quote, Date: day, Rate: decimal.RequireFromString(value)}
+	}
+
+	tests := []struct {
+		name  string
+		rates []ExchangeRate
+		want  []ExchangeRate
+	}{
+		{name: "empty", rates: nil, want: []ExchangeRate{}},
+		{
+			name:  "distinct",
+			rates: []ExchangeRate{rate("USD", friday, "1.08"), rate("GBP", friday, "0.86"), rate("USD", friday.AddDate(0, 0, 3), "1.09")},
+			want:  []ExchangeRate{rate("USD", friday, "1.08"), rate("GBP", friday, "0.86"), rate("USD", friday.AddDate(0, 0, 3), "1.09")},
+		},
+		{
+			name:  "same day twice keeps the last rate in place",
+			rates: []ExchangeRate{rate("USD", friday, "1.08"), rate("GBP", friday, "0.86"), rate("USD", friday, "1.085")},
+			want:  []ExchangeRate{rate("USD", friday, "1.085"), rate("GBP", friday, "0.86")},
+		},
+		{
+			name:  "times of day fall on the same day",
+			rates: []ExchangeRate{rate("USD", friday.Add(9*time.Hour), "1.08"), rate("USD", friday.Add(17*time.Hour), "1.081")},
+			want:  []ExchangeRate{rate("USD", friday, "1.081")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := uniqueRates(tt.rates)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d rates, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if got[i].Quote != tt.want[i].Quote || !got[i].Date.Equal(tt.want[i].Date) || !got[i].Rate.Equal(tt.want[i].Rate) {
+					t.Errorf("rate %d = %s %s %s, want %s %s %s", i,
+						got[i].Quote, got[i].Date.Format(dateLayout), got[i].Rate,
+						tt.want[i].Quote, tt.want[i].Date.Format(dateLayout), tt.want[i].Rate)
+				}
+			}
+		})
+	}
+}
+
+type stubProvider struct {
+	rates []ExchangeRate
+}
+
+func (p *stubProvider) Name() string {
+	return "stub"
+}
+
+func (p *stubProvider) Rates(_ context.Context, _, _ time.Time) ([]ExchangeRate, error) {
+	rates := make([]ExchangeRate, len(p.rates))
+	copy(rates, p.rates)
+	return rates, nil
+}
+
+type testLogger struct{}
+
+func (testLogger) Info(string, ...interface{})  {}
+func (testLogger) Error(string, ...interface{}) {}
+func (testLogger) Debug(string, ...interface{}) {}
+
+// testDB connects to the Postgres database in TEST_DATABASE_URL, skipping the test without one.
+func testDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	if err := db.AutoMigrate(&ExchangeRate{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+func TestImportRatesIsIdempotent(t *testing.T) {
+	db := testDB(t)
+
+	// A base no real provider publishes keeps the test clear of other rows
+	const base = common.Currency("XTS")
+	friday := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+	t.Cleanup(func() { db.Unscoped().Where("base = ?", base).Delete(&ExchangeRate{}) })
+
+	provider := &stubProvider{rates: []ExchangeRate{
+		{Base: base, Quote: "USD", Date: friday, Rate: decimal.RequireFromString("1.08")},
+		{Base: base, Quote: "GBP", Date: friday, Rate: decimal.RequireFromString("0.86")},
+		// The weekend answered with Friday's rates
+		{Base: base, Quote: "USD", Date: friday, Rate: decimal.RequireFromString("1.08")},
+		{Base: base, Quote: "GBP", Date: friday, Rate: decimal.RequireFromString("0.86")},
+	}}
+	service := NewService(db, provider, config.FXConfig{BaseCurrency: string(base)}, testLogger{})
+
+	for run := 1; run <= 2; run++ {
+		count, err := service.ImportRates(context.Background(), friday, friday.AddDate(0, 0, 2))
+		if err != nil {
+			t.Fatalf("import %d: %v", run, err)
+		}
+		if count != 2 {
+			t.Errorf("import %d stored %d rates, want 2", run, count)
+		}
+	}
+
+	provider.rates[0].Rate = decimal.RequireFromString("1.09")
+	if _, err := service.ImportRates(context.Background(), friday, friday); err != nil {
+		t.Fatalf("import revised rate: %v", err)
+	}
+
+	var stored []ExchangeRate
+	if err := db.Where("base = ?", base).Order("quote").Find(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 2 {
+		t.Fatalf("stored %d rows, want 2", len(stored))
+	}
+	if stored[1].Quote != "USD" || !stored[1].Rate.Equal(decimal.RequireFromString("1.09")) {
+		t.Errorf("USD rate = %s, want the revised 1.09", stored[1].Rate)
+	}
+	if stored[0].Source != "stub" {
+		t.Errorf("source = %q, want stub", stored[0].Source)
+	}
+}
@@ -0,0 +1,274 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/pastorenue/kinance/internal/common"
+	"github.com/shopspring/decimal"
+)
+
+// readCAMT reads an ISO 20022 camt.053 bank to customer statement. A file may hold several
+// statements, such as one a day, as long as they are all for the same account.
+func readCAMT(data []byte) (*statement, error) {
+	root, err := parseXML(data)
+	if err != nil {
+		return nil, err
+	}
+	statements := root.findAll("Stmt")
+	if len(statements) == 0 {
+		return nil, fmt.Errorf("%w: no camt.053 statement in the file", ErrInvalidFile)
+	}
+
+	st := &statement{}
+	references := make(map[string]int)
+	for _, stmt := range statements {
+		acct := stmt.child("Acct")
+		number := acct.child("Id").value("IBAN")
+		if number == "" {
+			number = acct.child("Id").child("Othr").value("Id")
+		}
+		if st.AccountNumber != "" && number != st.AccountNumber {
+			return nil, fmt.Errorf("%w: the file holds statements of several accounts; export one account at a time", ErrInvalidFile)
+		}
+		st.AccountNumber = number
+		st.AccountName = acct.value("Nm")
+		st.Currency = common.Currency(strings.ToUpper(acct.value("Ccy")))
+
+		if period := stmt.child("FrToDt"); period != nil {
+			if start, err := camtDate(period.value("FrDtTm")); err == nil && (st.Start == nil || start.Before(*st.Start)) {
+				st.Start = &start
+			}
+			if end, err := camtDate(period.value("ToDtTm")); err == nil && (st.End == nil || end.After(*st.End)) {
+				st.End = &end
+			}
+		}
+		for _, bal := range stmt.findAll("Bal") {
+			if bal.child("Tp").child("CdOrPrtry").value("Cd") != "CLBD" {
+				continue
+			}
+			amount, currency, err := camtAmount(bal)
+			date, dateErr := camtDate(bal.child("Dt").value("Dt") + bal.child("Dt").value("DtTm"))
+			if err != nil || dateErr != nil || (st.ClosingDate != nil && date.Before(*st.ClosingDate)) {
+				continue
+			}
+			st.ClosingBalance, st.ClosingDate = &amount, &date
+			if st.Currency == "" {
+				st.Currency = currency
+			}
+		}
+
+		for _, ntry := range stmt.findAll("Ntry") {
+			for _, e := range camtEntries(ntry) {
+				if len(st.Entries) == maxRows {
+					return nil, fmt.Errorf("%w: more than %d entries", ErrInvalidFile, maxRows)
+				}
+				if e.Reference == "" && e.Err == nil {
+					e.Reference = contentReference("camt", e)
+				}
+				e.Reference = uniqueReference(e.Reference, references)
+				st.Entries = append(st.Entries, e)
+			}
+		}
+	}
+	return st, nil
+}
+
+// camtEntries reads an entry of a statement. A batch booked as one entry is split into its
+// transactions when each of them states its amount.
+func camtEntries(ntry *node) []entry {
+	e := entry{Line: ntry.Line}
+	amount, currency, err := camtAmount(ntry)
+	if err != nil {
+		e.Err = err
+		return []entry{e}
+	}
+	e.Currency = currency
+	e.Amount = amount
+	if ntry.value("CdtDbtInd") == "DBIT" {
+		e.Amount = amount.Neg()
+	}
+
+	// Pending and information-only entries may still change, and change their reference.
+	status := ntry.value("Sts")
+	if status == "" {
+		status = ntry.child("Sts").value("Cd")
+	}
+	if status != "" && status != "BOOK" {
+		e.Err = fmt.Errorf("entry is not booked yet (status %s)", status)
+		return []entry{e}
+	}
+
+	booking, err := camtDate(ntry.child("BookgDt").value("Dt") + ntry.child("BookgDt").value("DtTm"))
+	if err != nil {
+		e.Err = errors.New("entry has no booking date")
+		return []entry{e}
+	}
+	e.Date = booking
+	if value, err := camtDate(ntry.child("ValDt").value("Dt") + ntry.child("ValDt").value("DtTm")); err == nil {
+		e.ValueDate = value
+	}
+
+	e.Reference = ntry.value("AcctSvcrRef")
+	if e.Reference == "" {
+		e.Reference = ntry.value("NtryRef")
+	}
+	e.Description = ntry.value("AddtlNtryInf")
+
+	details := ntry.findAll("TxDtls")
+	split := len(details) > 1
+	for _, tx := range details {
+		if _, _, err := camtAmount(tx.child("AmtDtls").child("TxAmt")); err != nil && tx.child("Amt") == nil {
+			split = false
+		}
+	}
+	if !split {
+		if len(details) == 1 {
+			camtDetails(&e, details[0], e.Amount.IsNegative())
+		}
+		return []entry{e}
+	}
+
+	entries := make([]entry, 0, len(details))
+	for i, tx := range details {
+		part := e
+		part.Line = tx.Line
+		if e.Reference != "" {
+			part.Reference = fmt.Sprintf("%s/%d", e.Reference, i+1)
+		}
+		amount, currency, err := camtAmount(tx)
+		if err != nil {
+			amount, currency, err = camtAmount(tx.child("AmtDtls").child("TxAmt"))
+		}
+		part.Amount, part.Currency, part.Err = amount, currency, err
+		if e.Amount.IsNegative() {
+			part.Amount = amount.Neg()
+		}
+		camtDetails(&part, tx, e.Amount.IsNegative())
+		entries = append(entries, part)
+	}
+	return entries
+}
+
+// camtDetails reads the counterparty, reference and remittance information of a transaction. The
+// counterparty of a debit is its creditor, of a credit its debtor.
+func camtDetails(e *entry, tx *node, debit bool) {
+	refs := tx.child("Refs")
+	if reference := refs.value("AcctSvcrRef"); reference != "" {
+		e.Reference = reference
+	}
+	if e.Reference == "" {
+		for _, name := range []string{"TxId", "EndToEndId"} {
+			if reference := refs.value(name); reference != "" && reference != "NOTPROVIDED" {
+				e.Reference = reference
+				break
+			}
+		}
+	}
+
+	party, agent := "Dbtr", "DbtrAgt"
+	if debit {
+		party, agent = "Cdtr", "CdtrAgt"
+	}
+	parties := tx.child("RltdPties")
+	// Version 8 and later wrap the party in Pty.
+	name := parties.child(party).value("Nm")
+	if name == "" {
+		name = parties.child(party).child("Pty").value("Nm")
+	}
+	if name != "" {
+		e.Merchant = name
+	}
+	accountID := parties.child(party + "Acct").child("Id")
+	e.CounterpartyIBAN = accountID.value("IBAN")
+	if e.CounterpartyIBAN == "" {
+		e.CounterpartyIBAN = accountID.child("Othr").value("Id")
+	}
+	institution := tx.child("RltdAgts").child(agent).child("FinInstnId")
+	e.CounterpartyBIC = institution.value("BICFI")
+	if e.CounterpartyBIC == "" {
+		e.CounterpartyBIC = institution.value("BIC")
+	}
+
+	var remittance []string
+	for _, c := range tx.child("RmtInf").findAll("Ustrd") {
+		if c.Value != "" {
+			remittance = append(remittance, c.Value)
+		}
+	}
+	if len(remittance) > 0 {
+		e.Description = strings.Join(remittance, " ")
+	} else if info := tx.value("AddtlTxInf"); info != "" {
+		e.Description = info
+	}
+}
+
+// camtAmount reads the Amt element below n, with its Ccy attribute.
+func camtAmount(n *node) (decimal.Decimal, common.Currency, error) {
+	amt := n.child("Amt")
+	if amt == nil {
+		return decimal.Zero, "", errors.New("entry has no amount")
+	}
+	amount, err := decimal.NewFromString(amt.Value)
+	if err != nil {
+		return decimal.Zero, "", fmt.Errorf("amount %q is not a number", amt.Value)
+	}
+	// Balances carry their sign in CdtDbtInd; entries are signed by the caller.
+	if n.Name == "Bal" && n.value("CdtDbtInd") == "DBIT" {
+		amount = amount.Neg()
+	}
+	return amount, common.Currency(strings.ToUpper(amt.Attrs["Ccy"])), nil
+}
+
+// camtDate reads the day of an ISO date or date time, such as 2025-01-31 or 2025-01-31T10:00:00+01:00.
+func camtDate(value string) (time.Time, error) {
+	if len(value) < 10 {
+		return time.Time{}, fmt.Errorf("%q is not an ISO date", value)
+	}
+	return time.Parse("2006-01-02", value[:10])
+}
+
+// parseXML builds the element tree of an XML document, naming elements without their namespace,
+// which differs between the versions of camt.053.
+func parseXML(data []byte) (*node, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	root := &node{}
+	stack := []*node{root}
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			line, _ := decoder.InputPos()
+			child := &node{Name: t.Name.Local, Line: line}
+			for _, attr := range t.Attr {
+				if child.Attrs == nil {
+					child.Attrs = make(map[string]string)
+				}
+				child.Attrs[attr.Name.Local] = attr.Value
+			}
+			top := stack[len(stack)-1]
+			top.Children = append(top.Children, child)
+			stack = append(stack, child)
+		case xml.EndElement:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			if value := strings.TrimSpace(string(t)); value != "" {
+				stack[len(stack)-1].Value += value
+			}
+		}
+	}
+	return root, nil
+}
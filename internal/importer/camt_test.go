@@ -0,0 +1,146 @@
+package importer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const camt053 = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">
+<BkToCstmrStmt>
+<Stmt>
+  <Acct><Id><IBAN>DE89370400440532013000</IBAN></Id><Ccy>eur</Ccy><Nm>Girokonto</Nm></Acct>
+  <FrToDt><FrDtTm>2026-10-14T00:00:00+02:00</FrDtTm><ToDtTm>2026-10-14T23:59:59+02:00</ToDtTm></FrToDt>
+  <Bal><Tp><CdOrPrtry><Cd>OPBD</Cd></CdOrPrtry></Tp><Amt Ccy="EUR">100.00</Amt><CdtDbtInd>CRDT</CdtDbtInd><Dt><Dt>2026-10-13</Dt></Dt></Bal>
+  <Bal><Tp><CdOrPrtry><Cd>CLBD</Cd></CdOrPrtry></Tp><Amt Ccy="EUR">57.90</Amt><CdtDbtInd>CRDT</CdtDbtInd><Dt><Dt>2026-10-14</Dt></Dt></Bal>
+  <Ntry>
+    <Amt Ccy="EUR">42.10</Amt>
+    <CdtDbtInd>DBIT</CdtDbtInd>
+    <Sts>BOOK</Sts>
+    <BookgDt><Dt>2026-10-14</Dt></BookgDt>
+    <ValDt><Dt>2026-10-15</Dt></ValDt>
+    <AcctSvcrRef>BANK-1</AcctSvcrRef>
+    <NtryDtls><TxDtls>
+      <RltdPties>
+        <Dbtr><Nm>Me</Nm></Dbtr>
+        <Cdtr><Nm>Stadtwerke</Nm></Cdtr>
+        <CdtrAcct><Id><IBAN>DE02120300000000202051</IBAN></Id></CdtrAcct>
+      </RltdPties>
+      <RltdAgts><CdtrAgt><FinInstnId><BIC>BYLADEM1001</BIC></FinInstnId></CdtrAgt></RltdAgts>
+      <RmtInf><Ustrd>Strom</Ustrd><Ustrd>Oktober</Ustrd></RmtInf>
+    </TxDtls></NtryDtls>
+  </Ntry>
+</Stmt>
+<Stmt>
+  <Acct><Id><IBAN>DE89370400440532013000</IBAN></Id><Ccy>EUR</Ccy><Nm>Girokonto</Nm></Acct>
+  <FrToDt><FrDtTm>2026-10-15T00:00:00+02:00</FrDtTm><ToDtTm>2026-10-15T23:59:59+02:00</ToDtTm></FrToDt>
+  <Bal><Tp><CdOrPrtry><Cd>CLBD</Cd></CdOrPrtry></Tp><Amt Ccy="EUR">30.00</Amt><CdtDbtInd>DBIT</CdtDbtInd><Dt><Dt>2026-10-15</Dt></Dt></Bal>
+  <Ntry>
+    <Amt Ccy="EUR">87.90</Amt>
+    <CdtDbtInd>DBIT</CdtDbtInd>
+    <Sts><Cd>BOOK</Cd></Sts>
+    <BookgDt><DtTm>2026-10-15T09:00:00+02:00</DtTm></BookgDt>
+    <NtryRef>BATCH</NtryRef>
+    <AddtlNtryInf>Sammler</AddtlNtryInf>
+    <NtryDtls>
+      <TxDtls><Amt Ccy="EUR">80.00</Amt><RltdPties><Cdtr><Pty><Nm>Vermieter</Nm></Pty></Cdtr></RltdPties></TxDtls>
+      <TxDtls><AmtDtls><TxAmt><Amt Ccy="EUR">7.90</Amt></TxAmt></AmtDtls><Refs><EndToEndId>NOTPROVIDED</EndToEndId></Refs></TxDtls>
+    </NtryDtls>
+  </Ntry>
+  <Ntry>
+    <Amt Ccy="EUR">15.00</Amt>
+    <CdtDbtInd>CRDT</CdtDbtInd>
+    <Sts>PDNG</Sts>
+    <BookgDt><Dt>2026-10-15</Dt></BookgDt>
+  </Ntry>
+  <Ntry>
+    <Amt Ccy="EUR">1.00</Amt>
+    <CdtDbtInd>CRDT</CdtDbtInd>
+  </Ntry>
+</Stmt>
+</BkToCstmrStmt>
+</Document>
+`
+
+func TestReadCAMT(t *testing.T) {
+	st, err := readCAMT([]byte(camt053))
+	if err != nil {
+		t.Fatalf("readCAMT: %v", err)
+	}
+	if st.AccountNumber != "DE89370400440532013000" || st.AccountName != "Girokonto" || st.Currency != "EUR" {
+		t.Errorf("account = %s %q %s, want the IBAN, name and currency of the statements", st.AccountNumber, st.AccountName, st.Currency)
+	}
+	if st.Start == nil || st.End == nil || st.Start.Format("2006-01-02") != "2026-10-14" || st.End.Format("2006-01-02") != "2026-10-15" {
+		t.Errorf("period = %v to %v, want both statements", st.Start, st.End)
+	}
+	if st.ClosingBalance == nil || st.ClosingBalance.String() != "-30" || st.ClosingDate.Format("2006-01-02") != "2026-10-15" {
+		t.Errorf("closing balance = %v on %v, want the debit balance of the last statement", st.ClosingBalance, st.ClosingDate)
+	}
+
+	checkEntries(t, st.Entries, []string{
+		`9 2026-10-14 -42.1 EUR "Strom Oktober" "Stadtwerke"`,
+		`39 2026-10-15 -80 EUR "Sammler" "Vermieter"`,
+		`40 2026-10-15 -7.9 EUR "Sammler" ""`,
+		"43 error: entry is not booked yet (status PDNG)",
+		"49 error: entry has no booking date",
+	})
+
+	references := []string{"BANK-1", "BATCH/1", "BATCH/2"}
+	for i, want := range references {
+		if got := st.Entries[i].Reference; got != want {
+			t.Errorf("entry %d reference = %q, want %q", i, got, want)
+		}
+	}
+	first := st.Entries[0]
+	if first.CounterpartyIBAN != "DE02120300000000202051" || first.CounterpartyBIC != "BYLADEM1001" {
+		t.Errorf("counterparty = %s %s, want the creditor of the debit", first.CounterpartyIBAN, first.CounterpartyBIC)
+	}
+	if first.ValueDate.Format("2006-01-02") != "2026-10-15" {
+		t.Errorf("value date = %s, want 2026-10-15", first.ValueDate.Format("2006-01-02"))
+	}
+}
+
+func TestReadCAMTReferences(t *testing.T) {
+	// Entries without a bank reference get one from their content, numbered when two are alike.
+	data := `<Document><BkToCstmrStmt><Stmt><Acct><Id><Othr><Id>12345</Id></Othr></Id></Acct>` +
+		strings.Repeat(`<Ntry><Amt Ccy="EUR">3.00</Amt><CdtDbtInd>DBIT</CdtDbtInd><BookgDt><Dt>2026-10-14</Dt></BookgDt><AddtlNtryInf>Kaffee</AddtlNtryInf></Ntry>`, 2) +
+		`</Stmt></BkToCstmrStmt></Document>`
+	st, err := readCAMT([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.AccountNumber != "12345" {
+		t.Errorf("account = %q, want the other account ID", st.AccountNumber)
+	}
+	if len(st.Entries) != 2 || st.Entries[0].Reference == "" || st.Entries[0].Reference == st.Entries[1].Reference {
+		t.Errorf("references = %q and %q, want two distinct references", st.Entries[0].Reference, st.Entries[1].Reference)
+	}
+}
+
+func TestReadCAMTErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{name: "no statement", data: `<Document><BkToCstmrAcctRpt></BkToCstmrAcctRpt></Document>`, want: "no camt.053 statement"},
+		{
+			name: "several accounts",
+			data: `<Document><BkToCstmrStmt>` +
+				`<Stmt><Acct><Id><IBAN>DE89370400440532013000</IBAN></Id></Acct></Stmt>` +
+				`<Stmt><Acct><Id><IBAN>DE02120300000000202051</IBAN></Id></Acct></Stmt>` +
+				`</BkToCstmrStmt></Document>`,
+			want: "several accounts",
+		},
+		{name: "malformed XML", data: `<Document><Stmt></Document>`, want: "XML syntax error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readCAMT([]byte(tt.data))
+			if !errors.Is(err, ErrInvalidFile) || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("readCAMT error = %v, want ErrInvalidFile containing %q", err, tt.want)
+			}
+		})
+	}
+}
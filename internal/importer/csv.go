@@ -0,0 +1,246 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pastorenue/kinance/internal/common"
+	"github.com/shopspring/decimal"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// columns are the positions of the mapped columns of a CSV file; -1 when not mapped.
+type columns struct {
+	date, amount, debit, credit, description, merchant, currency, category int
+}
+
+// readCSV reads the statement lines of a CSV export as described by the profile.
+func readCSV(data []byte, profile *MappingProfile) ([]entry, error) {
+	text, err := decode(data, profile.Encoding)
+	if err != nil {
+		return nil, err
+	}
+	layout, err := dateLayout(profile.DateFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(bytes.NewReader(text))
+	for i := 0; i < profile.SkipRows; i++ {
+		if _, err := reader.ReadString('\n'); err != nil {
+			return nil, fmt.Errorf("%w: the file has fewer than %d lines to skip", ErrInvalidFile, profile.SkipRows)
+		}
+	}
+
+	records := csv.NewReader(reader)
+	records.Comma = []rune(profile.Delimiter)[0]
+	records.FieldsPerRecord = -1
+	records.LazyQuotes = true
+	records.TrimLeadingSpace = true
+
+	var header []string
+	if profile.HasHeader {
+		header, err = records.Read()
+		if err != nil {
+			return nil, fmt.Errorf("%w: cannot read the header row: %v", ErrInvalidFile, err)
+		}
+	}
+	cols, err := mapColumns(profile, header)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []entry
+	for {
+		record, err := records.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		line, _ := records.FieldPos(0)
+		line += profile.SkipRows
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidFile, line, err)
+		}
+		if blank(record) {
+			continue
+		}
+		if len(entries) == maxRows {
+			return nil, fmt.Errorf("%w: more than %d rows", ErrInvalidFile, maxRows)
+		}
+		entries = append(entries, csvEntry(record, line, cols, layout, profile))
+	}
+	return entries, nil
+}
+
+func csvEntry(record []string, line int, cols columns, layout string, profile *MappingProfile) entry {
+	e := entry{
+		Line:        line,
+		Description: field(record, cols.description),
+		Merchant:    field(record, cols.merchant),
+		Currency:    common.Currency(strings.ToUpper(field(record, cols.currency))),
+		Category:    field(record, cols.category),
+	}
+
+	date, err := time.Parse(layout, dateOnly(field(record, cols.date), layout))
+	if err != nil {
+		e.Err = fmt.Errorf("date %q does not match %s", field(record, cols.date), profile.DateFormat)
+		return e
+	}
+	e.Date = date
+
+	if cols.amount >= 0 {
+		amount, err := parseAmount(field(record, cols.amount), profile.DecimalComma)
+		if err != nil {
+			e.Err = err
+			return e
+		}
+		if profile.AmountSign == SignPositiveIsExpense {
+			amount = amount.Neg()
+		}
+		e.Amount = amount
+		return e
+	}
+
+	// Some banks sign the debit column, others do not; either way it is money out.
+	debit, credit := field(record, cols.debit), field(record, cols.credit)
+	switch {
+	case debit != "" && credit != "":
+		e.Err = errors.New("both the debit and the credit column are filled in")
+	case debit != "":
+		amount, err := parseAmount(debit, profile.DecimalComma)
+		e.Amount, e.Err = amount.Abs().Neg(), err
+	case credit != "":
+		amount, err := parseAmount(credit, profile.DecimalComma)
+		e.Amount, e.Err = amount.Abs(), err
+	default:
+		e.Err = errors.New("neither the debit nor the credit column is filled in")
+	}
+	return e
+}
+
+func mapColumns(profile *MappingProfile, header []string) (columns, error) {
+	var cols columns
+	var err error
+	lookup := func(name string) int {
+		if name == "" || err != nil {
+			return -1
+		}
+		var index int
+		index, err = columnIndex(name, header)
+		return index
+	}
+	cols.date = lookup(profile.DateColumn)
+	cols.amount = lookup(profile.AmountColumn)
+	cols.debit = lookup(profile.DebitColumn)
+	cols.credit = lookup(profile.CreditColumn)
+	cols.description = lookup(profile.DescriptionColumn)
+	cols.merchant = lookup(profile.MerchantColumn)
+	cols.currency = lookup(profile.CurrencyColumn)
+	cols.category = lookup(profile.CategoryColumn)
+	return cols, err
+}
+
+// columnIndex finds a column by its header, ignoring case, or by its position from 1.
+func columnIndex(name string, header []string) (int, error) {
+	for i, title := range header {
+		if strings.EqualFold(strings.TrimSpace(title), strings.TrimSpace(name)) {
+			return i, nil
+		}
+	}
+	if position, err := strconv.Atoi(name); err == nil && position > 0 {
+		return position - 1, nil
+	}
+	return -1, fmt.Errorf("%w: column %q is not in the header", ErrInvalidFile, name)
+}
+
+func field(record []string, index int) string {
+	if index < 0 || index >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[index])
+}
+
+func blank(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// dateOnly drops the time of day some banks write after the date.
+func dateOnly(value, layout string) string {
+	if len(value) > len(layout) && (value[len(layout)] == ' ' || value[len(layout)] == 'T') {
+		return value[:len(layout)]
+	}
+	return value
+}
+
+// decode converts the file to UTF-8 and drops a byte order mark.
+func decode(data []byte, encoding Encoding) ([]byte, error) {
+	switch encoding {
+	case EncodingISO88591:
+		return charmap.ISO8859_1.NewDecoder().Bytes(data)
+	case EncodingWindows1252:
+		return charmap.Windows1252.NewDecoder().Bytes(data)
+	case EncodingUTF16:
+		text, err := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewDecoder().Bytes(data)
+		if err != nil {
+			return nil, fmt.Errorf("%w: not UTF-16 text", ErrInvalidFile)
+		}
+		return text, nil
+	default:
+		return bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")), nil
+	}
+}
+
+// dateLayout turns a date format such as DD.MM.YYYY into a Go time layout.
+func dateLayout(format string) (string, error) {
+	layout := strings.NewReplacer("YYYY", "2006", "YY", "06", "MM", "01", "DD", "02").Replace(strings.ToUpper(format))
+	if !strings.Contains(layout, "06") || !strings.Contains(layout, "01") || !strings.Contains(layout, "02") {
+		return "", fmt.Errorf("%w: date format %q needs a year (YYYY or YY), a month (MM) and a day (DD)", ErrInvalidProfile, format)
+	}
+	return layout, nil
+}
+
+// parseAmount reads an amount as banks write it, such as "-1.234,56", "1,234.56", "12.30-",
+// "(12.30)", "1'234.50" or "€ 12,30".
+func parseAmount(value string, decimalComma bool) (decimal.Decimal, error) {
+	cleaned := strings.Map(func(r rune) rune {
+		switch {
+		case r >= '0' && r <= '9', r == '.', r == ',', r == '-':
+			return r
+		case r == '(':
+			return '-'
+		default:
+			return -1
+		}
+	}, value)
+
+	negative := strings.Contains(cleaned, "-")
+	cleaned = strings.ReplaceAll(cleaned, "-", "")
+	if decimalComma {
+		cleaned = strings.ReplaceAll(cleaned, ".", "")
+		cleaned = strings.ReplaceAll(cleaned, ",", ".")
+	} else {
+		cleaned = strings.ReplaceAll(cleaned, ",", "")
+	}
+
+	amount, err := decimal.NewFromString(cleaned)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("amount %q is not a number", value)
+	}
+	if negative {
+		amount = amount.Neg()
+	}
+	return amount, nil
+}
@@ -0,0 +1,219 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// describe writes the entries of an import as `line date amount currency "description" "merchant"`,
+// or "line error: ..." so that tests compare them at a glance. A missing currency is written as "-".
+func describe(entries []entry) []string {
+	lines := make([]string, len(entries))
+	for i, e := range entries {
+		if e.Err != nil {
+			lines[i] = fmt.Sprintf("%d error: %v", e.Line, e.Err)
+			continue
+		}
+		currency := string(e.Currency)
+		if currency == "" {
+			currency = "-"
+		}
+		lines[i] = fmt.Sprintf("%d %s %s %s %q %q", e.Line, e.Date.Format("2006-01-02"), e.Amount, currency, e.Description, e.Merchant)
+	}
+	return lines
+}
+
+func checkEntries(t *testing.T, entries []entry, want []string) {
+	t.Helper()
+	got := describe(entries)
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("entries:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile MappingProfile
+		data    string
+		want    []string
+	}{
+		{
+			name: "signed amounts with a header",
+			profile: MappingProfile{
+				Delimiter: ",", HasHeader: true, DateFormat: "YYYY-MM-DD",
+				DateColumn: "Date", AmountColumn: "Amount", DescriptionColumn: "Text", MerchantColumn: "payee",
+			},
+			data: "Date,Payee,Text,Amount\n" +
+				"2026-10-14,Bakery,Bread,-3.20\n" +
+				"\n" +
+				"2026-10-15,ACME Inc,Salary,\"2,500.00\"\n",
+			want: []string{
+				`2 2026-10-14 -3.2 - "Bread" "Bakery"`,
+				`4 2026-10-15 2500 - "Salary" "ACME Inc"`,
+			},
+		},
+		{
+			name: "German export with skipped lines, decimal commas and debit and credit columns",
+			profile: MappingProfile{
+				Delimiter: ";", SkipRows: 2, HasHeader: true, DateFormat: "DD.MM.YYYY", DecimalComma: true,
+				DateColumn: "Buchungstag", DebitColumn: "Soll", CreditColumn: "Haben", DescriptionColumn: "Verwendungszweck",
+				CurrencyColumn: "Währung",
+			},
+			data: "Kontonummer;DE89 3704 0044 0532 0130 00\n" +
+				"Zeitraum;01.10.2026 - 31.10.2026\n" +
+				"Buchungstag;Verwendungszweck;Soll;Haben;Währung\n" +
+				"14.10.2026;Miete;1.250,00;;eur\n" +
+				"15.10.2026 08:30;Gehalt;;3.100,50;EUR\n" +
+				"16.10.2026;Storno;-12,00;;EUR\n" +
+				"17.10.2026;Beides;1,00;1,00;EUR\n" +
+				"18.10.2026;Keines;;;EUR\n",
+			want: []string{
+				`4 2026-10-14 -1250 EUR "Miete" ""`,
+				`5 2026-10-15 3100.5 EUR "Gehalt" ""`,
+				`6 2026-10-16 -12 EUR "Storno" ""`,
+				"7 error: both the debit and the credit column are filled in",
+				"8 error: neither the debit nor the credit column is filled in",
+			},
+		},
+		{
+			name: "credit card export without a header",
+			profile: MappingProfile{
+				Delimiter: "\t", DateFormat: "MM/DD/YY", AmountSign: SignPositiveIsExpense,
+				DateColumn: "1", AmountColumn: "3", DescriptionColumn: "2",
+			},
+			data: "10/14/26\tCoffee\t4.50\n" +
+				"10/15/26\tRefund\t(20.00)\n" +
+				"31/10/26\tWrong date\t1.00\n" +
+				"10/16/26\tNo amount\tn/a\n",
+			want: []string{
+				`1 2026-10-14 -4.5 - "Coffee" ""`,
+				`2 2026-10-15 20 - "Refund" ""`,
+				`3 error: date "31/10/26" does not match MM/DD/YY`,
+				`4 error: amount "n/a" is not a number`,
+			},
+		},
+		{
+			name: "Windows-1252",
+			profile: MappingProfile{
+				Delimiter: ";", Encoding: EncodingWindows1252, HasHeader: true, DateFormat: "DD.MM.YYYY", DecimalComma: true,
+				DateColumn: "Datum", AmountColumn: "Betrag", DescriptionColumn: "Text",
+			},
+			data: "Datum;Text;Betrag\n" +
+				"14.10.2026;Caf\xe9 M\xfcller;-3,80\n",
+			want: []string{`2 2026-10-14 -3.8 - "Café Müller" ""`},
+		},
+		{
+			name: "UTF-8 byte order mark before the header",
+			profile: MappingProfile{
+				Delimiter: ",", HasHeader: true, DateFormat: "YYYY-MM-DD",
+				DateColumn: "date", AmountColumn: "amount",
+			},
+			data: "\xef\xbb\xbfdate,amount\n2026-10-14,1\n",
+			want: []string{`2 2026-10-14 1 - "" ""`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := readCSV([]byte(tt.data), &tt.profile)
+			if err != nil {
+				t.Fatalf("readCSV: %v", err)
+			}
+			checkEntries(t, entries, tt.want)
+		})
+	}
+}
+
+func TestReadCSVErrors(t *testing.T) {
+	profile := MappingProfile{Delimiter: ",", HasHeader: true, DateFormat: "YYYY-MM-DD", DateColumn: "Date", AmountColumn: "Amount"}
+	tests := []struct {
+		name    string
+		profile MappingProfile
+		data    string
+		wantErr error
+		want    string
+	}{
+		{name: "missing column", profile: profile, data: "Date,Value\n2026-10-14,1\n", wantErr: ErrInvalidFile, want: `column "Amount" is not in the header`},
+		{name: "too few lines to skip", profile: MappingProfile{Delimiter: ",", SkipRows: 3, DateFormat: "YYYY-MM-DD"}, data: "a\nb\n", wantErr: ErrInvalidFile, want: "fewer than 3 lines"},
+		{name: "empty file with a header", profile: profile, data: "", wantErr: ErrInvalidFile, want: "cannot read the header row"},
+		{name: "date format without a day", profile: MappingProfile{Delimiter: ",", DateFormat: "MM/YYYY"}, data: "", wantErr: ErrInvalidProfile, want: "needs a year"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readCSV([]byte(tt.data), &tt.profile)
+			if !errors.Is(err, tt.wantErr) || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("readCSV error = %v, want %v containing %q", err, tt.wantErr, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		value        string
+		decimalComma bool
+		want         string
+		wantErr      bool
+	}{
+		{value: "12.30", want: "12.3"},
+		{value: "-1,234.56", want: "-1234.56"},
+		{value: "1.234,56", decimalComma: true, want: "1234.56"},
+		{value: "-1.234,56", decimalComma: true, want: "-1234.56"},
+		{value: "12.30-", want: "-12.3"},
+		{value: "(12.30)", want: "-12.3"},
+		{value: "1'234.50", want: "1234.5"},
+		{value: "€ 12,30", decimalComma: true, want: "12.3"},
+		{value: "USD 7", want: "7"},
+		{value: "", wantErr: true},
+		{value: "n/a", wantErr: true},
+		{value: "1.2.3", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseAmount(tt.value, tt.decimalComma)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseAmount(%q) = %s, want an error", tt.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("parseAmount(%q) = %s, want %s", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDateLayout(t *testing.T) {
+	tests := []struct {
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{format: "YYYY-MM-DD", want: "2006-01-02"},
+		{format: "DD.MM.YYYY", want: "02.01.2006"},
+		{format: "mm/dd/yy", want: "01/02/06"},
+		{format: "YYYYMMDD", want: "20060102"},
+		{format: "MM/YYYY", wantErr: true},
+		{format: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			got, err := dateLayout(tt.format)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidProfile) {
+					t.Fatalf("dateLayout(%q) error = %v, want ErrInvalidProfile", tt.format, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("dateLayout(%q) = %q, %v, want %q", tt.format, got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,322 @@
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+	"github.com/pastorenue/kinance/internal/account"
+	"github.com/pastorenue/kinance/internal/common"
+	"github.com/pastorenue/kinance/pkg/middleware"
+	"github.com/pastorenue/kinance/pkg/pagination"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) CreateProfile(c *gin.Context) {
+	userID, _ := c.Get(middleware.UserIDKey)
+
+	var req MappingProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeBadRequest(c, err.Error())
+		return
+	}
+
+	profile, err := h.service.CreateProfile(c.Request.Context(), userID.(uuid.UUID), &req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, common.APIResponse{
+		Success:    true,
+		StatusCode: http.StatusCreated,
+		Data:       profile,
+	})
+}
+
+func (h *Handler) GetProfiles(c *gin.Context) {
+	userID, _ := c.Get(middleware.UserIDKey)
+
+	var params pagination.Params
+	if err := c.ShouldBindQuery(&params); err != nil {
+		writeBadRequest(c, err.Error())
+		return
+	}
+
+	profiles, err := h.service.GetProfiles(c.Request.Context(), userID.(uuid.UUID), params)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, common.APIResponse{
+		Success:    true,
+		StatusCode: http.StatusOK,
+		Data:       profiles,
+	})
+}
+
+func (h *Handler) GetProfile(c *gin.Context) {
+	userID, _ := c.Get(middleware.UserIDKey)
+	profileID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		writeBadRequest(c, "Invalid profile ID")
+		return
+	}
+
+	profile, err := h.service.GetProfile(c.Request.Context(), userID.(uuid.UUID), profileID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, common.APIResponse{
+		Success:    true,
+		StatusCode: http.StatusOK,
+		Data:       profile,
+	})
+}
+
+func (h *Handler) UpdateProfile(c *gin.Context) {
+	userID, _ := c.Get(middleware.UserIDKey)
+	profileID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		writeBadRequest(c, "Invalid profile ID")
+		return
+	}
+
+	var req MappingProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeBadRequest(c, err.Error())
+		return
+	}
+
+	profile, err := h.service.UpdateProfile(c.Request.Context(), userID.(uuid.UUID), profileID, &req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, common.APIResponse{
+		Success:    true,
+		StatusCode: http.StatusOK,
+		Data:       profile,
+	})
+}
+
+func (h *Handler) DeleteProfile(c *gin.Context) {
+	userID, _ := c.Get(middleware.UserIDKey)
+	profileID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		writeBadRequest(c, "Invalid profile ID")
+		return
+	}
+
+	if err := h.service.DeleteProfile(c.Request.Context(), userID.(uuid.UUID), profileID); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// PreviewCSV reads an uploaded CSV export and returns the rows an import would create.
+func (h *Handler) PreviewCSV(c *gin.Context) {
+	h.csv(c, h.service.PreviewCSV)
+}
+
+// ImportCSV imports an uploaded CSV export.
+func (h *Handler) ImportCSV(c *gin.Context) {
+	h.csv(c, h.service.ImportCSV)
+}
+
+// csv reads the multipart form of a CSV import: the file, and either the profile_id of a saved
+// profile or a profile as JSON. An account_id overrides the account of the profile.
+func (h *Handler) csv(c *gin.Context, run func(ctx context.Context, userID uuid.UUID, file string, data []byte, profile *MappingProfile, accountID *uuid.UUID) (*Result, error)) {
+	userID, _ := c.Get(middleware.UserIDKey)
+
+	name, data, ok := readFile(c)
+	if !ok {
+		return
+	}
+
+	var profileID *uuid.UUID
+	if value := c.PostForm("profile_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			writeBadRequest(c, "Invalid profile_id")
+			return
+		}
+		profileID = &id
+	}
+	var inline *MappingProfileRequest
+	if value := c.PostForm("profile"); value != "" && profileID == nil {
+		inline = &MappingProfileRequest{}
+		if err := json.Unmarshal([]byte(value), inline); err != nil {
+			writeBadRequest(c, "Invalid profile: "+err.Error())
+			return
+		}
+		if err := binding.Validator.ValidateStruct(inline); err != nil {
+			writeBadRequest(c, "Invalid profile: "+err.Error())
+			return
+		}
+	}
+	accountID, ok := formAccountID(c)
+	if !ok {
+		return
+	}
+
+	profile, err := h.service.ResolveProfile(c.Request.Context(), userID.(uuid.UUID), profileID, inline)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	result, err := run(c.Request.Context(), userID.(uuid.UUID), name, data, profile, accountID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, common.APIResponse{
+		Success:    true,
+		StatusCode: http.StatusOK,
+		Data:       result,
+	})
+}
+
+// PreviewStatement reads an uploaded statement file and returns the rows an import would create.
+func (h *Handler) PreviewStatement(c *gin.Context) {
+	h.statement(c, h.service.PreviewStatement)
+}
+
+// ImportStatement imports an uploaded statement file.
+func (h *Handler) ImportStatement(c *gin.Context) {
+	h.statement(c, h.service.ImportStatement)
+}
+
+// statement reads the multipart form of a statement import: the file, the categories for lines
+// without a matching one, and optionally the account, currency and, for QIF, the date order.
+func (h *Handler) statement(c *gin.Context, run func(ctx context.Context, userID uuid.UUID, file string, data []byte, opts *StatementOptions) (*Result, error)) {
+	userID, _ := c.Get(middleware.UserIDKey)
+
+	name, data, ok := readFile(c)
+	if !ok {
+		return
+	}
+
+	opts := &StatementOptions{
+		Currency:  common.Currency(strings.ToUpper(c.PostForm("currency"))),
+		DateOrder: DateOrder(strings.ToLower(c.PostForm("date_order"))),
+	}
+	if opts.AccountID, ok = formAccountID(c); !ok {
+		return
+	}
+	var err error
+	if opts.ExpenseCategoryID, err = uuid.Parse(c.PostForm("expense_category_id")); err != nil {
+		writeBadRequest(c, "Invalid expense_category_id")
+		return
+	}
+	if opts.IncomeCategoryID, err = uuid.Parse(c.PostForm("income_category_id")); err != nil {
+		writeBadRequest(c, "Invalid income_category_id")
+		return
+	}
+	if opts.Currency != "" && !opts.Currency.IsValid() {
+		writeBadRequest(c, "Invalid currency")
+		return
+	}
+	switch opts.DateOrder {
+	case "", OrderMDY, OrderDMY, OrderYMD:
+	default:
+		writeBadRequest(c, "date_order must be mdy, dmy or ymd")
+		return
+	}
+	if value := c.PostForm("decimal_comma"); value != "" {
+		if opts.DecimalComma, err = strconv.ParseBool(value); err != nil {
+			writeBadRequest(c, "Invalid decimal_comma")
+			return
+		}
+	}
+
+	result, err := run(c.Request.Context(), userID.(uuid.UUID), name, data, opts)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, common.APIResponse{
+		Success:    true,
+		StatusCode: http.StatusOK,
+		Data:       result,
+	})
+}
+
+// readFile reads the file field of a multipart upload.
+func readFile(c *gin.Context) (string, []byte, bool) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxFileSize)
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		writeBadRequest(c, "Missing file, or larger than 10 MB")
+		return "", nil, false
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		writeBadRequest(c, "Cannot read file")
+		return "", nil, false
+	}
+	return header.Filename, data, true
+}
+
+func formAccountID(c *gin.Context) (*uuid.UUID, bool) {
+	value := c.PostForm("account_id")
+	if value == "" {
+		return nil, true
+	}
+	id, err := uuid.Parse(value)
+	if err != nil {
+		writeBadRequest(c, "Invalid account_id")
+		return nil, false
+	}
+	return &id, true
+}
+
+func writeBadRequest(c *gin.Context, message string) {
+	c.JSON(http.StatusBadRequest, common.APIResponse{
+		Success:    false,
+		StatusCode: http.StatusBadRequest,
+		Error:      message,
+	})
+}
+
+func writeError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrInvalidFile), errors.Is(err, ErrInvalidProfile), errors.Is(err, pagination.ErrInvalid):
+		status = http.StatusBadRequest
+	case errors.Is(err, ErrProfileNotFound), errors.Is(err, account.ErrAccountNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrDuplicateProfile):
+		status = http.StatusConflict
+	}
+	c.JSON(status, common.APIResponse{
+		Success:    false,
+		StatusCode: status,
+		Error:      err.Error(),
+	})
+}
@@ -0,0 +1,143 @@
+package importer
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pastorenue/kinance/internal/common"
+	"github.com/pastorenue/kinance/internal/transaction"
+	"github.com/shopspring/decimal"
+)
+
+type Format string
+
+const (
+	FormatCSV   Format = "csv"
+	FormatOFX   Format = "ofx" // OFX 1.x (SGML) and 2.x (XML)
+	FormatQFX   Format = "qfx" // OFX as exported for Quicken
+	FormatQIF   Format = "qif"
+	FormatCAMT  Format = "camt.053" // ISO 20022 bank to customer statement
+	FormatMT940 Format = "mt940"    // SWIFT customer statement
+)
+
+type AmountSign string
+
+const (
+	SignNegativeIsExpense AmountSign = "negative_is_expense" // Most bank accounts: money out is negative
+	SignPositiveIsExpense AmountSign = "positive_is_expense" // Most credit cards: charges are positive
+)
+
+type Encoding string
+
+const (
+	EncodingUTF8        Encoding = "utf-8"
+	EncodingUTF16       Encoding = "utf-16" // With a byte order mark; little endian without one
+	EncodingISO88591    Encoding = "iso-8859-1"
+	EncodingWindows1252 Encoding = "windows-1252"
+)
+
+// MappingProfile describes the CSV export of a bank: which columns hold what, and how dates and
+// amounts are written. Columns are named by their header, or by their position from 1 in files
+// without a header row.
+type MappingProfile struct {
+	common.BaseModel
+	UserID            uuid.UUID       `json:"user_id" gorm:"not null;uniqueIndex:idx_mapping_profile_user_name"`
+	Name              string          `json:"name" gorm:"not null;uniqueIndex:idx_mapping_profile_user_name"`
+	Bank              string          `json:"bank"`
+	Delimiter         string          `json:"delimiter" gorm:"type:varchar(1)"`
+	Encoding          Encoding        `json:"encoding" gorm:"type:varchar(20)"`
+	SkipRows          int             `json:"skip_rows"` // Lines before the header, such as account details
+	HasHeader         bool            `json:"has_header"`
+	DateColumn        string          `json:"date_column" gorm:"not null"`
+	DateFormat        string          `json:"date_format" gorm:"not null"` // e.g. DD.MM.YYYY or MM/DD/YY
+	AmountColumn      string          `json:"amount_column"`               // Signed amounts; or use debit and credit columns
+	DebitColumn       string          `json:"debit_column"`                // Money out
+	CreditColumn      string          `json:"credit_column"`               // Money in
+	AmountSign        AmountSign      `json:"amount_sign" gorm:"type:varchar(20)"`
+	DecimalComma      bool            `json:"decimal_comma"` // 1.234,56 rather than 1,234.56
+	DescriptionColumn string          `json:"description_column"`
+	MerchantColumn    string          `json:"merchant_column"`
+	CurrencyColumn    string          `json:"currency_column"`
+	CategoryColumn    string          `json:"category_column"`                 // Matched against category names
+	Currency          common.Currency `json:"currency" gorm:"type:varchar(3)"` // Without a currency column; defaults to the currency of the account
+	AccountID         *uuid.UUID      `json:"account_id" gorm:"type:uuid"`
+	ExpenseCategoryID uuid.UUID       `json:"expense_category_id" gorm:"type:uuid;not null"` // For rows without a matching category
+	IncomeCategoryID  uuid.UUID       `json:"income_category_id" gorm:"type:uuid;not null"`
+}
+
+type MappingProfileRequest struct {
+	Name              string          `json:"name" binding:"required"`
+	Bank              string          `json:"bank"`
+	Delimiter         string          `json:"delimiter" binding:"omitempty,len=1"` // Defaults to a comma; "\t" for tabs
+	Encoding          Encoding        `json:"encoding" binding:"omitempty,oneof=utf-8 utf-16 iso-8859-1 windows-1252"`
+	SkipRows          int             `json:"skip_rows" binding:"min=0,max=50"`
+	HasHeader         *bool           `json:"has_header"` // Defaults to true
+	DateColumn        string          `json:"date_column" binding:"required"`
+	DateFormat        string          `json:"date_format"` // Defaults to YYYY-MM-DD
+	AmountColumn      string          `json:"amount_column"`
+	DebitColumn       string          `json:"debit_column"`
+	CreditColumn      string          `json:"credit_column"`
+	AmountSign        AmountSign      `json:"amount_sign" binding:"omitempty,oneof=negative_is_expense positive_is_expense"`
+	DecimalComma      bool            `json:"decimal_comma"`
+	DescriptionColumn string          `json:"description_column"`
+	MerchantColumn    string          `json:"merchant_column"`
+	CurrencyColumn    string          `json:"currency_column"`
+	CategoryColumn    string          `json:"category_column"`
+	Currency          common.Currency `json:"currency" binding:"omitempty,currency"`
+	AccountID         *uuid.UUID      `json:"account_id"`
+	ExpenseCategoryID uuid.UUID       `json:"expense_category_id" binding:"required"`
+	IncomeCategoryID  uuid.UUID       `json:"income_category_id" binding:"required"`
+}
+
+// Statement records an imported file: the account it was for, the period it covers and the
+// closing balance the bank reported, to reconcile the account against.
+type Statement struct {
+	common.BaseModel
+	UserID         uuid.UUID        `json:"user_id" gorm:"not null;index"`
+	AccountID      *uuid.UUID       `json:"account_id" gorm:"type:uuid;index"`
+	Format         Format           `json:"format" gorm:"type:varchar(10);not null"`
+	File           string           `json:"file"`
+	AccountNumber  string           `json:"account_number"` // As written in the file
+	Currency       common.Currency  `json:"currency" gorm:"type:varchar(3)"`
+	StartDate      *time.Time       `json:"start_date"`
+	EndDate        *time.Time       `json:"end_date"`
+	ClosingBalance *decimal.Decimal `json:"closing_balance" gorm:"type:decimal(20,4)"` // Unset when the file has none, as in CSV and QIF
+	ClosingDate    *time.Time       `json:"closing_date"`
+	Imported       int              `json:"imported"`
+}
+
+// Row is a statement line read from an import, with the transaction it becomes.
+type Row struct {
+	Line             int                         `json:"line"`                 // Line of the file the row starts on
+	Date             *time.Time                  `json:"date,omitempty"`       // Booking date
+	ValueDate        *time.Time                  `json:"value_date,omitempty"` // When the money moved, if the file says and it differs
+	Type             transaction.TransactionType `json:"type,omitempty"`
+	Amount           decimal.Decimal             `json:"amount"` // Always positive; Type tells the direction
+	Currency         common.Currency             `json:"currency,omitempty"`
+	Description      string                      `json:"description"`
+	Merchant         string                      `json:"merchant,omitempty"` // Or payer of an income
+	CounterpartyIBAN string                      `json:"counterparty_iban,omitempty"`
+	CounterpartyBIC  string                      `json:"counterparty_bic,omitempty"`
+	CategoryID       uuid.UUID                   `json:"category_id"`
+	Reference        string                      `json:"reference,omitempty"`      // ID of the line at the bank
+	Duplicate        bool                        `json:"duplicate,omitempty"`      // Already imported; skipped
+	Error            string                      `json:"error,omitempty"`          // Why the row cannot be imported
+	TransactionID    *uuid.UUID                  `json:"transaction_id,omitempty"` // Set once imported
+}
+
+// Result reports an import, or what an import would do in a preview.
+type Result struct {
+	Format         Format           `json:"format"`
+	File           string           `json:"file"`
+	AccountNumber  string           `json:"account_number,omitempty"` // As written in the file
+	AccountID      *uuid.UUID       `json:"account_id,omitempty"`     // The account the rows go to
+	Currency       common.Currency  `json:"currency"`
+	ClosingBalance *decimal.Decimal `json:"closing_balance,omitempty"`
+	ClosingDate    *time.Time       `json:"closing_date,omitempty"`
+	StatementID    *uuid.UUID       `json:"statement_id,omitempty"` // Set once imported
+	Rows           []Row            `json:"rows"`
+	Valid          int              `json:"valid"`      // Rows that can be imported
+	Invalid        int              `json:"invalid"`    // Rows with an error
+	Duplicates     int              `json:"duplicates"` // Rows imported before
+	Imported       int              `json:"imported"`   // Rows imported; zero in a preview
+}
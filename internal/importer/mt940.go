@@ -0,0 +1,311 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pastorenue/kinance/internal/common"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	// mt940Tag starts a field, such as :61: or :60F:.
+	mt940Tag = regexp.MustCompile(`^:(\d{2}[A-Z]?):`)
+	// mt940Line is the statement line of field 61: value date, booking date, debit or credit mark,
+	// funds code, amount, transaction type and the references of the customer and the bank.
+	mt940Line = regexp.MustCompile(`^(\d{6})(\d{4})?(R?[CD])([A-Z])?(\d+,\d*)([A-Z][A-Z0-9]{3})(.*)$`)
+	// mt940Balance is an opening or closing balance: mark, date, currency and amount.
+	mt940Balance = regexp.MustCompile(`^([CD])(\d{6})([A-Z]{3})(\d+,\d*)`)
+	// mt940Keys are the codes of the structured narratives of SWIFT and many Dutch and Belgian banks.
+	mt940Keys = regexp.MustCompile(`/(TRTP|IBAN|BIC|NAME|REMI|EREF|ORDP|BENM|CNTP|CSID|MARF|PREF|ADDR|ISDT|RTRN|SVCL|ULTB|ULTD|PURP|ID)/`)
+	// sepaTags are the parts of the purpose of SEPA payments in German statements.
+	sepaTags = regexp.MustCompile(`(EREF|KREF|MREF|CRED|DEBT|SVWZ|ABWA|ABWE)\+`)
+)
+
+type mt940Field struct {
+	Tag   string
+	Value string
+	Line  int
+}
+
+// readMT940 reads a SWIFT MT940 customer statement. A file may hold several statements, such as
+// one a day, as long as they are all for the same account.
+func readMT940(data []byte) (*statement, error) {
+	if !utf8.Valid(data) {
+		text, err := decode(data, EncodingWindows1252)
+		if err != nil {
+			return nil, err
+		}
+		data = text
+	}
+	fields, err := mt940Fields(data)
+	if err != nil {
+		return nil, err
+	}
+
+	st := &statement{}
+	references := make(map[string]int)
+	var current *entry
+	flush := func() error {
+		if current == nil {
+			return nil
+		}
+		if len(st.Entries) == maxRows {
+			return fmt.Errorf("%w: more than %d statement lines", ErrInvalidFile, maxRows)
+		}
+		if current.Reference == "" && current.Err == nil {
+			current.Reference = contentReference("mt940", *current)
+		}
+		current.Reference = uniqueReference(current.Reference, references)
+		st.Entries = append(st.Entries, *current)
+		current = nil
+		return nil
+	}
+
+	for _, f := range fields {
+		switch f.Tag {
+		case "25":
+			if st.AccountNumber != "" && f.Value != st.AccountNumber {
+				return nil, fmt.Errorf("%w: the file holds statements of several accounts; export one account at a time", ErrInvalidFile)
+			}
+			st.AccountNumber = f.Value
+		case "60F", "60M":
+			if _, currency, date, err := mt940ReadBalance(f.Value); err == nil {
+				st.Currency = currency
+				if st.Start == nil || date.Before(*st.Start) {
+					st.Start = &date
+				}
+			}
+		case "61":
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			e := mt940Entry(f)
+			e.Currency = st.Currency
+			current = &e
+		case "86":
+			if current != nil {
+				mt940Narrative(current, f.Value)
+			}
+		case "62F", "62M":
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			amount, currency, date, err := mt940ReadBalance(f.Value)
+			if err != nil || (st.ClosingDate != nil && date.Before(*st.ClosingDate)) {
+				continue
+			}
+			st.ClosingBalance, st.ClosingDate, st.End = &amount, &date, &date
+			st.Currency = currency
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	if st.AccountNumber == "" {
+		return nil, fmt.Errorf("%w: no :25: account field", ErrInvalidFile)
+	}
+	return st, nil
+}
+
+// mt940Fields splits the file into its fields, joining the lines a field continues on. The
+// envelope of SWIFT messages, {1:...}{2:...}{4:, and the - ending each statement are dropped.
+func mt940Fields(data []byte) ([]mt940Field, error) {
+	var fields []mt940Field
+	scanner := bufio.NewScanner(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r ")
+		if i := strings.LastIndex(text, "{4:"); i >= 0 {
+			text = text[i+3:]
+		}
+		if text == "" || text == "-" || text == "-}" || strings.HasPrefix(text, "{") {
+			continue
+		}
+		if m := mt940Tag.FindStringSubmatch(text); m != nil {
+			fields = append(fields, mt940Field{Tag: m[1], Value: text[len(m[0]):], Line: line})
+			continue
+		}
+		if len(fields) > 0 {
+			fields[len(fields)-1].Value += "\n" + text
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%w: no MT940 fields in the file", ErrInvalidFile)
+	}
+	return fields, nil
+}
+
+// mt940Entry reads a :61: statement line. Its first date is the value date, the optional second
+// one, without a year, the booking date.
+func mt940Entry(f mt940Field) entry {
+	e := entry{Line: f.Line}
+	first, supplementary, _ := strings.Cut(f.Value, "\n")
+	m := mt940Line.FindStringSubmatch(first)
+	if m == nil {
+		e.Err = fmt.Errorf("statement line %q cannot be read", first)
+		return e
+	}
+
+	value, err := time.Parse("060102", m[1])
+	if err != nil {
+		e.Err = fmt.Errorf("value date %q is not a date", m[1])
+		return e
+	}
+	e.ValueDate, e.Date = value, value
+	if m[2] != "" {
+		booking, err := time.Parse("20060102", fmt.Sprintf("%d%s", value.Year(), m[2]))
+		if err != nil {
+			e.Err = fmt.Errorf("booking date %q is not a date", m[2])
+			return e
+		}
+		// Around new year the booking date can fall in the year before or after the value date.
+		switch {
+		case booking.Sub(value) > 180*24*time.Hour:
+			booking = booking.AddDate(-1, 0, 0)
+		case value.Sub(booking) > 180*24*time.Hour:
+			booking = booking.AddDate(1, 0, 0)
+		}
+		e.Date = booking
+	}
+
+	amount, err := parseAmount(m[5], true)
+	if err != nil {
+		e.Err = err
+		return e
+	}
+	// D is money out; RC reverses a credit and so is money out too.
+	if m[3] == "D" || m[3] == "RC" {
+		amount = amount.Neg()
+	}
+	e.Amount = amount
+
+	customer, bank, _ := strings.Cut(m[7], "//")
+	e.Reference = strings.TrimSpace(bank)
+	if e.Reference == "" && !strings.EqualFold(strings.TrimSpace(customer), "NONREF") {
+		e.Reference = strings.TrimSpace(customer)
+	}
+	e.Description = strings.TrimSpace(supplementary)
+	return e
+}
+
+// mt940Narrative reads the :86: information of a statement line. Banks structure it either with
+// ?nn subfields, as German banks do, or with /CODE/ keys; others write free text.
+func mt940Narrative(e *entry, value string) {
+	text := strings.ReplaceAll(value, "\n", "")
+	switch {
+	case len(text) > 4 && text[3] == '?':
+		mt940Subfields(e, text[3:])
+	case mt940Keys.MatchString(text):
+		mt940Coded(e, text)
+	default:
+		e.Description = strings.Join(strings.Fields(strings.ReplaceAll(value, "\n", " ")), " ")
+	}
+}
+
+// mt940Subfields reads ?nn subfields: ?20 to ?29 and ?60 to ?63 the purpose, ?30 the bank code or
+// BIC, ?31 the account or IBAN and ?32 and ?33 the name of the counterparty.
+func mt940Subfields(e *entry, text string) {
+	var purpose, name strings.Builder
+	for _, part := range strings.Split(text, "?")[1:] {
+		if len(part) < 2 {
+			continue
+		}
+		code, content := part[:2], part[2:]
+		switch {
+		case code >= "20" && code <= "29", code >= "60" && code <= "63":
+			purpose.WriteString(content)
+		case code == "30":
+			e.CounterpartyBIC = strings.TrimSpace(content)
+		case code == "31":
+			e.CounterpartyIBAN = strings.TrimSpace(content)
+		case code == "32", code == "33":
+			name.WriteString(content)
+		}
+	}
+	if name.Len() > 0 {
+		e.Merchant = strings.TrimSpace(name.String())
+	}
+	// SEPA payments tag their parts, such as EREF+ for the end to end reference; the remittance
+	// information follows SVWZ+.
+	description := purpose.String()
+	if m := sepaTags.FindAllStringSubmatchIndex(description, -1); m != nil {
+		remittance := ""
+		for i, tag := range m {
+			if description[tag[2]:tag[3]] != "SVWZ" {
+				continue
+			}
+			end := len(description)
+			if i+1 < len(m) {
+				end = m[i+1][0]
+			}
+			remittance = description[tag[1]:end]
+		}
+		description = remittance
+	}
+	if description = strings.TrimSpace(description); description != "" {
+		e.Description = description
+	}
+	if len(e.CounterpartyBIC) != 8 && len(e.CounterpartyBIC) != 11 {
+		e.CounterpartyBIC = ""
+	}
+}
+
+// mt940Coded reads /CODE/value narratives, such as /NAME/ACME BV/REMI/Invoice 12/.
+func mt940Coded(e *entry, text string) {
+	values := make(map[string]string)
+	matches := mt940Keys.FindAllStringSubmatchIndex(text, -1)
+	for i, m := range matches {
+		end := len(text)
+		if i+1 < len(matches) {
+			end = matches[i+1][0]
+		}
+		values[text[m[2]:m[3]]] = strings.Trim(text[m[1]:end], "/ ")
+	}
+
+	// CNTP holds the counterparty as account/BIC/name/city.
+	if counterparty, ok := values["CNTP"]; ok {
+		parts := strings.Split(counterparty, "/")
+		for len(parts) < 4 {
+			parts = append(parts, "")
+		}
+		e.CounterpartyIBAN, e.CounterpartyBIC, e.Merchant = parts[0], parts[1], parts[2]
+	}
+	for key, target := range map[string]*string{"NAME": &e.Merchant, "IBAN": &e.CounterpartyIBAN, "BIC": &e.CounterpartyBIC} {
+		if v := values[key]; v != "" {
+			*target = v
+		}
+	}
+	if remittance := values["REMI"]; remittance != "" {
+		// Unstructured remittance information is written as USTD//text.
+		e.Description = strings.TrimPrefix(strings.TrimPrefix(remittance, "USTD"), "//")
+	}
+}
+
+// mt940ReadBalance reads a :60: or :62: balance, such as C250131EUR1234,56.
+func mt940ReadBalance(value string) (decimal.Decimal, common.Currency, time.Time, error) {
+	m := mt940Balance.FindStringSubmatch(value)
+	if m == nil {
+		return decimal.Zero, "", time.Time{}, errors.New("balance cannot be read")
+	}
+	date, err := time.Parse("060102", m[2])
+	if err != nil {
+		return decimal.Zero, "", time.Time{}, err
+	}
+	amount, err := parseAmount(m[4], true)
+	if err != nil {
+		return decimal.Zero, "", time.Time{}, err
+	}
+	if m[1] == "D" {
+		amount = amount.Neg()
+	}
+	return amount, common.Currency(m[3]), date, nil
+}
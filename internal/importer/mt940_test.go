@@ -0,0 +1,125 @@
+package importer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// mt940 is a statement in a SWIFT envelope, written in Windows-1252 as German banks do.
+const mt940 = "{1:F01COBADEFFAXXX0000000000}{2:I940COBADEFFXXXXN}{4:\r\n" +
+	":20:STARTUMSE\r\n" +
+	":25:37040044/0532013000\r\n" +
+	":28C:00001/001\r\n" +
+	":60F:C261013EUR100,00\r\n" +
+	":61:2610141014DR42,10NMSCNONREF//BANK-1\r\n" +
+	":86:105?00SEPA-BASISLASTSCHRIFT?20EREF+ABC123?21SVWZ+Strom Oktob\r\n" +
+	"er?30BYLADEM1001?31DE02120300000000202051?32Stadtwerke M\xfcn?33chen\r\n" +
+	":61:261015C2500,00NTRFPAYROLL\r\n" +
+	":86:/TRTP/SEPA CREDIT TRANSFER/NAME/ACME BV/IBAN/NL91ABNA0417164300/REMI/USTD//Salary October/\r\n" +
+	":61:2612310102RC5,00NMSCNONREF\r\n" +
+	":86:Free text\r\n" +
+	" continued\r\n" +
+	":61:garbage\r\n" +
+	":62F:C261231EUR2552,90\r\n" +
+	"-}\r\n"
+
+func TestReadMT940(t *testing.T) {
+	st, err := readMT940([]byte(mt940))
+	if err != nil {
+		t.Fatalf("readMT940: %v", err)
+	}
+	if st.AccountNumber != "37040044/0532013000" || st.Currency != "EUR" {
+		t.Errorf("account = %s %s, want 37040044/0532013000 EUR", st.AccountNumber, st.Currency)
+	}
+	if st.Start == nil || st.End == nil || st.Start.Format("2006-01-02") != "2026-10-13" || st.End.Format("2006-01-02") != "2026-12-31" {
+		t.Errorf("period = %v to %v, want the dates of the opening and closing balance", st.Start, st.End)
+	}
+	if st.ClosingBalance == nil || st.ClosingBalance.String() != "2552.9" {
+		t.Errorf("closing balance = %v, want 2552.9", st.ClosingBalance)
+	}
+
+	checkEntries(t, st.Entries, []string{
+		`6 2026-10-14 -42.1 EUR "Strom Oktober" "Stadtwerke München"`,
+		`9 2026-10-15 2500 EUR "Salary October" "ACME BV"`,
+		`11 2027-01-02 -5 EUR "Free text continued" ""`,
+		`14 error: statement line "garbage" cannot be read`,
+	})
+
+	tests := []struct {
+		reference, iban, bic string
+	}{
+		{reference: "BANK-1", iban: "DE02120300000000202051", bic: "BYLADEM1001"},
+		{reference: "PAYROLL", iban: "NL91ABNA0417164300"},
+		{reference: "mt940:"},
+	}
+	for i, tt := range tests {
+		e := st.Entries[i]
+		if !strings.HasPrefix(e.Reference, tt.reference) || e.CounterpartyIBAN != tt.iban || e.CounterpartyBIC != tt.bic {
+			t.Errorf("entry %d = %s %s %s, want %s %s %s", i, e.Reference, e.CounterpartyIBAN, e.CounterpartyBIC, tt.reference, tt.iban, tt.bic)
+		}
+	}
+	if got := st.Entries[2].ValueDate.Format("2006-01-02"); got != "2026-12-31" {
+		t.Errorf("value date = %s, want 2026-12-31 before the booking date in the new year", got)
+	}
+}
+
+func TestMT940Narrative(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		description string
+		merchant    string
+		bic         string
+	}{
+		{
+			name:        "subfields without SEPA tags",
+			value:       "020?00UEBERWEISUNG?20Miete?21 Oktober?30INVALID?32Vermieter",
+			description: "Miete Oktober", merchant: "Vermieter",
+		},
+		{
+			name:        "subfields with a purpose after SVWZ",
+			value:       "166?00GUTSCHRIFT?20EREF+X1?21SVWZ+Rechnung 7?22ABWA+Jemand?30COBADEFF?32Kunde",
+			description: "Rechnung 7", merchant: "Kunde", bic: "COBADEFF",
+		},
+		{
+			name:        "counterparty of a coded narrative",
+			value:       "/CNTP/NL91ABNA0417164300/ABNANL2A/ACME BV/AMSTERDAM/REMI/Invoice 12/",
+			description: "Invoice 12", merchant: "ACME BV", bic: "ABNANL2A",
+		},
+		{
+			name:        "free text over several lines",
+			value:       "Card payment\nBakery  Main St",
+			description: "Card payment Bakery Main St",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var e entry
+			mt940Narrative(&e, tt.value)
+			if e.Description != tt.description || e.Merchant != tt.merchant || e.CounterpartyBIC != tt.bic {
+				t.Errorf("narrative = %q %q %q, want %q %q %q", e.Description, e.Merchant, e.CounterpartyBIC, tt.description, tt.merchant, tt.bic)
+			}
+		})
+	}
+}
+
+func TestReadMT940Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{name: "no fields", data: "Kontoauszug Oktober\n", want: "no MT940 fields"},
+		{name: "no account", data: ":20:STARTUMSE\n:60F:C261013EUR100,00\n", want: "no :25: account field"},
+		{name: "several accounts", data: ":25:37040044/0532013000\n-\n:25:37040044/0532013001\n", want: "several accounts"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readMT940([]byte(tt.data))
+			if !errors.Is(err, ErrInvalidFile) || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("readMT940 error = %v, want ErrInvalidFile containing %q", err, tt.want)
+			}
+		})
+	}
+}
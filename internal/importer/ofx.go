@@ -0,0 +1,242 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/pastorenue/kinance/internal/common"
+	"github.com/shopspring/decimal"
+)
+
+// node is an element of an OFX or camt.053 document. OFX 1.x is SGML, where elements holding a
+// value have no end tag; OFX 2.x and camt.053 are XML. All read into the same tree.
+type node struct {
+	Name     string
+	Value    string
+	Attrs    map[string]string // Only read from XML
+	Line     int
+	Children []*node
+}
+
+// readOFX reads the statement of an OFX or QFX file: its account, its transactions and the
+// closing balance the bank reported.
+func readOFX(data []byte) (*statement, error) {
+	if charset := ofxHeader(data, "CHARSET"); charset == "1252" || strings.EqualFold(charset, "ISO-8859-1") {
+		text, err := decode(data, EncodingWindows1252)
+		if err != nil {
+			return nil, err
+		}
+		data = text
+	}
+	root, err := parseOFX(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var statements []*node
+	for _, name := range []string{"STMTRS", "CCSTMTRS"} {
+		statements = append(statements, root.findAll(name)...)
+	}
+	switch len(statements) {
+	case 0:
+		return nil, fmt.Errorf("%w: no bank or credit card statement in the file", ErrInvalidFile)
+	case 1:
+	default:
+		return nil, fmt.Errorf("%w: the file holds %d statements; export one account at a time", ErrInvalidFile, len(statements))
+	}
+	rs := statements[0]
+
+	st := &statement{
+		Currency:      common.Currency(strings.ToUpper(rs.value("CURDEF"))),
+		AccountNumber: rs.child("BANKACCTFROM").value("ACCTID"),
+	}
+	if st.AccountNumber == "" {
+		st.AccountNumber = rs.child("CCACCTFROM").value("ACCTID")
+	}
+
+	list := rs.child("BANKTRANLIST")
+	if start, err := ofxDate(list.value("DTSTART")); err == nil {
+		st.Start = &start
+	}
+	if end, err := ofxDate(list.value("DTEND")); err == nil {
+		st.End = &end
+	}
+	if balance := rs.child("LEDGERBAL"); balance != nil {
+		amount, amountErr := ofxAmount(balance.value("BALAMT"))
+		date, dateErr := ofxDate(balance.value("DTASOF"))
+		if amountErr == nil && dateErr == nil {
+			st.ClosingBalance = &amount
+			st.ClosingDate = &date
+		}
+	}
+
+	for _, trn := range list.findAll("STMTTRN") {
+		if len(st.Entries) == maxRows {
+			return nil, fmt.Errorf("%w: more than %d transactions", ErrInvalidFile, maxRows)
+		}
+		st.Entries = append(st.Entries, ofxEntry(trn))
+	}
+	return st, nil
+}
+
+func ofxEntry(trn *node) entry {
+	e := entry{
+		Line:        trn.Line,
+		Reference:   trn.value("FITID"),
+		Description: trn.value("MEMO"),
+		Merchant:    trn.value("NAME"),
+		Currency:    common.Currency(strings.ToUpper(trn.child("CURRENCY").value("CURSYM"))),
+	}
+	if e.Merchant == "" {
+		e.Merchant = trn.child("PAYEE").value("NAME")
+	}
+	if e.Reference == "" {
+		e.Err = fmt.Errorf("transaction has no FITID")
+		return e
+	}
+
+	// DTUSER is when the purchase was made, DTPOSTED when the bank booked it.
+	date, err := ofxDate(trn.value("DTUSER"))
+	if err != nil {
+		date, err = ofxDate(trn.value("DTPOSTED"))
+	}
+	if err != nil {
+		e.Err = fmt.Errorf("date %q is not an OFX date", trn.value("DTPOSTED"))
+		return e
+	}
+	e.Date = date
+
+	amount, err := ofxAmount(trn.value("TRNAMT"))
+	if err != nil {
+		e.Err = err
+		return e
+	}
+	e.Amount = amount
+	return e
+}
+
+// parseOFX builds the element tree of an OFX document, skipping the SGML header of OFX 1.x and
+// the XML declarations of OFX 2.x.
+func parseOFX(data []byte) (*node, error) {
+	start := bytes.Index(bytes.ToUpper(data), []byte("<OFX>"))
+	if start < 0 {
+		return nil, fmt.Errorf("%w: no <OFX> element", ErrInvalidFile)
+	}
+	line := 1 + bytes.Count(data[:start], []byte("\n"))
+	text := string(data[start:])
+
+	root := &node{}
+	stack := []*node{root}
+	for len(text) > 0 {
+		open := strings.IndexByte(text, '<')
+		if open < 0 {
+			break
+		}
+		if value := strings.TrimSpace(html.UnescapeString(text[:open])); value != "" {
+			stack[len(stack)-1].Value = value
+		}
+		line += strings.Count(text[:open], "\n")
+		text = text[open:]
+
+		end := strings.IndexByte(text, '>')
+		if end < 0 {
+			return nil, fmt.Errorf("%w: unterminated tag on line %d", ErrInvalidFile, line)
+		}
+		tag := text[1:end]
+		text = text[end+1:]
+		if strings.HasPrefix(tag, "?") || strings.HasPrefix(tag, "!") {
+			continue
+		}
+
+		top := stack[len(stack)-1]
+		if strings.HasPrefix(tag, "/") {
+			name := strings.ToUpper(strings.TrimSpace(tag[1:]))
+			for i := len(stack) - 1; i > 0; i-- {
+				if stack[i].Name == name {
+					stack = stack[:i]
+					break
+				}
+			}
+			continue
+		}
+
+		// An element with a value and no end tag ends where the next element starts.
+		if top != root && top.Value != "" {
+			stack = stack[:len(stack)-1]
+			top = stack[len(stack)-1]
+		}
+		child := &node{Name: strings.ToUpper(strings.TrimSpace(strings.TrimSuffix(tag, "/"))), Line: line}
+		top.Children = append(top.Children, child)
+		if !strings.HasSuffix(tag, "/") {
+			stack = append(stack, child)
+		}
+	}
+	return root, nil
+}
+
+// ofxHeader returns a field of the SGML header of OFX 1.x files, such as CHARSET:1252.
+func ofxHeader(data []byte, name string) string {
+	end := bytes.IndexByte(data, '<')
+	if end < 0 {
+		end = len(data)
+	}
+	for _, line := range strings.Split(string(data[:end]), "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if ok && strings.EqualFold(key, name) {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// ofxDate reads the day of an OFX date such as 20250131, 20250131120000 or
+// 20250131120000.000[-5:EST].
+func ofxDate(value string) (time.Time, error) {
+	if len(value) < 8 {
+		return time.Time{}, fmt.Errorf("%q is not an OFX date", value)
+	}
+	return time.Parse("20060102", value[:8])
+}
+
+// ofxAmount reads an OFX amount. A few banks write a decimal comma.
+func ofxAmount(value string) (decimal.Decimal, error) {
+	return parseAmount(value, strings.Contains(value, ",") && !strings.Contains(value, "."))
+}
+
+func (n *node) child(name string) *node {
+	if n == nil {
+		return nil
+	}
+	for _, c := range n.Children {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func (n *node) value(name string) string {
+	if c := n.child(name); c != nil {
+		return c.Value
+	}
+	return ""
+}
+
+// findAll returns the elements with the name anywhere below n.
+func (n *node) findAll(name string) []*node {
+	if n == nil {
+		return nil
+	}
+	var found []*node
+	for _, c := range n.Children {
+		if c.Name == name {
+			found = append(found, c)
+			continue
+		}
+		found = append(found, c.findAll(name)...)
+	}
+	return found
+}
@@ -0,0 +1,176 @@
+package importer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const ofxSGML = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+CHARSET:1252
+
+<OFX>
+<SIGNONMSGSRSV1><SONRS><STATUS><CODE>0<SEVERITY>INFO</STATUS></SONRS></SIGNONMSGSRSV1>
+<BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<CURDEF>EUR
+<BANKACCTFROM><BANKID>37040044<ACCTID>0532013000<ACCTTYPE>CHECKING</BANKACCTFROM>
+<BANKTRANLIST>
+<DTSTART>20261001
+<DTEND>20261031
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20261015120000.000[-5:EST]
+<DTUSER>20261014
+<TRNAMT>-42,10
+<FITID>2026101401
+<NAME>Caf` + "\xe9" + ` Central
+<MEMO>Lunch &amp; coffee
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20261016
+<TRNAMT>2500.00
+<FITID>2026101601
+<PAYEE><NAME>ACME Inc</PAYEE>
+<CURRENCY><CURSYM>usd<CURRATE>1.08</CURRENCY>
+</STMTTRN>
+<STMTTRN>
+<DTPOSTED>20261017
+<TRNAMT>1.00
+</STMTTRN>
+<STMTTRN>
+<FITID>2026101801
+<DTPOSTED>soon
+<TRNAMT>1.00
+</STMTTRN>
+</BANKTRANLIST>
+<LEDGERBAL><BALAMT>1234.56<DTASOF>20261031</LEDGERBAL>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`
+
+const ofxXML = `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="220"?>
+<OFX>
+  <CREDITCARDMSGSRSV1><CCSTMTTRNRS><CCSTMTRS>
+    <CURDEF>USD</CURDEF>
+    <CCACCTFROM><ACCTID>4111 1111 1111 1111</ACCTID></CCACCTFROM>
+    <BANKTRANLIST>
+      <DTSTART>20261001</DTSTART>
+      <DTEND>20261031</DTEND>
+      <STMTTRN>
+        <TRNTYPE>DEBIT</TRNTYPE>
+        <DTPOSTED>20261012</DTPOSTED>
+        <TRNAMT>-19.99</TRNAMT>
+        <FITID>CC-1</FITID>
+        <NAME>Streaming</NAME>
+        <MEMO/>
+      </STMTTRN>
+    </BANKTRANLIST>
+  </CCSTMTRS></CCSTMTTRNRS></CREDITCARDMSGSRSV1>
+</OFX>
+`
+
+func TestReadOFX(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		account string
+		want    []string
+		closing string
+	}{
+		{
+			name:    "OFX 1.x in Windows-1252",
+			data:    ofxSGML,
+			account: "0532013000",
+			want: []string{
+				`14 2026-10-14 -42.1 - "Lunch & coffee" "Café Central"`,
+				`23 2026-10-16 2500 USD "" "ACME Inc"`,
+				"31 error: transaction has no FITID",
+				`35 error: date "soon" is not an OFX date`,
+			},
+			closing: "1234.56 2026-10-31",
+		},
+		{
+			name:    "OFX 2.x credit card statement",
+			data:    ofxXML,
+			account: "4111 1111 1111 1111",
+			want:    []string{`10 2026-10-12 -19.99 - "" "Streaming"`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st, err := readOFX([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("readOFX: %v", err)
+			}
+			if st.AccountNumber != tt.account {
+				t.Errorf("account = %q, want %q", st.AccountNumber, tt.account)
+			}
+			if st.Start == nil || st.End == nil || st.Start.Format("2006-01-02") != "2026-10-01" || st.End.Format("2006-01-02") != "2026-10-31" {
+				t.Errorf("period = %v to %v, want October 2026", st.Start, st.End)
+			}
+			closing := ""
+			if st.ClosingBalance != nil {
+				closing = st.ClosingBalance.String() + " " + st.ClosingDate.Format("2006-01-02")
+			}
+			if closing != tt.closing {
+				t.Errorf("closing balance = %q, want %q", closing, tt.closing)
+			}
+			checkEntries(t, st.Entries, tt.want)
+		})
+	}
+}
+
+func TestReadOFXErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{name: "no OFX element", data: "OFXHEADER:100\n", want: "no <OFX> element"},
+		{name: "no statement", data: "<OFX><SIGNONMSGSRSV1></SIGNONMSGSRSV1></OFX>", want: "no bank or credit card statement"},
+		{name: "several statements", data: "<OFX><STMTRS><CURDEF>EUR</STMTRS><CCSTMTRS><CURDEF>EUR</CCSTMTRS></OFX>", want: "holds 2 statements"},
+		{name: "unterminated tag", data: "<OFX>\n<STMTRS\n", want: "unterminated tag on line 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readOFX([]byte(tt.data))
+			if !errors.Is(err, ErrInvalidFile) || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("readOFX error = %v, want ErrInvalidFile containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		data string
+		want Format
+	}{
+		{name: "OFX 1.x", file: "export.txt", data: ofxSGML, want: FormatOFX},
+		{name: "OFX 2.x", file: "export.ofx", data: ofxXML, want: FormatOFX},
+		{name: "QFX by extension", file: "export.QFX", data: ofxXML, want: FormatQFX},
+		{name: "QFX by Intuit bank ID", file: "export.ofx", data: "<OFX><INTU.BID>3000</OFX>", want: FormatQFX},
+		{name: "QIF", file: "export.qif", data: "\xef\xbb\xbf!Type:Bank\nD10/14/26\n^\n", want: FormatQIF},
+		{name: "QIF account list", file: "export.qif", data: "!Account\nNChecking\n^\n", want: FormatQIF},
+		{name: "camt.053", file: "statement.xml", data: `<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">`, want: FormatCAMT},
+		{name: "MT940", file: "statement.sta", data: ":20:STARTUMSE\n:25:37040044/0532013000\n", want: FormatMT940},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := detectFormat(tt.file, []byte(tt.data))
+			if err != nil || got != tt.want {
+				t.Errorf("detectFormat = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+
+	if _, err := detectFormat("export.csv", []byte("date,amount\n")); !errors.Is(err, ErrInvalidFile) {
+		t.Errorf("detectFormat of a CSV file error = %v, want ErrInvalidFile", err)
+	}
+}
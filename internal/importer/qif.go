@@ -0,0 +1,156 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type DateOrder string
+
+const (
+	OrderMDY DateOrder = "mdy" // 01/31/25, as written by US versions of Quicken
+	OrderDMY DateOrder = "dmy" // 31/01/25
+	OrderYMD DateOrder = "ymd" // 2025-01-31
+)
+
+// readQIF reads the transactions of a QIF file. QIF has no transaction IDs, so each transaction
+// gets a reference derived from its fields, which finds it again when the file is imported twice.
+func readQIF(data []byte, order DateOrder, decimalComma bool) (*statement, error) {
+	st := &statement{}
+	seen := make(map[string]int)
+	inAccount, inTransactions := false, false
+
+	var record map[byte]string
+	var start int
+	scanner := bufio.NewScanner(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		if strings.HasPrefix(text, "!") {
+			header := strings.ToLower(strings.TrimSpace(text))
+			inAccount = header == "!account"
+			// Investment, category and memorized transaction lists are not statements.
+			inTransactions = strings.HasPrefix(header, "!type:") && !strings.HasPrefix(header, "!type:invst") &&
+				!strings.HasPrefix(header, "!type:cat") && !strings.HasPrefix(header, "!type:class") &&
+				!strings.HasPrefix(header, "!type:memorized")
+			record = nil
+			continue
+		}
+
+		if text[0] == '^' {
+			if inTransactions && record != nil {
+				if len(st.Entries) == maxRows {
+					return nil, fmt.Errorf("%w: more than %d transactions", ErrInvalidFile, maxRows)
+				}
+				st.Entries = append(st.Entries, qifEntry(record, start, order, decimalComma, seen))
+			}
+			record = nil
+			continue
+		}
+
+		if inAccount {
+			if text[0] == 'N' && st.AccountName == "" {
+				st.AccountName = strings.TrimSpace(text[1:])
+			}
+			continue
+		}
+		if !inTransactions {
+			continue
+		}
+		if record == nil {
+			record = make(map[byte]string)
+			start = line
+		}
+		// Split lines (S, E, $) repeat; the total of the transaction is enough.
+		if _, ok := record[text[0]]; !ok {
+			record[text[0]] = strings.TrimSpace(text[1:])
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	if inTransactions && record != nil {
+		st.Entries = append(st.Entries, qifEntry(record, start, order, decimalComma, seen))
+	}
+	if len(st.Entries) == 0 && st.AccountName == "" {
+		return nil, fmt.Errorf("%w: no !Type header or transactions in the file", ErrInvalidFile)
+	}
+	return st, nil
+}
+
+func qifEntry(record map[byte]string, line int, order DateOrder, decimalComma bool, seen map[string]int) entry {
+	e := entry{
+		Line:        line,
+		Merchant:    record['P'],
+		Description: record['M'],
+	}
+	// Categories in brackets are transfers to another account, not categories.
+	if category := record['L']; !strings.HasPrefix(category, "[") {
+		e.Category, _, _ = strings.Cut(category, ":")
+	}
+
+	amount := record['T']
+	if amount == "" {
+		amount = record['U']
+	}
+	key := strings.Join([]string{record['D'], amount, record['P'], record['M'], record['N']}, "\x00")
+	seen[key]++
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%d", key, seen[key])))
+	e.Reference = "qif:" + hex.EncodeToString(hash[:12])
+
+	date, err := qifDate(record['D'], order)
+	if err != nil {
+		e.Err = err
+		return e
+	}
+	e.Date = date
+
+	e.Amount, e.Err = parseAmount(amount, decimalComma)
+	return e
+}
+
+// qifDate reads the many ways QIF files write dates, such as 1/31/25, 01/31'2025, 31.01.2025 or
+// 2025-01-31, in the given order of day, month and year.
+func qifDate(value string, order DateOrder) (time.Time, error) {
+	parts := strings.FieldsFunc(value, func(r rune) bool { return !unicode.IsDigit(r) })
+	if len(parts) != 3 {
+		return time.Time{}, fmt.Errorf("date %q is not a QIF date", value)
+	}
+	numbers := make([]int, 3)
+	for i, part := range parts {
+		numbers[i], _ = strconv.Atoi(part)
+	}
+
+	var year, month, day int
+	switch order {
+	case OrderDMY:
+		day, month, year = numbers[0], numbers[1], numbers[2]
+	case OrderYMD:
+		year, month, day = numbers[0], numbers[1], numbers[2]
+	default:
+		month, day, year = numbers[0], numbers[1], numbers[2]
+	}
+	// Two-digit years, and the years Quicken writes as 0-99 after 2000 with an apostrophe.
+	if year < 100 {
+		year += 2000
+		if year > time.Now().Year()+1 {
+			year -= 100
+		}
+	}
+
+	date := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if date.Month() != time.Month(month) || date.Day() != day {
+		return time.Time{}, fmt.Errorf("date %q is not a valid %s date", value, strings.ToUpper(string(order)))
+	}
+	return date, nil
+}
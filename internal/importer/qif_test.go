@@ -0,0 +1,144 @@
+package importer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReadQIF(t *testing.T) {
+	tests := []struct {
+		name         string
+		data         string
+		order        DateOrder
+		decimalComma bool
+		accountName  string
+		want         []string
+		categories   []string
+	}{
+		{
+			name: "US bank export",
+			data: "!Account\nNChecking\nTBank\n^\n" +
+				"!Type:Bank\n" +
+				"D10/14'26\nT-1,234.56\nPLandlord\nMRent October\nLHousing:Rent\n^\n" +
+				"D10/15/26\nU2,500.00\nPACME Inc\nLSalary\n^\n" +
+				"D10/16/26\nT-100.00\nPSavings\nL[Savings Account]\n^\n" +
+				"D10/17/2026\nT-10.00\nPSplit\nLFood\nSFood:Groceries\n$-6.00\nSHousehold\n$-4.00\n^\n" +
+				"D13/01/26\nT-1.00\n^\n",
+			order:       OrderMDY,
+			accountName: "Checking",
+			want: []string{
+				`6 2026-10-14 -1234.56 - "Rent October" "Landlord"`,
+				`12 2026-10-15 2500 - "" "ACME Inc"`,
+				`17 2026-10-16 -100 - "" "Savings"`,
+				`22 2026-10-17 -10 - "" "Split"`,
+				`31 error: date "13/01/26" is not a valid MDY date`,
+			},
+			categories: []string{"Housing", "Salary", "", "Food", ""},
+		},
+		{
+			name:         "European export without a final caret",
+			data:         "!Type:CCard\r\nD31.10.2026\r\nT-12,50\r\nPBäckerei\r\n^\r\nD01.11.26\r\nT3,00\r\nMRefund\r\n",
+			order:        OrderDMY,
+			decimalComma: true,
+			want: []string{
+				`2 2026-10-31 -12.5 - "" "Bäckerei"`,
+				`6 2026-11-01 3 - "Refund" ""`,
+			},
+			categories: []string{"", ""},
+		},
+		{
+			name: "investment and category lists are skipped",
+			data: "!Type:Cat\nNFood\nE\n^\n" +
+				"!Type:Invst\nD2026-10-14\nNBuy\nT-500\n^\n" +
+				"!Type:Cash\nD2026-10-14\nT-5\nPKiosk\n^\n",
+			order:      OrderYMD,
+			want:       []string{`11 2026-10-14 -5 - "" "Kiosk"`},
+			categories: []string{""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st, err := readQIF([]byte(tt.data), tt.order, tt.decimalComma)
+			if err != nil {
+				t.Fatalf("readQIF: %v", err)
+			}
+			if st.AccountName != tt.accountName {
+				t.Errorf("account name = %q, want %q", st.AccountName, tt.accountName)
+			}
+			checkEntries(t, st.Entries, tt.want)
+			for i, e := range st.Entries {
+				if i < len(tt.categories) && e.Category != tt.categories[i] {
+					t.Errorf("entry %d category = %q, want %q", i, e.Category, tt.categories[i])
+				}
+				if !strings.HasPrefix(e.Reference, "qif:") {
+					t.Errorf("entry %d reference = %q, want a qif: reference", i, e.Reference)
+				}
+			}
+		})
+	}
+}
+
+func TestReadQIFReferences(t *testing.T) {
+	// Two identical coffees on the same day are two transactions, told apart the same way on every import.
+	data := []byte("!Type:Bank\nD10/14/26\nT-3.00\nPCafe\n^\nD10/14/26\nT-3.00\nPCafe\n^\nD10/14/26\nT-3.50\nPCafe\n^\n")
+	first, err := readQIF(data, OrderMDY, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := readQIF(data, OrderMDY, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	seen := make(map[string]bool)
+	for i, e := range first.Entries {
+		if seen[e.Reference] {
+			t.Errorf("entry %d repeats reference %s", i, e.Reference)
+		}
+		seen[e.Reference] = true
+		if again.Entries[i].Reference != e.Reference {
+			t.Errorf("entry %d reference changed between imports: %s and %s", i, e.Reference, again.Entries[i].Reference)
+		}
+	}
+}
+
+func TestReadQIFErrors(t *testing.T) {
+	_, err := readQIF([]byte("D10/14/26\nT-3.00\n^\n"), OrderMDY, false)
+	if !errors.Is(err, ErrInvalidFile) || !strings.Contains(err.Error(), "no !Type header") {
+		t.Fatalf("readQIF error = %v, want ErrInvalidFile for a file without a header", err)
+	}
+}
+
+func TestQIFDate(t *testing.T) {
+	tests := []struct {
+		value   string
+		order   DateOrder
+		want    string
+		wantErr bool
+	}{
+		{value: "1/31/25", order: OrderMDY, want: "2025-01-31"},
+		{value: "01/31'2025", order: OrderMDY, want: "2025-01-31"},
+		{value: "1/31' 5", order: OrderMDY, want: "2005-01-31"},
+		{value: "31.01.2025", order: OrderDMY, want: "2025-01-31"},
+		{value: "2025-01-31", order: OrderYMD, want: "2025-01-31"},
+		{value: "12/31/99", order: OrderMDY, want: "1999-12-31"},
+		{value: "2/29/25", order: OrderMDY, wantErr: true},
+		{value: "31/01/25", order: OrderMDY, wantErr: true},
+		{value: "Jan 31", order: OrderMDY, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := qifDate(tt.value, tt.order)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("qifDate(%q) = %s, want an error", tt.value, got.Format("2006-01-02"))
+				}
+				return
+			}
+			if err != nil || got.Format("2006-01-02") != tt.want {
+				t.Errorf("qifDate(%q) = %s, %v, want %s", tt.value, got.Format("2006-01-02"), err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package importer
+
+import "github.com/gin-gonic/gin"
+
+func RegisterRoutes(versionedGroup *gin.RouterGroup, svc *Service) {
+	importHandler := NewHandler(svc)
+	protected := versionedGroup.Group("/imports")
+	protected.POST("/profiles", importHandler.CreateProfile)
+	protected.GET("/profiles", importHandler.GetProfiles)
+	protected.GET("/profiles/:id", importHandler.GetProfile)
+	protected.PUT("/profiles/:id", importHandler.UpdateProfile)
+	protected.DELETE("/profiles/:id", importHandler.DeleteProfile)
+	protected.POST("/csv/preview", importHandler.PreviewCSV)
+	protected.POST("/csv", importHandler.ImportCSV)
+	protected.POST("/statement/preview", importHandler.PreviewStatement)
+	protected.POST("/statement", importHandler.ImportStatement)
+}
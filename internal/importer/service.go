@@ -0,0 +1,686 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pastorenue/kinance/internal/account"
+	"github.com/pastorenue/kinance/internal/category"
+	"github.com/pastorenue/kinance/internal/common"
+	"github.com/pastorenue/kinance/internal/transaction"
+	"github.com/pastorenue/kinance/pkg/pagination"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+const (
+	maxFileSize = 10 << 20
+	maxRows     = 5000
+
+	defaultDescription = "Bank import"
+)
+
+// referenceIndexes are the unique indexes on the bank references of imported transactions.
+var referenceIndexes = []string{"idx_transactions_import_reference_account", "idx_transactions_import_reference_user"}
+
+var (
+	ErrInvalidFile      = errors.New("invalid import file")
+	ErrInvalidProfile   = errors.New("invalid mapping profile")
+	ErrProfileNotFound  = errors.New("mapping profile not found")
+	ErrDuplicateProfile = errors.New("a mapping profile with this name already exists")
+)
+
+// entry is a statement line as read from a file, before it is checked and categorized.
+type entry struct {
+	Line             int
+	Date             time.Time       // Booking date, when the file has one
+	ValueDate        time.Time       // When the money moved; zero when the file does not say
+	Amount           decimal.Decimal // Negative for money out
+	Currency         common.Currency // Empty when the file does not say
+	Description      string
+	Merchant         string // Or payer, the counterparty of the line
+	CounterpartyIBAN string
+	CounterpartyBIC  string
+	Category         string // Category name, if the file has one
+	Reference        string // ID of the line at the bank, such as the OFX FITID
+	Err              error  // Why the line cannot be read
+}
+
+// statement is the content of an import file: the account it is for, if the file says, its lines
+// and the closing balance the bank reported.
+type statement struct {
+	AccountNumber  string
+	AccountName    string
+	Currency       common.Currency
+	Start, End     *time.Time
+	ClosingBalance *decimal.Decimal
+	ClosingDate    *time.Time
+	Entries        []entry
+}
+
+// StatementOptions are the settings of a statement import. Files name their account and
+// currency, so these are only needed when the file does not.
+type StatementOptions struct {
+	AccountID         *uuid.UUID      // Overrides the account found from the statement
+	Currency          common.Currency // Overrides the currency of the statement
+	ExpenseCategoryID uuid.UUID       // For lines without a matching category
+	IncomeCategoryID  uuid.UUID
+	DateOrder         DateOrder // QIF only; defaults to mdy
+	DecimalComma      bool      // QIF only
+}
+
+// Target is where the rows of an import go.
+type Target struct {
+	AccountID         *uuid.UUID
+	Currency          common.Currency // For rows that do not state one
+	ExpenseCategoryID uuid.UUID       // For rows without a matching category
+	IncomeCategoryID  uuid.UUID
+}
+
+type Service struct {
+	db           *gorm.DB
+	transactions *transaction.Service
+	logger       common.Logger
+}
+
+func NewService(db *gorm.DB, transactions *transaction.Service, logger common.Logger) *Service {
+	return &Service{db: db, transactions: transactions, logger: logger}
+}
+
+// profilePages are the orders mapping profiles can be listed in.
+var profilePages = pagination.Spec[MappingProfile]{
+	Table: "mapping_profiles",
+	Sorts: map[string]pagination.Column[MappingProfile]{
+		"name":       {Expr: "mapping_profiles.name", Value: func(p *MappingProfile) any { return p.Name }},
+		"created_at": {Expr: "mapping_profiles.created_at", Value: func(p *MappingProfile) any { return p.CreatedAt }},
+	},
+	Default: "name",
+	Order:   pagination.Asc,
+	ID:      func(p *MappingProfile) uuid.UUID { return p.ID },
+}
+
+func (s *Service) CreateProfile(ctx context.Context, userID uuid.UUID, req *MappingProfileRequest) (*MappingProfile, error) {
+	profile, err := s.buildProfile(ctx, userID, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkProfileName(ctx, userID, uuid.Nil, profile.Name); err != nil {
+		return nil, err
+	}
+	if err := s.db.WithContext(ctx).Create(profile).Error; err != nil {
+		s.logger.Error("Failed to create mapping profile", "error", err)
+		return nil, err
+	}
+	return profile, nil
+}
+
+func (s *Service) GetProfiles(ctx context.Context, userID uuid.UUID, params pagination.Params) (*pagination.Page[MappingProfile], error) {
+	return pagination.Paginate(s.db.WithContext(ctx).Where("user_id = ?", userID), profilePages, params)
+}
+
+func (s *Service) GetProfile(ctx context.Context, userID, profileID uuid.UUID) (*MappingProfile, error) {
+	var profile MappingProfile
+	if err := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", profileID, userID).First(&profile).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrProfileNotFound
+		}
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// UpdateProfile replaces the mapping of a profile.
+func (s *Service) UpdateProfile(ctx context.Context, userID, profileID uuid.UUID, req *MappingProfileRequest) (*MappingProfile, error) {
+	existing, err := s.GetProfile(ctx, userID, profileID)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := s.buildProfile(ctx, userID, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkProfileName(ctx, userID, profileID, profile.Name); err != nil {
+		return nil, err
+	}
+	profile.BaseModel = existing.BaseModel
+	if err := s.db.WithContext(ctx).Save(profile).Error; err != nil {
+		s.logger.Error("Failed to update mapping profile", "profile_id", profileID, "error", err)
+		return nil, err
+	}
+	return profile, nil
+}
+
+// ResolveProfile returns the saved profile with the ID, or else a profile built from the request
+// that is used once without being saved.
+func (s *Service) ResolveProfile(ctx context.Context, userID uuid.UUID, profileID *uuid.UUID, req *MappingProfileRequest) (*MappingProfile, error) {
+	if profileID != nil {
+		return s.GetProfile(ctx, userID, *profileID)
+	}
+	if req == nil {
+		return nil, fmt.Errorf("%w: pass a profile_id or a profile", ErrInvalidProfile)
+	}
+	return s.buildProfile(ctx, userID, req)
+}
+
+func (s *Service) DeleteProfile(ctx context.Context, userID, profileID uuid.UUID) error {
+	result := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", profileID, userID).Delete(&MappingProfile{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrProfileNotFound
+	}
+	return nil
+}
+
+// PreviewCSV reads a CSV export with the profile and reports the rows an import would create,
+// without writing anything.
+func (s *Service) PreviewCSV(ctx context.Context, userID uuid.UUID, file string, data []byte, profile *MappingProfile, accountID *uuid.UUID) (*Result, error) {
+	entries, target, err := s.readCSV(ctx, userID, data, profile, accountID)
+	if err != nil {
+		return nil, err
+	}
+	return s.preview(ctx, userID, FormatCSV, file, &statement{Entries: entries}, target)
+}
+
+// ImportCSV imports the rows of a CSV export that can be read as transactions, each with its
+// expense or income, and reports the rows that cannot.
+func (s *Service) ImportCSV(ctx context.Context, userID uuid.UUID, file string, data []byte, profile *MappingProfile, accountID *uuid.UUID) (*Result, error) {
+	entries, target, err := s.readCSV(ctx, userID, data, profile, accountID)
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.preview(ctx, userID, FormatCSV, file, &statement{Entries: entries}, target)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.commit(ctx, userID, result, target); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// PreviewStatement reads an OFX, QFX, QIF, camt.053 or MT940 file and reports the rows an import
+// would create, without writing anything.
+func (s *Service) PreviewStatement(ctx context.Context, userID uuid.UUID, file string, data []byte, opts *StatementOptions) (*Result, error) {
+	format, st, target, err := s.readStatement(ctx, userID, file, data, opts)
+	if err != nil {
+		return nil, err
+	}
+	return s.preview(ctx, userID, format, file, st, target)
+}
+
+// ImportStatement imports the lines of a statement file that were not imported before, and
+// records the statement with its closing balance.
+func (s *Service) ImportStatement(ctx context.Context, userID uuid.UUID, file string, data []byte, opts *StatementOptions) (*Result, error) {
+	format, st, target, err := s.readStatement(ctx, userID, file, data, opts)
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.preview(ctx, userID, format, file, st, target)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.commit(ctx, userID, result, target); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *Service) readCSV(ctx context.Context, userID uuid.UUID, data []byte, profile *MappingProfile, accountID *uuid.UUID) ([]entry, *Target, error) {
+	if accountID == nil {
+		accountID = profile.AccountID
+	}
+	target, err := s.target(ctx, userID, accountID, profile.Currency, profile.ExpenseCategoryID, profile.IncomeCategoryID)
+	if err != nil {
+		return nil, nil, err
+	}
+	entries, err := readCSV(data, profile)
+	if err != nil {
+		return nil, nil, err
+	}
+	return entries, target, nil
+}
+
+func (s *Service) readStatement(ctx context.Context, userID uuid.UUID, file string, data []byte, opts *StatementOptions) (Format, *statement, *Target, error) {
+	format, err := detectFormat(file, data)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	var st *statement
+	switch format {
+	case FormatQIF:
+		order := opts.DateOrder
+		if order == "" {
+			order = OrderMDY
+		}
+		st, err = readQIF(data, order, opts.DecimalComma)
+	case FormatCAMT:
+		st, err = readCAMT(data)
+	case FormatMT940:
+		st, err = readMT940(data)
+	default:
+		st, err = readOFX(data)
+	}
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	accountID := opts.AccountID
+	if accountID == nil {
+		if accountID, err = s.matchAccount(ctx, userID, st); err != nil {
+			return "", nil, nil, err
+		}
+	}
+	currency := opts.Currency
+	if currency == "" && st.Currency.IsValid() {
+		currency = st.Currency
+	}
+	target, err := s.target(ctx, userID, accountID, currency, opts.ExpenseCategoryID, opts.IncomeCategoryID)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return format, st, target, nil
+}
+
+// matchAccount finds the account of the statement among the accounts the user can use: by its
+// number, which may be the end of an IBAN as in the bank code/account of MT940, else by the last
+// four digits most statements of cards show, else, for QIF, by its name. It returns nil when no
+// single account matches.
+func (s *Service) matchAccount(ctx context.Context, userID uuid.UUID, st *statement) (*uuid.UUID, error) {
+	if st.AccountNumber == "" && st.AccountName == "" {
+		return nil, nil
+	}
+	var accounts []account.Account
+	if err := s.db.WithContext(ctx).Select("id", "name", "number").
+		Where("id IN (?) AND is_archived = ?", account.AccessibleIDs(s.db, userID), false).
+		Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+
+	number := accountNumber(st.AccountNumber)
+	var byNumber, byDigits, byName []uuid.UUID
+	for _, acc := range accounts {
+		own := accountNumber(acc.Number)
+		switch {
+		case number != "" && (own == number || len(number) >= 8 && strings.HasSuffix(own, number)):
+			byNumber = append(byNumber, acc.ID)
+		case len(number) >= 4 && len(own) >= 4 && lastDigits(own) == lastDigits(number):
+			byDigits = append(byDigits, acc.ID)
+		}
+		if st.AccountName != "" && strings.EqualFold(strings.TrimSpace(acc.Name), st.AccountName) {
+			byName = append(byName, acc.ID)
+		}
+	}
+	for _, ids := range [][]uuid.UUID{byNumber, byDigits, byName} {
+		if len(ids) == 1 {
+			return &ids[0], nil
+		}
+		if len(ids) > 1 {
+			return nil, nil
+		}
+	}
+	return nil, nil
+}
+
+// target checks that the user may import into the account and categories. Rows without a
+// currency take the one given, else the currency of the account.
+func (s *Service) target(ctx context.Context, userID uuid.UUID, accountID *uuid.UUID, currency common.Currency, expenseCategoryID, incomeCategoryID uuid.UUID) (*Target, error) {
+	target := &Target{
+		AccountID:         accountID,
+		Currency:          currency,
+		ExpenseCategoryID: expenseCategoryID,
+		IncomeCategoryID:  incomeCategoryID,
+	}
+	if accountID != nil {
+		acc, err := account.Lookup(ctx, s.db, userID, *accountID)
+		if err != nil {
+			return nil, err
+		}
+		if target.Currency == "" {
+			target.Currency = acc.Currency
+		}
+	}
+	target.Currency = target.Currency.OrDefault()
+
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&category.Category{}).
+		Where("user_id = ? AND id IN ?", userID, []uuid.UUID{expenseCategoryID, incomeCategoryID}).
+		Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 || (count == 1 && expenseCategoryID != incomeCategoryID) {
+		return nil, fmt.Errorf("%w: the expense and income categories must be categories of yours", ErrInvalidProfile)
+	}
+	return target, nil
+}
+
+// preview turns the entries into rows and checks them.
+func (s *Service) preview(ctx context.Context, userID uuid.UUID, format Format, file string, st *statement, target *Target) (*Result, error) {
+	var categories []category.Category
+	if err := s.db.WithContext(ctx).Select("id", "name").Where("user_id = ?", userID).Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	categoryIDs := make(map[string]uuid.UUID, len(categories))
+	for _, c := range categories {
+		categoryIDs[strings.ToLower(c.Name)] = c.ID
+	}
+
+	imported, err := s.importedReferences(ctx, userID, target.AccountID, st.Entries)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		Format:         format,
+		File:           file,
+		AccountNumber:  st.AccountNumber,
+		AccountID:      target.AccountID,
+		Currency:       target.Currency,
+		ClosingBalance: st.ClosingBalance,
+		ClosingDate:    st.ClosingDate,
+		Rows:           make([]Row, 0, len(st.Entries)),
+	}
+	for _, e := range st.Entries {
+		row := Row{
+			Line:        e.Line,
+			Amount:      e.Amount.Abs(),
+			Currency:    e.Currency,
+			Description: e.Description,
+			Merchant:    e.Merchant,
+			Reference:   e.Reference,
+		}
+		row.CounterpartyIBAN, row.CounterpartyBIC = e.CounterpartyIBAN, e.CounterpartyBIC
+		if row.Description == "" {
+			row.Description = e.Merchant
+		}
+		if row.Description == "" {
+			row.Description = defaultDescription
+		}
+		if row.Currency == "" {
+			row.Currency = target.Currency
+		}
+		if !e.Date.IsZero() {
+			date := e.Date
+			row.Date = &date
+		}
+		if !e.ValueDate.IsZero() && !e.ValueDate.Equal(e.Date) {
+			value := e.ValueDate
+			row.ValueDate = &value
+		}
+
+		row.Type = transaction.TypeExpense
+		row.CategoryID = target.ExpenseCategoryID
+		if e.Amount.IsPositive() {
+			row.Type = transaction.TypeIncome
+			row.CategoryID = target.IncomeCategoryID
+		}
+		if id, ok := categoryIDs[strings.ToLower(e.Category)]; ok && e.Category != "" {
+			row.CategoryID = id
+		}
+
+		switch {
+		case e.Err != nil:
+			row.Error = e.Err.Error()
+		case e.Amount.IsZero():
+			row.Error = "amount is zero"
+		case !row.Currency.IsValid():
+			row.Error = fmt.Sprintf("currency %q is not supported", row.Currency)
+		}
+		switch {
+		case row.Error != "":
+			row.Type = ""
+			result.Invalid++
+		case e.Reference != "" && imported[e.Reference]:
+			row.Duplicate = true
+			result.Duplicates++
+		default:
+			result.Valid++
+		}
+		if e.Reference != "" && row.Error == "" {
+			imported[e.Reference] = true
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	return result, nil
+}
+
+// importedReferences returns which of the references were imported into the account before, by
+// anyone sharing it, or by the user without an account.
+func (s *Service) importedReferences(ctx context.Context, userID uuid.UUID, accountID *uuid.UUID, entries []entry) (map[string]bool, error) {
+	imported := make(map[string]bool)
+	var references []string
+	for _, e := range entries {
+		if e.Reference != "" {
+			references = append(references, e.Reference)
+		}
+	}
+	if len(references) == 0 {
+		return imported, nil
+	}
+
+	scope := func(db *gorm.DB) *gorm.DB {
+		if accountID != nil {
+			return db.Where("transactions.account_id = ?", *accountID)
+		}
+		return db.Where("transactions.user_id = ? AND transactions.account_id IS NULL", userID)
+	}
+	var found []string
+	if err := s.db.WithContext(ctx).Model(&transaction.Transaction{}).Scopes(scope).
+		Where("metadata->'import'->>'reference' IN ?", references).
+		Pluck("metadata->'import'->>'reference'", &found).Error; err != nil {
+		return nil, err
+	}
+	// Duplicates merged into another transaction keep their reference on it.
+	var merged []string
+	if err := s.db.WithContext(ctx).Model(&transaction.Transaction{}).Scopes(scope).
+		Joins(fmt.Sprintf("CROSS JOIN LATERAL jsonb_array_elements_text(COALESCE(transactions.metadata->'%s', '[]')) AS merged(reference)", transaction.MergedReferencesKey)).
+		Where("merged.reference IN ?", references).
+		Pluck("merged.reference", &merged).Error; err != nil {
+		return nil, err
+	}
+	for _, reference := range append(found, merged...) {
+		imported[reference] = true
+	}
+	return imported, nil
+}
+
+// commit records the statement and creates the transaction of each valid row. Rows are imported
+// one by one, so a row that fails is reported without undoing the others.
+func (s *Service) commit(ctx context.Context, userID uuid.UUID, result *Result, target *Target) error {
+	record := &Statement{
+		UserID:         userID,
+		AccountID:      target.AccountID,
+		Format:         result.Format,
+		File:           result.File,
+		AccountNumber:  result.AccountNumber,
+		Currency:       target.Currency,
+		ClosingBalance: result.ClosingBalance,
+		ClosingDate:    result.ClosingDate,
+	}
+	for _, row := range result.Rows {
+		if row.Date == nil || row.Error != "" {
+			continue
+		}
+		if record.StartDate == nil || row.Date.Before(*record.StartDate) {
+			record.StartDate = row.Date
+		}
+		if record.EndDate == nil || row.Date.After(*record.EndDate) {
+			record.EndDate = row.Date
+		}
+	}
+	if err := s.db.WithContext(ctx).Create(record).Error; err != nil {
+		s.logger.Error("Failed to record statement", "file", result.File, "error", err)
+		return err
+	}
+	result.StatementID = &record.ID
+
+	for i := range result.Rows {
+		row := &result.Rows[i]
+		if row.Error != "" || row.Duplicate {
+			continue
+		}
+		source := map[string]interface{}{
+			"format":       result.Format,
+			"file":         result.File,
+			"line":         row.Line,
+			"statement_id": record.ID,
+			"booking_date": row.Date.Format(time.DateOnly),
+		}
+		metadata := map[string]interface{}{"import": source}
+		if row.Reference != "" {
+			source["reference"] = row.Reference
+		}
+		if row.ValueDate != nil {
+			source["value_date"] = row.ValueDate.Format(time.DateOnly)
+		}
+		if row.CounterpartyIBAN != "" || row.CounterpartyBIC != "" {
+			source["counterparty"] = map[string]interface{}{"name": row.Merchant, "iban": row.CounterpartyIBAN, "bic": row.CounterpartyBIC}
+		}
+		if result.Format == FormatOFX || result.Format == FormatQFX {
+			metadata["fitid"] = row.Reference
+		}
+		response, err := s.transactions.CreateTransaction(ctx, userID, &transaction.CreateTransactionRequest{
+			Amount:          row.Amount,
+			Description:     row.Description,
+			CategoryID:      row.CategoryID,
+			AccountID:       target.AccountID,
+			Merchant:        row.Merchant,
+			TransactionDate: *row.Date,
+			Type:            row.Type,
+			Currency:        row.Currency,
+			PaymentMethod:   common.BankTransfer,
+			Metadata:        metadata,
+		})
+		if isDuplicateReference(err) {
+			// Imported by a concurrent request since the preview.
+			row.Duplicate = true
+			result.Valid--
+			result.Duplicates++
+			continue
+		}
+		if err != nil {
+			s.logger.Error("Failed to import row", "file", result.File, "line", row.Line, "error", err)
+			row.Error = err.Error()
+			result.Valid--
+			result.Invalid++
+			continue
+		}
+		row.TransactionID = &response.Transaction.ID
+		result.Imported++
+	}
+
+	if err := s.db.WithContext(ctx).Model(record).Update("imported", result.Imported).Error; err != nil {
+		s.logger.Error("Failed to update statement", "statement_id", record.ID, "error", err)
+	}
+	s.logger.Info("Import finished", "user_id", userID, "file", result.File, "imported", result.Imported,
+		"duplicates", result.Duplicates, "invalid", result.Invalid)
+	return nil
+}
+
+// CreateIndexes makes the bank reference of imported transactions unique per account, or per user
+// for transactions without an account, so that importing a statement twice, even at the same
+// time, never creates a transaction twice.
+func CreateIndexes(db *gorm.DB) error {
+	for _, statement := range []string{
+		`CREATE UNIQUE INDEX IF NOT EXISTS ` + referenceIndexes[0] + ` ON transactions
+			(account_id, (metadata->'import'->>'reference'))
+			WHERE account_id IS NOT NULL AND metadata->'import'->>'reference' IS NOT NULL`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS ` + referenceIndexes[1] + ` ON transactions
+			(user_id, (metadata->'import'->>'reference'))
+			WHERE account_id IS NULL AND metadata->'import'->>'reference' IS NOT NULL`,
+	} {
+		if err := db.Exec(statement).Error; err != nil {
+			return fmt.Errorf("failed to index the references of imported transactions: %w", err)
+		}
+	}
+	return nil
+}
+
+// buildProfile checks a mapping and fills in its defaults.
+func (s *Service) buildProfile(ctx context.Context, userID uuid.UUID, req *MappingProfileRequest) (*MappingProfile, error) {
+	profile := &MappingProfile{
+		UserID:            userID,
+		Name:              strings.TrimSpace(req.Name),
+		Bank:              req.Bank,
+		Delimiter:         req.Delimiter,
+		Encoding:          req.Encoding,
+		SkipRows:          req.SkipRows,
+		HasHeader:         req.HasHeader == nil || *req.HasHeader,
+		DateColumn:        req.DateColumn,
+		DateFormat:        req.DateFormat,
+		AmountColumn:      req.AmountColumn,
+		DebitColumn:       req.DebitColumn,
+		CreditColumn:      req.CreditColumn,
+		AmountSign:        req.AmountSign,
+		DecimalComma:      req.DecimalComma,
+		DescriptionColumn: req.DescriptionColumn,
+		MerchantColumn:    req.MerchantColumn,
+		CurrencyColumn:    req.CurrencyColumn,
+		CategoryColumn:    req.CategoryColumn,
+		Currency:          req.Currency,
+		AccountID:         req.AccountID,
+		ExpenseCategoryID: req.ExpenseCategoryID,
+		IncomeCategoryID:  req.IncomeCategoryID,
+	}
+	if profile.Delimiter == "" {
+		profile.Delimiter = ","
+	}
+	if profile.Encoding == "" {
+		profile.Encoding = EncodingUTF8
+	}
+	if profile.DateFormat == "" {
+		profile.DateFormat = "YYYY-MM-DD"
+	}
+	if profile.AmountSign == "" {
+		profile.AmountSign = SignNegativeIsExpense
+	}
+
+	if profile.Name == "" {
+		return nil, fmt.Errorf("%w: name cannot be empty", ErrInvalidProfile)
+	}
+	if profile.Delimiter == `"` || profile.Delimiter == "\n" || profile.Delimiter == "\r" {
+		return nil, fmt.Errorf("%w: %q cannot be a delimiter", ErrInvalidProfile, profile.Delimiter)
+	}
+	if _, err := dateLayout(profile.DateFormat); err != nil {
+		return nil, err
+	}
+	hasAmount := profile.AmountColumn != ""
+	hasDebitCredit := profile.DebitColumn != "" || profile.CreditColumn != ""
+	if hasAmount == hasDebitCredit {
+		return nil, fmt.Errorf("%w: map either an amount column or debit and credit columns", ErrInvalidProfile)
+	}
+	if hasDebitCredit && (profile.DebitColumn == "" || profile.CreditColumn == "") {
+		return nil, fmt.Errorf("%w: map both the debit and the credit column", ErrInvalidProfile)
+	}
+	if !profile.HasHeader {
+		for _, column := range []string{profile.DateColumn, profile.AmountColumn, profile.DebitColumn, profile.CreditColumn,
+			profile.DescriptionColumn, profile.MerchantColumn, profile.CurrencyColumn, profile.CategoryColumn} {
+			if _, err := columnIndex(column, nil); column != "" && err != nil {
+				return nil, fmt.Errorf("%w: without a header row, columns are numbered from 1", ErrInvalidProfile)
+			}
+		}
+	}
+
+	if _, err := s.target(ctx, userID, profile.AccountID, profile.Currency, profile.ExpenseCategoryID, profile.IncomeCategoryID); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+func (s *Service) checkProfileName(ctx context.Context, userID, profileID uuid.UUID, name string) error {
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&MappingProfile{}).
+		Where("user_id = ? AND name = ? AND id <> ?", userID, name, profileID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrDuplicateProfile
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package importer
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// detectFormat tells statement files apart by their content rather than their extension, which
+// banks do not use consistently.
+func detectFormat(file string, data []byte) (Format, error) {
+	head := data
+	if len(head) > 4096 {
+		head = head[:4096]
+	}
+	head = bytes.ToUpper(bytes.TrimSpace(bytes.TrimPrefix(head, []byte("\xef\xbb\xbf"))))
+
+	switch {
+	case bytes.Contains(head, []byte("OFXHEADER")) || bytes.Contains(head, []byte("<OFX>")):
+		if bytes.Contains(bytes.ToUpper(data), []byte("<INTU.BID>")) || strings.EqualFold(filepath.Ext(file), ".qfx") {
+			return FormatQFX, nil
+		}
+		return FormatOFX, nil
+	case bytes.HasPrefix(head, []byte("!TYPE")), bytes.HasPrefix(head, []byte("!ACCOUNT")), bytes.HasPrefix(head, []byte("!OPTION")):
+		return FormatQIF, nil
+	case bytes.Contains(head, []byte("CAMT.053")) || bytes.Contains(head, []byte("<BKTOCSTMRSTMT>")):
+		return FormatCAMT, nil
+	case bytes.Contains(head, []byte(":20:")) && bytes.Contains(head, []byte(":25:")):
+		return FormatMT940, nil
+	}
+	return "", fmt.Errorf("%w: not an OFX, QFX, QIF, camt.053 or MT940 file", ErrInvalidFile)
+}
+
+// accountNumber normalizes an account number for comparison, dropping the spaces and dashes
+// banks format IBANs and card numbers with.
+func accountNumber(number string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return -1
+	}, number)
+}
+
+func lastDigits(number string) string {
+	return number[len(number)-4:]
+}
+
+// uniqueReference numbers the second and later use of a reference within a file, so that lines
+// sharing a reference are told apart the same way each time the file is imported.
+func uniqueReference(reference string, seen map[string]int) string {
+	if reference == "" {
+		return ""
+	}
+	seen[reference]++
+	if n := seen[reference]; n > 1 {
+		return fmt.Sprintf("%s#%d", reference, n)
+	}
+	return reference
+}
+
+// contentReference stands in for the bank reference of a line that has none, derived from what
+// the line says so that it comes out the same when the file is imported again.
+func contentReference(format string, e entry) string {
+	key := strings.Join([]string{e.Date.Format("2006-01-02"), e.Amount.String(), e.Merchant, e.Description, e.CounterpartyIBAN}, "\x00")
+	hash := sha256.Sum256([]byte(key))
+	return format + ":" + hex.EncodeToString(hash[:12])
+}
+
+// isDuplicateReference reports whether err is a violation of the unique bank reference indexes.
+func isDuplicateReference(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && slices.Contains(referenceIndexes, pgErr.ConstraintName)
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/pastorenue/kinance/internal/common"
 	"github.com/pastorenue/kinance/pkg/middleware"
+	"github.com/pastorenue/kinance/pkg/pagination"
 )
 
 type Handler struct {
@@ -57,17 +58,37 @@ func (h *Handler) GetIncomes(c *gin.Context) {
 		})
 		return
 	}
-	var pagination common.PaginationParams
-	if err := c.ShouldBindQuery(&pagination); err != nil {
-		pagination = common.PaginationParams{Page: 1, PageSize: 20}
+	var params pagination.Params
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, common.APIResponse{
+			Success:    false,
+			Error:      err.Error(),
+			StatusCode: http.StatusBadRequest,
+		})
+		return
 	}
 
-	result, err := h.service.GetIncomes(c.Request.Context(), userID.(uuid.UUID), &pagination)
+	filter, err := common.ParseFilter(c.Query("filter"), IncomeFilters)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, common.APIResponse{
+		c.JSON(http.StatusBadRequest, common.APIResponse{
 			Success:    false,
-			Error:      "Failed to fetch incomes",
-			StatusCode: http.StatusInternalServerError,
+			Error:      err.Error(),
+			StatusCode: http.StatusBadRequest,
+		})
+		return
+	}
+
+	result, err := h.service.GetIncomes(c.Request.Context(), userID.(uuid.UUID), filter, params)
+	if err != nil {
+		status := pagination.HTTPStatus(err)
+		message := "Failed to fetch incomes"
+		if status == http.StatusBadRequest {
+			message = err.Error()
+		}
+		c.JSON(status, common.APIResponse{
+			Success:    false,
+			Error:      message,
+			StatusCode: status,
 		})
 		return
 	}
@@ -157,13 +178,28 @@ func (h *Handler) CreateSource(c *gin.Context) {
 func (h *Handler) GetSources(c *gin.Context) {
 	userID, _ := c.Get(middleware.UserIDKey)
 
-	result, err := h.service.GetSources(c.Request.Context(), userID.(uuid.UUID))
+	var params pagination.Params
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, common.APIResponse{
+			Success:    false,
+			Error:      err.Error(),
+			StatusCode: http.StatusBadRequest,
+		})
+		return
+	}
+
+	result, err := h.service.GetSources(c.Request.Context(), userID.(uuid.UUID), params)
 	if err != nil {
 		h.service.logger.Error("Failed to fetch sources", "error", err)
-		c.JSON(http.StatusInternalServerError, common.APIResponse{
+		status := pagination.HTTPStatus(err)
+		message := "Failed to fetch sources"
+		if status == http.StatusBadRequest {
+			message = err.Error()
+		}
+		c.JSON(status, common.APIResponse{
 			Success:    false,
-			Error:      "Failed to fetch sources",
-			StatusCode: http.StatusInternalServerError,
+			Error:      message,
+			StatusCode: status,
 		})
 		return
 	}
@@ -26,11 +26,11 @@ type Source struct {
 
 type Income struct {
 	common.BaseModel
-	Amount     decimal.Decimal    `json:"amount" gorm:"type:decimal(20,8);not null"`
-	Currency   *common.Currency   `json:"currency" gorm:"not null;default:EUR"`
+	Amount     common.Money       `json:"amount" gorm:"embedded"`
 	SourceID   uuid.UUID          `json:"source" gorm:"index;not null"`
 	Source     *Source            `json:"source_details" gorm:"foreignKey:SourceID"`
 	UserID     uuid.UUID          `json:"user_id" gorm:"type:varchar(36);not null"`
+	AccountID  *uuid.UUID         `json:"account_id,omitempty" gorm:"type:uuid;index"` // Account the income was paid into
 	Status     IncomeStatus       `json:"status" gorm:"default:'pending'"`
 	Note       string             `json:"note" gorm:"type:text"`
 	Metadata   string             `json:"metadata" gorm:"type:text"`
@@ -40,7 +40,9 @@ type Income struct {
 
 type CreateIncomeRequest struct {
 	Amount     decimal.Decimal `json:"amount" binding:"required"`
+	Currency   common.Currency `json:"currency" binding:"omitempty,currency"` // Defaults to EUR
 	SwiftCode  string          `json:"swift_code" binding:"omitempty,len=8|len=11"`
+	AccountID  *uuid.UUID      `json:"account_id"`
 	Note       *string         `json:"note"`
 	CategoryID *uuid.UUID      `json:"category_id"`
 }
@@ -15,4 +15,4 @@ func RegisterRoutes(versionedGroup *gin.RouterGroup, svc *Service) {
 	protected.GET("/:id", incomeHandler.GetIncomeByID)
 	protected.PUT("/:id", incomeHandler.UpdateIncome)
 	protected.DELETE("/:id", incomeHandler.DeleteIncome)
-}
\ No newline at end of file
+}
@@ -3,10 +3,14 @@ package income
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"github.com/google/uuid"
+	"github.com/pastorenue/kinance/internal/account"
 	"github.com/pastorenue/kinance/internal/common"
+	"github.com/pastorenue/kinance/internal/ledger"
 	"github.com/pastorenue/kinance/internal/user"
+	"github.com/pastorenue/kinance/pkg/pagination"
 	"gorm.io/gorm"
 )
 
@@ -20,6 +24,11 @@ func NewService(db *gorm.DB, logger common.Logger) *Service {
 }
 
 func (s *Service) CreateIncome(ctx context.Context, userID uuid.UUID, req *CreateIncomeRequest) (*Income, error) {
+	if req.AccountID != nil {
+		if err := account.CheckAccess(ctx, s.db, userID, *req.AccountID); err != nil {
+			return nil, err
+		}
+	}
 	var createdIncome *Income
 
 	// Check if Source exists using swift code
@@ -44,10 +53,17 @@ func (s *Service) CreateIncome(ctx context.Context, userID uuid.UUID, req *Creat
 		}
 
 		income := &Income{
-			Amount:   req.Amount,
-			SourceID: source.ID,
-			UserID:   userID,
-			Source:   &source, // Assign the source directly
+			Amount:    common.NewMoney(req.Amount, req.Currency.OrDefault()),
+			SourceID:  source.ID,
+			UserID:    userID,
+			AccountID: req.AccountID,
+			Source:    &source, // Assign the source directly
+		}
+		if req.Note != nil {
+			income.Note = *req.Note
+		}
+		if req.CategoryID != nil {
+			income.CategoryID = *req.CategoryID
 		}
 
 		income.ID = uuid.New()
@@ -56,6 +72,9 @@ func (s *Service) CreateIncome(ctx context.Context, userID uuid.UUID, req *Creat
 			s.logger.Error("Failed to create income", "error", err)
 			return err
 		}
+		if err := ledger.Post(tx, LedgerEntry(income)); err != nil {
+			return err
+		}
 
 		s.logger.Info("Income created successfully", "income_id", income.ID)
 		createdIncome = income
@@ -66,38 +85,49 @@ func (s *Service) CreateIncome(ctx context.Context, userID uuid.UUID, req *Creat
 	return createdIncome, nil
 }
 
-func (s *Service) GetIncomes(
-	ctx context.Context,
-	userID uuid.UUID,
-	pagination *common.PaginationParams,
-) (*common.PaginatedResponse, error) {
-	var incomes []Income
-	if err := s.db.WithContext(ctx).
-		Where("user_id = ?", userID).
-		Preload("Source"). // Preload source when fetching incomes
-		Limit(pagination.PageSize).
-		Offset((pagination.Page - 1) * pagination.PageSize).
-		Find(&incomes).Error; err != nil {
-		return nil, err
-	}
+// incomePages are the orders incomes can be listed in. Incomes are dated on the day they were recorded.
+var incomePages = pagination.Spec[Income]{
+	Table: "incomes",
+	Sorts: map[string]pagination.Column[Income]{
+		"date":   {Expr: "incomes.created_at", Value: func(i *Income) any { return i.CreatedAt }},
+		"amount": {Expr: "incomes.amount", Value: func(i *Income) any { return i.Amount.Amount }},
+	},
+	Default: "date",
+	ID:      func(i *Income) uuid.UUID { return i.ID },
+}
 
-	// Preloading is now done in the initial query
-	// No need for separate preloading
+// sourcePages are the orders income sources can be listed in, alphabetical by default.
+var sourcePages = pagination.Spec[Source]{
+	Table: "sources",
+	Sorts: map[string]pagination.Column[Source]{
+		"name":       {Expr: "sources.name", Value: func(s *Source) any { return s.Name }},
+		"created_at": {Expr: "sources.created_at", Value: func(s *Source) any { return s.CreatedAt }},
+	},
+	Default: "name",
+	Order:   pagination.Asc,
+	ID:      func(s *Source) uuid.UUID { return s.ID },
+}
 
-	totalPages := int(len(incomes)) / int(pagination.PageSize)
-	if int(len(incomes))%int(pagination.PageSize) > 0 {
-		totalPages++
-	}
+// IncomeFilters are the fields incomes can be filtered on.
+var IncomeFilters = common.FilterFields{
+	"amount":      {Column: "incomes.amount", Kind: common.FilterNumber},
+	"currency":    {Column: "incomes.currency"},
+	"source_id":   {Column: "incomes.source_id", Kind: common.FilterUUID},
+	"account_id":  {Column: "incomes.account_id", Kind: common.FilterUUID},
+	"category_id": {Column: "incomes.category_id", Kind: common.FilterUUID},
+	"status":      {Column: "incomes.status", Values: []string{string(IncomeStatusPending), string(IncomeStatusCompleted), string(IncomeStatusFailed)}},
+	"note":        {Column: "incomes.note", Ops: []common.FilterOp{common.OpLike}},
+	"created_at":  {Column: "incomes.created_at", Kind: common.FilterTime},
+}
 
-	return &common.PaginatedResponse{
-		Data: incomes,
-		Pagination: common.Pagination{
-			Page:       pagination.Page,
-			PageSize:   pagination.PageSize,
-			Total:      int64(len(incomes)),
-			TotalPages: totalPages,
-		},
-	}, nil
+func (s *Service) GetIncomes(
+	ctx context.Context,
+	userID uuid.UUID,
+	filter common.Filter,
+	params pagination.Params,
+) (*pagination.Page[Income], error) {
+	query := s.db.WithContext(ctx).Where("user_id = ?", userID).Scopes(filter.Scope)
+	return pagination.Paginate(query, incomePages, params, "Source")
 }
 
 func (s *Service) GetIncomeByID(ctx context.Context, userID uuid.UUID, incomeID uuid.UUID) (*Income, error) {
@@ -122,6 +152,7 @@ func (s *Service) UpdateIncome(ctx context.Context, userID uuid.UUID, incomeID u
 		}
 		return nil, err
 	}
+	changesBalance := req.Status != nil && *req.Status != income.Status
 
 	if req.Status != nil {
 		income.Status = *req.Status
@@ -136,17 +167,54 @@ func (s *Service) UpdateIncome(ctx context.Context, userID uuid.UUID, incomeID u
 		income.Note = *req.Note
 	}
 
-	if err := s.db.WithContext(ctx).Save(&income).Error; err != nil {
+	if err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if changesBalance {
+			locked, err := reconciled(tx, userID, income.ID)
+			if err != nil {
+				return err
+			}
+			if locked {
+				return fmt.Errorf("%w: only the note, category and metadata of its income can be changed", common.ErrReconciled)
+			}
+		}
+		if err := tx.Save(&income).Error; err != nil {
+			return err
+		}
+		if err := syncTransaction(tx, &income); err != nil {
+			return err
+		}
+		if !income.InLedger() {
+			return ledger.Reverse(tx, ledger.SourceIncome, income.ID)
+		}
+		return ledger.Replace(tx, LedgerEntry(&income))
+	}); err != nil {
 		return nil, err
 	}
 	return &income, nil
 }
 
+// DeleteIncome deletes the income together with the transaction it stands for.
 func (s *Service) DeleteIncome(ctx context.Context, userID uuid.UUID, incomeID uuid.UUID) error {
-	if err := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", incomeID, userID).Delete(&Income{}).Error; err != nil {
-		return err
-	}
-	return nil
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		locked, err := reconciled(tx, userID, incomeID)
+		if err != nil {
+			return err
+		}
+		if locked {
+			return common.ErrReconciled
+		}
+		result := tx.Where("id = ? AND user_id = ?", incomeID, userID).Delete(&Income{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		if err := deleteTransaction(tx, userID, incomeID); err != nil {
+			return err
+		}
+		return ledger.Reverse(tx, ledger.SourceIncome, incomeID)
+	})
 }
 
 func (s *Service) GetIncomeByCategory(ctx context.Context, userID uuid.UUID, categoryID uuid.UUID) ([]Income, error) {
@@ -178,17 +246,13 @@ func (s *Service) CreateSource(ctx context.Context, userID uuid.UUID, req *Creat
 	return source, nil
 }
 
-func (s *Service) GetSources(ctx context.Context, userID uuid.UUID) ([]Source, error) {
+func (s *Service) GetSources(ctx context.Context, userID uuid.UUID, params pagination.Params) (*pagination.Page[Source], error) {
 	if !isSuperAdmin(s.db, userID) {
 		s.logger.Error("Unauthorized access attempt", "user_id", userID)
 		return nil, errors.New("forbidden")
 	}
 
-	var sources []Source
-	if err := s.db.WithContext(ctx).Find(&sources).Error; err != nil {
-		return nil, err
-	}
-	return sources, nil
+	return pagination.Paginate(s.db.WithContext(ctx), sourcePages, params)
 }
 
 func (s *Service) GetSourceBySwiftCode(ctx context.Context, userID uuid.UUID, swiftCode string) (*Source, error) {
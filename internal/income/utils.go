@@ -0,0 +1,68 @@
+package income
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pastorenue/kinance/internal/ledger"
+	"gorm.io/gorm"
+)
+
+// LedgerEntry returns the journal entry of the income, dated on the day it was recorded.
+func LedgerEntry(i *Income) *ledger.JournalEntry {
+	return ledger.IncomeEntry(i.UserID, ledger.SourceIncome, i.ID, i.CreatedAt, i.Note, i.Amount, i.CategoryID, i.AccountID)
+}
+
+// InLedger reports whether the income counts in the journal. Failed payments never arrived.
+func (i *Income) InLedger() bool {
+	return i.Status != IncomeStatusFailed
+}
+
+// syncTransaction copies the income to the transaction it stands for, if any. The transaction is
+// kept out of the journal while it has an income, so only its row changes. The transactions table
+// is used directly, as the transaction package depends on this one.
+func syncTransaction(tx *gorm.DB, i *Income) error {
+	status := "completed"
+	switch i.Status {
+	case IncomeStatusFailed:
+		status = "canceled"
+	case IncomeStatusPending:
+		status = "pending"
+	}
+	return tx.Table("transactions").
+		Where("processing_object_id = ? AND user_id = ?", i.ID, i.UserID).
+		Updates(map[string]interface{}{
+			"description": i.Note,
+			"category_id": i.CategoryID,
+			"status":      status,
+			"updated_at":  time.Now(),
+		}).Error
+}
+
+// reconciled reports whether the transaction the income stands for is reconciled, which locks
+// what the income adds to the balance of its account.
+func reconciled(tx *gorm.DB, userID uuid.UUID, incomeID uuid.UUID) (bool, error) {
+	var count int64
+	err := tx.Table("transactions").
+		Where("processing_object_id = ? AND user_id = ? AND clearing = ?", incomeID, userID, "reconciled").
+		Count(&count).Error
+	return count > 0, err
+}
+
+// deleteTransaction deletes the transaction the income stands for, if any, like deleting the
+// transaction deletes its income.
+func deleteTransaction(tx *gorm.DB, userID uuid.UUID, incomeID uuid.UUID) error {
+	var ids []uuid.UUID
+	if err := tx.Table("transactions").
+		Where("processing_object_id = ? AND user_id = ?", incomeID, userID).
+		Pluck("id", &ids).Error; err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := tx.Exec("DELETE FROM transaction_tags WHERE transaction_id IN ?", ids).Error; err != nil {
+		return err
+	}
+	return tx.Exec("DELETE FROM transactions WHERE id IN ?", ids).Error
+}
@@ -0,0 +1,94 @@
+package ledger
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/pastorenue/kinance/internal/common"
+	"github.com/pastorenue/kinance/pkg/middleware"
+	"github.com/pastorenue/kinance/pkg/pagination"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) GetEntries(c *gin.Context) {
+	userID, _ := c.Get(middleware.UserIDKey)
+
+	var params pagination.Params
+	if err := c.ShouldBindQuery(&params); err != nil {
+		writeBadRequest(c, err.Error())
+		return
+	}
+
+	var sourceID *uuid.UUID
+	if value := c.Query("source_id"); value != "" {
+		parsed, err := uuid.Parse(value)
+		if err != nil {
+			writeBadRequest(c, "Invalid source_id parameter")
+			return
+		}
+		sourceID = &parsed
+	}
+
+	entries, err := h.service.GetEntries(c.Request.Context(), userID.(uuid.UUID), SourceType(c.Query("source_type")), sourceID, params)
+	if err != nil {
+		c.JSON(pagination.HTTPStatus(err), common.APIResponse{
+			Success:    false,
+			StatusCode: pagination.HTTPStatus(err),
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, common.APIResponse{
+		Success:    true,
+		StatusCode: http.StatusOK,
+		Data:       entries,
+	})
+}
+
+func (h *Handler) GetTrialBalance(c *gin.Context) {
+	userID, _ := c.Get(middleware.UserIDKey)
+
+	asOf := time.Now()
+	if value := c.Query("as_of"); value != "" {
+		parsed, err := time.Parse(dateLayout, value)
+		if err != nil {
+			writeBadRequest(c, "Invalid as_of parameter, expected YYYY-MM-DD")
+			return
+		}
+		asOf = parsed
+	}
+
+	balance, err := h.service.GetTrialBalance(c.Request.Context(), userID.(uuid.UUID), asOf)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, common.APIResponse{
+			Success:    false,
+			StatusCode: http.StatusInternalServerError,
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, common.APIResponse{
+		Success:    true,
+		StatusCode: http.StatusOK,
+		Data:       balance,
+	})
+}
+
+func writeBadRequest(c *gin.Context, message string) {
+	c.JSON(http.StatusBadRequest, common.APIResponse{
+		Success:    false,
+		StatusCode: http.StatusBadRequest,
+		Error:      message,
+	})
+}
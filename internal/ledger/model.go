@@ -0,0 +1,70 @@
+package ledger
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pastorenue/kinance/internal/common"
+)
+
+type SourceType string
+
+const (
+	SourceExpense     SourceType = "expense"
+	SourceIncome      SourceType = "income"
+	SourceTransfer    SourceType = "transfer"
+	SourceTransaction SourceType = "transaction" // Transactions without a linked expense, income or transfer
+)
+
+type LedgerType string
+
+const (
+	LedgerAccount    LedgerType = "account"    // An account.Account: assets such as bank accounts, liabilities such as credit cards
+	LedgerCategory   LedgerType = "category"   // An income or expense category; CategoryID is nil for uncategorized activity
+	LedgerUnassigned LedgerType = "unassigned" // Money that moved through no known account
+	LedgerExchange   LedgerType = "exchange"   // Currency conversion of cross-currency transfers
+)
+
+// JournalEntry records one economic event, such as an expense, as postings whose amounts add up to
+// zero in every currency. Entries are append-only: a change to the source is recorded by posting
+// an entry that reverses the previous one, followed by a new entry.
+type JournalEntry struct {
+	common.BaseModel
+	UserID      uuid.UUID  `json:"user_id" gorm:"not null;index"`
+	Date        time.Time  `json:"date" gorm:"not null;index"`
+	Description string     `json:"description"`
+	SourceType  SourceType `json:"source_type" gorm:"type:varchar(20);not null;index:idx_journal_entry_source"`
+	SourceID    uuid.UUID  `json:"source_id" gorm:"type:uuid;not null;index:idx_journal_entry_source"`
+	ReversesID  *uuid.UUID `json:"reverses_id,omitempty" gorm:"type:uuid;uniqueIndex"` // Set on entries that cancel an earlier entry
+	Postings    []Posting  `json:"postings" gorm:"foreignKey:EntryID"`
+}
+
+// Posting is one line of a journal entry. Positive amounts are debits and negative amounts credits.
+type Posting struct {
+	common.BaseModel
+	EntryID    uuid.UUID    `json:"entry_id" gorm:"type:uuid;not null;index"`
+	UserID     uuid.UUID    `json:"user_id" gorm:"not null;index"`
+	Ledger     LedgerType   `json:"ledger" gorm:"type:varchar(20);not null"`
+	AccountID  *uuid.UUID   `json:"account_id,omitempty" gorm:"type:uuid;index"`
+	CategoryID *uuid.UUID   `json:"category_id,omitempty" gorm:"type:uuid;index"`
+	Amount     common.Money `json:"amount" gorm:"embedded"`
+}
+
+// TrialBalance lists the debits and credits of every ledger. The journal is consistent when
+// debits equal credits in every currency.
+type TrialBalance struct {
+	AsOf     string             `json:"as_of"`
+	Lines    []TrialBalanceLine `json:"lines"`
+	Totals   []TrialBalanceLine `json:"totals"` // One line per currency
+	Balanced bool               `json:"balanced"`
+}
+
+type TrialBalanceLine struct {
+	Ledger     LedgerType   `json:"ledger,omitempty"`
+	AccountID  *uuid.UUID   `json:"account_id,omitempty"`
+	CategoryID *uuid.UUID   `json:"category_id,omitempty"`
+	Name       string       `json:"name,omitempty"`
+	Debit      common.Money `json:"debit"`
+	Credit     common.Money `json:"credit"`
+	Balance    common.Money `json:"balance"` // Debit minus credit
+}
@@ -0,0 +1,10 @@
+package ledger
+
+import "github.com/gin-gonic/gin"
+
+func RegisterRoutes(versionedGroup *gin.RouterGroup, svc *Service) {
+	ledgerHandler := NewHandler(svc)
+	protected := versionedGroup.Group("/ledger")
+	protected.GET("/entries", ledgerHandler.GetEntries)
+	protected.GET("/trial-balance", ledgerHandler.GetTrialBalance)
+}
@@ -0,0 +1,122 @@
+package ledger
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pastorenue/kinance/internal/common"
+	"github.com/pastorenue/kinance/pkg/pagination"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+const dateLayout = "2006-01-02"
+
+type Service struct {
+	db     *gorm.DB
+	logger common.Logger
+}
+
+func NewService(db *gorm.DB, logger common.Logger) *Service {
+	return &Service{db: db, logger: logger}
+}
+
+// entryPages are the orders journal entries can be listed in.
+var entryPages = pagination.Spec[JournalEntry]{
+	Table: "journal_entries",
+	Sorts: map[string]pagination.Column[JournalEntry]{
+		"date":       {Expr: "journal_entries.date", Value: func(e *JournalEntry) any { return e.Date }},
+		"created_at": {Expr: "journal_entries.created_at", Value: func(e *JournalEntry) any { return e.CreatedAt }},
+	},
+	Default: "date",
+	ID:      func(e *JournalEntry) uuid.UUID { return e.ID },
+}
+
+// GetEntries returns the user's journal entries, newest first by default, optionally limited to one source.
+func (s *Service) GetEntries(ctx context.Context, userID uuid.UUID, sourceType SourceType, sourceID *uuid.UUID, params pagination.Params) (*pagination.Page[JournalEntry], error) {
+	query := s.db.WithContext(ctx).Where("user_id = ?", userID)
+	if sourceType != "" {
+		query = query.Where("source_type = ?", sourceType)
+	}
+	if sourceID != nil {
+		query = query.Where("source_id = ?", *sourceID)
+	}
+	return pagination.Paginate(query, entryPages, params, "Postings")
+}
+
+// GetTrialBalance adds up the postings of every ledger in entries dated on or before asOf.
+func (s *Service) GetTrialBalance(ctx context.Context, userID uuid.UUID, asOf time.Time) (*TrialBalance, error) {
+	type row struct {
+		Ledger     LedgerType
+		AccountID  *uuid.UUID
+		CategoryID *uuid.UUID
+		Name       string
+		Currency   common.Currency
+		Debit      decimal.Decimal
+		Credit     decimal.Decimal
+	}
+
+	var rows []row
+	if err := s.db.WithContext(ctx).Raw(`
+		SELECT p.ledger, p.account_id, p.category_id,
+		       COALESCE(a.name, c.name, '') AS name,
+		       p.currency,
+		       SUM(CASE WHEN p.amount > 0 THEN p.amount ELSE 0 END) AS debit,
+		       SUM(CASE WHEN p.amount < 0 THEN -p.amount ELSE 0 END) AS credit
+		FROM postings p
+		JOIN journal_entries e ON e.id = p.entry_id
+		LEFT JOIN accounts a ON a.id = p.account_id
+		LEFT JOIN categories c ON c.id = p.category_id
+		WHERE p.user_id = ? AND e.date < ?
+		GROUP BY p.ledger, p.account_id, p.category_id, a.name, c.name, p.currency
+		ORDER BY p.ledger, name, p.currency`, userID, asOf.AddDate(0, 0, 1)).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	balance := &TrialBalance{
+		AsOf:     asOf.Format(dateLayout),
+		Lines:    make([]TrialBalanceLine, 0, len(rows)),
+		Totals:   []TrialBalanceLine{},
+		Balanced: true,
+	}
+	totals := make(map[common.Currency]*TrialBalanceLine)
+	for _, r := range rows {
+		balance.Lines = append(balance.Lines, trialBalanceLine(r.Ledger, r.AccountID, r.CategoryID, r.Name, r.Currency, r.Debit, r.Credit))
+
+		total, ok := totals[r.Currency]
+		if !ok {
+			total = &TrialBalanceLine{Debit: common.ZeroMoney(r.Currency), Credit: common.ZeroMoney(r.Currency)}
+			totals[r.Currency] = total
+		}
+		total.Debit.Amount = total.Debit.Amount.Add(r.Debit)
+		total.Credit.Amount = total.Credit.Amount.Add(r.Credit)
+	}
+
+	for currency, total := range totals {
+		line := trialBalanceLine("", nil, nil, "", currency, total.Debit.Amount, total.Credit.Amount)
+		if !line.Balance.IsZero() {
+			balance.Balanced = false
+			s.logger.Error("Trial balance does not balance", "user_id", userID, "currency", currency, "difference", line.Balance.String())
+		}
+		balance.Totals = append(balance.Totals, line)
+	}
+	sort.Slice(balance.Totals, func(i, j int) bool {
+		return balance.Totals[i].Debit.Currency < balance.Totals[j].Debit.Currency
+	})
+	return balance, nil
+}
+
+func trialBalanceLine(ledger LedgerType, accountID, categoryID *uuid.UUID, name string, currency common.Currency, debit, credit decimal.Decimal) TrialBalanceLine {
+	return TrialBalanceLine{
+		Ledger:     ledger,
+		AccountID:  accountID,
+		CategoryID: categoryID,
+		Name:       name,
+		Debit:      common.NewMoney(debit, currency),
+		Credit:     common.NewMoney(credit, currency),
+		Balance:    common.NewMoney(debit.Sub(credit), currency),
+	}
+}
@@ -0,0 +1,208 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pastorenue/kinance/internal/common"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+var ErrUnbalancedEntry = errors.New("journal entry is not balanced")
+
+// Post validates the entry and writes it with its postings. Callers pass the database transaction
+// that writes the source, so the journal never disagrees with it.
+func Post(tx *gorm.DB, entry *JournalEntry) error {
+	if err := validate(entry); err != nil {
+		return err
+	}
+	if entry.ID == uuid.Nil {
+		entry.ID = uuid.New()
+	}
+	for i := range entry.Postings {
+		entry.Postings[i].ID = uuid.New()
+		entry.Postings[i].EntryID = entry.ID
+		entry.Postings[i].UserID = entry.UserID
+	}
+	return tx.Create(entry).Error
+}
+
+// Reverse posts an entry cancelling each entry of the source that is still in effect. The
+// reversal is dated like the entry it cancels, so reports as of any date reflect the correction.
+func Reverse(tx *gorm.DB, sourceType SourceType, sourceID uuid.UUID) error {
+	var entries []JournalEntry
+	if err := tx.Preload("Postings").
+		Where("source_type = ? AND source_id = ? AND reverses_id IS NULL", sourceType, sourceID).
+		Where("NOT EXISTS (SELECT 1 FROM journal_entries r WHERE r.reverses_id = journal_entries.id)").
+		Find(&entries).Error; err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		reversal := &JournalEntry{
+			UserID:      entry.UserID,
+			Date:        entry.Date,
+			Description: "Reversal: " + entry.Description,
+			SourceType:  entry.SourceType,
+			SourceID:    entry.SourceID,
+			ReversesID:  &entry.ID,
+		}
+		for _, posting := range entry.Postings {
+			reversal.Postings = append(reversal.Postings, Posting{
+				Ledger:     posting.Ledger,
+				AccountID:  posting.AccountID,
+				CategoryID: posting.CategoryID,
+				Amount:     posting.Amount.Neg(),
+			})
+		}
+		if err := Post(tx, reversal); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Replace reverses the entries in effect for the source of entry and posts entry instead.
+func Replace(tx *gorm.DB, entry *JournalEntry) error {
+	if err := Reverse(tx, entry.SourceType, entry.SourceID); err != nil {
+		return err
+	}
+	return Post(tx, entry)
+}
+
+// DailyTotal is the net amount posted in one currency on one day.
+type DailyTotal struct {
+	Currency common.Currency
+	Day      time.Time
+	Total    decimal.Decimal
+}
+
+// AccountActivity returns the daily net postings to the account in entries dated before until,
+// ordered by day. Debits, such as incomes, add to the balance and credits subtract from it.
+func AccountActivity(db *gorm.DB, accountID uuid.UUID, until time.Time) ([]DailyTotal, error) {
+	var days []DailyTotal
+	if err := db.Raw(`
+		SELECT p.currency, DATE(e.date) AS day, SUM(p.amount) AS total
+		FROM postings p
+		JOIN journal_entries e ON e.id = p.entry_id
+		WHERE p.ledger = ? AND p.account_id = ? AND e.date < ?
+		GROUP BY p.currency, day
+		ORDER BY day, p.currency`, LedgerAccount, accountID, until).
+		Scan(&days).Error; err != nil {
+		return nil, err
+	}
+	return days, nil
+}
+
+// CategoryActivity returns the daily net postings to the user's categories in entries dated in
+// [from, until) that record spending, or income when kind is SourceIncome: expenses or incomes,
+// and standalone transactions of that type unless they are excluded from analytics. Spending is
+// positive and income negative. categories is a subquery selecting category IDs, or nil for all.
+func CategoryActivity(db *gorm.DB, userID uuid.UUID, kind SourceType, categories *gorm.DB, from, until time.Time) ([]DailyTotal, error) {
+	// Reversals share the source of the entry they cancel, so both are selected or neither is
+	query := db.Table("postings AS p").
+		Select("p.currency, DATE(e.date) AS day, SUM(p.amount) AS total").
+		Joins("JOIN journal_entries e ON e.id = p.entry_id").
+		Joins("LEFT JOIN transactions t ON e.source_type = ? AND t.id = e.source_id", SourceTransaction).
+		Where("p.user_id = ? AND p.ledger = ? AND e.date < ?", userID, LedgerCategory, until).
+		Where("e.source_type = ? OR (t.type = ? AND NOT t.exclude_from_analytics)", kind, kind)
+	if !from.IsZero() {
+		query = query.Where("e.date >= ?", from)
+	}
+	if categories != nil {
+		query = query.Where("p.category_id IN (?)", categories)
+	}
+
+	var days []DailyTotal
+	if err := query.Group("p.currency, day").Order("day, p.currency").Scan(&days).Error; err != nil {
+		return nil, err
+	}
+	return days, nil
+}
+
+// ExpenseEntry debits the expense category and credits the account the money was paid from.
+func ExpenseEntry(userID uuid.UUID, sourceType SourceType, sourceID uuid.UUID, date time.Time, description string, amount common.Money, categoryID uuid.UUID, accountID *uuid.UUID) *JournalEntry {
+	return &JournalEntry{
+		UserID:      userID,
+		Date:        date,
+		Description: description,
+		SourceType:  sourceType,
+		SourceID:    sourceID,
+		Postings: []Posting{
+			categoryPosting(categoryID, amount),
+			accountPosting(accountID, amount.Neg()),
+		},
+	}
+}
+
+// IncomeEntry debits the account the money was paid into and credits the income category.
+func IncomeEntry(userID uuid.UUID, sourceType SourceType, sourceID uuid.UUID, date time.Time, description string, amount common.Money, categoryID uuid.UUID, accountID *uuid.UUID) *JournalEntry {
+	return &JournalEntry{
+		UserID:      userID,
+		Date:        date,
+		Description: description,
+		SourceType:  sourceType,
+		SourceID:    sourceID,
+		Postings: []Posting{
+			accountPosting(accountID, amount),
+			categoryPosting(categoryID, amount.Neg()),
+		},
+	}
+}
+
+// TransferEntry debits the destination account with what it received and credits the source account
+// with what was sent. Between currencies, the exchange ledger balances each currency.
+func TransferEntry(userID, transferID uuid.UUID, date time.Time, description string, fromAccountID, toAccountID uuid.UUID, sent, received common.Money) *JournalEntry {
+	entry := &JournalEntry{
+		UserID:      userID,
+		Date:        date,
+		Description: description,
+		SourceType:  SourceTransfer,
+		SourceID:    transferID,
+		Postings: []Posting{
+			accountPosting(&toAccountID, received),
+			accountPosting(&fromAccountID, sent.Neg()),
+		},
+	}
+	if sent.Currency != received.Currency {
+		entry.Postings = append(entry.Postings,
+			Posting{Ledger: LedgerExchange, Amount: sent},
+			Posting{Ledger: LedgerExchange, Amount: received.Neg()},
+		)
+	}
+	return entry
+}
+
+func accountPosting(accountID *uuid.UUID, amount common.Money) Posting {
+	if accountID == nil {
+		return Posting{Ledger: LedgerUnassigned, Amount: amount}
+	}
+	return Posting{Ledger: LedgerAccount, AccountID: accountID, Amount: amount}
+}
+
+func categoryPosting(categoryID uuid.UUID, amount common.Money) Posting {
+	posting := Posting{Ledger: LedgerCategory, Amount: amount}
+	if categoryID != uuid.Nil {
+		posting.CategoryID = &categoryID
+	}
+	return posting
+}
+
+func validate(entry *JournalEntry) error {
+	if len(entry.Postings) < 2 {
+		return fmt.Errorf("%w: an entry needs at least two postings", ErrUnbalancedEntry)
+	}
+	sums := make(map[common.Currency]decimal.Decimal)
+	for _, posting := range entry.Postings {
+		sums[posting.Amount.Currency] = sums[posting.Amount.Currency].Add(posting.Amount.Amount)
+	}
+	for currency, sum := range sums {
+		if !sum.IsZero() {
+			return fmt.Errorf("%w: postings in %s add up to %s", ErrUnbalancedEntry, currency, sum)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package ledger
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pastorenue/kinance/internal/common"
+	"github.com/shopspring/decimal"
+)
+
+func money(amount string, currency common.Currency) common.Money {
+	return common.Money{Amount: decimal.RequireFromString(amount), Currency: currency}
+}
+
+func TestValidate(t *testing.T) {
+	user, source, category := uuid.New(), uuid.New(), uuid.New()
+	checking, savings := uuid.New(), uuid.New()
+	day := time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		entry *JournalEntry
+		want  string
+	}{
+		{
+			name:  "expense",
+			entry: ExpenseEntry(user, SourceExpense, source, day, "Rent", money("1250.00", "EUR"), category, &checking),
+		},
+		{
+			name:  "income without an account",
+			entry: IncomeEntry(user, SourceIncome, source, day, "Salary", money("3100.50", "EUR"), uuid.Nil, nil),
+		},
+		{
+			name:  "transfer",
+			entry: TransferEntry(user, source, day, "Savings", checking, savings, money("500", "EUR"), money("500", "EUR")),
+		},
+		{
+			name:  "transfer between currencies",
+			entry: TransferEntry(user, source, day, "Holiday money", checking, savings, money("500", "EUR"), money("541.35", "USD")),
+		},
+		{
+			name:  "no postings",
+			entry: &JournalEntry{},
+			want:  "at least two postings",
+		},
+		{
+			name:  "one posting",
+			entry: &JournalEntry{Postings: []Posting{{Ledger: LedgerAccount, Amount: money("0", "EUR")}}},
+			want:  "at least two postings",
+		},
+		{
+			name: "debits exceed credits",
+			entry: &JournalEntry{Postings: []Posting{
+				{Ledger: LedgerCategory, Amount: money("12.50", "EUR")},
+				{Ledger: LedgerAccount, Amount: money("-12.49", "EUR")},
+			}},
+			want: "postings in EUR add up to 0.01",
+		},
+		{
+			name: "balanced across currencies only",
+			entry: &JournalEntry{Postings: []Posting{
+				{Ledger: LedgerAccount, Amount: money("500", "USD")},
+				{Ledger: LedgerAccount, Amount: money("-500", "EUR")},
+			}},
+			want: "add up to",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validate(tt.entry)
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("validate: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrUnbalancedEntry) || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("validate error = %v, want ErrUnbalancedEntry containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEntryPostings(t *testing.T) {
+	user, source, category, account := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	day := time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)
+
+	expense := ExpenseEntry(user, SourceTransaction, source, day, "Bakery", money("3.20", "EUR"), category, nil)
+	if got := expense.Postings; got[0].Ledger != LedgerCategory || *got[0].CategoryID != category || !got[0].Amount.IsPositive() ||
+		got[1].Ledger != LedgerUnassigned || got[1].AccountID != nil || !got[1].Amount.IsNegative() {
+		t.Errorf("expense postings = %+v, want the category debited and the unassigned ledger credited", got)
+	}
+
+	income := IncomeEntry(user, SourceIncome, source, day, "Salary", money("3100", "EUR"), uuid.Nil, &account)
+	if got := income.Postings; got[0].Ledger != LedgerAccount || *got[0].AccountID != account || !got[0].Amount.IsPositive() ||
+		got[1].CategoryID != nil || !got[1].Amount.IsNegative() {
+		t.Errorf("income postings = %+v, want the account debited and the uncategorized category ledger credited", got)
+	}
+
+	transfer := TransferEntry(user, source, day, "Holiday money", account, uuid.New(), money("500", "EUR"), money("541.35", "USD"))
+	if len(transfer.Postings) != 4 || transfer.Postings[2].Ledger != LedgerExchange || transfer.Postings[3].Ledger != LedgerExchange {
+		t.Errorf("transfer postings = %+v, want the exchange ledger to balance each currency", transfer.Postings)
+	}
+}
+
+func TestPostRejectsUnbalanced(t *testing.T) {
+	entry := &JournalEntry{Postings: []Posting{
+		{Ledger: LedgerCategory, Amount: money("1", "EUR")},
+		{Ledger: LedgerAccount, Amount: money("1", "EUR")},
+	}}
+	// The entry is rejected before the database is touched.
+	if err := Post(nil, entry); !errors.Is(err, ErrUnbalancedEntry) {
+		t.Fatalf("Post error = %v, want ErrUnbalancedEntry", err)
+	}
+	if entry.ID != uuid.Nil {
+		t.Errorf("rejected entry was given ID %s", entry.ID)
+	}
+}
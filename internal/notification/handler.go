@@ -0,0 +1,97 @@
+package notification
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/pastorenue/kinance/internal/common"
+	"github.com/pastorenue/kinance/pkg/middleware"
+	"github.com/pastorenue/kinance/pkg/pagination"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) GetNotifications(c *gin.Context) {
+	userID, _ := c.Get(middleware.UserIDKey)
+
+	var params pagination.Params
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, common.APIResponse{
+			Success:    false,
+			StatusCode: http.StatusBadRequest,
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	notifications, err := h.service.GetNotifications(c.Request.Context(), userID.(uuid.UUID), c.Query("unread") == "true", params)
+	if err != nil {
+		c.JSON(pagination.HTTPStatus(err), common.APIResponse{
+			Success:    false,
+			StatusCode: pagination.HTTPStatus(err),
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, common.APIResponse{
+		Success:    true,
+		StatusCode: http.StatusOK,
+		Data:       notifications,
+	})
+}
+
+func (h *Handler) MarkAsRead(c *gin.Context) {
+	userID, _ := c.Get(middleware.UserIDKey)
+	notificationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.APIResponse{
+			Success:    false,
+			StatusCode: http.StatusBadRequest,
+			Error:      "Invalid notification ID",
+		})
+		return
+	}
+
+	if err := h.service.MarkAsRead(c.Request.Context(), userID.(uuid.UUID), notificationID); err != nil {
+		c.JSON(http.StatusInternalServerError, common.APIResponse{
+			Success:    false,
+			StatusCode: http.StatusInternalServerError,
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, common.APIResponse{
+		Success:    true,
+		StatusCode: http.StatusOK,
+		Message:    "Notification marked as read",
+	})
+}
+
+func (h *Handler) MarkAllAsRead(c *gin.Context) {
+	userID, _ := c.Get(middleware.UserIDKey)
+
+	count, err := h.service.MarkAllAsRead(c.Request.Context(), userID.(uuid.UUID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, common.APIResponse{
+			Success:    false,
+			StatusCode: http.StatusInternalServerError,
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, common.APIResponse{
+		Success:    true,
+		StatusCode: http.StatusOK,
+		Data:       gin.H{"updated": count},
+	})
+}
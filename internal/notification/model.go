@@ -0,0 +1,28 @@
+package notification
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pastorenue/kinance/internal/common"
+)
+
+// Notification is an entry in the user's in-app inbox.
+type Notification struct {
+	common.BaseModel
+	UserID uuid.UUID              `json:"user_id" gorm:"not null;index"`
+	Type   string                 `json:"type" gorm:"type:varchar(50);not null"`
+	Title  string                 `json:"title" gorm:"not null"`
+	Body   string                 `json:"body" gorm:"type:text"`
+	Data   map[string]interface{} `json:"data" gorm:"type:jsonb;serializer:json"`
+	ReadAt *time.Time             `json:"read_at"`
+}
+
+// Message is an event to deliver to a user through one or more notifiers.
+type Message struct {
+	UserID uuid.UUID              `json:"user_id"`
+	Type   string                 `json:"type"`
+	Title  string                 `json:"title"`
+	Body   string                 `json:"body"`
+	Data   map[string]interface{} `json:"data,omitempty"`
+}
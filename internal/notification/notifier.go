@@ -0,0 +1,217 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pastorenue/kinance/internal/common"
+	"github.com/pastorenue/kinance/pkg/config"
+	"gorm.io/gorm"
+)
+
+// Notifier delivers a message to a user over a single channel.
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
+
+// NewNotifier returns a notifier that always writes to the in-app inbox and additionally
+// delivers over webhook and SMTP when those channels are configured.
+func NewNotifier(db *gorm.DB, cfg config.NotificationConfig, logger common.Logger) Notifier {
+	notifiers := MultiNotifier{NewInboxNotifier(db)}
+	if cfg.WebhookURL != "" {
+		notifiers = append(notifiers, NewWebhookNotifier(cfg.WebhookURL, cfg.WebhookSecret))
+	}
+	if cfg.SMTPHost != "" {
+		notifiers = append(notifiers, NewSMTPNotifier(db, cfg))
+	}
+	logger.Info("Notifiers configured", "channels", len(notifiers))
+	return notifiers
+}
+
+// MultiNotifier fans a message out to every notifier. A failing channel does not stop the others.
+type MultiNotifier []Notifier
+
+func (m MultiNotifier) Notify(ctx context.Context, msg Message) error {
+	var errs []error
+	for _, notifier := range m {
+		if err := notifier.Notify(ctx, msg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// InboxNotifier stores messages as in-app notifications.
+type InboxNotifier struct {
+	db *gorm.DB
+}
+
+func NewInboxNotifier(db *gorm.DB) *InboxNotifier {
+	return &InboxNotifier{db: db}
+}
+
+func (n *InboxNotifier) Notify(ctx context.Context, msg Message) error {
+	notification := &Notification{
+		UserID: msg.UserID,
+		Type:   msg.Type,
+		Title:  msg.Title,
+		Body:   msg.Body,
+		Data:   msg.Data,
+	}
+	notification.ID = uuid.New()
+
+	if err := n.db.WithContext(ctx).Create(notification).Error; err != nil {
+		return fmt.Errorf("inbox: %w", err)
+	}
+	return nil
+}
+
+// WebhookNotifier posts messages as JSON to a URL. When a secret is set, the body is signed
+// with HMAC-SHA256 in the X-Kinance-Signature header so receivers can verify the sender.
+type WebhookNotifier struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+func NewWebhookNotifier(url, secret string) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:    url,
+		secret: secret,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, msg Message) error {
+	payload, err := json.Marshal(struct {
+		Message
+		SentAt time.Time `json:"sent_at"`
+	}{msg, time.Now().UTC()})
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.secret != "" {
+		mac := hmac.New(sha256.New, []byte(n.secret))
+		mac.Write(payload)
+		req.Header.Set("X-Kinance-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook: endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// SMTPNotifier emails messages to the address of the user's account. Authentication is
+// skipped when no username is configured, which suits local stand-ins such as Mailpit.
+// Deliveries the server defers with a 4xx reply, or that cannot reach it, are retried.
+type SMTPNotifier struct {
+	db       *gorm.DB
+	addr     string
+	auth     smtp.Auth
+	from     string
+	attempts int
+	backoff  time.Duration // Wait before the second attempt, doubled for each further one
+}
+
+func NewSMTPNotifier(db *gorm.DB, cfg config.NotificationConfig) *SMTPNotifier {
+	var auth smtp.Auth
+	if cfg.SMTPUsername != "" {
+		auth = smtp.PlainAuth("", cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPHost)
+	}
+	return &SMTPNotifier{
+		db:       db,
+		addr:     fmt.Sprintf("%s:%d", cfg.SMTPHost, cfg.SMTPPort),
+		auth:     auth,
+		from:     cfg.SMTPFrom,
+		attempts: 3,
+		backoff:  2 * time.Second,
+	}
+}
+
+func (n *SMTPNotifier) Notify(ctx context.Context, msg Message) error {
+	var to string
+	if err := n.db.WithContext(ctx).Table("users").
+		Select("email").
+		Where("id = ?", msg.UserID).
+		Row().Scan(&to); err != nil {
+		return fmt.Errorf("smtp: failed to look up recipient: %w", err)
+	}
+	return n.send(ctx, to, msg)
+}
+
+// send delivers the message to one address, retrying temporary failures.
+func (n *SMTPNotifier) send(ctx context.Context, to string, msg Message) error {
+	// The configured sender may carry a display name, which the envelope cannot
+	sender := n.from
+	if address, err := mail.ParseAddress(n.from); err == nil {
+		sender = address.Address
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "From: %s\r\n", n.from)
+	fmt.Fprintf(&body, "To: %s\r\n", to)
+	fmt.Fprintf(&body, "Subject: %s\r\n", strings.ReplaceAll(msg.Title, "\n", " "))
+	body.WriteString("MIME-Version: 1.0\r\n")
+	body.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	body.WriteString("\r\n")
+	body.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	body.WriteString("\r\n")
+
+	wait := n.backoff
+	for attempt := 1; ; attempt++ {
+		err := smtp.SendMail(n.addr, n.auth, sender, []string{to}, []byte(body.String()))
+		if err == nil {
+			return nil
+		}
+		if attempt >= n.attempts || !temporary(err) {
+			return fmt.Errorf("smtp: delivery failed after %d attempt(s): %w", attempt, err)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("smtp: %w", ctx.Err())
+		case <-timer.C:
+		}
+		wait *= 2
+	}
+}
+
+// temporary reports whether a failed delivery may succeed later: the server deferred it with a
+// 4xx reply, or could not be reached. 5xx replies are permanent.
+func temporary(err error) bool {
+	var reply *textproto.Error
+	if errors.As(err, &reply) {
+		return reply.Code >= 400 && reply.Code < 500
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,302 @@
+package notification
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// smtpServer is a minimal SMTP stand-in. Each accepted message is answered with the next of
+// replies after DATA; once replies run out every message is accepted.
+type smtpServer struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	replies  []string
+	attempts int
+	senders  []string
+	messages []string
+}
+
+func newSMTPServer(t *testing.T, replies ...string) *smtpServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	server := &smtpServer{listener: listener, replies: replies}
+	t.Cleanup(func() { listener.Close() })
+	go server.serve()
+	return server
+}
+
+func (s *smtpServer) addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *smtpServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *smtpServer) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+	reply("220 localhost ESMTP stand-in")
+	var sender string
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(command, "MAIL FROM:"):
+			sender = strings.TrimSpace(line)[len("MAIL FROM:"):]
+			reply("250 OK")
+		case strings.HasPrefix(command, "RCPT TO:"):
+			reply("250 OK")
+		case command == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(dataLine)
+			}
+			reply(s.accept(sender, data.String()))
+		case command == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func (s *smtpServer) accept(sender, message string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts++
+	if len(s.replies) > 0 {
+		reply := s.replies[0]
+		s.replies = s.replies[1:]
+		if !strings.HasPrefix(reply, "2") {
+			return reply
+		}
+	}
+	s.senders = append(s.senders, sender)
+	s.messages = append(s.messages, message)
+	return "250 OK: queued"
+}
+
+func (s *smtpServer) result() (int, []string, []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.attempts, s.senders, s.messages
+}
+
+func newTestSMTPNotifier(addr string) *SMTPNotifier {
+	return &SMTPNotifier{
+		addr:     addr,
+		from:     "Kinance <no-reply@kinance.local>",
+		attempts: 3,
+		backoff:  time.Millisecond,
+	}
+}
+
+var testMessage = Message{
+	UserID: uuid.New(),
+	Type:   "budget_threshold_reached",
+	Title:  "Budget \"Groceries\" reached 80%",
+	Body:   "You have spent 400.00 EUR of 500.00 EUR.\nSecond line.",
+}
+
+func TestSMTPNotifierDelivers(t *testing.T) {
+	server := newSMTPServer(t)
+	notifier := newTestSMTPNotifier(server.addr())
+
+	if err := notifier.send(context.Background(), "jane@example.com", testMessage); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	attempts, senders, messages := server.result()
+	if attempts != 1 || len(messages) != 1 {
+		t.Fatalf("got %d attempts and %d messages, want 1 and 1", attempts, len(messages))
+	}
+	if senders[0] != "<no-reply@kinance.local>" {
+		t.Errorf("envelope sender = %q, want the bare address", senders[0])
+	}
+	for _, want := range []string{
+		"From: Kinance <no-reply@kinance.local>\r\n",
+		"To: jane@example.com\r\n",
+		"Subject: Budget \"Groceries\" reached 80%\r\n",
+		"\r\nYou have spent 400.00 EUR of 500.00 EUR.\r\nSecond line.\r\n",
+	} {
+		if !strings.Contains(messages[0], want) {
+			t.Errorf("message lacks %q:\n%s", want, messages[0])
+		}
+	}
+}
+
+func TestSMTPNotifierRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		replies      []string
+		wantErr      bool
+		wantAttempts int
+		wantMessages int
+	}{
+		{name: "deferred then accepted", replies: []string{"451 4.3.0 try again later"}, wantAttempts: 2, wantMessages: 1},
+		{name: "deferred twice then accepted", replies: []string{"421 busy", "452 out of storage"}, wantAttempts: 3, wantMessages: 1},
+		{name: "deferred every time", replies: []string{"451 later", "451 later", "451 later", "451 later"}, wantErr: true, wantAttempts: 3},
+		{name: "rejected permanently", replies: []string{"550 5.1.1 no such user"}, wantErr: true, wantAttempts: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newSMTPServer(t, tt.replies...)
+			notifier := newTestSMTPNotifier(server.addr())
+
+			err := notifier.send(context.Background(), "jane@example.com", testMessage)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("send error = %v, want error %v", err, tt.wantErr)
+			}
+			attempts, _, messages := server.result()
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+			if len(messages) != tt.wantMessages {
+				t.Errorf("delivered %d messages, want %d", len(messages), tt.wantMessages)
+			}
+		})
+	}
+}
+
+func TestSMTPNotifierUnreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	notifier := newTestSMTPNotifier(addr)
+	err = notifier.send(context.Background(), "jane@example.com", testMessage)
+	if err == nil || !strings.Contains(err.Error(), "after 3 attempt(s)") {
+		t.Fatalf("send error = %v, want a failure after 3 attempts", err)
+	}
+}
+
+func TestSMTPNotifierStopsRetryingOnCancel(t *testing.T) {
+	server := newSMTPServer(t, "451 later", "451 later", "451 later")
+	notifier := newTestSMTPNotifier(server.addr())
+	notifier.backoff = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := notifier.send(ctx, "jane@example.com", testMessage)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("send error = %v, want the context deadline", err)
+	}
+	if attempts, _, _ := server.result(); attempts != 1 {
+		t.Errorf("attempts = %d, want 1", attempts)
+	}
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	tests := []struct {
+		name    string
+		secret  string
+		status  int
+		wantErr bool
+	}{
+		{name: "signed", secret: "s3cret", status: http.StatusNoContent},
+		{name: "unsigned", status: http.StatusOK},
+		{name: "endpoint failure", status: http.StatusBadGateway, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body []byte
+			var signature string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ = io.ReadAll(r.Body)
+				signature = r.Header.Get("X-Kinance-Signature")
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			err := NewWebhookNotifier(server.URL, tt.secret).Notify(context.Background(), testMessage)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Notify error = %v, want error %v", err, tt.wantErr)
+			}
+
+			var payload map[string]interface{}
+			if err := json.Unmarshal(body, &payload); err != nil {
+				t.Fatalf("payload is not JSON: %v", err)
+			}
+			if payload["title"] != testMessage.Title || payload["sent_at"] == nil {
+				t.Errorf("unexpected payload %s", body)
+			}
+
+			if tt.secret == "" {
+				if signature != "" {
+					t.Errorf("unsigned webhook sent signature %q", signature)
+				}
+				return
+			}
+			mac := hmac.New(sha256.New, []byte(tt.secret))
+			mac.Write(body)
+			if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); signature != want {
+				t.Errorf("signature = %q, want %q", signature, want)
+			}
+		})
+	}
+}
+
+type notifierFunc func(ctx context.Context, msg Message) error
+
+func (f notifierFunc) Notify(ctx context.Context, msg Message) error {
+	return f(ctx, msg)
+}
+
+func TestMultiNotifierDeliversDespiteFailures(t *testing.T) {
+	var delivered int
+	failing := notifierFunc(func(context.Context, Message) error { return errors.New("channel down") })
+	working := notifierFunc(func(context.Context, Message) error { delivered++; return nil })
+
+	err := MultiNotifier{failing, working, failing}.Notify(context.Background(), testMessage)
+	if err == nil || !strings.Contains(err.Error(), "channel down") {
+		t.Fatalf("Notify error = %v, want the channel failure", err)
+	}
+	if delivered != 1 {
+		t.Errorf("working channel delivered %d times, want 1", delivered)
+	}
+}
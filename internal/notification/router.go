@@ -0,0 +1,11 @@
+package notification
+
+import "github.com/gin-gonic/gin"
+
+func RegisterRoutes(versionedGroup *gin.RouterGroup, svc *Service) {
+	notificationHandler := NewHandler(svc)
+	protected := versionedGroup.Group("/notifications")
+	protected.GET("/", notificationHandler.GetNotifications)
+	protected.POST("/read", notificationHandler.MarkAllAsRead)
+	protected.POST("/:id/read", notificationHandler.MarkAsRead)
+}
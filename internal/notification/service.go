@@ -0,0 +1,62 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pastorenue/kinance/internal/common"
+	"github.com/pastorenue/kinance/pkg/pagination"
+	"gorm.io/gorm"
+)
+
+type Service struct {
+	db     *gorm.DB
+	logger common.Logger
+}
+
+func NewService(db *gorm.DB, logger common.Logger) *Service {
+	return &Service{db: db, logger: logger}
+}
+
+// notificationPages are the orders notifications can be listed in.
+var notificationPages = pagination.Spec[Notification]{
+	Table: "notifications",
+	Sorts: map[string]pagination.Column[Notification]{
+		"created_at": {Expr: "notifications.created_at", Value: func(n *Notification) any { return n.CreatedAt }},
+	},
+	Default: "created_at",
+	ID:      func(n *Notification) uuid.UUID { return n.ID },
+}
+
+func (s *Service) GetNotifications(ctx context.Context, userID uuid.UUID, unreadOnly bool, params pagination.Params) (*pagination.Page[Notification], error) {
+	query := s.db.WithContext(ctx).Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	return pagination.Paginate(query, notificationPages, params)
+}
+
+func (s *Service) MarkAsRead(ctx context.Context, userID uuid.UUID, notificationID uuid.UUID) error {
+	result := s.db.WithContext(ctx).Model(&Notification{}).
+		Where("id = ? AND user_id = ? AND read_at IS NULL", notificationID, userID).
+		Update("read_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("notification not found")
+	}
+	return nil
+}
+
+func (s *Service) MarkAllAsRead(ctx context.Context, userID uuid.UUID) (int64, error) {
+	result := s.db.WithContext(ctx).Model(&Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now())
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
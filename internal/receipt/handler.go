@@ -1,12 +1,92 @@
 package receipt
 
-import "github.com/gin-gonic/gin"
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/pastorenue/kinance/internal/common"
+	"github.com/pastorenue/kinance/pkg/middleware"
+	"github.com/pastorenue/kinance/pkg/pagination"
+)
 
 // ...existing code...
 
 func (h *Handler) UploadReceipt(c *gin.Context) {}
-func (h *Handler) GetReceipts(c *gin.Context)   {}
-func (h *Handler) GetReceipt(c *gin.Context)    {}
+
+func (h *Handler) GetReceipts(c *gin.Context) {
+	userID, _ := c.Get(middleware.UserIDKey)
+
+	var params pagination.Params
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(http.StatusBadRequest, common.APIResponse{
+			Success:    false,
+			StatusCode: http.StatusBadRequest,
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	filter, err := common.ParseFilter(c.Query("filter"), ReceiptFilters)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.APIResponse{
+			Success:    false,
+			StatusCode: http.StatusBadRequest,
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	receipts, err := h.service.GetReceipts(c.Request.Context(), userID.(uuid.UUID), filter, params)
+	if err != nil {
+		c.JSON(pagination.HTTPStatus(err), common.APIResponse{
+			Success:    false,
+			StatusCode: pagination.HTTPStatus(err),
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, common.APIResponse{
+		Success:    true,
+		StatusCode: http.StatusOK,
+		Data:       receipts,
+	})
+}
+
+func (h *Handler) GetReceipt(c *gin.Context) {
+	userID, _ := c.Get(middleware.UserIDKey)
+	receiptID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, common.APIResponse{
+			Success:    false,
+			StatusCode: http.StatusBadRequest,
+			Error:      "Invalid receipt ID",
+		})
+		return
+	}
+
+	receipt, err := h.service.GetReceiptByID(c.Request.Context(), userID.(uuid.UUID), receiptID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrReceiptNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, common.APIResponse{
+			Success:    false,
+			StatusCode: status,
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, common.APIResponse{
+		Success:    true,
+		StatusCode: http.StatusOK,
+		Data:       receipt,
+	})
+}
 
 type Handler struct {
 	service *Service
@@ -12,8 +12,8 @@ type Receipt struct {
 	OriginalImageURL  string                 `json:"original_image_url" gorm:"not null"`
 	ProcessedImageURL string                 `json:"processed_image_url"`
 	Merchant          string                 `json:"merchant"`
-	Total             float64                `json:"total"`
-	Tax               float64                `json:"tax"`
+	Total             common.Money           `json:"total" gorm:"embedded;embeddedPrefix:total_"`
+	Tax               common.Money           `json:"tax" gorm:"embedded;embeddedPrefix:tax_"`
 	ProcessingStatus  ProcessingStatus       `json:"processing_status" gorm:"default:pending"`
 	OCRData           map[string]interface{} `json:"ocr_data" gorm:"type:jsonb"`
 	Items             []ReceiptItem          `json:"items"`
@@ -22,13 +22,13 @@ type Receipt struct {
 
 type ReceiptItem struct {
 	common.BaseModel
-	ReceiptID  uuid.UUID `json:"receipt_id" gorm:"not null;index"`
-	Name       string    `json:"name" gorm:"not null"`
-	Quantity   int       `json:"quantity" gorm:"default:1"`
-	UnitPrice  float64   `json:"unit_price"`
-	TotalPrice float64   `json:"total_price"`
-	Category   string    `json:"category"`
-	Barcode    string    `json:"barcode"`
+	ReceiptID  uuid.UUID    `json:"receipt_id" gorm:"not null;index"`
+	Name       string       `json:"name" gorm:"not null"`
+	Quantity   int          `json:"quantity" gorm:"default:1"`
+	UnitPrice  common.Money `json:"unit_price" gorm:"embedded;embeddedPrefix:unit_price_"`
+	TotalPrice common.Money `json:"total_price" gorm:"embedded;embeddedPrefix:total_price_"`
+	Category   string       `json:"category"`
+	Barcode    string       `json:"barcode"`
 }
 
 type ProcessingStatus string
@@ -5,14 +5,21 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/google/uuid"
+	"github.com/pastorenue/kinance/internal/common"
 	"github.com/pastorenue/kinance/pkg/config"
+	"github.com/pastorenue/kinance/pkg/pagination"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
+var ErrReceiptNotFound = errors.New("receipt not found")
+
 type Service struct {
 	db       *gorm.DB
 	aiConfig config.AIConfig
@@ -84,19 +91,20 @@ func (s *Service) processReceiptAsync(receiptID uuid.UUID, imageData string) {
 
 type OCRResult struct {
 	Merchant   string                 `json:"merchant"`
-	Total      float64                `json:"total"`
-	Tax        float64                `json:"tax"`
+	Currency   common.Currency        `json:"currency"` // Defaults to EUR when the OCR service cannot tell or reads an unknown code
+	Total      decimal.Decimal        `json:"total"`
+	Tax        decimal.Decimal        `json:"tax"`
 	Items      []OCRItem              `json:"items"`
 	Confidence float64                `json:"confidence"`
 	RawData    map[string]interface{} `json:"raw_data"`
 }
 
 type OCRItem struct {
-	Name       string  `json:"name"`
-	Quantity   int     `json:"quantity"`
-	UnitPrice  float64 `json:"unit_price"`
-	TotalPrice float64 `json:"total_price"`
-	Barcode    string  `json:"barcode,omitempty"`
+	Name       string          `json:"name"`
+	Quantity   int             `json:"quantity"`
+	UnitPrice  decimal.Decimal `json:"unit_price"`
+	TotalPrice decimal.Decimal `json:"total_price"`
+	Barcode    string          `json:"barcode,omitempty"`
 }
 
 func (s *Service) callOCRService(imageData []byte) (*OCRResult, error) {
@@ -140,14 +148,23 @@ func (s *Service) callOCRService(imageData []byte) (*OCRResult, error) {
 }
 
 func (s *Service) updateReceiptWithOCRResult(receiptID uuid.UUID, ocrResult *OCRResult) error {
+	currency := common.Currency(strings.ToUpper(string(ocrResult.Currency)))
+	if !currency.IsValid() {
+		currency = common.DefaultCurrency
+	}
+	total := common.NewMoney(ocrResult.Total, currency)
+	tax := common.NewMoney(ocrResult.Tax, currency)
+
 	return s.db.Transaction(func(tx *gorm.DB) error {
 		// Update receipt with OCR data
 		updates := map[string]interface{}{
-			"merchant":   ocrResult.Merchant,
-			"total":      ocrResult.Total,
-			"tax":        ocrResult.Tax,
-			"confidence": ocrResult.Confidence,
-			"ocr_data":   ocrResult.RawData,
+			"merchant":       ocrResult.Merchant,
+			"total_amount":   total.Amount,
+			"total_currency": total.Currency,
+			"tax_amount":     tax.Amount,
+			"tax_currency":   tax.Currency,
+			"confidence":     ocrResult.Confidence,
+			"ocr_data":       ocrResult.RawData,
 		}
 
 		if err := tx.Model(&Receipt{}).Where("id = ?", receiptID).Updates(updates).Error; err != nil {
@@ -160,8 +177,8 @@ func (s *Service) updateReceiptWithOCRResult(receiptID uuid.UUID, ocrResult *OCR
 				ReceiptID:  receiptID,
 				Name:       item.Name,
 				Quantity:   item.Quantity,
-				UnitPrice:  item.UnitPrice,
-				TotalPrice: item.TotalPrice,
+				UnitPrice:  common.NewMoney(item.UnitPrice, currency),
+				TotalPrice: common.NewMoney(item.TotalPrice, currency),
 				Barcode:    item.Barcode,
 			}
 
@@ -178,17 +195,38 @@ func (s *Service) updateReceiptStatus(receiptID uuid.UUID, status ProcessingStat
 	s.db.Model(&Receipt{}).Where("id = ?", receiptID).Update("processing_status", status)
 }
 
-func (s *Service) GetReceipts(ctx context.Context, userID uuid.UUID) ([]Receipt, error) {
-	var receipts []Receipt
-	if err := s.db.WithContext(ctx).Preload("Items").Where("user_id = ?", userID).Find(&receipts).Error; err != nil {
-		return nil, err
-	}
-	return receipts, nil
+// receiptPages are the orders receipts can be listed in.
+var receiptPages = pagination.Spec[Receipt]{
+	Table: "receipts",
+	Sorts: map[string]pagination.Column[Receipt]{
+		"date":  {Expr: "receipts.created_at", Value: func(r *Receipt) any { return r.CreatedAt }},
+		"total": {Expr: "receipts.total_amount", Value: func(r *Receipt) any { return r.Total.Amount }},
+	},
+	Default: "date",
+	ID:      func(r *Receipt) uuid.UUID { return r.ID },
+}
+
+// ReceiptFilters are the fields receipts can be filtered on.
+var ReceiptFilters = common.FilterFields{
+	"total":             {Column: "receipts.total_amount", Kind: common.FilterNumber},
+	"currency":          {Column: "receipts.total_currency"},
+	"merchant":          {Column: "receipts.merchant", Ops: []common.FilterOp{common.OpEq, common.OpNe, common.OpLike}},
+	"processing_status": {Column: "receipts.processing_status", Values: []string{string(StatusPendingProcessing), string(StatusProcessing), string(StatusProcessed), string(StatusFailed)}},
+	"transaction_id":    {Column: "receipts.transaction_id", Kind: common.FilterUUID},
+	"date":              {Column: "receipts.created_at", Kind: common.FilterTime},
+}
+
+func (s *Service) GetReceipts(ctx context.Context, userID uuid.UUID, filter common.Filter, params pagination.Params) (*pagination.Page[Receipt], error) {
+	query := s.db.WithContext(ctx).Where("user_id = ?", userID).Scopes(filter.Scope)
+	return pagination.Paginate(query, receiptPages, params, "Items")
 }
 
 func (s *Service) GetReceiptByID(ctx context.Context, userID, receiptID uuid.UUID) (*Receipt, error) {
 	var receipt Receipt
 	if err := s.db.WithContext(ctx).Preload("Items").Where("id = ? AND user_id = ?", receiptID, userID).First(&receipt).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrReceiptNotFound
+		}
 		return nil, err
 	}
 	return &receipt, nil
@@ -0,0 +1,240 @@
+package reconciliation
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/pastorenue/kinance/internal/account"
+	"github.com/pastorenue/kinance/internal/common"
+	"github.com/pastorenue/kinance/pkg/middleware"
+	"github.com/pastorenue/kinance/pkg/pagination"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) CreateReconciliation(c *gin.Context) {
+	userID, _ := c.Get(middleware.UserIDKey)
+
+	var req CreateReconciliationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeBadRequest(c, err.Error())
+		return
+	}
+
+	reconciliation, err := h.service.CreateReconciliation(c.Request.Context(), userID.(uuid.UUID), &req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, common.APIResponse{
+		Success:    true,
+		StatusCode: http.StatusCreated,
+		Data:       reconciliation,
+	})
+}
+
+func (h *Handler) GetReconciliations(c *gin.Context) {
+	userID, _ := c.Get(middleware.UserIDKey)
+
+	var params pagination.Params
+	if err := c.ShouldBindQuery(&params); err != nil {
+		writeBadRequest(c, err.Error())
+		return
+	}
+	var accountID *uuid.UUID
+	if value := c.Query("account_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			writeBadRequest(c, "Invalid account_id")
+			return
+		}
+		accountID = &id
+	}
+
+	reconciliations, err := h.service.GetReconciliations(c.Request.Context(), userID.(uuid.UUID), accountID, params)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, common.APIResponse{
+		Success:    true,
+		StatusCode: http.StatusOK,
+		Data:       reconciliations,
+	})
+}
+
+func (h *Handler) GetReconciliation(c *gin.Context) {
+	userID, reconciliationID, ok := getUserAndReconciliationID(c)
+	if !ok {
+		return
+	}
+
+	reconciliation, err := h.service.GetReconciliation(c.Request.Context(), userID, reconciliationID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, common.APIResponse{
+		Success:    true,
+		StatusCode: http.StatusOK,
+		Data:       reconciliation,
+	})
+}
+
+func (h *Handler) UpdateReconciliation(c *gin.Context) {
+	userID, reconciliationID, ok := getUserAndReconciliationID(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateReconciliationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeBadRequest(c, err.Error())
+		return
+	}
+
+	reconciliation, err := h.service.UpdateReconciliation(c.Request.Context(), userID, reconciliationID, &req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, common.APIResponse{
+		Success:    true,
+		StatusCode: http.StatusOK,
+		Data:       reconciliation,
+	})
+}
+
+func (h *Handler) DeleteReconciliation(c *gin.Context) {
+	userID, reconciliationID, ok := getUserAndReconciliationID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteReconciliation(c.Request.Context(), userID, reconciliationID); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetTransactions lists the transactions to tick, or those a finished reconciliation locked.
+func (h *Handler) GetTransactions(c *gin.Context) {
+	userID, reconciliationID, ok := getUserAndReconciliationID(c)
+	if !ok {
+		return
+	}
+
+	var params pagination.Params
+	if err := c.ShouldBindQuery(&params); err != nil {
+		writeBadRequest(c, err.Error())
+		return
+	}
+
+	transactions, err := h.service.GetTransactions(c.Request.Context(), userID, reconciliationID, params)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, common.APIResponse{
+		Success:    true,
+		StatusCode: http.StatusOK,
+		Data:       transactions,
+	})
+}
+
+// Clear ticks or unticks transactions and returns the updated difference.
+func (h *Handler) Clear(c *gin.Context) {
+	userID, reconciliationID, ok := getUserAndReconciliationID(c)
+	if !ok {
+		return
+	}
+
+	var req ClearRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		writeBadRequest(c, err.Error())
+		return
+	}
+
+	reconciliation, err := h.service.Clear(c.Request.Context(), userID, reconciliationID, &req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, common.APIResponse{
+		Success:    true,
+		StatusCode: http.StatusOK,
+		Data:       reconciliation,
+	})
+}
+
+func (h *Handler) FinishReconciliation(c *gin.Context) {
+	userID, reconciliationID, ok := getUserAndReconciliationID(c)
+	if !ok {
+		return
+	}
+
+	reconciliation, err := h.service.FinishReconciliation(c.Request.Context(), userID, reconciliationID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, common.APIResponse{
+		Success:    true,
+		StatusCode: http.StatusOK,
+		Data:       reconciliation,
+	})
+}
+
+func getUserAndReconciliationID(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	userID, _ := c.Get(middleware.UserIDKey)
+	reconciliationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		writeBadRequest(c, "Invalid reconciliation ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID.(uuid.UUID), reconciliationID, true
+}
+
+func writeBadRequest(c *gin.Context, message string) {
+	c.JSON(http.StatusBadRequest, common.APIResponse{
+		Success:    false,
+		StatusCode: http.StatusBadRequest,
+		Error:      message,
+	})
+}
+
+func writeError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrInvalidReconciliation), errors.Is(err, pagination.ErrInvalid):
+		status = http.StatusBadRequest
+	case errors.Is(err, ErrReconciliationNotFound), errors.Is(err, account.ErrAccountNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrAlreadyOpen), errors.Is(err, ErrFinished):
+		status = http.StatusConflict
+	case errors.Is(err, ErrUnbalanced):
+		status = http.StatusUnprocessableEntity
+	}
+	c.JSON(status, common.APIResponse{
+		Success:    false,
+		StatusCode: status,
+		Error:      err.Error(),
+	})
+}
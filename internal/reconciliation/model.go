@@ -0,0 +1,61 @@
+package reconciliation
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pastorenue/kinance/internal/common"
+	"github.com/shopspring/decimal"
+)
+
+type Status string
+
+const (
+	StatusOpen     Status = "open"
+	StatusFinished Status = "finished"
+)
+
+// Reconciliation checks an account against a bank statement. Transactions on the statement are
+// ticked as cleared until the cleared balance matches the statement balance; finishing the
+// reconciliation then locks them as reconciled. An account has at most one open reconciliation.
+type Reconciliation struct {
+	common.BaseModel
+	UserID           uuid.UUID       `json:"user_id" gorm:"not null;index"`
+	AccountID        uuid.UUID       `json:"account_id" gorm:"type:uuid;not null;index"`
+	StatementID      *uuid.UUID      `json:"statement_id,omitempty" gorm:"type:uuid"`  // Imported statement it was started from
+	StatementDate    time.Time       `json:"statement_date" gorm:"type:date;not null"` // Last day of the statement
+	StatementBalance decimal.Decimal `json:"statement_balance" gorm:"type:decimal(20,4);not null"`
+	OpeningBalance   decimal.Decimal `json:"opening_balance" gorm:"type:decimal(20,4);not null"` // Statement balance of the previous reconciliation, or the opening balance of the account
+	Currency         common.Currency `json:"currency" gorm:"type:varchar(3);not null"`
+	Status           Status          `json:"status" gorm:"type:varchar(10);not null"`
+	FinishedAt       *time.Time      `json:"finished_at,omitempty"`
+}
+
+// CreateReconciliationRequest starts a reconciliation from the end date and balance of a
+// statement, or from an imported statement, whose transactions are then cleared already.
+type CreateReconciliationRequest struct {
+	AccountID        uuid.UUID        `json:"account_id" binding:"required"`
+	StatementID      *uuid.UUID       `json:"statement_id"`      // Defaults the date and balance to those of the imported statement
+	StatementDate    *time.Time       `json:"statement_date"`    // Required without statement_id
+	StatementBalance *decimal.Decimal `json:"statement_balance"` // Required without statement_id
+}
+
+type UpdateReconciliationRequest struct {
+	StatementDate    *time.Time       `json:"statement_date"`
+	StatementBalance *decimal.Decimal `json:"statement_balance"`
+}
+
+// ClearRequest ticks transactions of the account as cleared, or unticks them.
+type ClearRequest struct {
+	TransactionIDs []uuid.UUID `json:"transaction_ids" binding:"required,min=1,max=500"`
+	Cleared        bool        `json:"cleared"`
+}
+
+// ReconciliationResponse is a reconciliation with its progress.
+type ReconciliationResponse struct {
+	Reconciliation
+	ClearedBalance common.Money `json:"cleared_balance"` // Opening balance plus the cleared transactions and the standalone expenses and incomes
+	Difference     common.Money `json:"difference"`      // Statement balance minus cleared balance; must be zero to finish
+	Cleared        int64        `json:"cleared"`         // Transactions cleared, or reconciled once finished
+	Standalone     int64        `json:"standalone"`      // Expenses and incomes of the account without a transaction, dated in the period of the statement
+}
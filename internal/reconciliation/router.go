@@ -0,0 +1,16 @@
+package reconciliation
+
+import "github.com/gin-gonic/gin"
+
+func RegisterRoutes(versionedGroup *gin.RouterGroup, svc *Service) {
+	reconciliationHandler := NewHandler(svc)
+	protected := versionedGroup.Group("/reconciliations")
+	protected.POST("", reconciliationHandler.CreateReconciliation)
+	protected.GET("", reconciliationHandler.GetReconciliations)
+	protected.GET("/:id", reconciliationHandler.GetReconciliation)
+	protected.PUT("/:id", reconciliationHandler.UpdateReconciliation)
+	protected.DELETE("/:id", reconciliationHandler.DeleteReconciliation)
+	protected.GET("/:id/transactions", reconciliationHandler.GetTransactions)
+	protected.POST("/:id/clear", reconciliationHandler.Clear)
+	protected.POST("/:id/finish", reconciliationHandler.FinishReconciliation)
+}
@@ -0,0 +1,508 @@
+package reconciliation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pastorenue/kinance/internal/account"
+	"github.com/pastorenue/kinance/internal/common"
+	"github.com/pastorenue/kinance/internal/expense"
+	"github.com/pastorenue/kinance/internal/importer"
+	"github.com/pastorenue/kinance/internal/income"
+	"github.com/pastorenue/kinance/internal/transaction"
+	"github.com/pastorenue/kinance/pkg/pagination"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrReconciliationNotFound = errors.New("reconciliation not found")
+	ErrInvalidReconciliation  = errors.New("invalid reconciliation")
+	ErrAlreadyOpen            = errors.New("the account already has an open reconciliation")
+	ErrFinished               = errors.New("reconciliation is finished")
+	ErrUnbalanced             = errors.New("cleared balance does not match the statement balance")
+)
+
+// signedAmount is what a transaction adds to the balance of its account, as in account balances.
+const signedAmount = `CASE type
+	WHEN 'income' THEN amount
+	WHEN 'expense' THEN -amount
+	WHEN 'transfer' THEN CASE direction WHEN 'incoming' THEN amount WHEN 'outgoing' THEN -amount ELSE 0 END
+	ELSE 0
+END`
+
+// openIndex allows one open reconciliation per account.
+const openIndex = "idx_reconciliations_open_account"
+
+type Service struct {
+	db     *gorm.DB
+	logger common.Logger
+}
+
+func NewService(db *gorm.DB, logger common.Logger) *Service {
+	return &Service{db: db, logger: logger}
+}
+
+// CreateReconciliation starts reconciling the account against a statement. The opening balance is
+// the statement balance of the previous reconciliation. Started from an imported statement, the
+// transactions imported from it are cleared right away.
+func (s *Service) CreateReconciliation(ctx context.Context, userID uuid.UUID, req *CreateReconciliationRequest) (*ReconciliationResponse, error) {
+	acct, err := account.Lookup(ctx, s.db, userID, req.AccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Reconciliation{
+		UserID:         userID,
+		AccountID:      acct.ID,
+		StatementID:    req.StatementID,
+		OpeningBalance: acct.OpeningBalance,
+		Currency:       acct.Currency,
+		Status:         StatusOpen,
+	}
+	if req.StatementID != nil {
+		var statement importer.Statement
+		if err := s.db.WithContext(ctx).
+			Where("id = ? AND account_id = ?", *req.StatementID, acct.ID).
+			First(&statement).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, fmt.Errorf("%w: statement %s was not imported into the account", ErrInvalidReconciliation, *req.StatementID)
+			}
+			return nil, err
+		}
+		if statement.ClosingBalance != nil {
+			r.StatementBalance = *statement.ClosingBalance
+		}
+		switch {
+		case statement.ClosingDate != nil:
+			r.StatementDate = *statement.ClosingDate
+		case statement.EndDate != nil:
+			r.StatementDate = *statement.EndDate
+		}
+		if statement.ClosingBalance == nil && req.StatementBalance == nil {
+			return nil, fmt.Errorf("%w: the statement has no closing balance; enter statement_balance", ErrInvalidReconciliation)
+		}
+	}
+	if req.StatementBalance != nil {
+		r.StatementBalance = *req.StatementBalance
+	}
+	if req.StatementDate != nil {
+		r.StatementDate = *req.StatementDate
+	}
+	if r.StatementDate.IsZero() {
+		return nil, fmt.Errorf("%w: statement_date is required", ErrInvalidReconciliation)
+	}
+	if req.StatementID == nil && req.StatementBalance == nil {
+		return nil, fmt.Errorf("%w: statement_balance is required", ErrInvalidReconciliation)
+	}
+	r.StatementDate = dateOf(r.StatementDate)
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var open int64
+		if err := tx.Model(&Reconciliation{}).
+			Where("account_id = ? AND status = ?", acct.ID, StatusOpen).
+			Count(&open).Error; err != nil {
+			return err
+		}
+		if open > 0 {
+			return ErrAlreadyOpen
+		}
+
+		previous, err := lastFinished(tx, acct.ID)
+		if err != nil {
+			return err
+		}
+		if previous != nil {
+			if r.StatementDate.Before(previous.StatementDate) {
+				return fmt.Errorf("%w: the account is reconciled up to %s", ErrInvalidReconciliation, previous.StatementDate.Format(dateLayout))
+			}
+			r.OpeningBalance = previous.StatementBalance
+		}
+
+		if err := tx.Create(r).Error; err != nil {
+			if isOpenViolation(err) {
+				return ErrAlreadyOpen
+			}
+			return err
+		}
+		if r.StatementID == nil {
+			return nil
+		}
+		return s.clearable(tx, r).
+			Where("clearing = ? AND metadata->'import'->>'statement_id' = ?", transaction.ClearingUncleared, r.StatementID.String()).
+			Update("clearing", transaction.ClearingCleared).Error
+	})
+	if err != nil {
+		s.logger.Error("Failed to create reconciliation", "account_id", req.AccountID, "error", err)
+		return nil, err
+	}
+
+	s.logger.Info("Reconciliation started", "reconciliation_id", r.ID, "account_id", acct.ID)
+	return s.summarize(ctx, r)
+}
+
+// reconciliationPages are the orders reconciliations can be listed in, latest statement first by default.
+var reconciliationPages = pagination.Spec[Reconciliation]{
+	Table: "reconciliations",
+	Sorts: map[string]pagination.Column[Reconciliation]{
+		"statement_date": {Expr: "reconciliations.statement_date", Value: func(r *Reconciliation) any { return r.StatementDate }},
+		"created_at":     {Expr: "reconciliations.created_at", Value: func(r *Reconciliation) any { return r.CreatedAt }},
+	},
+	Default: "statement_date",
+	ID:      func(r *Reconciliation) uuid.UUID { return r.ID },
+}
+
+// GetReconciliations returns the reconciliations of the accounts of the user, or of one account.
+func (s *Service) GetReconciliations(ctx context.Context, userID uuid.UUID, accountID *uuid.UUID, params pagination.Params) (*pagination.Page[Reconciliation], error) {
+	query := s.db.WithContext(ctx).Where("account_id IN (?)", account.AccessibleIDs(s.db, userID))
+	if accountID != nil {
+		query = query.Where("account_id = ?", *accountID)
+	}
+	return pagination.Paginate(query, reconciliationPages, params)
+}
+
+func (s *Service) GetReconciliation(ctx context.Context, userID uuid.UUID, reconciliationID uuid.UUID) (*ReconciliationResponse, error) {
+	r, err := s.find(s.db.WithContext(ctx), userID, reconciliationID)
+	if err != nil {
+		return nil, err
+	}
+	return s.summarize(ctx, r)
+}
+
+// transactionPages are the orders the transactions of a reconciliation can be listed in, oldest
+// first by default, as on the statement.
+var transactionPages = pagination.Spec[transaction.Transaction]{
+	Table: "transactions",
+	Sorts: map[string]pagination.Column[transaction.Transaction]{
+		"date":   {Expr: "transactions.transaction_date", Value: func(t *transaction.Transaction) any { return t.TransactionDate }},
+		"amount": {Expr: "transactions.amount", Value: func(t *transaction.Transaction) any { return t.Amount.Amount }},
+	},
+	Default: "date",
+	Order:   pagination.Asc,
+	ID:      func(t *transaction.Transaction) uuid.UUID { return t.ID },
+}
+
+// GetTransactions returns the transactions to tick in an open reconciliation: those of the
+// account up to the statement date that are not reconciled yet, and any cleared later ones. Of a
+// finished reconciliation it returns the transactions it reconciled.
+func (s *Service) GetTransactions(ctx context.Context, userID uuid.UUID, reconciliationID uuid.UUID, params pagination.Params) (*pagination.Page[transaction.Transaction], error) {
+	db := s.db.WithContext(ctx)
+	r, err := s.find(db, userID, reconciliationID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := db.Where("transactions.reconciliation_id = ?", r.ID)
+	if r.Status == StatusOpen {
+		query = s.clearable(db, r).
+			Where("transactions.transaction_date < ? OR transactions.clearing = ?", r.StatementDate.AddDate(0, 0, 1), transaction.ClearingCleared)
+	}
+	return pagination.Paginate(query, transactionPages, params, "Category", "Merchant")
+}
+
+// UpdateReconciliation corrects the statement date or balance of an open reconciliation.
+func (s *Service) UpdateReconciliation(ctx context.Context, userID uuid.UUID, reconciliationID uuid.UUID, req *UpdateReconciliationRequest) (*ReconciliationResponse, error) {
+	var r *Reconciliation
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if r, err = s.findOpen(tx, userID, reconciliationID); err != nil {
+			return err
+		}
+		if req.StatementBalance != nil {
+			r.StatementBalance = *req.StatementBalance
+		}
+		if req.StatementDate != nil {
+			previous, err := lastFinished(tx, r.AccountID)
+			if err != nil {
+				return err
+			}
+			date := dateOf(*req.StatementDate)
+			if previous != nil && date.Before(previous.StatementDate) {
+				return fmt.Errorf("%w: the account is reconciled up to %s", ErrInvalidReconciliation, previous.StatementDate.Format(dateLayout))
+			}
+			r.StatementDate = date
+		}
+		return tx.Save(r).Error
+	})
+	if err != nil {
+		s.logger.Error("Failed to update reconciliation", "reconciliation_id", reconciliationID, "error", err)
+		return nil, err
+	}
+	return s.summarize(ctx, r)
+}
+
+// Clear ticks transactions of the account as cleared, or unticks them, and returns the updated
+// difference. Reconciled and canceled transactions, and those in another currency than the
+// account, cannot be ticked.
+func (s *Service) Clear(ctx context.Context, userID uuid.UUID, reconciliationID uuid.UUID, req *ClearRequest) (*ReconciliationResponse, error) {
+	ids := make([]uuid.UUID, 0, len(req.TransactionIDs))
+	seen := make(map[uuid.UUID]bool, len(req.TransactionIDs))
+	for _, id := range req.TransactionIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	state := transaction.ClearingUncleared
+	if req.Cleared {
+		state = transaction.ClearingCleared
+	}
+
+	var r *Reconciliation
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if r, err = s.findOpen(tx, userID, reconciliationID); err != nil {
+			return err
+		}
+		result := s.clearable(tx, r).Where("transactions.id IN ?", ids).Update("clearing", state)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != int64(len(ids)) {
+			return fmt.Errorf("%w: %d of the transactions are not on the account, canceled, reconciled already or in another currency than %s",
+				ErrInvalidReconciliation, int64(len(ids))-result.RowsAffected, r.Currency)
+		}
+		return nil
+	})
+	if err != nil {
+		s.logger.Error("Failed to clear transactions", "reconciliation_id", reconciliationID, "error", err)
+		return nil, err
+	}
+	return s.summarize(ctx, r)
+}
+
+// FinishReconciliation locks the cleared transactions as reconciled once the cleared balance
+// matches the statement balance.
+func (s *Service) FinishReconciliation(ctx context.Context, userID uuid.UUID, reconciliationID uuid.UUID) (*ReconciliationResponse, error) {
+	var r *Reconciliation
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if r, err = s.findOpen(tx.Clauses(clause.Locking{Strength: "UPDATE"}), userID, reconciliationID); err != nil {
+			return err
+		}
+		cleared, err := s.clearedTotal(tx, r)
+		if err != nil {
+			return err
+		}
+		if difference := r.StatementBalance.Sub(r.OpeningBalance.Add(cleared.Sum)); !difference.IsZero() {
+			return fmt.Errorf("%w: %s %s apart", ErrUnbalanced, difference.StringFixed(2), r.Currency)
+		}
+
+		if err := s.clearable(tx, r).
+			Where("transactions.clearing = ?", transaction.ClearingCleared).
+			Updates(map[string]interface{}{"clearing": transaction.ClearingReconciled, "reconciliation_id": r.ID}).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		r.Status, r.FinishedAt = StatusFinished, &now
+		return tx.Save(r).Error
+	})
+	if err != nil {
+		s.logger.Error("Failed to finish reconciliation", "reconciliation_id", reconciliationID, "error", err)
+		return nil, err
+	}
+
+	s.logger.Info("Reconciliation finished", "reconciliation_id", r.ID, "account_id", r.AccountID)
+	return s.summarize(ctx, r)
+}
+
+// DeleteReconciliation abandons an open reconciliation, leaving its transactions cleared, or
+// undoes the latest finished one of the account, unlocking its transactions as cleared.
+func (s *Service) DeleteReconciliation(ctx context.Context, userID uuid.UUID, reconciliationID uuid.UUID) error {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		r, err := s.find(tx, userID, reconciliationID)
+		if err != nil {
+			return err
+		}
+		if r.Status == StatusFinished {
+			latest, err := lastFinished(tx, r.AccountID)
+			if err != nil {
+				return err
+			}
+			var open int64
+			if err := tx.Model(&Reconciliation{}).
+				Where("account_id = ? AND status = ?", r.AccountID, StatusOpen).
+				Count(&open).Error; err != nil {
+				return err
+			}
+			if latest.ID != r.ID || open > 0 {
+				return fmt.Errorf("%w: only the latest reconciliation of the account can be undone, once no other is open", ErrFinished)
+			}
+			if err := tx.Model(&transaction.Transaction{}).
+				Where("reconciliation_id = ?", r.ID).
+				Updates(map[string]interface{}{"clearing": transaction.ClearingCleared, "reconciliation_id": nil}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(r).Error
+	})
+	if err != nil {
+		s.logger.Error("Failed to delete reconciliation", "reconciliation_id", reconciliationID, "error", err)
+		return err
+	}
+
+	s.logger.Info("Reconciliation deleted", "reconciliation_id", reconciliationID)
+	return nil
+}
+
+// summarize adds the cleared balance and the difference to the statement balance.
+func (s *Service) summarize(ctx context.Context, r *Reconciliation) (*ReconciliationResponse, error) {
+	cleared, err := s.clearedTotal(s.db.WithContext(ctx), r)
+	if err != nil {
+		return nil, err
+	}
+	balance := r.OpeningBalance.Add(cleared.Sum)
+	return &ReconciliationResponse{
+		Reconciliation: *r,
+		ClearedBalance: common.NewMoney(balance, r.Currency),
+		Difference:     common.NewMoney(r.StatementBalance.Sub(balance), r.Currency),
+		Cleared:        cleared.Transactions,
+		Standalone:     cleared.Standalone,
+	}, nil
+}
+
+// clearedTotals is what the cleared balance of a reconciliation adds to its opening balance.
+type clearedTotals struct {
+	Sum          decimal.Decimal
+	Transactions int64
+	Standalone   int64
+}
+
+// clearedTotal returns the sum and number of the transactions cleared in an open reconciliation,
+// or reconciled by a finished one. Expenses and incomes of the account without a transaction
+// cannot be ticked, so those dated in the period of the statement count as cleared.
+func (s *Service) clearedTotal(tx *gorm.DB, r *Reconciliation) (*clearedTotals, error) {
+	query := tx.Model(&transaction.Transaction{}).Where("transactions.reconciliation_id = ?", r.ID)
+	if r.Status == StatusOpen {
+		query = s.clearable(tx, r).Where("transactions.clearing = ?", transaction.ClearingCleared)
+	}
+	var total struct {
+		Sum   decimal.Decimal
+		Count int64
+	}
+	if err := query.Select(fmt.Sprintf("COALESCE(SUM(%s), 0) AS sum, COUNT(*) AS count", signedAmount)).
+		Scan(&total).Error; err != nil {
+		return nil, err
+	}
+	totals := &clearedTotals{Sum: total.Sum, Transactions: total.Count}
+
+	previous, err := previousFinished(tx, r)
+	if err != nil {
+		return nil, err
+	}
+	until := r.StatementDate.AddDate(0, 0, 1)
+	standalone := func(query *gorm.DB, table, date, signed string) error {
+		var total struct {
+			Sum   decimal.Decimal
+			Count int64
+		}
+		query = query.
+			Where(table+".account_id = ? AND "+table+".currency = ?", r.AccountID, r.Currency).
+			Where("NOT EXISTS (SELECT 1 FROM transactions t WHERE t.processing_object_id = "+table+".id)").
+			Where(date+" < ?", until)
+		if previous != nil {
+			query = query.Where(date+" >= ?", previous.StatementDate.AddDate(0, 0, 1))
+		}
+		if err := query.Select(fmt.Sprintf("COALESCE(SUM(%s), 0) AS sum, COUNT(*) AS count", signed)).
+			Scan(&total).Error; err != nil {
+			return err
+		}
+		totals.Sum = totals.Sum.Add(total.Sum)
+		totals.Standalone += total.Count
+		return nil
+	}
+	if err := standalone(tx.Model(&expense.Expense{}), "expenses", "COALESCE(expenses.due_date, expenses.created_at)", "-expenses.amount"); err != nil {
+		return nil, err
+	}
+	if err := standalone(tx.Model(&income.Income{}).Where("incomes.status <> ?", income.IncomeStatusFailed),
+		"incomes", "incomes.created_at", "incomes.amount"); err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
+
+// clearable selects the transactions of the account that can be ticked in the reconciliation.
+func (s *Service) clearable(tx *gorm.DB, r *Reconciliation) *gorm.DB {
+	return tx.Model(&transaction.Transaction{}).
+		Where("transactions.account_id = ? AND transactions.currency = ?", r.AccountID, r.Currency).
+		Where("transactions.status <> ? AND transactions.clearing <> ?", transaction.StatusCanceled, transaction.ClearingReconciled)
+}
+
+// find returns the reconciliation if it is of an account the user owns or shares.
+func (s *Service) find(tx *gorm.DB, userID uuid.UUID, reconciliationID uuid.UUID) (*Reconciliation, error) {
+	var r Reconciliation
+	if err := tx.Where("id = ? AND account_id IN (?)", reconciliationID, account.AccessibleIDs(s.db, userID)).
+		First(&r).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrReconciliationNotFound
+		}
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (s *Service) findOpen(tx *gorm.DB, userID uuid.UUID, reconciliationID uuid.UUID) (*Reconciliation, error) {
+	r, err := s.find(tx, userID, reconciliationID)
+	if err != nil {
+		return nil, err
+	}
+	if r.Status != StatusOpen {
+		return nil, ErrFinished
+	}
+	return r, nil
+}
+
+// previousFinished returns the finished reconciliation of the account before r, if any. Its
+// statement date is where the period of r starts.
+func previousFinished(tx *gorm.DB, r *Reconciliation) (*Reconciliation, error) {
+	if r.Status == StatusOpen {
+		return lastFinished(tx, r.AccountID)
+	}
+	var previous Reconciliation
+	err := tx.Where("account_id = ? AND status = ? AND id <> ? AND finished_at < ?", r.AccountID, StatusFinished, r.ID, r.FinishedAt).
+		Order("statement_date DESC, finished_at DESC").
+		First(&previous).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &previous, nil
+}
+
+// lastFinished returns the latest finished reconciliation of the account, if any.
+func lastFinished(tx *gorm.DB, accountID uuid.UUID) (*Reconciliation, error) {
+	var r Reconciliation
+	err := tx.Where("account_id = ? AND status = ?", accountID, StatusFinished).
+		Order("statement_date DESC, finished_at DESC").
+		First(&r).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// CreateIndexes adds the index allowing one open reconciliation per account. It is safe to run on
+// every start.
+func CreateIndexes(db *gorm.DB) error {
+	if err := db.Exec(fmt.Sprintf(
+		"CREATE UNIQUE INDEX IF NOT EXISTS %s ON reconciliations (account_id) WHERE status = '%s'", openIndex, StatusOpen,
+	)).Error; err != nil {
+		return fmt.Errorf("failed to create the open reconciliation index: %w", err)
+	}
+	return nil
+}
+
+func isOpenViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == openIndex
+}
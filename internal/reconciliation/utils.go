@@ -0,0 +1,11 @@
+package reconciliation
+
+import "time"
+
+const dateLayout = "2006-01-02"
+
+// dateOf returns the day of t, as statements are dated by day.
+func dateOf(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,98 @@
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/teambition/rrule-go"
+)
+
+// Rule is an RFC 5545 recurrence rule anchored at a start date.
+type Rule struct {
+	rrule *rrule.RRule
+	text  string
+}
+
+// Parse parses an RRULE value such as "FREQ=MONTHLY;BYMONTHDAY=15,-1" and anchors it at start.
+// The optional "RRULE:" prefix is accepted. DTSTART is always taken from start, and
+// sub-daily frequencies are rejected because occurrences are tracked per day.
+func Parse(rule string, start time.Time) (*Rule, error) {
+	text := strings.ToUpper(strings.TrimPrefix(strings.TrimSpace(rule), "RRULE:"))
+	if text == "" {
+		return nil, errors.New("recurrence rule cannot be empty")
+	}
+	if strings.ContainsAny(text, "\r\n") {
+		return nil, errors.New("recurrence rule must be a single RRULE value")
+	}
+
+	opt, err := rrule.StrToROptionInLocation(text, start.Location())
+	if err != nil {
+		return nil, fmt.Errorf("invalid recurrence rule: %w", err)
+	}
+	if opt.Freq > rrule.DAILY {
+		return nil, errors.New("invalid recurrence rule: frequencies shorter than a day are not supported")
+	}
+	opt.Dtstart = start
+
+	r, err := rrule.NewRRule(*opt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recurrence rule: %w", err)
+	}
+	return &Rule{rrule: r, text: opt.RRuleString()}, nil
+}
+
+// FromFrequency returns the rule equivalent to a simple daily/weekly/monthly/yearly schedule
+// starting at start. Monthly and yearly rules clamp to the last day of shorter months, so a
+// series starting on Jan 31 continues on Feb 28 and Mar 31 instead of drifting.
+func FromFrequency(frequency string, start time.Time) string {
+	switch strings.ToLower(frequency) {
+	case "daily":
+		return "FREQ=DAILY"
+	case "weekly":
+		return "FREQ=WEEKLY"
+	case "yearly":
+		if start.Month() == time.February && start.Day() == 29 {
+			return "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29,-1;BYSETPOS=1"
+		}
+		return "FREQ=YEARLY"
+	default:
+		if start.Day() > 28 {
+			return fmt.Sprintf("FREQ=MONTHLY;BYMONTHDAY=%d,-1;BYSETPOS=1", start.Day())
+		}
+		return "FREQ=MONTHLY"
+	}
+}
+
+// String returns the normalised RRULE value without the DTSTART.
+func (r *Rule) String() string {
+	return r.text
+}
+
+// Frequency returns the base frequency of the rule: daily, weekly, monthly or yearly.
+func (r *Rule) Frequency() string {
+	return strings.ToLower(r.rrule.OrigOptions.Freq.String())
+}
+
+// First returns the first occurrence on or after the start date.
+func (r *Rule) First() (time.Time, bool) {
+	return r.NextOnOrAfter(r.rrule.GetDTStart())
+}
+
+// Next returns the first occurrence strictly after t. It returns false once the rule is exhausted.
+func (r *Rule) Next(t time.Time) (time.Time, bool) {
+	next := r.rrule.After(t, false)
+	return next, !next.IsZero()
+}
+
+// NextOnOrAfter returns the first occurrence at or after t. It returns false once the rule is exhausted.
+func (r *Rule) NextOnOrAfter(t time.Time) (time.Time, bool) {
+	next := r.rrule.After(t, true)
+	return next, !next.IsZero()
+}
+
+// Between returns all occurrences within [from, to].
+func (r *Rule) Between(from, to time.Time) []time.Time {
+	return r.rrule.Between(from, to, true)
+}
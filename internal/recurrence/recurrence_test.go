@@ -0,0 +1,229 @@
+package recurrence
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+func day(t *testing.T, value string) time.Time {
+	t.Helper()
+	d, err := time.Parse(dateLayout, value)
+	if err != nil {
+		t.Fatalf("parse %q: %v", value, err)
+	}
+	return d
+}
+
+func dates(times []time.Time) string {
+	days := make([]string, len(times))
+	for i, t := range times {
+		days[i] = t.Format(dateLayout)
+	}
+	return strings.Join(days, " ")
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    string
+		want    string
+		wantErr string
+	}{
+		{name: "plain", rule: "FREQ=MONTHLY;BYMONTHDAY=15,-1", want: "FREQ=MONTHLY;BYMONTHDAY=15,-1"},
+		{name: "prefix and lower case", rule: " rrule:freq=weekly;byday=mo,fr ", want: "FREQ=WEEKLY;BYDAY=MO,FR"},
+		{name: "empty", rule: "  ", wantErr: "cannot be empty"},
+		{name: "several lines", rule: "FREQ=DAILY\nRRULE:FREQ=WEEKLY", wantErr: "single RRULE"},
+		{name: "hourly", rule: "FREQ=HOURLY", wantErr: "shorter than a day"},
+		{name: "unknown part", rule: "FREQ=DAILY;EVERY=2", wantErr: "invalid recurrence rule"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule, day(t, "2026-01-01"))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Parse(%q) error = %v, want one containing %q", tt.rule, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.rule, err)
+			}
+			if rule.String() != tt.want {
+				t.Errorf("String() = %q, want %q", rule.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestFromFrequency(t *testing.T) {
+	tests := []struct {
+		name      string
+		frequency string
+		start     string
+		want      string
+		between   [2]string
+		dates     string
+	}{
+		{
+			name: "daily", frequency: "daily", start: "2026-03-30",
+			want:    "FREQ=DAILY",
+			between: [2]string{"2026-03-30", "2026-04-02"}, dates: "2026-03-30 2026-03-31 2026-04-01 2026-04-02",
+		},
+		{
+			name: "weekly", frequency: "Weekly", start: "2026-10-16",
+			want:    "FREQ=WEEKLY",
+			between: [2]string{"2026-10-16", "2026-11-06"}, dates: "2026-10-16 2026-10-23 2026-10-30 2026-11-06",
+		},
+		{
+			name: "monthly on the 15th", frequency: "monthly", start: "2026-01-15",
+			want:    "FREQ=MONTHLY",
+			between: [2]string{"2026-01-01", "2026-03-31"}, dates: "2026-01-15 2026-02-15 2026-03-15",
+		},
+		{
+			name: "monthly on the 31st clamps to short months", frequency: "monthly", start: "2026-01-31",
+			want:    "FREQ=MONTHLY;BYMONTHDAY=31,-1;BYSETPOS=1",
+			between: [2]string{"2026-01-01", "2026-04-30"}, dates: "2026-01-31 2026-02-28 2026-03-31 2026-04-30",
+		},
+		{
+			name: "monthly on the 29th in a leap year", frequency: "monthly", start: "2028-01-29",
+			want:    "FREQ=MONTHLY;BYMONTHDAY=29,-1;BYSETPOS=1",
+			between: [2]string{"2028-01-01", "2028-03-31"}, dates: "2028-01-29 2028-02-29 2028-03-29",
+		},
+		{
+			name: "yearly", frequency: "yearly", start: "2026-10-16",
+			want:    "FREQ=YEARLY",
+			between: [2]string{"2026-01-01", "2028-12-31"}, dates: "2026-10-16 2027-10-16 2028-10-16",
+		},
+		{
+			name: "yearly on a leap day", frequency: "yearly", start: "2028-02-29",
+			want:    "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=29,-1;BYSETPOS=1",
+			between: [2]string{"2028-01-01", "2032-12-31"}, dates: "2028-02-29 2029-02-28 2030-02-28 2031-02-28 2032-02-29",
+		},
+		{
+			name: "unknown frequencies are monthly", frequency: "fortnightly", start: "2026-01-10",
+			want:    "FREQ=MONTHLY",
+			between: [2]string{"2026-01-01", "2026-02-28"}, dates: "2026-01-10 2026-02-10",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := day(t, tt.start)
+			got := FromFrequency(tt.frequency, start)
+			if got != tt.want {
+				t.Fatalf("FromFrequency(%q, %s) = %q, want %q", tt.frequency, tt.start, got, tt.want)
+			}
+
+			rule, err := Parse(got, start)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", got, err)
+			}
+			if occurrences := dates(rule.Between(day(t, tt.between[0]), day(t, tt.between[1]))); occurrences != tt.dates {
+				t.Errorf("Between(%s, %s) = %s, want %s", tt.between[0], tt.between[1], occurrences, tt.dates)
+			}
+		})
+	}
+}
+
+func TestBetween(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    string
+		start   string
+		between [2]string
+		dates   string
+	}{
+		{
+			name: "last business day of the month", rule: "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", start: "2026-01-01",
+			between: [2]string{"2026-01-01", "2026-06-30"}, dates: "2026-01-30 2026-02-27 2026-03-31 2026-04-30 2026-05-29 2026-06-30",
+		},
+		{
+			name: "first business day of the month", rule: "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=1", start: "2026-01-01",
+			between: [2]string{"2026-01-01", "2026-03-31"}, dates: "2026-01-01 2026-02-02 2026-03-02",
+		},
+		{
+			name: "biweekly", rule: "FREQ=WEEKLY;INTERVAL=2", start: "2026-10-16",
+			between: [2]string{"2026-10-01", "2026-11-30"}, dates: "2026-10-16 2026-10-30 2026-11-13 2026-11-27",
+		},
+		{
+			name: "biweekly on two weekdays", rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH", start: "2026-10-12",
+			between: [2]string{"2026-10-12", "2026-11-09"}, dates: "2026-10-12 2026-10-15 2026-10-26 2026-10-29 2026-11-09",
+		},
+		{
+			name: "quarterly", rule: "FREQ=MONTHLY;INTERVAL=3", start: "2026-01-15",
+			between: [2]string{"2026-01-01", "2026-12-31"}, dates: "2026-01-15 2026-04-15 2026-07-15 2026-10-15",
+		},
+		{
+			name: "quarterly on the last day", rule: "FREQ=MONTHLY;INTERVAL=3;BYMONTHDAY=-1", start: "2026-03-31",
+			between: [2]string{"2026-01-01", "2026-12-31"}, dates: "2026-03-31 2026-06-30 2026-09-30 2026-12-31",
+		},
+		{
+			name: "count ends the series", rule: "FREQ=MONTHLY;COUNT=3", start: "2026-01-10",
+			between: [2]string{"2026-01-01", "2026-12-31"}, dates: "2026-01-10 2026-02-10 2026-03-10",
+		},
+		{
+			name: "count includes occurrences before the window", rule: "FREQ=WEEKLY;COUNT=4", start: "2026-10-02",
+			between: [2]string{"2026-10-20", "2026-12-31"}, dates: "2026-10-23",
+		},
+		{
+			name: "until is inclusive", rule: "FREQ=DAILY;UNTIL=20261020", start: "2026-10-17",
+			between: [2]string{"2026-10-01", "2026-10-31"}, dates: "2026-10-17 2026-10-18 2026-10-19 2026-10-20",
+		},
+		{
+			name: "window before the start", rule: "FREQ=MONTHLY", start: "2026-10-17",
+			between: [2]string{"2026-01-01", "2026-09-30"}, dates: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := Parse(tt.rule, day(t, tt.start))
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tt.rule, err)
+			}
+			if occurrences := dates(rule.Between(day(t, tt.between[0]), day(t, tt.between[1]))); occurrences != tt.dates {
+				t.Errorf("Between(%s, %s) = %s, want %s", tt.between[0], tt.between[1], occurrences, tt.dates)
+			}
+		})
+	}
+}
+
+func TestRuleNext(t *testing.T) {
+	start := day(t, "2026-10-16")
+	rule, err := Parse("FREQ=WEEKLY;COUNT=3", start)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rule.Frequency() != "weekly" {
+		t.Errorf("Frequency() = %q, want weekly", rule.Frequency())
+	}
+
+	tests := []struct {
+		name   string
+		next   func(time.Time) (time.Time, bool)
+		after  string
+		want   string
+		wantOK bool
+	}{
+		{name: "next skips the day itself", next: rule.Next, after: "2026-10-16", want: "2026-10-23", wantOK: true},
+		{name: "next on or after keeps the day itself", next: rule.NextOnOrAfter, after: "2026-10-16", want: "2026-10-16", wantOK: true},
+		{name: "next between occurrences", next: rule.Next, after: "2026-10-20", want: "2026-10-23", wantOK: true},
+		{name: "exhausted after the count", next: rule.Next, after: "2026-10-30", wantOK: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.next(day(t, tt.after))
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && got.Format(dateLayout) != tt.want {
+				t.Errorf("got %s, want %s", got.Format(dateLayout), tt.want)
+			}
+		})
+	}
+
+	if first, ok := rule.First(); !ok || !first.Equal(start) {
+		t.Errorf("First() = %s, %v, want the start date", first.Format(dateLayout), ok)
+	}
+}
@@ -0,0 +1,34 @@
+package scheduler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pastorenue/kinance/internal/common"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) GetStatus(c *gin.Context) {
+	status, err := h.service.Status(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, common.APIResponse{
+			Success:    false,
+			StatusCode: http.StatusInternalServerError,
+			Error:      err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, common.APIResponse{
+		Success:    true,
+		StatusCode: http.StatusOK,
+		Data:       status,
+	})
+}
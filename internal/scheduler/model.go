@@ -0,0 +1,39 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/pastorenue/kinance/internal/common"
+)
+
+// Job is a unit of background work run on a fixed interval by the scheduler.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// JobState is the persisted run history of a job. It doubles as the leader
+// lease: only the replica holding LockedBy/LockedUntil may run the job.
+type JobState struct {
+	common.BaseModel
+	Name                string     `json:"name" gorm:"not null;uniqueIndex"`
+	IntervalSeconds     int64      `json:"interval_seconds"`
+	LastRunAt           *time.Time `json:"last_run_at"`
+	LastSuccessAt       *time.Time `json:"last_success_at"`
+	LastFailureAt       *time.Time `json:"last_failure_at"`
+	LastError           string     `json:"last_error"`
+	LastDurationMs      int64      `json:"last_duration_ms"`
+	NextRunAt           *time.Time `json:"next_run_at"`
+	TotalRuns           int64      `json:"total_runs" gorm:"default:0"`
+	TotalFailures       int64      `json:"total_failures" gorm:"default:0"`
+	ConsecutiveFailures int64      `json:"consecutive_failures" gorm:"default:0"`
+	LockedBy            string     `json:"locked_by"`
+	LockedUntil         *time.Time `json:"locked_until"`
+}
+
+type JobStatusResponse struct {
+	JobState
+	Running bool `json:"running"`
+}
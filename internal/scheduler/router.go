@@ -0,0 +1,9 @@
+package scheduler
+
+import "github.com/gin-gonic/gin"
+
+func RegisterRoutes(versionedGroup *gin.RouterGroup, svc *Service) {
+	schedulerHandler := NewHandler(svc)
+	protected := versionedGroup.Group("/scheduler")
+	protected.GET("/status", schedulerHandler.GetStatus)
+}
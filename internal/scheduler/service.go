@@ -0,0 +1,304 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pastorenue/kinance/internal/common"
+	"github.com/pastorenue/kinance/pkg/config"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Service struct {
+	db           *gorm.DB
+	logger       common.Logger
+	instanceID   string
+	pollInterval time.Duration
+	lockTTL      time.Duration
+
+	mu     sync.Mutex
+	jobs   []Job
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewService(db *gorm.DB, cfg config.SchedulerConfig, logger common.Logger) *Service {
+	hostname, _ := os.Hostname()
+	return &Service{
+		db:           db,
+		logger:       logger,
+		instanceID:   fmt.Sprintf("%s-%s", hostname, uuid.New().String()[:8]),
+		pollInterval: time.Duration(cfg.PollInterval) * time.Second,
+		lockTTL:      time.Duration(cfg.LockTTL) * time.Second,
+	}
+}
+
+// Register adds a job to the scheduler. Jobs must be registered before Start.
+func (s *Service) Register(job Job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs = append(s.jobs, job)
+}
+
+// Start launches one loop per registered job. Each loop polls the persisted job
+// state and only runs the job when it is due and this replica wins the lease.
+func (s *Service) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancel != nil {
+		return errors.New("scheduler already started")
+	}
+
+	for _, job := range s.jobs {
+		if err := s.ensureState(ctx, job); err != nil {
+			return err
+		}
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(runCtx, job)
+	}
+
+	s.logger.Info("Scheduler started", "instance_id", s.instanceID, "jobs", len(s.jobs))
+	return nil
+}
+
+// Stop cancels all job loops and waits for in-flight runs to finish or for ctx to expire.
+func (s *Service) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	cancel := s.cancel
+	s.mu.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		s.logger.Info("Scheduler stopped", "instance_id", s.instanceID)
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Status returns the last run, next run and failure counters of every registered job.
+func (s *Service) Status(ctx context.Context) ([]JobStatusResponse, error) {
+	var states []JobState
+	if err := s.db.WithContext(ctx).Order("name").Find(&states).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]JobStatusResponse, len(states))
+	for i, state := range states {
+		result[i] = JobStatusResponse{
+			JobState: state,
+			Running:  state.LockedUntil != nil && state.LockedUntil.After(time.Now()),
+		}
+	}
+	return result, nil
+}
+
+func (s *Service) ensureState(ctx context.Context, job Job) error {
+	state := &JobState{
+		Name:            job.Name,
+		IntervalSeconds: int64(job.Interval / time.Second),
+	}
+	if err := s.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "name"}},
+			DoUpdates: clause.AssignmentColumns([]string{"interval_seconds"}),
+		}).
+		Create(state).Error; err != nil {
+		return fmt.Errorf("failed to register job %s: %w", job.Name, err)
+	}
+	return nil
+}
+
+func (s *Service) loop(ctx context.Context, job Job) {
+	defer s.wg.Done()
+
+	poll := s.pollInterval
+	if poll <= 0 || poll > job.Interval {
+		poll = job.Interval
+	}
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+
+	// Check immediately so that runs missed while every replica was down are caught up on boot
+	s.tick(ctx, job)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.tick(ctx, job)
+		}
+	}
+}
+
+func (s *Service) tick(ctx context.Context, job Job) {
+	acquired, missed, err := s.acquire(ctx, job)
+	if err != nil {
+		s.logger.Error("Failed to acquire job lease", "job", job.Name, "error", err)
+		return
+	}
+	if !acquired {
+		return
+	}
+	if missed > 0 {
+		s.logger.Info("Catching up missed job runs", "job", job.Name, "missed", missed)
+	}
+
+	// Keep the lease alive while the job runs; a run outlasting the TTL would otherwise be
+	// picked up by another replica. Losing the lease cancels the run.
+	runCtx, cancel := context.WithCancel(ctx)
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		s.renew(runCtx, cancel, job)
+	}()
+
+	start := time.Now()
+	runErr := s.run(runCtx, job)
+	finished := time.Now()
+	cancel()
+	<-renewed
+
+	if err := s.release(job, start, finished, runErr); err != nil {
+		s.logger.Error("Failed to record job run", "job", job.Name, "error", err)
+	}
+
+	if runErr != nil {
+		s.logger.Error("Job run failed", "job", job.Name, "error", runErr)
+		return
+	}
+	s.logger.Info("Job run completed", "job", job.Name, "duration", finished.Sub(start).String())
+}
+
+// run invokes the job, turning a panic into an error so a faulty job cannot take down the loop.
+func (s *Service) run(ctx context.Context, job Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return job.Run(ctx)
+}
+
+// acquire takes the job lease if the job is due and no other replica holds an unexpired lease.
+// It also reports how many whole intervals were missed since the job was last due.
+func (s *Service) acquire(ctx context.Context, job Job) (bool, int64, error) {
+	now := time.Now()
+	lockedUntil := now.Add(s.lockTTL)
+
+	var state JobState
+	if err := s.db.WithContext(ctx).Where("name = ?", job.Name).First(&state).Error; err != nil {
+		return false, 0, err
+	}
+
+	result := s.db.WithContext(ctx).Model(&JobState{}).
+		Where("name = ?", job.Name).
+		Where("next_run_at IS NULL OR next_run_at <= ?", now).
+		Where("locked_until IS NULL OR locked_until < ? OR locked_by = ?", now, s.instanceID).
+		Updates(map[string]interface{}{
+			"locked_by":    s.instanceID,
+			"locked_until": lockedUntil,
+		})
+	if result.Error != nil {
+		return false, 0, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, 0, nil
+	}
+
+	var missed int64
+	if state.NextRunAt != nil && job.Interval > 0 {
+		missed = int64(now.Sub(*state.NextRunAt) / job.Interval)
+	}
+	return true, missed, nil
+}
+
+// renew extends the lease every third of its TTL until ctx is done. If another replica took the
+// lease over, it calls cancel so the run stops instead of overlapping with the new holder.
+func (s *Service) renew(ctx context.Context, cancel context.CancelFunc, job Job) {
+	interval := s.lockTTL / 3
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			result := s.db.WithContext(ctx).Model(&JobState{}).
+				Where("name = ? AND locked_by = ?", job.Name, s.instanceID).
+				Update("locked_until", time.Now().Add(s.lockTTL))
+			if ctx.Err() != nil {
+				return
+			}
+			if result.Error != nil {
+				s.logger.Error("Failed to renew job lease", "job", job.Name, "error", result.Error)
+				continue
+			}
+			if result.RowsAffected == 0 {
+				s.logger.Error("Lost job lease, cancelling run", "job", job.Name)
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// release records the outcome of a run, schedules the next one and drops the lease.
+// It uses a fresh context so the result is persisted even while shutting down.
+func (s *Service) release(job Job, start, finished time.Time, runErr error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	nextRun := finished.Add(job.Interval)
+	updates := map[string]interface{}{
+		"last_run_at":      start,
+		"last_duration_ms": finished.Sub(start).Milliseconds(),
+		"next_run_at":      nextRun,
+		"total_runs":       gorm.Expr("total_runs + 1"),
+		"locked_by":        "",
+		"locked_until":     nil,
+	}
+	if runErr != nil {
+		updates["last_failure_at"] = finished
+		updates["last_error"] = runErr.Error()
+		updates["total_failures"] = gorm.Expr("total_failures + 1")
+		updates["consecutive_failures"] = gorm.Expr("consecutive_failures + 1")
+	} else {
+		updates["last_success_at"] = finished
+		updates["last_error"] = ""
+		updates["consecutive_failures"] = 0
+	}
+
+	return s.db.WithContext(ctx).Model(&JobState{}).
+		Where("name = ? AND locked_by = ?", job.Name, s.instanceID).
+		Updates(updates).Error
+}
@@ -0,0 +1,92 @@
+package search
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/pastorenue/kinance/internal/common"
+	"github.com/pastorenue/kinance/pkg/middleware"
+)
+
+const dateLayout = "2006-01-02"
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) Search(c *gin.Context) {
+	userID, _ := c.Get(middleware.UserIDKey)
+
+	query := &Query{Text: strings.TrimSpace(c.Query("q"))}
+	if query.Text == "" {
+		writeBadRequest(c, "Missing q parameter")
+		return
+	}
+	if value := c.Query("types"); value != "" {
+		for _, name := range strings.Split(value, ",") {
+			t := ResultType(strings.TrimSpace(name))
+			if _, ok := searches[t]; !ok {
+				writeBadRequest(c, "Invalid types parameter, expected a list of transaction, expense, income, merchant and receipt_item")
+				return
+			}
+			query.Types = append(query.Types, t)
+		}
+	}
+	if value := c.Query("from"); value != "" {
+		from, err := time.Parse(dateLayout, value)
+		if err != nil {
+			writeBadRequest(c, "Invalid from parameter, expected YYYY-MM-DD")
+			return
+		}
+		query.From = &from
+	}
+	if value := c.Query("to"); value != "" {
+		to, err := time.Parse(dateLayout, value)
+		if err != nil {
+			writeBadRequest(c, "Invalid to parameter, expected YYYY-MM-DD")
+			return
+		}
+		to = to.AddDate(0, 0, 1)
+		query.To = &to
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > MaxLimit {
+			writeBadRequest(c, "Invalid limit parameter, expected 1 to 50")
+			return
+		}
+		query.Limit = limit
+	}
+
+	results, err := h.service.Search(c.Request.Context(), userID.(uuid.UUID), query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, common.APIResponse{
+			Success:    false,
+			StatusCode: http.StatusInternalServerError,
+			Error:      "Failed to search",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, common.APIResponse{
+		Success:    true,
+		StatusCode: http.StatusOK,
+		Data:       results,
+	})
+}
+
+func writeBadRequest(c *gin.Context, message string) {
+	c.JSON(http.StatusBadRequest, common.APIResponse{
+		Success:    false,
+		StatusCode: http.StatusBadRequest,
+		Error:      message,
+	})
+}
@@ -0,0 +1,39 @@
+package search
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pastorenue/kinance/internal/common"
+)
+
+type ResultType string
+
+const (
+	TypeTransaction ResultType = "transaction"
+	TypeExpense     ResultType = "expense"
+	TypeIncome      ResultType = "income"
+	TypeMerchant    ResultType = "merchant"
+	TypeReceiptItem ResultType = "receipt_item"
+)
+
+// Query is a search of the records of the caller and their family.
+type Query struct {
+	Text  string       // Words to look for; misspelt words still match similar ones
+	Types []ResultType // Defaults to every type
+	From  *time.Time   // Inclusive
+	To    *time.Time   // Exclusive
+	Limit int
+}
+
+// Result is a record matching a search. Receipt items are identified by their receipt.
+type Result struct {
+	Type      ResultType    `json:"type"`
+	ID        uuid.UUID     `json:"id"`
+	UserID    uuid.UUID     `json:"user_id"`
+	Text      string        `json:"text"`      // Description, note or name that matched
+	Highlight string        `json:"highlight"` // Text escaped for HTML, with the matching words wrapped in <mark> tags
+	Date      time.Time     `json:"date"`
+	Amount    *common.Money `json:"amount,omitempty"` // Absent for merchants
+	Rank      float64       `json:"rank"`
+}
@@ -0,0 +1,8 @@
+package search
+
+import "github.com/gin-gonic/gin"
+
+func RegisterRoutes(versionedGroup *gin.RouterGroup, svc *Service) {
+	searchHandler := NewHandler(svc)
+	versionedGroup.GET("/search", searchHandler.Search)
+}
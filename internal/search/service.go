@@ -0,0 +1,181 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pastorenue/kinance/internal/common"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 50
+
+	// similarityThreshold is how close a word must be to the search to match it despite typos.
+	// pg_trgm defaults to 0.6, which misses most misspellings of short words such as "ikae".
+	similarityThreshold = 0.3
+)
+
+type Service struct {
+	db     *gorm.DB
+	logger common.Logger
+}
+
+func NewService(db *gorm.DB, logger common.Logger) *Service {
+	return &Service{db: db, logger: logger}
+}
+
+// document is a searchable column. Each one gets a generated search_vector column with a GIN
+// index for full-text matches and a trigram index for misspelt words.
+type document struct {
+	Table  string
+	Column string
+}
+
+var documents = map[ResultType]document{
+	TypeTransaction: {Table: "transactions", Column: "description"},
+	TypeExpense:     {Table: "expenses", Column: "description"},
+	TypeIncome:      {Table: "incomes", Column: "note"},
+	TypeMerchant:    {Table: "merchants", Column: "name"},
+	TypeReceiptItem: {Table: "receipt_items", Column: "name"},
+}
+
+// searches select the records of each type with the columns of a Result. @members is the caller
+// and their family.
+var searches = map[ResultType]string{
+	TypeTransaction: `
+		SELECT 'transaction' AS type, id, user_id, description AS text, search_vector, transaction_date AS date, amount, currency
+		FROM transactions WHERE user_id IN (@members)`,
+	TypeExpense: `
+		SELECT 'expense' AS type, id, user_id, description AS text, search_vector, COALESCE(due_date, created_at) AS date, amount, currency
+		FROM expenses WHERE user_id IN (@members)`,
+	TypeIncome: `
+		SELECT 'income' AS type, id, user_id::uuid AS user_id, note AS text, search_vector, created_at AS date, amount, currency
+		FROM incomes WHERE user_id::uuid IN (@members)`,
+	TypeMerchant: `
+		SELECT 'merchant' AS type, id, user_id, name AS text, search_vector, created_at AS date, NULL::numeric AS amount, NULL AS currency
+		FROM merchants WHERE user_id IN (@members)`,
+	TypeReceiptItem: `
+		SELECT 'receipt_item' AS type, r.id, r.user_id, i.name AS text, i.search_vector, r.created_at AS date, i.total_price_amount AS amount, i.total_price_currency AS currency
+		FROM receipt_items i JOIN receipts r ON r.id = i.receipt_id WHERE r.user_id IN (@members)`,
+}
+
+// ResultTypes lists the types in the order they are searched.
+var ResultTypes = []ResultType{TypeTransaction, TypeExpense, TypeIncome, TypeMerchant, TypeReceiptItem}
+
+// Search returns the records of the caller and their family matching the query, best match first.
+// Records match when they contain the words of the query or words similar to it.
+func (s *Service) Search(ctx context.Context, userID uuid.UUID, query *Query) ([]Result, error) {
+	types := query.Types
+	if len(types) == 0 {
+		types = ResultTypes
+	}
+	selects := make([]string, 0, len(types))
+	for _, t := range types {
+		selects = append(selects, searches[t])
+	}
+
+	conditions := []string{"(r.search_vector @@ websearch_to_tsquery('simple', @text) OR @text <% r.text)"}
+	args := map[string]interface{}{
+		"text":     query.Text,
+		"headline": headlineOptions,
+		"members": s.db.Raw(`
+			SELECT id FROM users
+			WHERE id = ? OR family_id IN (SELECT family_id FROM users WHERE id = ? AND family_id IS NOT NULL)`, userID, userID),
+	}
+	if query.From != nil {
+		conditions = append(conditions, "r.date >= @from")
+		args["from"] = *query.From
+	}
+	if query.To != nil {
+		conditions = append(conditions, "r.date < @to")
+		args["to"] = *query.To
+	}
+	limit := query.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+	args["limit"] = limit
+
+	sql := fmt.Sprintf(`
+		SELECT r.type, r.id, r.user_id, r.text,
+		       ts_headline('simple', r.text, websearch_to_tsquery('simple', @text), @headline) AS highlight,
+		       r.date, r.amount, r.currency,
+		       ts_rank(r.search_vector, websearch_to_tsquery('simple', @text)) + word_similarity(@text, r.text) AS rank
+		FROM (%s) r
+		WHERE %s
+		ORDER BY rank DESC, r.date DESC
+		LIMIT @limit`, strings.Join(selects, " UNION ALL "), strings.Join(conditions, " AND "))
+
+	type row struct {
+		Type      ResultType
+		ID        uuid.UUID
+		UserID    uuid.UUID
+		Text      string
+		Highlight string
+		Date      time.Time
+		Amount    decimal.NullDecimal
+		Currency  *common.Currency
+		Rank      float64
+	}
+
+	var rows []row
+	if err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(fmt.Sprintf("SET LOCAL pg_trgm.word_similarity_threshold = %v", similarityThreshold)).Error; err != nil {
+			return err
+		}
+		return tx.Raw(sql, args).Scan(&rows).Error
+	}); err != nil {
+		s.logger.Error("Failed to search", "user_id", userID, "error", err)
+		return nil, err
+	}
+
+	results := make([]Result, 0, len(rows))
+	for _, r := range rows {
+		result := Result{
+			Type:      r.Type,
+			ID:        r.ID,
+			UserID:    r.UserID,
+			Text:      r.Text,
+			Highlight: markMatches(r.Highlight),
+			Date:      r.Date,
+			Rank:      r.Rank,
+		}
+		if r.Amount.Valid && r.Currency != nil {
+			amount := common.NewMoney(r.Amount.Decimal, *r.Currency)
+			result.Amount = &amount
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// CreateIndexes adds the generated search_vector columns and the full-text and trigram indexes
+// searches rely on. It is safe to run on every start.
+func CreateIndexes(db *gorm.DB) error {
+	if err := db.Exec("CREATE EXTENSION IF NOT EXISTS pg_trgm").Error; err != nil {
+		return fmt.Errorf("failed to create the pg_trgm extension: %w", err)
+	}
+	for _, t := range ResultTypes {
+		doc := documents[t]
+		for _, statement := range []string{
+			fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS search_vector tsvector
+				GENERATED ALWAYS AS (to_tsvector('simple', COALESCE(%s, ''))) STORED`, doc.Table, doc.Column),
+			fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_search ON %s USING GIN (search_vector)", doc.Table, doc.Table),
+			fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_%s_%s_trgm ON %s USING GIN (%s gin_trgm_ops)", doc.Table, doc.Column, doc.Table, doc.Column),
+		} {
+			if err := db.Exec(statement).Error; err != nil {
+				return fmt.Errorf("failed to index %s.%s for search: %w", doc.Table, doc.Column, err)
+			}
+		}
+	}
+	return nil
+}
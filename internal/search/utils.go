@@ -0,0 +1,44 @@
+package search
+
+import (
+	"html"
+	"strings"
+)
+
+// Delimiters ts_headline wraps matching words in. They are control characters rather than tags,
+// so the text can be escaped for HTML before the matches are marked up.
+const (
+	startSel = "\x01"
+	stopSel  = "\x02"
+)
+
+// headlineOptions are the ts_headline options of a search.
+var headlineOptions = "StartSel=" + startSel + ", StopSel=" + stopSel + ", HighlightAll=true"
+
+// markMatches escapes the headline for HTML and wraps the words ts_headline delimited in <mark>
+// tags. Stray delimiters, such as control characters in the text itself, are dropped.
+func markMatches(headline string) string {
+	var b strings.Builder
+	open := false
+	for {
+		i := strings.IndexAny(headline, startSel+stopSel)
+		if i < 0 {
+			b.WriteString(html.EscapeString(headline))
+			break
+		}
+		b.WriteString(html.EscapeString(headline[:i]))
+		switch delimiter := headline[i : i+1]; {
+		case delimiter == startSel && !open:
+			b.WriteString("<mark>")
+			open = true
+		case delimiter == stopSel && open:
+			b.WriteString("</mark>")
+			open = false
+		}
+		headline = headline[i+1:]
+	}
+	if open {
+		b.WriteString("</mark>")
+	}
+	return b.String()
+}
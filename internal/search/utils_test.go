@@ -0,0 +1,28 @@
+package search
+
+import "testing"
+
+func TestMarkMatches(t *testing.T) {
+	tests := []struct {
+		name     string
+		headline string
+		want     string
+	}{
+		{name: "no match", headline: "Groceries", want: "Groceries"},
+		{name: "one match", headline: "Weekly \x01groceries\x02 run", want: "Weekly <mark>groceries</mark> run"},
+		{name: "several matches", headline: "\x01Coffee\x02 and \x01coffee\x02 beans", want: "<mark>Coffee</mark> and <mark>coffee</mark> beans"},
+		{
+			name:     "markup in the text is escaped",
+			headline: "<img src=x onerror=\"alert(1)\"> \x01lunch\x02 & <mark>tip</mark>",
+			want:     "&lt;img src=x onerror=&#34;alert(1)&#34;&gt; <mark>lunch</mark> &amp; &lt;mark&gt;tip&lt;/mark&gt;",
+		},
+		{name: "stray delimiters are dropped", headline: "a\x02b \x01c\x01d\x02 e\x01f", want: "ab <mark>cd</mark> e<mark>f</mark>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := markMatches(tt.headline); got != tt.want {
+				t.Errorf("markMatches(%q) = %q, want %q", tt.headline, got, tt.want)
+			}
+		})
+	}
+}
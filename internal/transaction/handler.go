@@ -1,11 +1,24 @@
 package transaction
 
 import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/pastorenue/kinance/internal/account"
+	"github.com/pastorenue/kinance/internal/category"
+	"github.com/pastorenue/kinance/internal/common"
+	"github.com/pastorenue/kinance/internal/fx"
 	"github.com/pastorenue/kinance/pkg/middleware"
+	"github.com/pastorenue/kinance/pkg/pagination"
+	"github.com/shopspring/decimal"
 )
 
+const dateLayout = "2006-01-02"
+
 // ...existing code...
 type Handler struct {
 	service *Service
@@ -18,18 +31,83 @@ func NewHandler(service *Service) *Handler {
 func (h *Handler) ListTransactions(c *gin.Context) {
 	userID, _ := c.Get(middleware.UserIDKey)
 
-	transactions, err := h.service.GetTransactions(c.Request.Context(), userID.(uuid.UUID))
+	var params pagination.Params
+	if err := c.ShouldBindQuery(&params); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter, err := parseFilter(c)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	transactions, err := h.service.GetTransactions(c.Request.Context(), userID.(uuid.UUID), filter, params)
 	if err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+		c.JSON(transactionErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(200, transactions)
 }
 
-func (h *Handler) CreateTransaction(c *gin.Context) {}
-func (h *Handler) UpdateTransaction(c *gin.Context) {}
-func (h *Handler) DeleteTransaction(c *gin.Context) {}
+func (h *Handler) CreateTransaction(c *gin.Context) {
+	userID, _ := c.Get(middleware.UserIDKey)
+	var req CreateTransactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	trnxResponse, err := h.service.CreateTransaction(c.Request.Context(), userID.(uuid.UUID), &req)
+	if err != nil {
+		c.JSON(transactionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(201, trnxResponse)
+}
+
+func (h *Handler) UpdateTransaction(c *gin.Context) {
+	userID, _ := c.Get(middleware.UserIDKey)
+	transactionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid transaction ID"})
+		return
+	}
+
+	var req UpdateTransactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	transaction, err := h.service.UpdateTransaction(c.Request.Context(), userID.(uuid.UUID), transactionID, &req)
+	if err != nil {
+		c.JSON(transactionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, transaction)
+}
+
+func (h *Handler) DeleteTransaction(c *gin.Context) {
+	userID, _ := c.Get(middleware.UserIDKey)
+	transactionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid transaction ID"})
+		return
+	}
+
+	if err := h.service.DeleteTransaction(c.Request.Context(), userID.(uuid.UUID), transactionID); err != nil {
+		c.JSON(transactionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Status(204)
+}
+
 func (h *Handler) CreateExpenseTransaction(c *gin.Context) {
 	userID, _ := c.Get(middleware.UserIDKey)
 	var req CreateTransactionRequest
@@ -40,7 +118,7 @@ func (h *Handler) CreateExpenseTransaction(c *gin.Context) {
 
 	trnxResponse, err := h.service.CreateExpenseTransaction(c.Request.Context(), userID.(uuid.UUID), &req)
 	if err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+		c.JSON(transactionErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -57,14 +135,61 @@ func (h *Handler) CreateIncomeTransaction(c *gin.Context) {
 
 	trnxResponse, err := h.service.CreateIncomeTransaction(c.Request.Context(), userID.(uuid.UUID), &req)
 	if err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+		c.JSON(transactionErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(201, trnxResponse)
 }
 
-func (h *Handler) CreateTransferTransaction(c *gin.Context) {}
+func (h *Handler) CreateTransferTransaction(c *gin.Context) {
+	userID, _ := c.Get(middleware.UserIDKey)
+	var req CreateTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	transfer, err := h.service.CreateTransfer(c.Request.Context(), userID.(uuid.UUID), &req)
+	if err != nil {
+		c.JSON(transferErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(201, transfer)
+}
+
+func (h *Handler) GetTransfer(c *gin.Context) {
+	userID, _ := c.Get(middleware.UserIDKey)
+	transferID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid transfer ID"})
+		return
+	}
+
+	transfer, err := h.service.GetTransfer(c.Request.Context(), userID.(uuid.UUID), transferID)
+	if err != nil {
+		c.JSON(transferErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, transfer)
+}
+
+func transferErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidTransfer):
+		return 400
+	case errors.Is(err, ErrTransferNotFound), errors.Is(err, account.ErrAccountNotFound), errors.Is(err, category.ErrCategoryNotFound):
+		return 404
+	case errors.Is(err, fx.ErrRateNotFound):
+		// The client can still send the received amount or rate itself
+		return 422
+	default:
+		return 500
+	}
+}
+
 func (h *Handler) GetTransaction(c *gin.Context) {
 	userID, _ := c.Get(middleware.UserIDKey)
 	transactionID, err := uuid.Parse(c.Param("id"))
@@ -75,12 +200,127 @@ func (h *Handler) GetTransaction(c *gin.Context) {
 
 	transaction, err := h.service.GetTransactionByID(c.Request.Context(), userID.(uuid.UUID), transactionID)
 	if err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+		c.JSON(transactionErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(200, transaction)
 }
-func (h *Handler) LinkExpenseToTransaction(c *gin.Context)  {}
-func (h *Handler) LinkIncomeToTransaction(c *gin.Context)   {}
-func (h *Handler) LinkTransferToTransaction(c *gin.Context) {}
+
+func (h *Handler) LinkExpenseToTransaction(c *gin.Context) {
+	h.link(c, h.service.LinkExpenseToTransaction)
+}
+
+func (h *Handler) LinkIncomeToTransaction(c *gin.Context) {
+	h.link(c, h.service.LinkIncomeToTransaction)
+}
+
+func (h *Handler) LinkTransferToTransaction(c *gin.Context) {
+	h.link(c, h.service.LinkTransferToTransaction)
+}
+
+func (h *Handler) link(c *gin.Context, link func(ctx context.Context, userID, transactionID, objectID uuid.UUID) (*Transaction, error)) {
+	userID, _ := c.Get(middleware.UserIDKey)
+	transactionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "Invalid transaction ID"})
+		return
+	}
+
+	var req LinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	transaction, err := link(c.Request.Context(), userID.(uuid.UUID), transactionID, req.ID)
+	if err != nil {
+		c.JSON(transactionErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(200, transaction)
+}
+
+func transactionErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidTransaction), errors.Is(err, pagination.ErrInvalid):
+		return 400
+	case errors.Is(err, ErrTransactionNotFound), errors.Is(err, ErrLinkTargetNotFound), errors.Is(err, account.ErrAccountNotFound):
+		return 404
+	case errors.Is(err, ErrTransferLeg), errors.Is(err, ErrAlreadyLinked), errors.Is(err, ErrReconciled):
+		return 409
+	default:
+		return 500
+	}
+}
+
+// parseFilter reads the filters of ListTransactions from the query string. Dates are YYYY-MM-DD
+// and both ends of the range are inclusive.
+func parseFilter(c *gin.Context) (*TransactionFilter, error) {
+	filter := &TransactionFilter{
+		Type:     TransactionType(c.Query("type")),
+		Merchant: c.Query("merchant"),
+		Tag:      c.Query("tag"),
+		Status:   TransactionStatus(c.Query("status")),
+		Currency: common.Currency(strings.ToUpper(c.Query("currency"))),
+	}
+
+	if value := c.Query("from"); value != "" {
+		from, err := time.Parse(dateLayout, value)
+		if err != nil {
+			return nil, errors.New("invalid from date, expected YYYY-MM-DD")
+		}
+		filter.From = &from
+	}
+	if value := c.Query("to"); value != "" {
+		to, err := time.Parse(dateLayout, value)
+		if err != nil {
+			return nil, errors.New("invalid to date, expected YYYY-MM-DD")
+		}
+		to = to.AddDate(0, 0, 1)
+		filter.To = &to
+	}
+	if value := c.Query("category_id"); value != "" {
+		categoryID, err := uuid.Parse(value)
+		if err != nil {
+			return nil, errors.New("invalid category_id")
+		}
+		filter.CategoryID = &categoryID
+	}
+	if value := c.Query("min_amount"); value != "" {
+		amount, err := decimal.NewFromString(value)
+		if err != nil {
+			return nil, errors.New("invalid min_amount")
+		}
+		filter.MinAmount = &amount
+	}
+	if value := c.Query("max_amount"); value != "" {
+		amount, err := decimal.NewFromString(value)
+		if err != nil {
+			return nil, errors.New("invalid max_amount")
+		}
+		filter.MaxAmount = &amount
+	}
+
+	switch filter.Type {
+	case "", TypeIncome, TypeExpense, TypeTransfer:
+	default:
+		return nil, errors.New("invalid type, expected income, expense or transfer")
+	}
+	switch filter.Status {
+	case "", StatusPending, StatusCompleted, StatusCanceled:
+	default:
+		return nil, errors.New("invalid status, expected pending, completed or canceled")
+	}
+	if filter.Currency != "" && !filter.Currency.IsValid() {
+		return nil, errors.New("invalid currency")
+	}
+
+	where, err := common.ParseFilter(c.Query("filter"), TransactionFilters)
+	if err != nil {
+		return nil, err
+	}
+	filter.Where = where
+	return filter, nil
+}
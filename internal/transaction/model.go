@@ -19,12 +19,19 @@ const (
 
 type Merchant struct {
 	common.BaseModel
-	Name    string    `json:"name" gorm:"not null;uniqueIndex"`
+	Name    string    `json:"name" gorm:"not null;uniqueIndex:idx_merchant_user_name"`
 	Website string    `json:"website"`
 	LogoURL string    `json:"logo_url"`
-	UserID  uuid.UUID `json:"user_id" gorm:"not null;index"`
+	UserID  uuid.UUID `json:"user_id" gorm:"not null;index;uniqueIndex:idx_merchant_user_name"`
 }
 
+type TransferDirection string
+
+const (
+	DirectionOutgoing TransferDirection = "outgoing"
+	DirectionIncoming TransferDirection = "incoming"
+)
+
 type TransactionStatus string
 
 const (
@@ -33,10 +40,26 @@ const (
 	StatusCanceled  TransactionStatus = "canceled"
 )
 
+// ClearingStatus tracks a transaction against the statements of its bank: cleared once ticked off
+// a statement, reconciled once the reconciliation of that statement is finished. Reconciled
+// transactions are locked against changes to their amount, date, account and status.
+type ClearingStatus string
+
+const (
+	ClearingUncleared  ClearingStatus = "uncleared"
+	ClearingCleared    ClearingStatus = "cleared"
+	ClearingReconciled ClearingStatus = "reconciled"
+)
+
+// MergedReferencesKey is the metadata key listing the import references of the duplicates merged
+// into a transaction.
+const MergedReferencesKey = "merged_references"
+
 type Transaction struct {
 	common.BaseModel
 	UserID               uuid.UUID              `json:"user_id" gorm:"not null;index"`
-	Amount               decimal.Decimal        `json:"amount" gorm:"not null"`
+	AccountID            *uuid.UUID             `json:"account_id" gorm:"type:uuid;index"`
+	Amount               common.Money           `json:"amount" gorm:"embedded"`
 	Description          string                 `json:"description"`
 	CategoryID           uuid.UUID              `json:"category_id" gorm:"index"`
 	Category             *category.Category     `json:"category" gorm:"foreignKey:CategoryID"`
@@ -45,35 +68,105 @@ type Transaction struct {
 	Tags                 []Tag                  `json:"tags" gorm:"many2many:transaction_tags;"`
 	Type                 TransactionType        `json:"type" gorm:"not null"`
 	ProcessingObjectID   *uuid.UUID             `json:"processing_object_id" gorm:"uniqueIndex"`
-	Currency             *common.Currency       `json:"currency" gorm:"not null;default:EUR"`
 	ExcludeFromAnalytics bool                   `json:"exclude_from_analytics" gorm:"default:false"`
+	TransferID           *uuid.UUID             `json:"transfer_id,omitempty" gorm:"type:uuid;index"`
+	Direction            TransferDirection      `json:"direction,omitempty" gorm:"type:varchar(10)"` // Leg of a transfer; amounts are always positive
 	MerchantID           *uuid.UUID             `json:"merchant" gorm:"index"`
 	Merchant             *Merchant              `json:"merchant_details" gorm:"foreignKey:MerchantID"`
 	ReceiptID            *uuid.UUID             `json:"receipt" gorm:"index"`
 	Metadata             map[string]interface{} `json:"metadata" gorm:"type:jsonb"`
 	PaymentMethod        common.PaymentMethod   `json:"payment_method" gorm:"type:payment_method"`
+	Clearing             ClearingStatus         `json:"clearing" gorm:"type:varchar(10);not null;default:uncleared"`
+	ReconciliationID     *uuid.UUID             `json:"reconciliation_id,omitempty" gorm:"type:uuid;index"` // Reconciliation that locked the transaction
+}
+
+// Transfer moves money between two accounts of the user. It is written as an outgoing leg on the
+// source account and an incoming leg on the destination account, plus an expense for the fee, if
+// any. The legs are excluded from income and expense analytics.
+type Transfer struct {
+	common.BaseModel
+	UserID         uuid.UUID       `json:"user_id" gorm:"not null;index"`
+	FromAccountID  uuid.UUID       `json:"from_account_id" gorm:"type:uuid;not null;index"`
+	ToAccountID    uuid.UUID       `json:"to_account_id" gorm:"type:uuid;not null;index"`
+	Amount         common.Money    `json:"amount" gorm:"embedded"`                                   // Sent, in the currency of the source account
+	ReceivedAmount common.Money    `json:"received_amount" gorm:"embedded;embeddedPrefix:received_"` // In the currency of the destination account
+	Rate           decimal.Decimal `json:"rate" gorm:"type:decimal(24,10);not null"`                 // ReceivedAmount / Amount
+	Fee            common.Money    `json:"fee" gorm:"embedded;embeddedPrefix:fee_"`                  // Charged to the source account on top of Amount
+	TransferDate   time.Time       `json:"transfer_date" gorm:"not null"`
+	Description    string          `json:"description"`
+	Transactions   []Transaction   `json:"transactions" gorm:"foreignKey:TransferID"`
 }
 
 type Tag struct {
 	common.BaseModel
-	Name   string    `json:"name" gorm:"uniqueIndex;not null"`
+	Name   string    `json:"name" gorm:"uniqueIndex:idx_tag_user_name;not null"`
 	Color  string    `json:"color" gorm:"default:#007bff"`
-	UserID uuid.UUID `json:"user_id" gorm:"not null"`
+	UserID uuid.UUID `json:"user_id" gorm:"not null;uniqueIndex:idx_tag_user_name"`
 }
 
 type CreateTransactionRequest struct {
 	Amount          decimal.Decimal        `json:"amount" binding:"required"`
 	Description     string                 `json:"description" binding:"required"`
 	CategoryID      uuid.UUID              `json:"category_id" binding:"required"`
+	AccountID       *uuid.UUID             `json:"account_id"`
 	Merchant        string                 `json:"merchant"`
 	TransactionDate time.Time              `json:"transaction_date" binding:"required"`
 	Type            TransactionType        `json:"type" binding:"required"`
 	Tags            []string               `json:"tags"`
-	Currency        common.Currency        `json:"currency" binding:"required,oneof=USD EUR GBP JPY CHF NGN"`
+	Currency        common.Currency        `json:"currency" binding:"required,currency"`
 	PaymentMethod   common.PaymentMethod   `json:"payment_method" binding:"required,oneof=cash card bank_transfer"`
 	Metadata        map[string]interface{} `json:"metadata"`
 }
 
+// UpdateTransactionRequest changes a transaction and the expense or income linked to it.
+// The type of a transaction cannot change.
+type UpdateTransactionRequest struct {
+	Amount               *decimal.Decimal       `json:"amount"`
+	Currency             *common.Currency       `json:"currency" binding:"omitempty,currency"`
+	Description          *string                `json:"description"`
+	CategoryID           *uuid.UUID             `json:"category_id"`
+	AccountID            *uuid.UUID             `json:"account_id"`
+	Merchant             *string                `json:"merchant"` // Empty removes the merchant
+	TransactionDate      *time.Time             `json:"transaction_date"`
+	Status               *TransactionStatus     `json:"status" binding:"omitempty,oneof=pending completed canceled"`
+	Tags                 *[]string              `json:"tags"` // Replaces all tags
+	PaymentMethod        *common.PaymentMethod  `json:"payment_method" binding:"omitempty,oneof=cash card bank_transfer"`
+	Metadata             map[string]interface{} `json:"metadata"`
+	ExcludeFromAnalytics *bool                  `json:"exclude_from_analytics"`
+}
+
+// LinkRequest names the expense, income or transfer a transaction is linked to.
+type LinkRequest struct {
+	ID uuid.UUID `json:"id" binding:"required"`
+}
+
+// TransactionFilter narrows ListTransactions. Empty fields do not filter.
+type TransactionFilter struct {
+	From       *time.Time // Inclusive
+	To         *time.Time // Exclusive
+	Type       TransactionType
+	CategoryID *uuid.UUID // Includes the subcategories
+	Merchant   string     // Case-insensitive substring of the merchant name
+	Tag        string     // Tag name
+	MinAmount  *decimal.Decimal
+	MaxAmount  *decimal.Decimal
+	Status     TransactionStatus
+	Currency   common.Currency
+	Where      common.Filter // Parsed from the filter query parameter against TransactionFilters
+}
+
+type CreateTransferRequest struct {
+	FromAccountID  uuid.UUID        `json:"from_account_id" binding:"required"`
+	ToAccountID    uuid.UUID        `json:"to_account_id" binding:"required,nefield=FromAccountID"`
+	Amount         decimal.Decimal  `json:"amount" binding:"required"` // In the currency of the source account
+	ReceivedAmount *decimal.Decimal `json:"received_amount"`           // Cross-currency only; derived from rate when empty
+	Rate           *decimal.Decimal `json:"rate"`                      // Cross-currency only; defaults to the stored rate of the day
+	Fee            decimal.Decimal  `json:"fee"`                       // In the currency of the source account
+	FeeCategoryID  *uuid.UUID       `json:"fee_category_id"`           // Category of the fee expense; required with a fee
+	TransferDate   time.Time        `json:"transfer_date"`             // Defaults to now
+	Description    string           `json:"description"`
+}
+
 type TransactionResponse struct {
 	StatusCode  int         `json:"status_code"`
 	Message     string      `json:"message"`
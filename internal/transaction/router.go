@@ -12,4 +12,9 @@ func RegisterRoutes(versionedGroup *gin.RouterGroup, svc *Service) {
 	protected.DELETE("/:id", transHandler.DeleteTransaction)
 	protected.POST("/expense", transHandler.CreateExpenseTransaction)
 	protected.POST("/income", transHandler.CreateIncomeTransaction)
-}
\ No newline at end of file
+	protected.POST("/transfer", transHandler.CreateTransferTransaction)
+	protected.GET("/transfer/:id", transHandler.GetTransfer)
+	protected.POST("/:id/link/expense", transHandler.LinkExpenseToTransaction)
+	protected.POST("/:id/link/income", transHandler.LinkIncomeToTransaction)
+	protected.POST("/:id/link/transfer", transHandler.LinkTransferToTransaction)
+}
@@ -2,23 +2,60 @@ package transaction
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/pastorenue/kinance/internal/account"
+	"github.com/pastorenue/kinance/internal/category"
 	"github.com/pastorenue/kinance/internal/common"
 	"github.com/pastorenue/kinance/internal/expense"
+	"github.com/pastorenue/kinance/internal/fx"
 	"github.com/pastorenue/kinance/internal/income"
+	"github.com/pastorenue/kinance/internal/ledger"
+	"github.com/pastorenue/kinance/internal/receipt"
+	"github.com/pastorenue/kinance/pkg/pagination"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrTransactionNotFound = errors.New("transaction not found")
+	ErrInvalidTransaction  = errors.New("invalid transaction")
+	ErrTransferLeg         = errors.New("transfer legs can only be changed through their transfer")
+	ErrAlreadyLinked       = errors.New("already linked to another transaction")
+	ErrLinkTargetNotFound  = errors.New("expense, income or transfer to link not found")
+	ErrTransferNotFound    = errors.New("transfer not found")
+	ErrInvalidTransfer     = errors.New("invalid transfer")
+	ErrReconciled          = common.ErrReconciled
 )
 
 type Service struct {
-	db     *gorm.DB
-	logger common.Logger
+	db        *gorm.DB
+	rates     *fx.Service
+	logger    common.Logger
+	listeners []Listener
+}
+
+// Listener is called after a transaction has been committed, for example to evaluate budget alerts.
+type Listener func(ctx context.Context, transaction *Transaction)
+
+func NewService(db *gorm.DB, rates *fx.Service, logger common.Logger) *Service {
+	return &Service{db: db, rates: rates, logger: logger}
 }
 
-func NewService(db *gorm.DB, logger common.Logger) *Service {
-	return &Service{db: db, logger: logger}
+// AddListener registers a listener for created transactions. Listeners must be added before the service is used.
+func (s *Service) AddListener(listener Listener) {
+	s.listeners = append(s.listeners, listener)
+}
+
+func (s *Service) notifyCreated(ctx context.Context, transaction *Transaction) {
+	for _, listener := range s.listeners {
+		listener(ctx, transaction)
+	}
 }
 
 func (s *Service) CreateExpenseTransaction(
@@ -27,17 +64,22 @@ func (s *Service) CreateExpenseTransaction(
 	req *CreateTransactionRequest,
 ) (*TransactionResponse, error) {
 	if req.CategoryID == uuid.Nil {
-		return nil, fmt.Errorf("category ID cannot be empty")
+		return nil, fmt.Errorf("%w: category ID cannot be empty", ErrInvalidTransaction)
 	}
-	if req.Type != "expense" {
-		return nil, fmt.Errorf("transaction type must be 'expense'")
+	if req.Type != TypeExpense {
+		return nil, fmt.Errorf("%w: transaction type must be 'expense'", ErrInvalidTransaction)
+	}
+	if req.AccountID != nil {
+		if err := account.CheckAccess(ctx, s.db, userID, *req.AccountID); err != nil {
+			return nil, err
+		}
 	}
 
 	transaction := &Transaction{
 		UserID:          userID,
+		AccountID:       req.AccountID,
 		Type:            req.Type,
-		Amount:          req.Amount,
-		Currency:        &req.Currency,
+		Amount:          common.NewMoney(req.Amount, req.Currency),
 		TransactionDate: req.TransactionDate,
 		CategoryID:      req.CategoryID,
 		Description:     req.Description,
@@ -46,18 +88,22 @@ func (s *Service) CreateExpenseTransaction(
 	}
 	transaction.ID = uuid.New()
 
-	expense := &expense.Expense{
+	newExpense := &expense.Expense{
 		UserID:        userID,
-		Amount:        req.Amount,
-		Currency:      &req.Currency,
+		AccountID:     req.AccountID,
+		Amount:        common.NewMoney(req.Amount, req.Currency),
 		TransactionID: &transaction.ID,
 		Description:   req.Description,
 		CategoryID:    req.CategoryID,
 		PaymentMethod: req.PaymentMethod,
+		DueDate:       &transaction.TransactionDate,
 	}
-	expense.ID = uuid.New()
+	newExpense.ID = uuid.New()
 
 	err := s.db.WithContext(ctx).Transaction((func(tx *gorm.DB) error {
+		if err := resolveDetails(tx, transaction, req.Merchant, req.Tags); err != nil {
+			return err
+		}
 		if err := tx.Create(transaction).Error; err != nil {
 			s.logger.Error("Failed to create transaction", "error", err)
 			return err
@@ -65,21 +111,23 @@ func (s *Service) CreateExpenseTransaction(
 			s.logger.Info("Transaction created successfully", "transaction_id", transaction.ID)
 		}
 
-		if err := tx.Create(expense).Error; err != nil {
+		if err := tx.Create(newExpense).Error; err != nil {
 			tx.Rollback()
 			s.logger.Error("Failed to create expense", "error", err)
 			return err
 		} else {
-			s.logger.Info("Expense created successfully", "expense_id", expense.ID)
+			s.logger.Info("Expense created successfully", "expense_id", newExpense.ID)
 		}
 
-		transaction.ProcessingObjectID = &expense.ID
+		transaction.ProcessingObjectID = &newExpense.ID
 		if err := tx.Save(transaction).Error; err != nil {
 			tx.Rollback()
 			s.logger.Error("Failed to link expense to transaction", "error", err)
 			return err
 		}
-		return nil
+
+		// The expense is the source of the journal entry, so the transaction is not posted separately
+		return ledger.Post(tx, expense.LedgerEntry(newExpense))
 	}))
 
 	if err != nil {
@@ -87,54 +135,345 @@ func (s *Service) CreateExpenseTransaction(
 	}
 
 	// Preload Category before returning the transaction
-	if err := s.db.Preload("Category").First(transaction, transaction.ID).Error; err != nil {
+	if err := s.db.Preload("Category").Preload("Merchant").Preload("Tags").First(transaction, transaction.ID).Error; err != nil {
 		s.logger.Error("Failed to preload category", "error", err)
 		return nil, err
 	}
 
+	s.notifyCreated(ctx, transaction)
+
 	// Create a response struct that includes both transaction and linked expense if needed
 	response := &TransactionResponse{
 		StatusCode:  http.StatusOK,
 		Message:     "Success",
 		Transaction: *transaction,
-		Entity:      expense,
+		Entity:      newExpense,
 	}
 
 	return response, nil
 }
 
-func (s *Service) GetTransactions(ctx context.Context, userID uuid.UUID) ([]Transaction, error) {
-	var transactions []Transaction
-	if err := s.db.WithContext(ctx).Where("user_id = ?", userID).Find(&transactions).Error; err != nil {
+// CreateTransaction records an income or expense transaction together with the income or expense
+// it stands for. Transfers are created with CreateTransfer.
+func (s *Service) CreateTransaction(ctx context.Context, userID uuid.UUID, req *CreateTransactionRequest) (*TransactionResponse, error) {
+	switch req.Type {
+	case TypeExpense:
+		return s.CreateExpenseTransaction(ctx, userID, req)
+	case TypeIncome:
+		return s.CreateIncomeTransaction(ctx, userID, req)
+	default:
+		return nil, fmt.Errorf("%w: type must be 'income' or 'expense'; transfers are created on /transaction/transfer", ErrInvalidTransaction)
+	}
+}
+
+// TransactionFilters are the fields transactions can be filtered on with the filter query parameter.
+var TransactionFilters = common.FilterFields{
+	"amount":                 {Column: "transactions.amount", Kind: common.FilterNumber},
+	"currency":               {Column: "transactions.currency"},
+	"type":                   {Column: "transactions.type", Values: []string{string(TypeIncome), string(TypeExpense), string(TypeTransfer)}},
+	"status":                 {Column: "transactions.status", Values: []string{string(StatusPending), string(StatusCompleted), string(StatusCanceled)}},
+	"category_id":            {Column: "transactions.category_id", Kind: common.FilterUUID},
+	"account_id":             {Column: "transactions.account_id", Kind: common.FilterUUID},
+	"merchant_id":            {Column: "transactions.merchant_id", Kind: common.FilterUUID},
+	"transfer_id":            {Column: "transactions.transfer_id", Kind: common.FilterUUID},
+	"payment_method":         {Column: "transactions.payment_method", Values: []string{string(common.Cash), string(common.Card), string(common.BankTransfer)}},
+	"description":            {Column: "transactions.description", Ops: []common.FilterOp{common.OpEq, common.OpLike}},
+	"exclude_from_analytics": {Column: "transactions.exclude_from_analytics", Kind: common.FilterBool},
+	"date":                   {Column: "transactions.transaction_date", Kind: common.FilterTime},
+	"created_at":             {Column: "transactions.created_at", Kind: common.FilterTime},
+}
+
+// transactionPages are the orders transactions can be listed in.
+var transactionPages = pagination.Spec[Transaction]{
+	Table: "transactions",
+	Sorts: map[string]pagination.Column[Transaction]{
+		"date":       {Expr: "transactions.transaction_date", Value: func(t *Transaction) any { return t.TransactionDate }},
+		"amount":     {Expr: "transactions.amount", Value: func(t *Transaction) any { return t.Amount.Amount }},
+		"created_at": {Expr: "transactions.created_at", Value: func(t *Transaction) any { return t.CreatedAt }},
+	},
+	Default: "date",
+	ID:      func(t *Transaction) uuid.UUID { return t.ID },
+}
+
+// GetTransactions returns a page of the user's transactions matching the filter, newest first by default.
+func (s *Service) GetTransactions(
+	ctx context.Context,
+	userID uuid.UUID,
+	filter *TransactionFilter,
+	params pagination.Params,
+) (*pagination.Page[Transaction], error) {
+	query := s.filterTransactions(s.db.WithContext(ctx).Where("transactions.user_id = ?", userID), userID, filter)
+
+	page, err := pagination.Paginate(query, transactionPages, params, "Category", "Merchant", "Tags")
+	if err != nil {
 		s.logger.Error("Failed to fetch transactions", "error", err)
 		return nil, err
 	}
-	return transactions, nil
+	return page, nil
+}
+
+func (s *Service) filterTransactions(query *gorm.DB, userID uuid.UUID, filter *TransactionFilter) *gorm.DB {
+	if filter.From != nil {
+		query = query.Where("transactions.transaction_date >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("transactions.transaction_date < ?", *filter.To)
+	}
+	if filter.Type != "" {
+		query = query.Where("transactions.type = ?", filter.Type)
+	}
+	if filter.CategoryID != nil {
+		query = query.Where("transactions.category_id IN (?)", category.SubtreeIDs(s.db, *filter.CategoryID))
+	}
+	if filter.Merchant != "" {
+		query = query.Where("transactions.merchant_id IN (?)", s.db.Model(&Merchant{}).
+			Select("id").
+			Where("user_id = ? AND name ILIKE ?", userID, "%"+escapeLike(filter.Merchant)+"%"))
+	}
+	if filter.Tag != "" {
+		query = query.Where("transactions.id IN (?)", s.db.Table("transaction_tags").
+			Select("transaction_tags.transaction_id").
+			Joins("JOIN tags ON tags.id = transaction_tags.tag_id").
+			Where("tags.user_id = ? AND LOWER(tags.name) = LOWER(?)", userID, filter.Tag))
+	}
+	if filter.MinAmount != nil {
+		query = query.Where("transactions.amount >= ?", *filter.MinAmount)
+	}
+	if filter.MaxAmount != nil {
+		query = query.Where("transactions.amount <= ?", *filter.MaxAmount)
+	}
+	if filter.Status != "" {
+		query = query.Where("transactions.status = ?", filter.Status)
+	}
+	if filter.Currency != "" {
+		query = query.Where("transactions.currency = ?", filter.Currency)
+	}
+	return query.Scopes(filter.Where.Scope)
 }
 
 func (s *Service) GetTransactionByID(ctx context.Context, userID uuid.UUID, transactionID uuid.UUID) (*Transaction, error) {
 	var transaction Transaction
-	if err := s.db.WithContext(ctx).Where("user_id = ? AND id = ?", userID, transactionID).First(&transaction).Error; err != nil {
+	if err := s.db.WithContext(ctx).
+		Preload("Category").Preload("Merchant").Preload("Tags").
+		Where("user_id = ? AND id = ?", userID, transactionID).
+		First(&transaction).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTransactionNotFound
+		}
 		s.logger.Error("Failed to fetch transaction", "error", err)
 		return nil, err
 	}
 	return &transaction, nil
 }
 
+// UpdateTransaction changes the transaction and carries the change over to the expense or income
+// linked through ProcessingObjectID. Canceling an expense transaction deletes its expense; a
+// canceled income transaction marks its income as failed.
+func (s *Service) UpdateTransaction(ctx context.Context, userID uuid.UUID, transactionID uuid.UUID, req *UpdateTransactionRequest) (*Transaction, error) {
+	if req.CategoryID != nil && *req.CategoryID == uuid.Nil {
+		return nil, fmt.Errorf("%w: category ID cannot be empty", ErrInvalidTransaction)
+	}
+	if req.AccountID != nil {
+		if err := account.CheckAccess(ctx, s.db, userID, *req.AccountID); err != nil {
+			return nil, err
+		}
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		transaction, err := findTransaction(tx, userID, transactionID)
+		if err != nil {
+			return err
+		}
+		if transaction.TransferID != nil {
+			return ErrTransferLeg
+		}
+		if transaction.Clearing == ClearingReconciled && changesBalance(transaction, req) {
+			return fmt.Errorf("%w: only the description, category, merchant, tags and metadata can be changed", ErrReconciled)
+		}
+
+		currency := transaction.Amount.Currency
+		if req.Currency != nil {
+			currency = *req.Currency
+		}
+		amount := transaction.Amount.Amount
+		if req.Amount != nil {
+			amount = *req.Amount
+		}
+		transaction.Amount = common.NewMoney(amount, currency)
+		if req.Description != nil {
+			transaction.Description = *req.Description
+		}
+		if req.CategoryID != nil {
+			transaction.CategoryID = *req.CategoryID
+		}
+		if req.AccountID != nil {
+			transaction.AccountID = req.AccountID
+		}
+		if req.TransactionDate != nil {
+			transaction.TransactionDate = *req.TransactionDate
+		}
+		if req.Status != nil {
+			transaction.Status = *req.Status
+		}
+		if req.PaymentMethod != nil {
+			transaction.PaymentMethod = *req.PaymentMethod
+		}
+		if req.Metadata != nil {
+			transaction.Metadata = req.Metadata
+		}
+		if req.ExcludeFromAnalytics != nil {
+			transaction.ExcludeFromAnalytics = *req.ExcludeFromAnalytics
+		}
+		if req.Merchant != nil {
+			if transaction.MerchantID, err = resolveMerchant(tx, userID, *req.Merchant); err != nil {
+				return err
+			}
+		}
+		if req.Tags != nil {
+			tags, err := resolveTags(tx, userID, *req.Tags)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(transaction).Association("Tags").Replace(tags); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Omit(clause.Associations).Save(transaction).Error; err != nil {
+			return err
+		}
+		return s.syncLinked(tx, transaction)
+	})
+	if err != nil {
+		s.logger.Error("Failed to update transaction", "transaction_id", transactionID, "error", err)
+		return nil, err
+	}
+
+	s.logger.Info("Transaction updated successfully", "transaction_id", transactionID)
+	return s.GetTransactionByID(ctx, userID, transactionID)
+}
+
+// syncLinked copies the transaction to its linked expense or income and updates the journal.
+func (s *Service) syncLinked(tx *gorm.DB, transaction *Transaction) error {
+	linkedExpense, linkedIncome, err := findLinked(tx, transaction)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case linkedExpense != nil && transaction.Status == StatusCanceled:
+		if err := tx.Delete(linkedExpense).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(transaction).Update("processing_object_id", nil).Error; err != nil {
+			return err
+		}
+		s.logger.Info("Expense of canceled transaction deleted", "expense_id", linkedExpense.ID, "transaction_id", transaction.ID)
+		return ledger.Reverse(tx, ledger.SourceExpense, linkedExpense.ID)
+	case linkedExpense != nil:
+		linkedExpense.Amount = transaction.Amount
+		linkedExpense.Description = transaction.Description
+		linkedExpense.CategoryID = transaction.CategoryID
+		linkedExpense.AccountID = transaction.AccountID
+		linkedExpense.PaymentMethod = transaction.PaymentMethod
+		linkedExpense.DueDate = &transaction.TransactionDate
+		if err := tx.Omit(clause.Associations).Save(linkedExpense).Error; err != nil {
+			return err
+		}
+		return ledger.Replace(tx, expense.LedgerEntry(linkedExpense))
+	case linkedIncome != nil:
+		linkedIncome.Amount = transaction.Amount
+		linkedIncome.Note = transaction.Description
+		linkedIncome.CategoryID = transaction.CategoryID
+		linkedIncome.AccountID = transaction.AccountID
+		linkedIncome.Status = incomeStatus(transaction.Status)
+		if err := tx.Omit(clause.Associations).Save(linkedIncome).Error; err != nil {
+			return err
+		}
+		if !linkedIncome.InLedger() {
+			return ledger.Reverse(tx, ledger.SourceIncome, linkedIncome.ID)
+		}
+		return ledger.Replace(tx, income.LedgerEntry(linkedIncome))
+	case transaction.Type == TypeTransfer:
+		return nil
+	case transaction.Status == StatusCanceled:
+		return ledger.Reverse(tx, ledger.SourceTransaction, transaction.ID)
+	default:
+		return ledger.Replace(tx, LedgerEntry(transaction))
+	}
+}
+
+// DeleteTransaction deletes the transaction together with its linked expense or income.
+func (s *Service) DeleteTransaction(ctx context.Context, userID uuid.UUID, transactionID uuid.UUID) error {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		transaction, err := findTransaction(tx, userID, transactionID)
+		if err != nil {
+			return err
+		}
+		if transaction.TransferID != nil {
+			return ErrTransferLeg
+		}
+		if transaction.Clearing == ClearingReconciled {
+			return ErrReconciled
+		}
+
+		linkedExpense, linkedIncome, err := findLinked(tx, transaction)
+		if err != nil {
+			return err
+		}
+		if linkedExpense != nil {
+			if err := tx.Delete(linkedExpense).Error; err != nil {
+				return err
+			}
+			if err := ledger.Reverse(tx, ledger.SourceExpense, linkedExpense.ID); err != nil {
+				return err
+			}
+		}
+		if linkedIncome != nil {
+			if err := tx.Delete(linkedIncome).Error; err != nil {
+				return err
+			}
+			if err := ledger.Reverse(tx, ledger.SourceIncome, linkedIncome.ID); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(transaction).Association("Tags").Clear(); err != nil {
+			return err
+		}
+		if err := tx.Delete(transaction).Error; err != nil {
+			return err
+		}
+		return ledger.Reverse(tx, ledger.SourceTransaction, transaction.ID)
+	})
+	if err != nil {
+		s.logger.Error("Failed to delete transaction", "transaction_id", transactionID, "error", err)
+		return err
+	}
+
+	s.logger.Info("Transaction deleted successfully", "transaction_id", transactionID)
+	return nil
+}
+
 func (s *Service) CreateIncomeTransaction(ctx context.Context, userID uuid.UUID, req *CreateTransactionRequest) (*TransactionResponse, error) {
 	if req.CategoryID == uuid.Nil {
-		return nil, fmt.Errorf("category ID cannot be empty")
+		return nil, fmt.Errorf("%w: category ID cannot be empty", ErrInvalidTransaction)
 	}
-	if req.Type != "income" {
-		return nil, fmt.Errorf("transaction type must be 'income'")
+	if req.Type != TypeIncome {
+		return nil, fmt.Errorf("%w: transaction type must be 'income'", ErrInvalidTransaction)
+	}
+	if req.AccountID != nil {
+		if err := account.CheckAccess(ctx, s.db, userID, *req.AccountID); err != nil {
+			return nil, err
+		}
 	}
 
 	// Transaction instance
 	transaction := &Transaction{
 		UserID:          userID,
+		AccountID:       req.AccountID,
 		Type:            req.Type,
-		Amount:          req.Amount,
-		Currency:        &req.Currency,
+		Amount:          common.NewMoney(req.Amount, req.Currency),
 		TransactionDate: req.TransactionDate,
 		CategoryID:      req.CategoryID,
 		Description:     req.Description,
@@ -144,114 +483,608 @@ func (s *Service) CreateIncomeTransaction(ctx context.Context, userID uuid.UUID,
 	transaction.ID = uuid.New()
 
 	// Income instance
-	income := &income.Income{
+	newIncome := &income.Income{
 		UserID:     userID,
-		Amount:     req.Amount,
-		Currency:   &req.Currency,
+		AccountID:  req.AccountID,
+		Amount:     common.NewMoney(req.Amount, req.Currency),
 		CategoryID: req.CategoryID,
+		Note:       req.Description,
+		Status:     income.IncomeStatusCompleted,
 	}
-	income.ID = uuid.New()
+	newIncome.ID = uuid.New()
+	transaction.ProcessingObjectID = &newIncome.ID
 
 	if err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-
-		if err := tx.Create(income).Error; err != nil {
-			tx.Rollback()
+		if err := resolveDetails(tx, transaction, req.Merchant, req.Tags); err != nil {
+			return err
+		}
+		if err := tx.Create(newIncome).Error; err != nil {
 			s.logger.Error("Failed to create income", "error", err)
 			return err
-		} else {
-			s.logger.Info("Income created successfully", "income_id", income.ID)
 		}
+		s.logger.Info("Income created successfully", "income_id", newIncome.ID)
 
-		transaction.ProcessingObjectID = &income.ID
-		if err := tx.Save(transaction).Error; err != nil {
-			tx.Rollback()
+		if err := tx.Create(transaction).Error; err != nil {
 			s.logger.Error("Failed to create income transaction", "error", err)
 			return err
-		} else {
-			s.logger.Info("Income transaction created successfully", "transaction_id", transaction.ID)
 		}
-		return nil
+		s.logger.Info("Income transaction created successfully", "transaction_id", transaction.ID)
+
+		// The income is the source of the journal entry, so the transaction is not posted separately
+		return ledger.Post(tx, income.LedgerEntry(newIncome))
 	}); err != nil {
 		return nil, err
 	}
 
 	// Preload Category before returning the transaction
-	if err := s.db.Preload("Category").First(transaction, transaction.ID).Error; err != nil {
+	if err := s.db.Preload("Category").Preload("Merchant").Preload("Tags").First(transaction, transaction.ID).Error; err != nil {
 		s.logger.Error("Failed to preload category", "error", err)
 		return nil, err
 	}
+
+	s.notifyCreated(ctx, transaction)
 	response := &TransactionResponse{
 		StatusCode:  http.StatusOK,
 		Message:     "Success",
 		Transaction: *transaction,
-		Entity:      *income,
+		Entity:      *newIncome,
 	}
 	return response, nil
 }
 
-func (s *Service) LinkExpenseToTransaction(ctx context.Context, transactionID uuid.UUID, expenseID uuid.UUID) error {
-	return s.linkToTransaction(ctx, expenseID, transactionID)
+// CreateTransfer writes the transfer and its legs atomically. Between accounts in different
+// currencies, the received amount is taken from the request, else derived from the requested
+// rate, else from the stored exchange rate of the transfer date.
+func (s *Service) CreateTransfer(ctx context.Context, userID uuid.UUID, req *CreateTransferRequest) (*Transfer, error) {
+	if !req.Amount.IsPositive() {
+		return nil, fmt.Errorf("%w: amount must be greater than zero", ErrInvalidTransfer)
+	}
+	if req.Fee.IsNegative() {
+		return nil, fmt.Errorf("%w: fee must not be negative", ErrInvalidTransfer)
+	}
+	if req.Fee.IsPositive() {
+		// The fee is booked as an expense, which needs a category of the user
+		if req.FeeCategoryID == nil || *req.FeeCategoryID == uuid.Nil {
+			return nil, fmt.Errorf("%w: fee_category_id is required with a fee", ErrInvalidTransfer)
+		}
+		if err := category.CheckAccess(ctx, s.db, userID, *req.FeeCategoryID); err != nil {
+			return nil, err
+		}
+	}
+
+	from, err := account.Lookup(ctx, s.db, userID, req.FromAccountID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := account.Lookup(ctx, s.db, userID, req.ToAccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	date := req.TransferDate
+	if date.IsZero() {
+		date = time.Now()
+	}
+	amount := common.NewMoney(req.Amount, from.Currency)
+	received, rate, err := s.receivedAmount(ctx, amount, to.Currency, req, date)
+	if err != nil {
+		return nil, err
+	}
+
+	transfer := &Transfer{
+		UserID:         userID,
+		FromAccountID:  from.ID,
+		ToAccountID:    to.ID,
+		Amount:         amount,
+		ReceivedAmount: received,
+		Rate:           rate,
+		Fee:            common.NewMoney(req.Fee, from.Currency),
+		TransferDate:   date,
+		Description:    req.Description,
+	}
+	transfer.ID = uuid.New()
+
+	legs := []Transaction{
+		newTransferLeg(transfer, from.ID, amount, DirectionOutgoing),
+		newTransferLeg(transfer, to.ID, received, DirectionIncoming),
+	}
+	if transfer.Fee.IsPositive() {
+		// The fee is real spending, so unlike the legs it counts towards analytics and budgets
+		fee := Transaction{
+			UserID:          userID,
+			AccountID:       &from.ID,
+			Amount:          transfer.Fee,
+			Description:     "Transfer fee",
+			TransactionDate: date,
+			Type:            TypeExpense,
+			Status:          StatusCompleted,
+			TransferID:      &transfer.ID,
+			CategoryID:      *req.FeeCategoryID,
+			PaymentMethod:   common.BankTransfer,
+		}
+		legs = append(legs, fee)
+	}
+
+	if err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Transactions").Create(transfer).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&legs).Error; err != nil {
+			return err
+		}
+		if err := ledger.Post(tx, TransferLedgerEntry(transfer)); err != nil {
+			return err
+		}
+		for i := range legs {
+			if legs[i].Type == TypeExpense {
+				if err := ledger.Post(tx, LedgerEntry(&legs[i])); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}); err != nil {
+		s.logger.Error("Failed to create transfer", "error", err)
+		return nil, err
+	}
+
+	s.logger.Info("Transfer created successfully", "transfer_id", transfer.ID, "user_id", userID)
+	for i := range legs {
+		s.notifyCreated(ctx, &legs[i])
+	}
+	return s.GetTransfer(ctx, userID, transfer.ID)
+}
+
+func (s *Service) GetTransfer(ctx context.Context, userID uuid.UUID, transferID uuid.UUID) (*Transfer, error) {
+	var transfer Transfer
+	if err := s.db.WithContext(ctx).
+		Preload("Transactions").
+		Where("id = ? AND user_id = ?", transferID, userID).
+		First(&transfer).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTransferNotFound
+		}
+		return nil, err
+	}
+	return &transfer, nil
 }
 
-func (s *Service) CreateTransferTransaction(ctx context.Context, userID uuid.UUID, req *CreateTransactionRequest) (*Transaction, error) {
-	transaction := &Transaction{
-		UserID:          userID,
-		Type:            req.Type,
-		Amount:          req.Amount,
-		Currency:        &req.Currency,
-		TransactionDate: req.TransactionDate,
-		Description:     req.Description,
-		Metadata:        req.Metadata,
+// receivedAmount returns what the destination account receives for amount and the rate applied.
+func (s *Service) receivedAmount(
+	ctx context.Context,
+	amount common.Money,
+	currency common.Currency,
+	req *CreateTransferRequest,
+	date time.Time,
+) (common.Money, decimal.Decimal, error) {
+	if amount.Currency == currency {
+		if req.ReceivedAmount != nil && !req.ReceivedAmount.Equal(amount.Amount) {
+			return common.Money{}, decimal.Zero, fmt.Errorf("%w: received amount must equal the amount between accounts in the same currency", ErrInvalidTransfer)
+		}
+		return amount, decimal.NewFromInt(1), nil
 	}
-	transaction.ID = uuid.New()
 
-	if err := s.db.WithContext(ctx).Create(transaction).Error; err != nil {
-		s.logger.Error("Failed to create transfer transaction", "error", err)
+	switch {
+	case req.ReceivedAmount != nil:
+		if !req.ReceivedAmount.IsPositive() {
+			return common.Money{}, decimal.Zero, fmt.Errorf("%w: received amount must be greater than zero", ErrInvalidTransfer)
+		}
+		received := common.NewMoney(*req.ReceivedAmount, currency)
+		return received, received.Amount.Div(amount.Amount).Round(10), nil
+	case req.Rate != nil:
+		if !req.Rate.IsPositive() {
+			return common.Money{}, decimal.Zero, fmt.Errorf("%w: rate must be greater than zero", ErrInvalidTransfer)
+		}
+		return common.NewMoney(amount.Amount.Mul(*req.Rate), currency), *req.Rate, nil
+	default:
+		rate, err := s.rates.Rate(ctx, amount.Currency, currency, date)
+		if err != nil {
+			return common.Money{}, decimal.Zero, err
+		}
+		return common.NewMoney(amount.Amount.Mul(rate), currency), rate, nil
+	}
+}
+
+func newTransferLeg(transfer *Transfer, accountID uuid.UUID, amount common.Money, direction TransferDirection) Transaction {
+	return Transaction{
+		UserID:               transfer.UserID,
+		AccountID:            &accountID,
+		Amount:               amount,
+		Description:          transfer.Description,
+		TransactionDate:      transfer.TransferDate,
+		Type:                 TypeTransfer,
+		Status:               StatusCompleted,
+		ExcludeFromAnalytics: true,
+		TransferID:           &transfer.ID,
+		Direction:            direction,
+		PaymentMethod:        common.BankTransfer,
+	}
+}
+
+// LinkExpenseToTransaction records that an expense transaction stands for an existing expense,
+// for example a bank transaction imported after the expense was entered by hand. The expense
+// becomes the source of the journal entry.
+func (s *Service) LinkExpenseToTransaction(ctx context.Context, userID uuid.UUID, transactionID uuid.UUID, expenseID uuid.UUID) (*Transaction, error) {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		transaction, err := s.linkableTransaction(tx, userID, transactionID, TypeExpense, expenseID)
+		if err != nil {
+			return err
+		}
+
+		var linked expense.Expense
+		if err := tx.Where("id = ? AND user_id = ?", expenseID, userID).First(&linked).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrLinkTargetNotFound
+			}
+			return err
+		}
+		if linked.TransactionID != nil && *linked.TransactionID != transaction.ID {
+			return fmt.Errorf("%w: expense %s", ErrAlreadyLinked, expenseID)
+		}
+
+		if err := tx.Model(transaction).Update("processing_object_id", expenseID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&linked).Update("transaction_id", transaction.ID).Error; err != nil {
+			return err
+		}
+		return ledger.Reverse(tx, ledger.SourceTransaction, transaction.ID)
+	})
+	if err != nil {
+		s.logger.Error("Failed to link expense to transaction", "transaction_id", transactionID, "expense_id", expenseID, "error", err)
 		return nil, err
 	}
-	return transaction, nil
+	return s.GetTransactionByID(ctx, userID, transactionID)
 }
 
-func (s *Service) LinkIncomeToTransaction(ctx context.Context, transactionID uuid.UUID, incomeID uuid.UUID) error {
-	return s.linkToTransaction(ctx, incomeID, transactionID)
+// LinkIncomeToTransaction records that an income transaction stands for an existing income.
+// The income becomes the source of the journal entry.
+func (s *Service) LinkIncomeToTransaction(ctx context.Context, userID uuid.UUID, transactionID uuid.UUID, incomeID uuid.UUID) (*Transaction, error) {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		transaction, err := s.linkableTransaction(tx, userID, transactionID, TypeIncome, incomeID)
+		if err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&income.Income{}).Where("id = ? AND user_id = ?", incomeID, userID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrLinkTargetNotFound
+		}
+
+		if err := tx.Model(transaction).Update("processing_object_id", incomeID).Error; err != nil {
+			return err
+		}
+		return ledger.Reverse(tx, ledger.SourceTransaction, transaction.ID)
+	})
+	if err != nil {
+		s.logger.Error("Failed to link income to transaction", "transaction_id", transactionID, "income_id", incomeID, "error", err)
+		return nil, err
+	}
+	return s.GetTransactionByID(ctx, userID, transactionID)
 }
 
-func (s *Service) LinkTransferToTransaction(ctx context.Context, transactionID uuid.UUID, transferID uuid.UUID) error {
-	return s.linkToTransaction(ctx, transferID, transactionID)
+// LinkTransferToTransaction makes a transfer transaction, such as one imported from a bank
+// statement, a leg of an existing transfer. It takes the place of the leg generated on the same
+// account, so the transfer is not counted twice in the account balance.
+func (s *Service) LinkTransferToTransaction(ctx context.Context, userID uuid.UUID, transactionID uuid.UUID, transferID uuid.UUID) (*Transaction, error) {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		transaction, err := findTransaction(tx, userID, transactionID)
+		if err != nil {
+			return err
+		}
+		if transaction.Type != TypeTransfer {
+			return fmt.Errorf("%w: only transfer transactions can be linked to a transfer", ErrInvalidTransaction)
+		}
+		if transaction.TransferID != nil {
+			if *transaction.TransferID == transferID {
+				return nil
+			}
+			return fmt.Errorf("%w: transaction %s", ErrAlreadyLinked, transactionID)
+		}
+
+		var transfer Transfer
+		if err := tx.Where("id = ? AND user_id = ?", transferID, userID).First(&transfer).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrLinkTargetNotFound
+			}
+			return err
+		}
+
+		var direction TransferDirection
+		var amount common.Money
+		switch {
+		case transaction.AccountID == nil:
+			return fmt.Errorf("%w: the transaction has no account", ErrInvalidTransaction)
+		case *transaction.AccountID == transfer.FromAccountID:
+			direction, amount = DirectionOutgoing, transfer.Amount
+		case *transaction.AccountID == transfer.ToAccountID:
+			direction, amount = DirectionIncoming, transfer.ReceivedAmount
+		default:
+			return fmt.Errorf("%w: the transaction is not on an account of the transfer", ErrInvalidTransaction)
+		}
+		if !transaction.Amount.Amount.Equal(amount.Amount) || transaction.Amount.Currency != amount.Currency {
+			return fmt.Errorf("%w: the transfer %s %s, not %s", ErrInvalidTransaction, direction, amount, transaction.Amount)
+		}
+
+		var generated []Transaction
+		if err := tx.Where("transfer_id = ? AND direction = ?", transferID, direction).Find(&generated).Error; err != nil {
+			return err
+		}
+		updates := map[string]interface{}{
+			"transfer_id":            transferID,
+			"direction":              direction,
+			"exclude_from_analytics": true,
+		}
+		for i := range generated {
+			// The leg may already have been ticked off a statement of the account.
+			if generated[i].Clearing != ClearingUncleared && transaction.Clearing == ClearingUncleared {
+				updates["clearing"] = generated[i].Clearing
+				updates["reconciliation_id"] = generated[i].ReconciliationID
+			}
+			if err := tx.Model(&generated[i]).Association("Tags").Clear(); err != nil {
+				return err
+			}
+			if err := tx.Delete(&generated[i]).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(transaction).Updates(updates).Error
+	})
+	if err != nil {
+		s.logger.Error("Failed to link transfer to transaction", "transaction_id", transactionID, "transfer_id", transferID, "error", err)
+		return nil, err
+	}
+	return s.GetTransactionByID(ctx, userID, transactionID)
 }
 
-func (s *Service) linkToTransaction(ctx context.Context, processingObjectID uuid.UUID, transactionID uuid.UUID) error {
-	var transaction Transaction
-	if err := s.db.WithContext(ctx).First(&transaction, "id = ?", transactionID).Error; err != nil {
-		s.logger.Error("Failed to find transaction", "error", err)
-		return err
+// linkableTransaction returns the transaction if it has the type and is not linked to another object.
+func (s *Service) linkableTransaction(tx *gorm.DB, userID uuid.UUID, transactionID uuid.UUID, transactionType TransactionType, objectID uuid.UUID) (*Transaction, error) {
+	transaction, err := findTransaction(tx, userID, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if transaction.Type != transactionType {
+		return nil, fmt.Errorf("%w: only %s transactions can be linked to an %s", ErrInvalidTransaction, transactionType, transactionType)
+	}
+	if transaction.ProcessingObjectID != nil && *transaction.ProcessingObjectID != objectID {
+		return nil, fmt.Errorf("%w: transaction %s", ErrAlreadyLinked, transactionID)
 	}
 
-	transaction.ProcessingObjectID = &processingObjectID
-	if err := s.db.WithContext(ctx).Save(&transaction).Error; err != nil {
-		s.logger.Error("Failed to link processing object to transaction", "error", err)
-		return err
+	var count int64
+	if err := tx.Model(&Transaction{}).
+		Where("processing_object_id = ? AND id <> ?", objectID, transactionID).
+		Count(&count).Error; err != nil {
+		return nil, err
 	}
-	return nil
+	if count > 0 {
+		return nil, fmt.Errorf("%w: %s %s", ErrAlreadyLinked, transactionType, objectID)
+	}
+	return transaction, nil
+}
+
+// MergeTransactions deletes the transaction removeID as a duplicate of keepID. The kept
+// transaction takes over its tags, receipts and the details it lacks, and its expense or income
+// when it has none of its own; otherwise the expense or income of the duplicate is deleted too.
+func (s *Service) MergeTransactions(ctx context.Context, userID uuid.UUID, keepID uuid.UUID, removeID uuid.UUID) (*Transaction, error) {
+	if keepID == removeID {
+		return nil, fmt.Errorf("%w: a transaction cannot be merged into itself", ErrInvalidTransaction)
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		keep, err := findTransaction(tx, userID, keepID)
+		if err != nil {
+			return err
+		}
+		duplicate, err := findTransaction(tx, userID, removeID)
+		if err != nil {
+			return err
+		}
+		if keep.TransferID != nil || duplicate.TransferID != nil {
+			return ErrTransferLeg
+		}
+		if duplicate.Clearing == ClearingReconciled {
+			return fmt.Errorf("%w: keep the reconciled transaction", ErrReconciled)
+		}
+		if keep.Type != duplicate.Type {
+			return fmt.Errorf("%w: an %s transaction cannot be merged into an %s transaction", ErrInvalidTransaction, duplicate.Type, keep.Type)
+		}
+
+		keepExpense, keepIncome, err := findLinked(tx, keep)
+		if err != nil {
+			return err
+		}
+		duplicateExpense, duplicateIncome, err := findLinked(tx, duplicate)
+		if err != nil {
+			return err
+		}
+
+		var tags []Tag
+		if err := tx.Model(duplicate).Association("Tags").Find(&tags); err != nil {
+			return err
+		}
+		if err := tx.Model(duplicate).Association("Tags").Clear(); err != nil {
+			return err
+		}
+		if err := tx.Model(&receipt.Receipt{}).
+			Where("transaction_id = ? AND user_id = ?", duplicate.ID, userID).
+			Update("transaction_id", keep.ID).Error; err != nil {
+			return err
+		}
+
+		// The duplicate goes first, so its expense or income and import reference are free to move.
+		if err := tx.Delete(duplicate).Error; err != nil {
+			return err
+		}
+		if err := ledger.Reverse(tx, ledger.SourceTransaction, duplicate.ID); err != nil {
+			return err
+		}
+
+		switch {
+		case keepExpense != nil && duplicateExpense != nil:
+			if err := expense.Absorb(tx, keepExpense, duplicateExpense); err != nil {
+				return err
+			}
+		case keepIncome != nil && duplicateIncome != nil:
+			if err := tx.Delete(duplicateIncome).Error; err != nil {
+				return err
+			}
+			if err := ledger.Reverse(tx, ledger.SourceIncome, duplicateIncome.ID); err != nil {
+				return err
+			}
+		case duplicateExpense != nil:
+			keep.ProcessingObjectID = &duplicateExpense.ID
+			if err := tx.Model(duplicateExpense).Update("transaction_id", keep.ID).Error; err != nil {
+				return err
+			}
+			if err := ledger.Reverse(tx, ledger.SourceTransaction, keep.ID); err != nil {
+				return err
+			}
+		case duplicateIncome != nil:
+			keep.ProcessingObjectID = &duplicateIncome.ID
+			if err := ledger.Reverse(tx, ledger.SourceTransaction, keep.ID); err != nil {
+				return err
+			}
+		}
+
+		if keep.AccountID == nil {
+			keep.AccountID = duplicate.AccountID
+		}
+		if keep.MerchantID == nil {
+			keep.MerchantID = duplicate.MerchantID
+		}
+		if keep.ReceiptID == nil {
+			keep.ReceiptID = duplicate.ReceiptID
+		}
+		keep.Metadata = mergeMetadata(keep.Metadata, duplicate.Metadata)
+		if err := tx.Omit(clause.Associations).Save(keep).Error; err != nil {
+			return err
+		}
+		if len(tags) > 0 {
+			if err := tx.Model(keep).Association("Tags").Append(tags); err != nil {
+				return err
+			}
+		}
+		return s.syncLinked(tx, keep)
+	})
+	if err != nil {
+		s.logger.Error("Failed to merge transactions", "keep_id", keepID, "remove_id", removeID, "error", err)
+		return nil, err
+	}
+
+	s.logger.Info("Transactions merged", "keep_id", keepID, "remove_id", removeID)
+	return s.GetTransactionByID(ctx, userID, keepID)
+}
+
+// MergeExpense deletes an expense entered by hand as a duplicate of an expense transaction. A
+// transaction without an expense is linked to it instead, as LinkExpenseToTransaction does, and
+// the expense takes the amount and date of the transaction.
+func (s *Service) MergeExpense(ctx context.Context, userID uuid.UUID, transactionID uuid.UUID, expenseID uuid.UUID) (*Transaction, error) {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		transaction, err := findTransaction(tx, userID, transactionID)
+		if err != nil {
+			return err
+		}
+		if transaction.TransferID != nil {
+			return ErrTransferLeg
+		}
+		if transaction.Type != TypeExpense {
+			return fmt.Errorf("%w: an expense can only be merged into an expense transaction", ErrInvalidTransaction)
+		}
+
+		var duplicate expense.Expense
+		if err := tx.Where("id = ? AND user_id = ?", expenseID, userID).First(&duplicate).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrLinkTargetNotFound
+			}
+			return err
+		}
+		var count int64
+		if err := tx.Model(&Transaction{}).
+			Where("processing_object_id = ? AND id <> ?", expenseID, transactionID).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 || (duplicate.TransactionID != nil && *duplicate.TransactionID != transaction.ID) {
+			return fmt.Errorf("%w: expense %s", ErrAlreadyLinked, expenseID)
+		}
+
+		linked, _, err := findLinked(tx, transaction)
+		if err != nil {
+			return err
+		}
+		if linked != nil && linked.ID != duplicate.ID {
+			return expense.Absorb(tx, linked, &duplicate)
+		}
+
+		transaction.ProcessingObjectID = &duplicate.ID
+		if err := tx.Model(transaction).Update("processing_object_id", duplicate.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&duplicate).Update("transaction_id", transaction.ID).Error; err != nil {
+			return err
+		}
+		if err := ledger.Reverse(tx, ledger.SourceTransaction, transaction.ID); err != nil {
+			return err
+		}
+		return s.syncLinked(tx, transaction)
+	})
+	if err != nil {
+		s.logger.Error("Failed to merge expense into transaction", "transaction_id", transactionID, "expense_id", expenseID, "error", err)
+		return nil, err
+	}
+
+	s.logger.Info("Expense merged into transaction", "transaction_id", transactionID, "expense_id", expenseID)
+	return s.GetTransactionByID(ctx, userID, transactionID)
 }
 
 func (s *Service) getAggregatedTransactionsByMonth(
 	ctx context.Context,
 	userID uuid.UUID,
 	groupBy string,
-) (map[string]float64, error) {
-	aggregated := make(map[string]float64)
+) (map[time.Time]common.Money, error) {
+	currency, err := s.rates.ReportingCurrency(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	type Result struct {
+		Month    time.Time
+		Currency common.Currency
+		Day      time.Time
+		Total    decimal.Decimal
+	}
 
+	var results []Result
 	query := s.db.WithContext(ctx).
 		Model(&Transaction{}).
-		Select("DATE_TRUNC(?, transaction_date) AS month, SUM(amount) AS total", groupBy).
+		Select("DATE_TRUNC(?, transaction_date) AS month, currency, DATE(transaction_date) AS day, SUM(amount) AS total", groupBy).
 		Where("user_id = ?", userID).
-		Group("month")
+		Where("type <> ? AND status <> ? AND exclude_from_analytics = ?", TypeTransfer, StatusCanceled, false).
+		Group("month, currency, day")
 
-	if err := query.Scan(&aggregated).Error; err != nil {
+	if err := query.Scan(&results).Error; err != nil {
 		s.logger.Error("Failed to get aggregated transactions", "error", err)
 		return nil, err
 	}
 
+	// Amounts are converted into the reporting currency at the rate of the day they were made
+	converter := s.rates.NewConverter(currency)
+	sums := make(map[time.Time]decimal.Decimal)
+	for _, r := range results {
+		converted, err := converter.Convert(ctx, r.Total, r.Currency, r.Day)
+		if err != nil {
+			return nil, err
+		}
+		sums[r.Month] = sums[r.Month].Add(converted)
+	}
+
+	aggregated := make(map[time.Time]common.Money, len(sums))
+	for month, sum := range sums {
+		aggregated[month] = common.NewMoney(sum, currency)
+	}
 	return aggregated, nil
 }
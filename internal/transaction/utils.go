@@ -0,0 +1,177 @@
+package transaction
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pastorenue/kinance/internal/expense"
+	"github.com/pastorenue/kinance/internal/income"
+	"github.com/pastorenue/kinance/internal/ledger"
+	"gorm.io/gorm"
+)
+
+// LedgerEntry returns the journal entry of an income or expense transaction that has no linked
+// income or expense, such as a transfer fee. Transactions with a linked income or expense are
+// posted through it, and transfer legs through their transfer.
+func LedgerEntry(t *Transaction) *ledger.JournalEntry {
+	if t.Type == TypeIncome {
+		return ledger.IncomeEntry(t.UserID, ledger.SourceTransaction, t.ID, t.TransactionDate, t.Description, t.Amount, t.CategoryID, t.AccountID)
+	}
+	return ledger.ExpenseEntry(t.UserID, ledger.SourceTransaction, t.ID, t.TransactionDate, t.Description, t.Amount, t.CategoryID, t.AccountID)
+}
+
+// TransferLedgerEntry returns the journal entry of the transfer, without its fee.
+func TransferLedgerEntry(t *Transfer) *ledger.JournalEntry {
+	return ledger.TransferEntry(t.UserID, t.ID, t.TransferDate, t.Description, t.FromAccountID, t.ToAccountID, t.Amount, t.ReceivedAmount)
+}
+
+func findTransaction(tx *gorm.DB, userID uuid.UUID, transactionID uuid.UUID) (*Transaction, error) {
+	var transaction Transaction
+	if err := tx.Where("id = ? AND user_id = ?", transactionID, userID).First(&transaction).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTransactionNotFound
+		}
+		return nil, err
+	}
+	return &transaction, nil
+}
+
+// findLinked returns the expense or income the transaction stands for, if any.
+func findLinked(tx *gorm.DB, t *Transaction) (*expense.Expense, *income.Income, error) {
+	if t.ProcessingObjectID == nil {
+		return nil, nil, nil
+	}
+
+	switch t.Type {
+	case TypeExpense:
+		var linked expense.Expense
+		err := tx.Where("id = ? AND user_id = ?", *t.ProcessingObjectID, t.UserID).First(&linked).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		return &linked, nil, nil
+	case TypeIncome:
+		var linked income.Income
+		err := tx.Where("id = ? AND user_id = ?", *t.ProcessingObjectID, t.UserID).First(&linked).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		return nil, &linked, nil
+	default:
+		return nil, nil, nil
+	}
+}
+
+// resolveDetails sets the merchant and tags of a new transaction from their names.
+func resolveDetails(tx *gorm.DB, t *Transaction, merchant string, tags []string) error {
+	merchantID, err := resolveMerchant(tx, t.UserID, merchant)
+	if err != nil {
+		return err
+	}
+	t.MerchantID = merchantID
+
+	t.Tags, err = resolveTags(tx, t.UserID, tags)
+	return err
+}
+
+// resolveMerchant returns the ID of the user's merchant with the name, creating the merchant on
+// first use. An empty name resolves to no merchant.
+func resolveMerchant(tx *gorm.DB, userID uuid.UUID, name string) (*uuid.UUID, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, nil
+	}
+
+	merchant := Merchant{Name: name, UserID: userID}
+	if err := tx.Where("user_id = ? AND LOWER(name) = LOWER(?)", userID, name).
+		FirstOrCreate(&merchant).Error; err != nil {
+		return nil, err
+	}
+	return &merchant.ID, nil
+}
+
+// resolveTags returns the user's tags with the names, creating the missing ones.
+func resolveTags(tx *gorm.DB, userID uuid.UUID, names []string) ([]Tag, error) {
+	tags := make([]Tag, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+
+		tag := Tag{Name: name, UserID: userID}
+		if err := tx.Where("user_id = ? AND LOWER(name) = LOWER(?)", userID, name).
+			FirstOrCreate(&tag).Error; err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, nil
+}
+
+// mergeMetadata adds the keys of a merged duplicate to the metadata of the kept transaction. The
+// import reference of the duplicate is listed under MergedReferencesKey, so importing its
+// statement again does not recreate it.
+func mergeMetadata(keep map[string]interface{}, duplicate map[string]interface{}) map[string]interface{} {
+	if len(duplicate) == 0 {
+		return keep
+	}
+	if keep == nil {
+		keep = make(map[string]interface{})
+	}
+
+	references, _ := keep[MergedReferencesKey].([]interface{})
+	if merged, ok := duplicate[MergedReferencesKey].([]interface{}); ok {
+		references = append(references, merged...)
+	}
+	if _, ok := keep["import"]; ok {
+		if imported, ok := duplicate["import"].(map[string]interface{}); ok && imported["reference"] != nil {
+			references = append(references, imported["reference"])
+		}
+	}
+	for key, value := range duplicate {
+		if _, ok := keep[key]; !ok && key != MergedReferencesKey {
+			keep[key] = value
+		}
+	}
+	if len(references) > 0 {
+		keep[MergedReferencesKey] = references
+	}
+	return keep
+}
+
+// changesBalance reports whether the update changes what the transaction adds to the balance of
+// its account, which reconciled transactions must keep.
+func changesBalance(t *Transaction, req *UpdateTransactionRequest) bool {
+	return (req.Amount != nil && !req.Amount.Equal(t.Amount.Amount)) ||
+		(req.Currency != nil && *req.Currency != t.Amount.Currency) ||
+		(req.AccountID != nil && (t.AccountID == nil || *req.AccountID != *t.AccountID)) ||
+		(req.TransactionDate != nil && !req.TransactionDate.Equal(t.TransactionDate)) ||
+		(req.Status != nil && *req.Status != t.Status)
+}
+
+// incomeStatus maps the status of a transaction to the status of its linked income.
+func incomeStatus(status TransactionStatus) income.IncomeStatus {
+	switch status {
+	case StatusCanceled:
+		return income.IncomeStatusFailed
+	case StatusPending:
+		return income.IncomeStatusPending
+	default:
+		return income.IncomeStatusCompleted
+	}
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern.
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
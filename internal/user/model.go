@@ -39,6 +39,8 @@ type User struct {
 	Address        []Address  `json:"address" gorm:"type:jsonb"`
 	Groups         []Group    `json:"groups" gorm:"many2many:user_groups;"`
 
+	ReportingCurrency common.Currency `json:"reporting_currency" gorm:"type:varchar(3);default:EUR"` // Totals are converted into this currency
+
 	// For Super Admins Only
 	IsSuperAdmin bool `json:"is_super_admin" gorm:"default:false"`
 	IsStaff      bool `json:"is_staff" gorm:"default:false"`
@@ -70,10 +72,11 @@ type CreateUserRequest struct {
 }
 
 type UpdateUserRequest struct {
-	FirstName   string `json:"first_name"`
-	LastName    string `json:"last_name"`
-	Phone       string `json:"phone"`
-	DateOfBirth string `json:"date_of_birth"`
+	FirstName         string          `json:"first_name"`
+	LastName          string          `json:"last_name"`
+	Phone             string          `json:"phone"`
+	DateOfBirth       string          `json:"date_of_birth"`
+	ReportingCurrency common.Currency `json:"reporting_currency" binding:"omitempty,currency"`
 }
 
 type CreateAddressRequest struct {
@@ -106,6 +106,9 @@ func (s *Service) UpdateUser(ctx context.Context, userID uuid.UUID, req *UpdateU
 		}
 		user.DateOfBirth = &dob
 	}
+	if req.ReportingCurrency != "" {
+		user.ReportingCurrency = req.ReportingCurrency
+	}
 
 	if err := s.db.WithContext(ctx).Save(&user).Error; err != nil {
 		return nil, err
@@ -8,15 +8,18 @@ import (
 )
 
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Redis    RedisConfig
-	Client   ClientConfig
-	JWT      JWTConfig
-	AI       AIConfig
-	Google   GoogleConfig
-	LogLevel string
+	Server         ServerConfig
+	Database       DatabaseConfig
+	Redis          RedisConfig
+	Client         ClientConfig
+	JWT            JWTConfig
+	AI             AIConfig
+	Google         GoogleConfig
+	LogLevel       string
 	MiddlewareConf MiddlewareConfig
+	Scheduler      SchedulerConfig
+	Notification   NotificationConfig
+	FX             FXConfig
 }
 
 type ClientConfig struct {
@@ -67,6 +70,38 @@ type MiddlewareConfig struct {
 	RateLimit int
 }
 
+type SchedulerConfig struct {
+	Enabled                  bool
+	PollInterval             int  // seconds between due checks
+	LockTTL                  int  // seconds a replica holds a job lease, renewed while a run lasts
+	RecurringExpenseInterval int  // seconds between recurring expense runs
+	RecurringExpenseBackfill bool // generate every missed occurrence per run instead of one
+	BudgetPeriodInterval     int  // seconds between closing ended budget periods
+	ExchangeRateInterval     int  // seconds between exchange rate syncs
+}
+
+// NotificationConfig configures the optional webhook and SMTP notification channels.
+// A channel is disabled while its URL or host is empty.
+type NotificationConfig struct {
+	WebhookURL    string
+	WebhookSecret string
+	SMTPHost      string
+	SMTPPort      int
+	SMTPUsername  string
+	SMTPPassword  string
+	SMTPFrom      string
+}
+
+// FXConfig selects where exchange rates come from. Provider is "csv", "http" or empty, in which
+// case rates are not synced and only rates already stored are used.
+type FXConfig struct {
+	Provider     string
+	CSVPath      string
+	HTTPURL      string
+	BaseCurrency string // Currency the provider publishes rates against
+	BackfillDays int    // days of history fetched on the first sync
+}
+
 func Load() *Config {
 	_ = godotenv.Load()
 	return &Config{
@@ -111,6 +146,31 @@ func Load() *Config {
 		MiddlewareConf: MiddlewareConfig{
 			RateLimit: getIntEnv("RATE_LIMIT", 100),
 		},
+		Scheduler: SchedulerConfig{
+			Enabled:                  getBoolEnv("SCHEDULER_ENABLED", true),
+			PollInterval:             getIntEnv("SCHEDULER_POLL_INTERVAL", 30),
+			LockTTL:                  getIntEnv("SCHEDULER_LOCK_TTL", 300),
+			RecurringExpenseInterval: getIntEnv("SCHEDULER_RECURRING_EXPENSE_INTERVAL", 3600),
+			RecurringExpenseBackfill: getBoolEnv("SCHEDULER_RECURRING_EXPENSE_BACKFILL", true),
+			BudgetPeriodInterval:     getIntEnv("SCHEDULER_BUDGET_PERIOD_INTERVAL", 3600),
+			ExchangeRateInterval:     getIntEnv("SCHEDULER_EXCHANGE_RATE_INTERVAL", 21600),
+		},
+		Notification: NotificationConfig{
+			WebhookURL:    getEnv("NOTIFY_WEBHOOK_URL", ""),
+			WebhookSecret: getEnv("NOTIFY_WEBHOOK_SECRET", ""),
+			SMTPHost:      getEnv("SMTP_HOST", ""),
+			SMTPPort:      getIntEnv("SMTP_PORT", 1025),
+			SMTPUsername:  getEnv("SMTP_USERNAME", ""),
+			SMTPPassword:  getEnv("SMTP_PASSWORD", ""),
+			SMTPFrom:      getEnv("SMTP_FROM", "Kinance <no-reply@kinance.local>"),
+		},
+		FX: FXConfig{
+			Provider:     getEnv("FX_PROVIDER", ""),
+			CSVPath:      getEnv("FX_CSV_PATH", ""),
+			HTTPURL:      getEnv("FX_HTTP_URL", ""),
+			BaseCurrency: getEnv("FX_BASE_CURRENCY", "EUR"),
+			BackfillDays: getIntEnv("FX_BACKFILL_DAYS", 365),
+		},
 	}
 }
 
@@ -129,3 +189,12 @@ func getIntEnv(key string, defaultVal int) int {
 	}
 	return defaultVal
 }
+
+func getBoolEnv(key string, defaultVal bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolVal, err := strconv.ParseBool(value); err == nil {
+			return boolVal
+		}
+	}
+	return defaultVal
+}
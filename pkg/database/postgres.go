@@ -7,12 +7,23 @@ import (
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 
+	"github.com/pastorenue/kinance/internal/account"
 	"github.com/pastorenue/kinance/internal/budget"
+	"github.com/pastorenue/kinance/internal/calendar"
+	"github.com/pastorenue/kinance/internal/duplicate"
 	"github.com/pastorenue/kinance/internal/expense"
+	"github.com/pastorenue/kinance/internal/fx"
 
-	// "github.com/pastorenue/kinance/internal/receipt"
 	"github.com/pastorenue/kinance/internal/category"
+	"github.com/pastorenue/kinance/internal/common"
+	"github.com/pastorenue/kinance/internal/importer"
 	"github.com/pastorenue/kinance/internal/income"
+	"github.com/pastorenue/kinance/internal/ledger"
+	"github.com/pastorenue/kinance/internal/notification"
+	"github.com/pastorenue/kinance/internal/receipt"
+	"github.com/pastorenue/kinance/internal/reconciliation"
+	"github.com/pastorenue/kinance/internal/scheduler"
+	"github.com/pastorenue/kinance/internal/search"
 	"github.com/pastorenue/kinance/internal/transaction"
 	"github.com/pastorenue/kinance/internal/user"
 	"github.com/pastorenue/kinance/pkg/config"
@@ -35,27 +46,246 @@ func NewPostgres(cfg config.DatabaseConfig) (*gorm.DB, error) {
 		return nil, err
 	}
 
+	if err := migrateMoneyColumns(db); err != nil {
+		return nil, err
+	}
+
+	if err := dropGlobalNameIndexes(db); err != nil {
+		return nil, err
+	}
+
 	// Auto-migrate models
 	err = db.AutoMigrate(
 		&user.User{},
 		// &user.Family{},
 		&category.Category{},
+		&account.Account{},
 		&expense.RecurringExpense{},
+		&expense.OccurrenceException{},
 		&expense.Expense{},
 		&budget.Budget{},
+		&budget.BudgetPeriod{},
+		&budget.BudgetAlert{},
+		&budget.EnvelopeAllocation{},
 		&transaction.Transaction{},
+		&transaction.Transfer{},
 		&income.Income{},
 		&transaction.Tag{},
-		// &receipt.Receipt{},
-		// &receipt.ReceiptItem{},
+		&transaction.Merchant{},
+		&scheduler.JobState{},
+		&calendar.FeedToken{},
+		&notification.Notification{},
+		&fx.ExchangeRate{},
+		&ledger.JournalEntry{},
+		&ledger.Posting{},
+		&receipt.Receipt{},
+		&receipt.ReceiptItem{},
+		&importer.MappingProfile{},
+		&importer.Statement{},
+		&duplicate.DismissedPair{},
+		&reconciliation.Reconciliation{},
 	)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := migrateBudgetCategories(db); err != nil {
+		return nil, err
+	}
+
+	if err := backfillLedger(db); err != nil {
+		return nil, err
+	}
+
+	if err := search.CreateIndexes(db); err != nil {
+		return nil, err
+	}
+
+	if err := importer.CreateIndexes(db); err != nil {
+		return nil, err
+	}
+
+	if err := reconciliation.CreateIndexes(db); err != nil {
+		return nil, err
+	}
+
 	return db, nil
 }
 
+// migrateBudgetCategories links budgets created with a free-text category to the user's category
+// of the same name, creating the category where the user has none, then drops the legacy category
+// and spent columns. Spending is derived from expenses and transactions now, so the stored spent
+// counter is no longer needed. The category column is kept while any budget could not be linked.
+func migrateBudgetCategories(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if migrator.HasColumn(&budget.Budget{}, "category") {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			// Category names are still unique across users, so a name taken by another user is
+			// skipped here and reported below.
+			if err := tx.Exec(`
+				INSERT INTO categories (id, name, user_id, created_at, updated_at)
+				SELECT gen_random_uuid(), MIN(TRIM(budgets.category)), budgets.user_id, NOW(), NOW()
+				FROM budgets
+				WHERE budgets.category_id IS NULL AND TRIM(COALESCE(budgets.category, '')) <> ''
+					AND NOT EXISTS (
+						SELECT 1 FROM categories
+						WHERE categories.user_id = budgets.user_id
+							AND LOWER(categories.name) = LOWER(TRIM(budgets.category)))
+				GROUP BY budgets.user_id, LOWER(TRIM(budgets.category))
+				ON CONFLICT DO NOTHING`).Error; err != nil {
+				return fmt.Errorf("failed to create budget categories: %w", err)
+			}
+			if err := tx.Exec(`
+				UPDATE budgets SET category_id = categories.id
+				FROM categories
+				WHERE budgets.category_id IS NULL
+					AND categories.user_id = budgets.user_id
+					AND LOWER(categories.name) = LOWER(TRIM(budgets.category))`).Error; err != nil {
+				return fmt.Errorf("failed to link budgets to categories: %w", err)
+			}
+
+			var unlinked int64
+			if err := tx.Table("budgets").
+				Where("category_id IS NULL AND TRIM(COALESCE(category, '')) <> ''").
+				Count(&unlinked).Error; err != nil {
+				return err
+			}
+			if unlinked > 0 {
+				return fmt.Errorf("%d budgets could not be linked to a category; rename their category or link them by hand before budgets.category is dropped", unlinked)
+			}
+			return tx.Migrator().DropColumn(&budget.Budget{}, "category")
+		})
+		if err != nil {
+			return fmt.Errorf("failed to migrate budget categories: %w", err)
+		}
+	}
+	if migrator.HasColumn(&budget.Budget{}, "spent") {
+		if err := migrator.DropColumn(&budget.Budget{}, "spent"); err != nil {
+			return fmt.Errorf("failed to drop budgets.spent: %w", err)
+		}
+	}
+	return nil
+}
+
+// dropGlobalNameIndexes drops the unique indexes that made tag and merchant names unique across
+// all users. AutoMigrate replaces them with indexes unique per user.
+func dropGlobalNameIndexes(db *gorm.DB) error {
+	migrator := db.Migrator()
+	for table, index := range map[string]string{"tags": "idx_tags_name", "merchants": "idx_merchants_name"} {
+		if !migrator.HasTable(table) || !migrator.HasIndex(table, index) {
+			continue
+		}
+		if err := migrator.DropIndex(table, index); err != nil {
+			return fmt.Errorf("failed to drop %s: %w", index, err)
+		}
+	}
+	return nil
+}
+
+// backfillLedger posts journal entries for the expenses, incomes, transfers and standalone
+// transactions recorded before the ledger existed. It only runs while the journal is empty.
+func backfillLedger(db *gorm.DB) error {
+	var count int64
+	if err := db.Model(&ledger.JournalEntry{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var expenses []expense.Expense
+		if err := tx.Find(&expenses).Error; err != nil {
+			return err
+		}
+		for i := range expenses {
+			if err := ledger.Post(tx, expense.LedgerEntry(&expenses[i])); err != nil {
+				return fmt.Errorf("failed to post expense %s: %w", expenses[i].ID, err)
+			}
+		}
+
+		var incomes []income.Income
+		if err := tx.Find(&incomes).Error; err != nil {
+			return err
+		}
+		for i := range incomes {
+			if !incomes[i].InLedger() {
+				continue
+			}
+			if err := ledger.Post(tx, income.LedgerEntry(&incomes[i])); err != nil {
+				return fmt.Errorf("failed to post income %s: %w", incomes[i].ID, err)
+			}
+		}
+
+		var transfers []transaction.Transfer
+		if err := tx.Find(&transfers).Error; err != nil {
+			return err
+		}
+		for i := range transfers {
+			if err := ledger.Post(tx, transaction.TransferLedgerEntry(&transfers[i])); err != nil {
+				return fmt.Errorf("failed to post transfer %s: %w", transfers[i].ID, err)
+			}
+		}
+
+		// Transactions recorded through an expense or income are already in the journal,
+		// and transfer legs are covered by their transfer, except for fees.
+		var transactions []transaction.Transaction
+		if err := tx.Where("type IN ? AND status <> ?",
+			[]transaction.TransactionType{transaction.TypeIncome, transaction.TypeExpense}, transaction.StatusCanceled).
+			Where("NOT EXISTS (SELECT 1 FROM expenses e WHERE e.transaction_id = transactions.id OR e.id = transactions.processing_object_id)").
+			Where("NOT EXISTS (SELECT 1 FROM incomes i WHERE i.id = transactions.processing_object_id)").
+			Find(&transactions).Error; err != nil {
+			return err
+		}
+		for i := range transactions {
+			if err := ledger.Post(tx, transaction.LedgerEntry(&transactions[i])); err != nil {
+				return fmt.Errorf("failed to post transaction %s: %w", transactions[i].ID, err)
+			}
+		}
+		return nil
+	})
+}
+
+// legacyMoneyColumns maps float amount columns to the amount column of the common.Money that
+// replaces them. The matching currency columns are added by AutoMigrate with the default currency.
+var legacyMoneyColumns = map[string][]string{
+	"budget_periods": {"budgeted", "rollover_in", "spent", "remaining", "rollover_out"},
+	"budget_alerts":  {"spent", "available"},
+	"receipts":       {"total", "tax"},
+	"receipt_items":  {"unit_price", "total_price"},
+}
+
+// migrateMoneyColumns prepares existing rows for common.Money before AutoMigrate runs: amounts
+// stored in float columns are renamed to their <name>_amount column, which AutoMigrate then
+// converts to a decimal, and rows recorded without a currency get the default currency.
+func migrateMoneyColumns(db *gorm.DB) error {
+	migrator := db.Migrator()
+	for table, columns := range legacyMoneyColumns {
+		if !migrator.HasTable(table) {
+			continue
+		}
+		for _, column := range columns {
+			if !migrator.HasColumn(table, column) || migrator.HasColumn(table, column+"_amount") {
+				continue
+			}
+			if err := migrator.RenameColumn(table, column, column+"_amount"); err != nil {
+				return fmt.Errorf("failed to rename %s.%s: %w", table, column, err)
+			}
+		}
+	}
+
+	for _, table := range []string{"expenses", "incomes", "transactions"} {
+		if !migrator.HasTable(table) || !migrator.HasColumn(table, "currency") {
+			continue
+		}
+		if err := db.Table(table).Where("currency IS NULL OR currency = ''").
+			Update("currency", common.DefaultCurrency).Error; err != nil {
+			return fmt.Errorf("failed to set default currency of %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
 func createEnumTypes(db *gorm.DB) error {
 	// Create payment_method enum type
 	paymentMethodSQL := `
@@ -96,14 +326,31 @@ func DropAllTables(db *gorm.DB) error {
 		&user.User{},
 		// &user.Family{},
 		&category.Category{},
+		&account.Account{},
 		&expense.RecurringExpense{},
+		&expense.OccurrenceException{},
 		&expense.Expense{},
 		&budget.Budget{},
+		&budget.BudgetPeriod{},
+		&budget.BudgetAlert{},
+		&budget.EnvelopeAllocation{},
 		&transaction.Transaction{},
+		&transaction.Transfer{},
 		&transaction.Tag{},
-		// &receipt.Receipt{},
-		// &receipt.ReceiptItem{},
+		&transaction.Merchant{},
+		&receipt.Receipt{},
+		&receipt.ReceiptItem{},
+		&importer.MappingProfile{},
+		&importer.Statement{},
+		&duplicate.DismissedPair{},
+		&reconciliation.Reconciliation{},
 		&income.Income{},
+		&scheduler.JobState{},
+		&calendar.FeedToken{},
+		&notification.Notification{},
+		&fx.ExchangeRate{},
+		&ledger.JournalEntry{},
+		&ledger.Posting{},
 	); err != nil {
 		return err
 	}
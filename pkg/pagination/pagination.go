@@ -0,0 +1,217 @@
+// Package pagination pages through lists with keyset cursors. A cursor holds the sort value and ID
+// of the last row of a page, so the next page starts right after it however many rows are added
+// or removed in the meantime, and deep pages cost no more than the first one.
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// ErrInvalid is returned for cursors and sort orders a list does not accept.
+var ErrInvalid = errors.New("invalid pagination")
+
+type Order string
+
+const (
+	Asc  Order = "asc"
+	Desc Order = "desc"
+)
+
+// Params are the query parameters of a list endpoint. A requested sort is ascending unless it is
+// prefixed with "-" or Order says otherwise; without one, the list uses the default of its Spec.
+type Params struct {
+	Cursor string `form:"cursor"`                                  // next_cursor of the previous page
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=100"` // Defaults to 20
+	Sort   string `form:"sort"`                                    // One of the sorts of the list, descending when prefixed with "-"
+	Order  Order  `form:"order" binding:"omitempty,oneof=asc desc"`
+}
+
+// Page is one page of a list.
+type Page[T any] struct {
+	Data       []T    `json:"data"`
+	NextCursor string `json:"next_cursor,omitempty"` // Empty on the last page
+	HasMore    bool   `json:"has_more"`
+	Total      int64  `json:"total"` // Rows in the list across all pages
+	Sort       string `json:"sort"`
+	Order      Order  `json:"order"`
+}
+
+// Column is a sort of a list: the SQL expression rows are ordered by and the value of a row.
+// The expression must not be NULL; ties are broken by ID, so the order is stable.
+type Column[T any] struct {
+	Expr  string
+	Value func(row *T) any // time.Time, a fmt.Stringer such as decimal.Decimal, or a string
+}
+
+// Spec describes how a list can be sorted.
+type Spec[T any] struct {
+	Table   string // Qualifies the id column
+	Sorts   map[string]Column[T]
+	Default string // Sort used when none is requested
+	Order   Order  // Order of the default sort; defaults to Desc
+	ID      func(row *T) uuid.UUID
+}
+
+type cursor struct {
+	Sort  string    `json:"s"`
+	Order Order     `json:"o"`
+	Value string    `json:"v"`
+	ID    uuid.UUID `json:"id"`
+}
+
+// Paginate returns the page of query that params ask for. Total counts every row of query, so it
+// must hold the filters of the list but not the pagination. Relations are preloaded into the page
+// only, as gorm cannot preload into a count.
+func Paginate[T any](query *gorm.DB, spec Spec[T], params Params, preloads ...string) (*Page[T], error) {
+	key, order := params.Sort, params.Order
+	if strings.HasPrefix(key, "-") {
+		if order == Asc {
+			return nil, fmt.Errorf("%w: sort %q conflicts with order asc", ErrInvalid, key)
+		}
+		key, order = key[1:], Desc
+	}
+	if key == "" {
+		key = spec.Default
+		if order == "" {
+			order = spec.Order
+		}
+	}
+	if order == "" && params.Sort != "" {
+		order = Asc
+	}
+	if order == "" {
+		order = Desc
+	}
+	column, ok := spec.Sorts[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown sort %q, expected one of %s", ErrInvalid, key, strings.Join(spec.keys(), ", "))
+	}
+	limit := params.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+
+	query = query.Model(new(T)).Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	idExpr := spec.Table + ".id"
+	page := query
+	if params.Cursor != "" {
+		after, err := decode(params.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if after.Sort != key || after.Order != order {
+			return nil, fmt.Errorf("%w: the cursor belongs to another sort order", ErrInvalid)
+		}
+		operator := "<"
+		if order == Asc {
+			operator = ">"
+		}
+		page = page.Where(fmt.Sprintf("(%s, %s) %s (?, ?)", column.Expr, idExpr, operator), after.Value, after.ID)
+	}
+	for _, preload := range preloads {
+		page = page.Preload(preload)
+	}
+
+	rows := []T{}
+	if err := page.
+		Order(fmt.Sprintf("%s %s, %s %s", column.Expr, order, idExpr, order)).
+		Limit(limit + 1).
+		Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	result := &Page[T]{Data: rows, Total: total, Sort: key, Order: order}
+	if len(rows) > limit {
+		result.Data = rows[:limit]
+		result.HasMore = true
+		last := &result.Data[limit-1]
+		result.NextCursor = encode(cursor{Sort: key, Order: order, Value: format(column.Value(last)), ID: spec.ID(last)})
+	}
+	return result, nil
+}
+
+// WithData returns the page with its rows replaced, for lists that return a response type
+// built from each row.
+func WithData[T, U any](page *Page[T], data []U) *Page[U] {
+	return &Page[U]{
+		Data:       data,
+		NextCursor: page.NextCursor,
+		HasMore:    page.HasMore,
+		Total:      page.Total,
+		Sort:       page.Sort,
+		Order:      page.Order,
+	}
+}
+
+// HTTPStatus returns the status code of an error returned while listing: invalid cursors and
+// sorts are bad requests, anything else a server error.
+func HTTPStatus(err error) int {
+	if errors.Is(err, ErrInvalid) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func (s Spec[T]) keys() []string {
+	keys := make([]string, 0, len(s.Sorts))
+	for key := range s.Sorts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func encode(c cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decode(value string) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalid)
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.ID == uuid.Nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalid)
+	}
+	return &c, nil
+}
+
+// format writes a sort value so that the database reads it back as the same value.
+func format(value any) string {
+	switch v := value.(type) {
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case string:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
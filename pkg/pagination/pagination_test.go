@@ -0,0 +1,235 @@
+package pagination
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type item struct {
+	ID        uuid.UUID
+	Amount    decimal.Decimal
+	CreatedAt time.Time
+}
+
+var itemPages = Spec[item]{
+	Table: "items",
+	Sorts: map[string]Column[item]{
+		"amount":     {Expr: "items.amount", Value: func(i *item) any { return i.Amount }},
+		"created_at": {Expr: "items.created_at", Value: func(i *item) any { return i.CreatedAt }},
+	},
+	Default: "created_at",
+	ID:      func(i *item) uuid.UUID { return i.ID },
+}
+
+// statements records the SQL gorm would run, with the arguments inlined.
+type statements struct {
+	logger.Interface
+	sql []string
+}
+
+func (s *statements) Trace(_ context.Context, _ time.Time, fc func() (string, int64), _ error) {
+	sql, _ := fc()
+	s.sql = append(s.sql, sql)
+}
+
+// dryRun returns a database that builds queries without running them.
+func dryRun(t *testing.T) (*gorm.DB, *statements) {
+	t.Helper()
+	log := &statements{Interface: logger.Discard}
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		Logger:               log,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, log
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	want := cursor{Sort: "amount", Order: Asc, Value: "12.5", ID: uuid.New()}
+	encoded := encode(want)
+	if strings.ContainsAny(encoded, "+/=") {
+		t.Errorf("cursor %q is not URL safe", encoded)
+	}
+	got, err := decode(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got != want {
+		t.Errorf("decode(encode(%+v)) = %+v", want, *got)
+	}
+}
+
+func TestDecodeMalformed(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{name: "not base64", cursor: "not a cursor!"},
+		{name: "not JSON", cursor: "bm90IGpzb24"},
+		{name: "no ID", cursor: encode(cursor{Sort: "amount", Order: Asc, Value: "1"})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decode(tt.cursor); !errors.Is(err, ErrInvalid) {
+				t.Errorf("decode(%q) error = %v, want ErrInvalid", tt.cursor, err)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	berlin := time.FixedZone("CEST", 2*60*60)
+	tests := []struct {
+		name  string
+		value any
+		want  string
+	}{
+		{name: "time in UTC with nanoseconds", value: time.Date(2026, 10, 16, 14, 30, 0, 123456789, berlin), want: "2026-10-16T12:30:00.123456789Z"},
+		{name: "decimal", value: decimal.RequireFromString("1234.5600"), want: "1234.56"},
+		{name: "string", value: "Groceries", want: "Groceries"},
+		{name: "integer", value: 42, want: "42"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := format(tt.value); got != tt.want {
+				t.Errorf("format(%v) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	id := uuid.MustParse("6f1c1c8e-2a4f-4f0e-9d6b-0a1b2c3d4e5f")
+
+	tests := []struct {
+		name      string
+		spec      Spec[item]
+		params    Params
+		wantSort  string
+		wantOrder Order
+		wantSQL   string
+	}{
+		{
+			name:     "default sort is descending",
+			spec:     itemPages,
+			wantSort: "created_at", wantOrder: Desc,
+			wantSQL: `ORDER BY items.created_at desc, items.id desc LIMIT 21`,
+		},
+		{
+			name:     "default order of the spec",
+			spec:     Spec[item]{Table: "items", Sorts: itemPages.Sorts, Default: "amount", Order: Asc, ID: itemPages.ID},
+			wantSort: "amount", wantOrder: Asc,
+			wantSQL: `ORDER BY items.amount asc, items.id asc LIMIT 21`,
+		},
+		{
+			name:     "requested sort is ascending",
+			spec:     itemPages,
+			params:   Params{Sort: "amount", Limit: 5},
+			wantSort: "amount", wantOrder: Asc,
+			wantSQL: `ORDER BY items.amount asc, items.id asc LIMIT 6`,
+		},
+		{
+			name:     "minus prefix sorts descending",
+			spec:     itemPages,
+			params:   Params{Sort: "-amount"},
+			wantSort: "amount", wantOrder: Desc,
+			wantSQL: `ORDER BY items.amount desc, items.id desc LIMIT 21`,
+		},
+		{
+			name:     "order without a sort applies to the default",
+			spec:     itemPages,
+			params:   Params{Order: Asc},
+			wantSort: "created_at", wantOrder: Asc,
+			wantSQL: `ORDER BY items.created_at asc, items.id asc LIMIT 21`,
+		},
+		{
+			name:     "limit is capped",
+			spec:     itemPages,
+			params:   Params{Limit: 1000},
+			wantSort: "created_at", wantOrder: Desc,
+			wantSQL: `LIMIT 101`,
+		},
+		{
+			name:     "descending cursor continues below the last row",
+			spec:     itemPages,
+			params:   Params{Sort: "-amount", Cursor: encode(cursor{Sort: "amount", Order: Desc, Value: "12.5", ID: id})},
+			wantSort: "amount", wantOrder: Desc,
+			wantSQL: `WHERE (items.amount, items.id) < ('12.5', '` + id.String() + `') ORDER BY items.amount desc`,
+		},
+		{
+			name:     "ascending cursor continues above the last row",
+			spec:     itemPages,
+			params:   Params{Sort: "amount", Cursor: encode(cursor{Sort: "amount", Order: Asc, Value: "12.5", ID: id})},
+			wantSort: "amount", wantOrder: Asc,
+			wantSQL: `WHERE (items.amount, items.id) > ('12.5', '` + id.String() + `') ORDER BY items.amount asc`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, log := dryRun(t)
+			page, err := Paginate(db.Table("items"), tt.spec, tt.params)
+			if err != nil {
+				t.Fatalf("Paginate: %v", err)
+			}
+			if page.Sort != tt.wantSort || page.Order != tt.wantOrder {
+				t.Errorf("page is sorted by %s %s, want %s %s", page.Sort, page.Order, tt.wantSort, tt.wantOrder)
+			}
+			if len(log.sql) != 2 || !strings.HasPrefix(log.sql[0], "SELECT count(*)") {
+				t.Fatalf("queries = %q, want a count and a select", log.sql)
+			}
+			if strings.Contains(log.sql[0], "items.id") {
+				t.Errorf("count %q is paginated", log.sql[0])
+			}
+			if !strings.Contains(log.sql[1], tt.wantSQL) {
+				t.Errorf("query %q\nwant it to contain %q", log.sql[1], tt.wantSQL)
+			}
+		})
+	}
+}
+
+func TestPaginateInvalid(t *testing.T) {
+	id := uuid.New()
+	tests := []struct {
+		name   string
+		params Params
+		want   string
+	}{
+		{name: "unknown sort", params: Params{Sort: "description"}, want: `unknown sort "description", expected one of amount, created_at`},
+		{name: "descending sort in ascending order", params: Params{Sort: "-amount", Order: Asc}, want: "conflicts with order asc"},
+		{name: "malformed cursor", params: Params{Cursor: "%%%"}, want: "malformed cursor"},
+		{
+			name:   "cursor of another sort",
+			params: Params{Sort: "amount", Cursor: encode(cursor{Sort: "created_at", Order: Asc, Value: "2026-10-16T00:00:00Z", ID: id})},
+			want:   "another sort order",
+		},
+		{
+			name:   "cursor of another order",
+			params: Params{Sort: "-amount", Cursor: encode(cursor{Sort: "amount", Order: Asc, Value: "1", ID: id})},
+			want:   "another sort order",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, _ := dryRun(t)
+			_, err := Paginate(db.Table("items"), itemPages, tt.params)
+			if !errors.Is(err, ErrInvalid) || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Paginate error = %v, want ErrInvalid containing %q", err, tt.want)
+			}
+			if HTTPStatus(err) != 400 {
+				t.Errorf("HTTPStatus = %d, want 400", HTTPStatus(err))
+			}
+		})
+	}
+}